@@ -9,6 +9,10 @@ import (
 	api "github.com/terrariumai/simulation/pkg/api/collective"
 	"github.com/terrariumai/simulation/pkg/collective"
 	"github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/logging"
+	"github.com/terrariumai/simulation/pkg/middleware"
+	"github.com/terrariumai/simulation/pkg/terrain"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
 
@@ -23,6 +27,12 @@ type Config struct {
 	EnvironmentAddr string
 	// Environment that the server is running in (dev or prod)
 	Env string
+	// World the server is running
+	WorldID string
+	// Token the collective calls the environment with
+	ServiceToken string
+	// Path to an ascii or png map of the world's terrain
+	TerrainFile string
 	// Log parameters section
 	// LogLevel is global log level: Debug(-1), Info(0), Warn(1), Error(2), DPanic(3), Panic(4), Fatal(5)
 	LogLevel int
@@ -36,41 +46,70 @@ func main() {
 	flag.StringVar(&cfg.GRPCPort, "grpc-port", "9090", "gRPC port to bind")
 	flag.StringVar(&cfg.RedisAddr, "redis-addr", "127.0.0.1:12345", "Redis address to connect to")
 	flag.StringVar(&cfg.EnvironmentAddr, "environment-addr", "127.0.0.1:9091", "Environment service address to connect to")
+	flag.StringVar(&cfg.WorldID, "world", datacom.DefaultWorldID, "World to run, it has to have been created with CreateWorld")
 	flag.StringVar(&cfg.Env, "env", "", "Environment the server is running in")
+	flag.StringVar(&cfg.TerrainFile, "terrain-file", "", "Ascii or png map of the world's terrain, the same one the environment was given")
+	flag.StringVar(&cfg.ServiceToken, "service-token", os.Getenv("SERVICE_TOKEN"), "Token to call the environment with, the same one the environment was given. Defaults to $SERVICE_TOKEN")
 	flag.IntVar(&cfg.LogLevel, "log-level", 0, "Global log level")
 	flag.StringVar(&cfg.LogTimeFormat, "log-time-format", "",
 		"Print time format for logger e.g. 2006-01-02T15:04:05Z07:00")
 	flag.Parse()
 
-	if len(cfg.GRPCPort) == 0 {
-		log.Fatalf("invalid TCP port for gRPC server: '%s'", cfg.GRPCPort)
+	// Everything the server logs goes through this logger
+	logger, err := logging.New(cfg.LogLevel, cfg.LogTimeFormat)
+	if err != nil {
+		log.Fatalf("Error initializing logger: %v", err)
 		os.Exit(1)
-		return
+	}
+	defer logger.Sync()
+	logger = logger.With(zap.String("service", "collective"), zap.String("env", cfg.Env))
+
+	if len(cfg.GRPCPort) == 0 {
+		logger.Fatal("invalid TCP port for gRPC server", zap.String("port", cfg.GRPCPort))
 	}
 	if len(cfg.RedisAddr) == 0 {
-		log.Fatalf("invalid Redis address: '%s'", cfg.RedisAddr)
-		os.Exit(1)
-		return
+		logger.Fatal("invalid Redis address", zap.String("addr", cfg.RedisAddr))
 	}
 	if len(cfg.EnvironmentAddr) == 0 {
-		log.Fatalf("invalid Environment address: '%s'", cfg.EnvironmentAddr)
-		os.Exit(1)
-		return
+		logger.Fatal("invalid environment address", zap.String("addr", cfg.EnvironmentAddr))
+	}
+	// Outside training and testing the collective can only drive agents with
+	// the service token
+	if len(cfg.ServiceToken) == 0 && cfg.Env != "training" && cfg.Env != "testing" {
+		logger.Fatal("missing service token, set -service-token or $SERVICE_TOKEN")
 	}
 
 	listen, err := net.Listen("tcp", ":"+cfg.GRPCPort)
 	if err != nil {
-		log.Fatalf("os.Stderr, '%v\n'", err)
-		os.Exit(1)
+		logger.Fatal("listening", zap.Error(err), zap.String("port", cfg.GRPCPort))
 	}
 
-	pubnubPAL := datacom.NewPubnubPAL(cfg.Env, "sub-c-b4ba4e28-a647-11e9-ad2c-6ad2737329fc", "pub-c-83ed11c2-81e1-4d7f-8e94-0abff2b85825")
-	serverAPI := collective.NewCollectiveServer(cfg.Env, cfg.RedisAddr, cfg.EnvironmentAddr, pubnubPAL)
+	pubnubPAL := datacom.NewPubnubPAL(cfg.Env, cfg.WorldID, "sub-c-b4ba4e28-a647-11e9-ad2c-6ad2737329fc", "pub-c-83ed11c2-81e1-4d7f-8e94-0abff2b85825", logger)
+	datacom, err := datacom.NewDatacom(cfg.Env, cfg.WorldID, cfg.RedisAddr, pubnubPAL)
+	if err != nil {
+		logger.Fatal("initializing datacom", zap.Error(err))
+	}
+	datacom.SetLogger(logger)
+	if exists, err := datacom.WorldExists(cfg.WorldID); err != nil || !exists {
+		logger.Fatal("world doesn't exist", zap.Error(err), zap.String("world", cfg.WorldID))
+	}
+	// Observations see the terrain
+	if len(cfg.TerrainFile) > 0 {
+		m, err := terrain.Load(cfg.TerrainFile)
+		if err != nil {
+			logger.Fatal("loading terrain", zap.Error(err))
+		}
+		if err := datacom.SetTerrain(m); err != nil {
+			logger.Fatal("loading terrain", zap.Error(err))
+		}
+	}
+	serverAPI := collective.NewCollectiveServer(cfg.Env, datacom, cfg.EnvironmentAddr, collective.WithLogger(logger), collective.WithServiceToken(cfg.ServiceToken))
 
-	opts := []grpc.ServerOption{}
+	// Log every call, models authenticate with their secret when they connect
+	opts := middleware.ServerOptions(logger, nil, nil)
 	server := grpc.NewServer(opts...)
 	api.RegisterCollectiveServer(server, serverAPI)
 
-	log.Printf("Starting Collective Server on port %v", cfg.GRPCPort)
+	logger.Info("starting collective server", zap.String("world", cfg.WorldID), zap.String("port", cfg.GRPCPort))
 	server.Serve(listen)
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/logging"
+)
+
+func main() {
+	redisAddr := flag.String("redis-addr", "", "Redis address to connect to")
+	worldID := flag.String("world", datacom.DefaultWorldID, "World to check")
+	repair := flag.String("repair", "", "Comma separated kinds of inconsistency to repair, or \"all\". Only reports if empty")
+	flag.Parse()
+
+	if len(*redisAddr) == 0 {
+		log.Fatalf("invalid Redis Address: '%s'", *redisAddr)
+	}
+
+	// Pick the kinds to repair
+	kinds := []datacom.InconsistencyKind{}
+	if *repair == "all" {
+		kinds = datacom.InconsistencyKinds
+	} else if len(*repair) > 0 {
+		for _, name := range strings.Split(*repair, ",") {
+			kind := datacom.InconsistencyKind(strings.TrimSpace(name))
+			known := false
+			for _, k := range datacom.InconsistencyKinds {
+				known = known || k == kind
+			}
+			if !known {
+				log.Fatalf("unknown inconsistency kind '%v', expected one of %v", kind, datacom.InconsistencyKinds)
+			}
+			kinds = append(kinds, kind)
+		}
+	}
+
+	// Failures are logged as they are found, on top of the report
+	logger, err := logging.New(0, "")
+	if err != nil {
+		log.Fatalf("Error initializing logger: %v", err)
+	}
+	defer logger.Sync()
+
+	// Only redis is used, the training env keeps firebase and pubnub out of it
+	dc, err := datacom.NewDatacom("training", *worldID, *redisAddr, datacom.NewPubnubPAL("training", *worldID, "", "", logger))
+	if err != nil {
+		log.Fatalf("Error initializing Datacom: %v", err)
+	}
+	dc.SetLogger(logger)
+
+	if len(kinds) > 0 {
+		repaired, err := dc.RepairConsistency(kinds...)
+		for _, problem := range repaired {
+			fmt.Printf("repaired %v\n", problem)
+		}
+		if err != nil {
+			log.Fatalf("Error repairing world: %v", err)
+		}
+	}
+
+	problems, err := dc.CheckConsistency()
+	if err != nil {
+		log.Fatalf("Error checking world: %v", err)
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	fmt.Printf("%v inconsistencies found\n", len(problems))
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
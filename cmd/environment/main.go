@@ -5,10 +5,16 @@ import (
 	"log"
 	"net"
 	"os"
+	"time"
 
 	api "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/auth"
 	"github.com/terrariumai/simulation/pkg/datacom"
 	"github.com/terrariumai/simulation/pkg/environment"
+	"github.com/terrariumai/simulation/pkg/logging"
+	"github.com/terrariumai/simulation/pkg/middleware"
+	"github.com/terrariumai/simulation/pkg/terrain"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
 
@@ -21,6 +27,27 @@ type Config struct {
 	RedisAddr string
 	// Environment that the server is running in (dev or prod)
 	Env string
+	// World the server is running
+	WorldID string
+	// Path to a json or yaml file with the world rules
+	RulesFile string
+	// Token the collective calls the environment with
+	ServiceToken string
+	// Path to a json or yaml file with the users given each role
+	RolesFile string
+	// How callers are authenticated: esp, hmac, rsa or none
+	Auth string
+	// Key signed tokens are verified with, for hmac and rsa
+	AuthKeyFile string
+	// Audience and issuer signed tokens have to carry, for hmac and rsa
+	AuthAudience string
+	AuthIssuer   string
+	// Path to an ascii or png map of the world's terrain
+	TerrainFile string
+	// Seed for the world's randomness, 0 seeds from the clock
+	Seed int64
+	// Time between ticks, realtime if 0
+	TickInterval time.Duration
 	// Log parameters section
 	// LogLevel is global log level: Debug(-1), Info(0), Warn(1), Error(2), DPanic(3), Panic(4), Fatal(5)
 	LogLevel int
@@ -33,48 +60,137 @@ func main() {
 	var cfg Config
 	flag.StringVar(&cfg.GRPCPort, "grpc-port", "9091", "gRPC port to bind")
 	flag.StringVar(&cfg.RedisAddr, "redis-addr", "", "Redis address to connect to")
+	flag.StringVar(&cfg.WorldID, "world", datacom.DefaultWorldID, "World to run, it has to have been created with CreateWorld")
 	flag.StringVar(&cfg.Env, "env", "training", "Environment the server is running in")
+	flag.StringVar(&cfg.RulesFile, "rules-file", "", "Json or yaml file with the world rules, uses the defaults if empty")
+	flag.StringVar(&cfg.TerrainFile, "terrain-file", "", "Ascii or png map of the world's terrain, all ground if empty")
+	flag.StringVar(&cfg.RolesFile, "roles-file", "", "Json or yaml file with the admins and operators, everyone is a player if empty")
+	flag.StringVar(&cfg.Auth, "auth", "esp", "How callers are authenticated: esp (behind Cloud Endpoints), hmac or rsa (signed tokens), or none (dev only)")
+	flag.StringVar(&cfg.AuthKeyFile, "auth-key-file", "", "Shared secret for hmac, or pem public key for rsa, that tokens are verified with")
+	flag.StringVar(&cfg.AuthAudience, "auth-audience", "", "Audience (aud) hmac and rsa tokens have to be for")
+	flag.StringVar(&cfg.AuthIssuer, "auth-issuer", "", "Issuer (iss) hmac and rsa tokens have to come from")
+	flag.StringVar(&cfg.ServiceToken, "service-token", os.Getenv("SERVICE_TOKEN"), "Token the collective calls with, defaults to $SERVICE_TOKEN")
+	flag.Int64Var(&cfg.Seed, "seed", 0, "Seed for the world's randomness, seeds from the clock if 0")
+	flag.DurationVar(&cfg.TickInterval, "tick-interval", 0, "Resolve actions together in ticks of this length (e.g. 250ms), realtime if 0")
 	flag.IntVar(&cfg.LogLevel, "log-level", 0, "Global log level")
 	flag.StringVar(&cfg.LogTimeFormat, "log-time-format", "",
 		"Print time format for logger e.g. 2006-01-02T15:04:05Z07:00")
 	flag.Parse()
 
-	if len(cfg.Env) == 0 {
-		log.Fatalf("invalid Environment: '%s'", cfg.GRPCPort)
+	// Everything the server logs goes through this logger
+	logger, err := logging.New(cfg.LogLevel, cfg.LogTimeFormat)
+	if err != nil {
+		log.Fatalf("Error initializing logger: %v", err)
 		os.Exit(1)
-		return
+	}
+	defer logger.Sync()
+	logger = logger.With(zap.String("service", "environment"), zap.String("env", cfg.Env))
+
+	if len(cfg.Env) == 0 {
+		logger.Fatal("invalid environment", zap.String("env", cfg.Env))
 	}
 	if len(cfg.GRPCPort) == 0 {
-		log.Fatalf("invalid TCP port for gRPC server: '%s'", cfg.GRPCPort)
-		os.Exit(1)
-		return
+		logger.Fatal("invalid TCP port for gRPC server", zap.String("port", cfg.GRPCPort))
 	}
 	if len(cfg.RedisAddr) == 0 {
-		log.Fatalf("invalid Redis Address: '%s'", cfg.RedisAddr)
-		os.Exit(1)
-		return
+		logger.Fatal("invalid Redis address", zap.String("addr", cfg.RedisAddr))
+	}
+	// Outside training and testing the collective can only drive agents with
+	// the service token
+	if len(cfg.ServiceToken) == 0 && cfg.Env != "training" && cfg.Env != "testing" {
+		logger.Fatal("missing service token, set -service-token or $SERVICE_TOKEN")
 	}
 
 	listen, err := net.Listen("tcp", ":"+cfg.GRPCPort)
 	if err != nil {
-		log.Fatalf("os.Stderr, '%v\n'", err)
-		os.Exit(1)
+		logger.Fatal("listening", zap.Error(err), zap.String("port", cfg.GRPCPort))
 	}
 
 	// Initialize pubnub pal
-	pubnubPAL := datacom.NewPubnubPAL(cfg.Env, "sub-c-b4ba4e28-a647-11e9-ad2c-6ad2737329fc", "pub-c-83ed11c2-81e1-4d7f-8e94-0abff2b85825")
-	datacom, err := datacom.NewDatacom(cfg.Env, cfg.RedisAddr, pubnubPAL)
+	pubnubPAL := datacom.NewPubnubPAL(cfg.Env, cfg.WorldID, "sub-c-b4ba4e28-a647-11e9-ad2c-6ad2737329fc", "pub-c-83ed11c2-81e1-4d7f-8e94-0abff2b85825", logger)
+	datacom, err := datacom.NewDatacom(cfg.Env, cfg.WorldID, cfg.RedisAddr, pubnubPAL)
 	if err != nil {
-		log.Fatalf("Error initializing Datacom: %v", err)
-		os.Exit(1)
+		logger.Fatal("initializing datacom", zap.Error(err))
+	}
+	datacom.SetLogger(logger)
+	if exists, err := datacom.WorldExists(cfg.WorldID); err != nil || !exists {
+		logger.Fatal("world doesn't exist", zap.Error(err), zap.String("world", cfg.WorldID))
+	}
+
+	// Load the world rules
+	rules := environment.DefaultWorldRules()
+	if len(cfg.RulesFile) > 0 {
+		rules, err = environment.LoadWorldRules(cfg.RulesFile)
+		if err != nil {
+			logger.Fatal("loading world rules", zap.Error(err))
+		}
+	}
+
+	// Seed the world, logging the seed so the run can be reproduced
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	logger.Info("using world seed", zap.Int64("seed", cfg.Seed))
+
+	serverOpts := []environment.ServerOption{environment.WithLogger(logger), environment.WithSeed(cfg.Seed), environment.WithWorld(cfg.WorldID), environment.WithWorldSize(datacom.WorldSize()), environment.WithWorldTopology(datacom.WorldTopology())}
+	if cfg.TickInterval > 0 {
+		logger.Info("running in tick mode", zap.Duration("interval", cfg.TickInterval))
+		serverOpts = append(serverOpts, environment.WithTickInterval(cfg.TickInterval))
+	}
+
+	// Load the terrain, the collective has to be given the same map
+	if len(cfg.TerrainFile) > 0 {
+		m, err := terrain.Load(cfg.TerrainFile)
+		if err != nil {
+			logger.Fatal("loading terrain", zap.Error(err))
+		}
+		if err := datacom.SetTerrain(m); err != nil {
+			logger.Fatal("loading terrain", zap.Error(err))
+		}
+		serverOpts = append(serverOpts, environment.WithTerrain(m))
+	}
+
+	if len(cfg.ServiceToken) > 0 {
+		serverOpts = append(serverOpts, environment.WithServiceToken(cfg.ServiceToken))
 	}
+	serverAPI := environment.NewEnvironmentServer(cfg.Env, datacom, rules, serverOpts...)
 
-	serverAPI := environment.NewEnvironmentServer(cfg.Env, datacom)
+	// Work out who is calling
+	var authenticator auth.Authenticator
+	switch cfg.Auth {
+	case "esp":
+		authenticator = auth.NewESPAuthenticator()
+	case "hmac", "rsa":
+		authenticator, err = auth.LoadJWTAuthenticator(cfg.Auth, cfg.AuthKeyFile, cfg.AuthAudience, cfg.AuthIssuer)
+		if err != nil {
+			logger.Fatal("loading auth key", zap.Error(err))
+		}
+	case "none":
+		logger.Warn("authentication is off, callers are whoever they say they are")
+		authenticator = auth.NewNoAuthAuthenticator()
+	default:
+		logger.Fatal("invalid auth", zap.String("auth", cfg.Auth))
+	}
+
+	// Check roles on every call, anyone can manage a training or testing world
+	roles := &auth.Roles{}
+	permissions := environment.MethodPermissions
+	if cfg.Env == "training" || cfg.Env == "testing" {
+		permissions = nil
+	} else if len(cfg.RolesFile) > 0 {
+		roles, err = auth.LoadRoles(cfg.RolesFile)
+		if err != nil {
+			logger.Fatal("loading roles", zap.Error(err))
+		}
+	} else {
+		logger.Warn("no roles file, nobody can manage the world")
+	}
 
-	opts := []grpc.ServerOption{}
+	// Log every call
+	opts := middleware.ServerOptions(logger, auth.UnaryServerInterceptor(authenticator, roles, permissions), auth.StreamServerInterceptor(authenticator, roles, permissions))
 	server := grpc.NewServer(opts...)
 	api.RegisterEnvironmentServer(server, serverAPI)
 
-	log.Printf("Starting Environment Server on port %v", cfg.GRPCPort)
+	logger.Info("starting environment server", zap.String("world", cfg.WorldID), zap.String("port", cfg.GRPCPort))
 	server.Serve(listen)
 }
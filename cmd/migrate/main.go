@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/logging"
+)
+
+func main() {
+	redisAddr := flag.String("redis-addr", "", "Redis address to connect to")
+	flag.Parse()
+
+	if len(*redisAddr) == 0 {
+		log.Fatalf("invalid Redis Address: '%s'", *redisAddr)
+	}
+
+	// Failures are logged as they are found, on top of the report
+	logger, err := logging.New(0, "")
+	if err != nil {
+		log.Fatalf("Error initializing logger: %v", err)
+	}
+	defer logger.Sync()
+
+	// Only redis is used, the training env keeps firebase and pubnub out of it
+	dc, err := datacom.NewDatacom("training", datacom.DefaultWorldID, *redisAddr, datacom.NewPubnubPAL("training", datacom.DefaultWorldID, "", "", logger))
+	if err != nil {
+		log.Fatalf("Error initializing Datacom: %v", err)
+	}
+	dc.SetLogger(logger)
+
+	result, err := dc.MigrateTextStorage()
+	if result != nil {
+		for _, key := range result.Keys {
+			fmt.Printf("converted %v\n", key)
+		}
+		for _, skipped := range result.Skipped {
+			fmt.Printf("skipped %q\n", skipped)
+		}
+		fmt.Printf("%v members converted, %v skipped and kept under v1:unmigrated: keys\n", result.Converted, len(result.Skipped))
+	}
+	if err != nil {
+		log.Fatalf("Error migrating world: %v", err)
+	}
+	if len(result.Skipped) > 0 {
+		os.Exit(1)
+	}
+}
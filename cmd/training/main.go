@@ -1,58 +1,146 @@
 package main
 
 import (
-	"io/ioutil"
-	"log"
+	"context"
+	"flag"
+	"fmt"
 	"net"
 	"os"
+	"time"
 
-	"github.com/alicebob/miniredis/v2"
 	collectiveApi "github.com/terrariumai/simulation/pkg/api/collective"
 	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/auth"
 	"github.com/terrariumai/simulation/pkg/collective"
 	"github.com/terrariumai/simulation/pkg/console"
 	"github.com/terrariumai/simulation/pkg/datacom"
 	"github.com/terrariumai/simulation/pkg/environment"
+	"github.com/terrariumai/simulation/pkg/logging"
+	"github.com/terrariumai/simulation/pkg/middleware"
+	"github.com/terrariumai/simulation/pkg/terrain"
+	"github.com/terrariumai/simulation/pkg/worldgen"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
 
 func main() {
-	// Disable logs because they mess with the console
-	log.SetFlags(0)
-	log.SetOutput(ioutil.Discard)
+	rulesFile := flag.String("rules-file", "", "Json or yaml file with the world rules, uses the defaults if empty")
+	seed := flag.Int64("seed", 0, "Seed for the world's randomness, seeds from the clock if 0")
+	tickInterval := flag.Duration("tick-interval", 0, "Resolve actions together in ticks of this length (e.g. 250ms), realtime if 0")
+	width := flag.Uint("width", uint(datacom.DefaultWorldSize.Width), "Width of the world")
+	height := flag.Uint("height", uint(datacom.DefaultWorldSize.Height), "Height of the world")
+	topology := flag.String("topology", string(datacom.Bounded), "What happens at the edges of the world, bounded or torus")
+	terrainFile := flag.String("terrain-file", "", "Ascii or png map of the world's terrain, the world takes its size. All ground if empty")
+	generatorFile := flag.String("generator-file", "", "Json or yaml file of world generator params, every reset generates fresh terrain and food from its seed")
+	logFile := flag.String("log-file", "", "File to write logs to, they would mess with the console so they are dropped if empty")
+	logLevel := flag.Int("log-level", 0, "Log level, Debug(-1) to Fatal(5)")
+	flag.Parse()
+
+	// Logs are kept out of the console, in a file when one is given
+	logger := zap.NewNop()
+	if len(*logFile) > 0 {
+		var err error
+		logger, err = logging.New(*logLevel, "", *logFile)
+		if err != nil {
+			fmt.Printf("Error initializing logger: %v\n", err)
+			os.Exit(1)
+		}
+		defer logger.Sync()
+	}
+
+	// Load the world rules
+	rules := environment.DefaultWorldRules()
+	if len(*rulesFile) > 0 {
+		var err error
+		rules, err = environment.LoadWorldRules(*rulesFile)
+		if err != nil {
+			fmt.Printf("Error loading world rules: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load the world generator
+	var generator *worldgen.Params
+	if len(*generatorFile) > 0 {
+		if len(*terrainFile) > 0 {
+			fmt.Printf("Error: a world can't have both a terrain file and a generator\n")
+			os.Exit(1)
+		}
+		params, err := worldgen.LoadParams(*generatorFile)
+		if err != nil {
+			fmt.Printf("Error loading world generator: %v\n", err)
+			os.Exit(1)
+		}
+		generator = &params
+	}
+
+	// Seed the world, printing the seed so the run can be reproduced
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	fmt.Printf("Using world seed %v\n", *seed)
 
 	// Create listeners
 	cListen, err := net.Listen("tcp", ":9090")
 	if err != nil {
-		log.Fatalf("os.Stderr, '%v\n'", err)
+		fmt.Printf("Error listening: %v\n", err)
 		os.Exit(1)
 	}
 	eListen, err := net.Listen("tcp", ":9091")
 	if err != nil {
-		log.Fatalf("os.Stderr, '%v\n'", err)
+		fmt.Printf("Error listening: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Start the redis service
-	redisServer, err := miniredis.Run()
+	// Create PAL (pubsub access layer) and DAL (data access layer). The world
+	// lives in memory, nothing else touches it during training.
+	pubnubPAL := datacom.NewPubnubPAL("training", datacom.DefaultWorldID, "", "", logger)
+	size := datacom.WorldSize{Width: uint32(*width), Height: uint32(*height)}
+	var terrainMap *terrain.Map
+	if len(*terrainFile) > 0 {
+		terrainMap, err = terrain.Load(*terrainFile)
+		if err != nil {
+			fmt.Printf("Error loading terrain: %v\n", err)
+			os.Exit(1)
+		}
+		size = datacom.WorldSize{Width: terrainMap.Width, Height: terrainMap.Height}
+	}
+	datacom, err := datacom.NewMemoryDatacom(pubnubPAL, size, datacom.Topology(*topology))
 	if err != nil {
-		panic(err)
+		fmt.Printf("Error creating the world: %v\n", err)
+		os.Exit(1)
+	}
+	datacom.SetLogger(logger)
+	if err := datacom.SetTerrain(terrainMap); err != nil {
+		fmt.Printf("Error loading terrain: %v\n", err)
+		os.Exit(1)
 	}
-	defer redisServer.Close()
-
-	// Create PAL (pubsub access layer) and DAL (data access layer)
-	pubnubPAL := datacom.NewPubnubPAL("training", "", "")
-	datacom, err := datacom.NewDatacom("training", redisServer.Addr(), pubnubPAL)
 
 	// Create APIs
-	cServerAPI := collective.NewCollectiveServer("training", redisServer.Addr(), "127.0.0.1:9091", pubnubPAL)
-	eServerAPI := environment.NewEnvironmentServer("training", datacom)
+	cServerAPI := collective.NewCollectiveServer("training", datacom, "127.0.0.1:9091", collective.WithLogger(logger))
+	eServerOpts := []environment.ServerOption{environment.WithLogger(logger), environment.WithSeed(*seed), environment.WithWorldSize(size), environment.WithWorldTopology(datacom.WorldTopology()), environment.WithTerrain(terrainMap)}
+	if *tickInterval > 0 {
+		eServerOpts = append(eServerOpts, environment.WithTickInterval(*tickInterval))
+	}
+	if generator != nil {
+		eServerOpts = append(eServerOpts, environment.WithGenerator(*generator))
+	}
+	eServerAPI := environment.NewEnvironmentServer("training", datacom, rules, eServerOpts...)
+
+	// Generate the first episode from the world seed
+	if generator != nil {
+		if _, err := eServerAPI.ResetWorld(context.Background(), &envApi.ResetWorldRequest{Seed: *seed}); err != nil {
+			fmt.Printf("Error generating the world: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	// Create servers
-	opts := []grpc.ServerOption{}
-	cServer := grpc.NewServer(opts...)
+	// Training runs locally, callers are whoever they say they are
+	cServer := grpc.NewServer(middleware.ServerOptions(logger, nil, nil)...)
 	collectiveApi.RegisterCollectiveServer(cServer, cServerAPI)
-	eServer := grpc.NewServer(opts...)
+	authenticator := auth.NewNoAuthAuthenticator()
+	eServer := grpc.NewServer(middleware.ServerOptions(logger, auth.UnaryServerInterceptor(authenticator, nil, nil), auth.StreamServerInterceptor(authenticator, nil, nil))...)
 	envApi.RegisterEnvironmentServer(eServer, eServerAPI)
 
 	// Start 'em up!
@@ -60,7 +148,7 @@ func main() {
 	defer eServer.Stop()
 	go cServer.Serve(cListen)
 	defer cServer.Stop()
-	log.Println("Training environment is running locally on port 9090.")
+	logger.Info("training environment is running locally", zap.String("port", "9090"))
 
 	// Start the console to listen for commands
 	console.StartConsole(eServerAPI)
@@ -8,6 +8,7 @@ require (
 	github.com/alicebob/miniredis v2.5.0+incompatible
 	github.com/alicebob/miniredis/v2 v2.9.0
 	github.com/go-redis/redis v6.15.2+incompatible
+	github.com/golang-jwt/jwt v3.2.2+incompatible
 	github.com/go-sql-driver/mysql v1.4.1
 	github.com/golang/protobuf v1.3.1
 	github.com/gomodule/redigo v2.0.0+incompatible // indirect
@@ -28,4 +29,5 @@ require (
 	google.golang.org/api v0.5.0
 	google.golang.org/genproto v0.0.0-20190516172635-bb713bdc0e52
 	google.golang.org/grpc v1.20.1
+	gopkg.in/yaml.v2 v2.2.1
 )
@@ -32,6 +32,10 @@ const (
 	Entity_AGENT Entity_Class = 1
 	Entity_ROCK  Entity_Class = 2
 	Entity_FOOD  Entity_Class = 3
+	// Terrain, for cells with no entity on them
+	Entity_WALL        Entity_Class = 4
+	Entity_WATER       Entity_Class = 5
+	Entity_SLOW_GROUND Entity_Class = 6
 )
 
 var Entity_Class_name = map[int32]string{
@@ -39,13 +43,19 @@ var Entity_Class_name = map[int32]string{
 	1: "AGENT",
 	2: "ROCK",
 	3: "FOOD",
+	4: "WALL",
+	5: "WATER",
+	6: "SLOW_GROUND",
 }
 
 var Entity_Class_value = map[string]int32{
-	"EMPTY": 0,
-	"AGENT": 1,
-	"ROCK":  2,
-	"FOOD":  3,
+	"EMPTY":       0,
+	"AGENT":       1,
+	"ROCK":        2,
+	"FOOD":        3,
+	"WALL":        4,
+	"WATER":       5,
+	"SLOW_GROUND": 6,
 }
 
 func (x Entity_Class) String() string {
@@ -530,46 +540,47 @@ func init() {
 func init() { proto.RegisterFile("collective.proto", fileDescriptor_e2c8c35156a1a162) }
 
 var fileDescriptor_e2c8c35156a1a162 = []byte{
-	// 612 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x94, 0xcf, 0x6f, 0xda, 0x30,
-	0x14, 0xc7, 0x71, 0x02, 0x01, 0x5e, 0x69, 0xe5, 0x59, 0x53, 0x15, 0xf5, 0x84, 0x7c, 0xe2, 0xb2,
-	0x6c, 0xa5, 0xc7, 0x69, 0x93, 0x32, 0x92, 0x52, 0xd4, 0x42, 0x98, 0x95, 0xb5, 0xda, 0xa9, 0x4a,
-	0xc1, 0x6d, 0xad, 0x85, 0xa4, 0x4a, 0x5c, 0xa4, 0x9e, 0x76, 0xdc, 0x69, 0xc7, 0x5d, 0xf6, 0x07,
-	0xec, 0xef, 0x9c, 0x6c, 0x07, 0xda, 0x6e, 0x07, 0xe8, 0x09, 0x3f, 0x9b, 0xef, 0xe7, 0x7d, 0xdf,
-	0x0f, 0x00, 0x3c, 0xcb, 0xd3, 0x94, 0xcf, 0xa4, 0x58, 0x72, 0xef, 0xae, 0xc8, 0x65, 0x4e, 0xba,
-	0x3c, 0x9b, 0xdf, 0xe5, 0x22, 0x93, 0xa5, 0x27, 0x79, 0x51, 0x24, 0x85, 0xb8, 0x5f, 0x24, 0xc2,
-	0x7b, 0xfc, 0x1e, 0xfd, 0x85, 0xc0, 0x09, 0x33, 0x29, 0xe4, 0x03, 0xd9, 0x03, 0x4b, 0xcc, 0x5d,
-	0xd4, 0x45, 0xbd, 0x36, 0xb3, 0xc4, 0x9c, 0x9c, 0x40, 0x73, 0x96, 0x26, 0x65, 0x39, 0x0a, 0x5c,
-	0xab, 0x8b, 0x7a, 0x7b, 0x7d, 0xcf, 0xdb, 0x84, 0xf3, 0x0c, 0xca, 0x1b, 0x28, 0x1d, 0x5b, 0xc9,
-	0xe9, 0x21, 0x34, 0xf4, 0x0d, 0x69, 0x43, 0x23, 0x1c, 0x4f, 0xe3, 0xaf, 0xb8, 0xa6, 0x8e, 0xfe,
-	0x30, 0x9c, 0xc4, 0x18, 0x91, 0x16, 0xd4, 0x59, 0x34, 0x38, 0xc5, 0x96, 0x3a, 0x1d, 0x47, 0x51,
-	0x80, 0x6d, 0xfa, 0x47, 0xf9, 0xba, 0xbe, 0xe6, 0x33, 0xf9, 0xd4, 0x07, 0xda, 0xda, 0x87, 0x96,
-	0xfe, 0xe3, 0x83, 0xbc, 0x86, 0xc6, 0x32, 0x49, 0xef, 0xb9, 0xae, 0x67, 0x97, 0x99, 0x80, 0x1c,
-	0x40, 0xab, 0x94, 0x05, 0xcf, 0x6e, 0xe4, 0xad, 0x6b, 0xeb, 0x87, 0x75, 0x4c, 0xbb, 0x2b, 0xe7,
-	0x2d, 0xa8, 0x4f, 0xa2, 0x49, 0x88, 0x6b, 0x64, 0x17, 0xda, 0xd3, 0x93, 0x90, 0x45, 0x63, 0x15,
-	0x22, 0xfa, 0xc3, 0x86, 0x9d, 0xe8, 0xaa, 0xe4, 0xc5, 0x32, 0x91, 0x22, 0xcf, 0x88, 0x0b, 0x4d,
-	0x51, 0xfa, 0xa9, 0x58, 0x72, 0xed, 0xb6, 0xc5, 0x56, 0x21, 0xd9, 0x07, 0x87, 0x67, 0xbc, 0xb8,
-	0x79, 0xa8, 0xd2, 0x57, 0x91, 0xba, 0xbf, 0xe5, 0x49, 0xba, 0xce, 0x5e, 0x45, 0xd5, 0x3c, 0xea,
-	0xeb, 0x79, 0x7c, 0x84, 0x46, 0x29, 0x6e, 0x6e, 0xa5, 0xdb, 0xe8, 0xda, 0xbd, 0x9d, 0x7e, 0x6f,
-	0xdb, 0x69, 0x30, 0x23, 0xd3, 0xfa, 0x05, 0x4f, 0x53, 0xd7, 0xd9, 0x5a, 0xaf, 0xbb, 0xc8, 0x8c,
-	0x8c, 0x5c, 0x42, 0x27, 0x99, 0xa9, 0x1a, 0xc7, 0x7c, 0x91, 0x17, 0x0f, 0x6e, 0x53, 0x0f, 0xe3,
-	0xfd, 0x66, 0xcc, 0x93, 0xf6, 0x78, 0x8c, 0x97, 0x77, 0x79, 0x56, 0xf2, 0x73, 0xd5, 0x7a, 0xf6,
-	0x0c, 0x48, 0x3f, 0xc0, 0xee, 0xb3, 0x67, 0xe2, 0x80, 0x15, 0x9d, 0xe2, 0x1a, 0xd9, 0x07, 0x12,
-	0x32, 0x76, 0x39, 0x9a, 0x9c, 0xfb, 0x67, 0xa3, 0xe0, 0x32, 0xf6, 0xd9, 0x30, 0x54, 0x8b, 0xd3,
-	0x81, 0x96, 0xba, 0x0f, 0x46, 0x61, 0x80, 0x2d, 0xfa, 0xdb, 0x02, 0xc7, 0xd7, 0xbc, 0xff, 0x56,
-	0x79, 0x08, 0x8e, 0xc9, 0x54, 0x6d, 0xf2, 0xdb, 0xcd, 0xa6, 0x0d, 0xa9, 0xfa, 0x60, 0x95, 0x9c,
-	0x4c, 0xa1, 0x3d, 0x17, 0x05, 0x37, 0x2c, 0x5b, 0xb3, 0xfa, 0x5b, 0xb3, 0x82, 0x95, 0x92, 0x3d,
-	0x42, 0xe8, 0xe1, 0xda, 0x74, 0x0b, 0xea, 0x17, 0xfe, 0x28, 0xc6, 0x35, 0x75, 0x1a, 0x47, 0xe7,
-	0x21, 0x46, 0xa4, 0x09, 0x76, 0xe8, 0xc7, 0xd8, 0x22, 0x00, 0x8e, 0x1f, 0xc7, 0xfe, 0xe0, 0x14,
-	0xdb, 0xb4, 0x0f, 0xed, 0x35, 0x4a, 0xf5, 0xe8, 0xcb, 0xd4, 0x68, 0x82, 0xe8, 0x62, 0x62, 0x7e,
-	0x4e, 0x67, 0xe1, 0xb1, 0x12, 0xb5, 0xa1, 0xc1, 0x46, 0xc3, 0x93, 0x18, 0xdb, 0xf4, 0x1a, 0x5e,
-	0x3d, 0x19, 0xc3, 0x34, 0x99, 0x7d, 0xe3, 0x92, 0x7c, 0x86, 0x4e, 0xfe, 0x78, 0x59, 0xba, 0x48,
-	0x2f, 0xc6, 0x9b, 0x17, 0x4d, 0x94, 0x3d, 0x43, 0x50, 0x06, 0x1d, 0x53, 0x4e, 0x95, 0xe2, 0x13,
-	0x34, 0x4d, 0xeb, 0x56, 0xf4, 0xde, 0xb6, 0xed, 0x62, 0x2b, 0x61, 0xff, 0x27, 0x02, 0x18, 0xac,
-	0x9f, 0xc9, 0x77, 0x20, 0x83, 0x3c, 0xcb, 0xd4, 0x66, 0xf2, 0x45, 0x2e, 0xf9, 0x38, 0x9f, 0xf3,
-	0x94, 0x78, 0xdb, 0x72, 0x8d, 0xb1, 0x83, 0xa3, 0x17, 0x55, 0x69, 0x44, 0xb4, 0xd6, 0x43, 0xef,
-	0xd0, 0x95, 0xa3, 0xff, 0x5c, 0x8f, 0xfe, 0x06, 0x00, 0x00, 0xff, 0xff, 0x48, 0xc0, 0xe6, 0x1d,
-	0x70, 0x05, 0x00, 0x00,
+	// 636 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xcd, 0x6e, 0xda, 0x40,
+	0x10, 0xc6, 0x36, 0x36, 0x30, 0x21, 0xe9, 0x76, 0x55, 0x45, 0x56, 0x4e, 0xc8, 0x27, 0x2e, 0x75,
+	0x5b, 0x72, 0xac, 0x5a, 0xc9, 0xc5, 0x1b, 0x82, 0x02, 0x98, 0x6e, 0x9d, 0xa0, 0x9e, 0x90, 0x63,
+	0x36, 0xc9, 0xaa, 0xc6, 0x8e, 0xec, 0x0d, 0x52, 0x4e, 0x3d, 0xf6, 0xd4, 0x17, 0xe8, 0x03, 0xf4,
+	0x25, 0xfa, 0x72, 0xd5, 0xae, 0x0d, 0x21, 0xed, 0x21, 0xe4, 0xe4, 0x99, 0xd9, 0xfd, 0xbe, 0xf9,
+	0xe6, 0x67, 0x0d, 0x28, 0xce, 0x92, 0x84, 0xc5, 0x82, 0xaf, 0x98, 0x7b, 0x9b, 0x67, 0x22, 0xc3,
+	0x1d, 0x96, 0x2e, 0x6e, 0x33, 0x9e, 0x8a, 0xc2, 0x15, 0x2c, 0xcf, 0xa3, 0x9c, 0xdf, 0x2d, 0x23,
+	0xee, 0x3e, 0xdc, 0x73, 0xfe, 0x68, 0x60, 0x91, 0x54, 0x70, 0x71, 0x8f, 0x0f, 0x40, 0xe7, 0x0b,
+	0x5b, 0xeb, 0x68, 0xdd, 0x16, 0xd5, 0xf9, 0x02, 0x9f, 0x42, 0x23, 0x4e, 0xa2, 0xa2, 0x18, 0xfa,
+	0xb6, 0xde, 0xd1, 0xba, 0x07, 0x3d, 0xd7, 0x7d, 0x8a, 0xce, 0x2d, 0xa9, 0xdc, 0xbe, 0xc4, 0xd1,
+	0x35, 0xdc, 0x99, 0x81, 0xa9, 0x22, 0xb8, 0x05, 0x26, 0x19, 0x4f, 0xc3, 0xaf, 0xa8, 0x26, 0x4d,
+	0x6f, 0x40, 0x26, 0x21, 0xd2, 0x70, 0x13, 0xea, 0x34, 0xe8, 0x9f, 0x21, 0x5d, 0x5a, 0x27, 0x41,
+	0xe0, 0x23, 0x43, 0x5a, 0x33, 0x6f, 0x34, 0x42, 0x75, 0x79, 0x71, 0xe6, 0x85, 0x84, 0x22, 0x13,
+	0xbf, 0x80, 0xbd, 0x2f, 0xa3, 0x60, 0x36, 0x1f, 0xd0, 0xe0, 0x7c, 0xe2, 0x23, 0xcb, 0xf9, 0x2d,
+	0xd5, 0x5f, 0x5d, 0xb1, 0x58, 0x6c, 0xab, 0xd5, 0x76, 0x56, 0xab, 0xa0, 0xff, 0xa8, 0xc5, 0xaf,
+	0xc0, 0x5c, 0x45, 0xc9, 0x1d, 0x53, 0x55, 0xef, 0xd3, 0xd2, 0xc1, 0x47, 0xd0, 0x2c, 0x44, 0xce,
+	0xd2, 0x6b, 0x71, 0x63, 0x1b, 0xea, 0x60, 0xe3, 0x3b, 0x9d, 0x75, 0x7d, 0x4d, 0xa8, 0x4f, 0x82,
+	0x09, 0x41, 0x35, 0xbc, 0x0f, 0xad, 0xe9, 0x29, 0xa1, 0xc1, 0x58, 0xba, 0x9a, 0xf3, 0xc3, 0x80,
+	0xbd, 0xe0, 0xb2, 0x60, 0xf9, 0x2a, 0x12, 0x3c, 0x4b, 0xb1, 0x0d, 0x0d, 0x5e, 0x78, 0x09, 0x5f,
+	0x31, 0xa5, 0xb6, 0x49, 0xd7, 0x2e, 0x3e, 0x04, 0x8b, 0xa5, 0x2c, 0xbf, 0xbe, 0xaf, 0xd2, 0x57,
+	0x9e, 0x8c, 0xdf, 0xb0, 0x28, 0xd9, 0x64, 0xaf, 0xbc, 0x6a, 0x6a, 0xf5, 0xcd, 0xd4, 0x3e, 0x82,
+	0x59, 0xf0, 0xeb, 0x1b, 0x61, 0x9b, 0x1d, 0xa3, 0xbb, 0xd7, 0xeb, 0xee, 0x3a, 0x33, 0x5a, 0xc2,
+	0x14, 0x7e, 0xc9, 0x92, 0xc4, 0xb6, 0x76, 0xc6, 0xab, 0x2e, 0xd2, 0x12, 0x86, 0xe7, 0xd0, 0x8e,
+	0x62, 0x59, 0xe3, 0x98, 0x2d, 0xb3, 0xfc, 0xde, 0x6e, 0xa8, 0x61, 0xbc, 0x7f, 0x9a, 0x66, 0xab,
+	0x3d, 0x2e, 0x65, 0xc5, 0x6d, 0x96, 0x16, 0xec, 0x42, 0xb6, 0x9e, 0x3e, 0x22, 0x74, 0x3e, 0xc0,
+	0xfe, 0xa3, 0x63, 0x6c, 0x81, 0x1e, 0x9c, 0xa1, 0x1a, 0x3e, 0x04, 0x4c, 0x28, 0x9d, 0x0f, 0x27,
+	0x17, 0xde, 0x68, 0xe8, 0xcf, 0x43, 0x8f, 0x0e, 0x88, 0x5c, 0xaf, 0x36, 0x34, 0x65, 0xdc, 0x1f,
+	0x12, 0x1f, 0xe9, 0xce, 0x2f, 0x1d, 0x2c, 0x4f, 0xf1, 0xfd, 0xb7, 0xf0, 0x03, 0xb0, 0xca, 0x4c,
+	0xd5, 0xbe, 0xbf, 0x79, 0x5a, 0x74, 0xc9, 0x54, 0x7d, 0x68, 0x05, 0xc7, 0x53, 0x68, 0x2d, 0x78,
+	0xce, 0x4a, 0x2e, 0x43, 0x71, 0xf5, 0x76, 0xe6, 0xf2, 0xd7, 0x48, 0xfa, 0x40, 0xe2, 0xbc, 0xdb,
+	0x88, 0x56, 0x0f, 0x63, 0x18, 0xa2, 0x9a, 0xb4, 0xc6, 0xc1, 0x05, 0x41, 0x1a, 0x6e, 0x80, 0x41,
+	0xbc, 0x10, 0xe9, 0x18, 0xc0, 0xf2, 0xc2, 0xd0, 0xeb, 0x9f, 0x21, 0xc3, 0xe9, 0x41, 0x6b, 0x43,
+	0x25, 0x7b, 0x74, 0x3e, 0x2d, 0x31, 0x7e, 0x30, 0x9b, 0x94, 0x8f, 0x6e, 0x44, 0x4e, 0x24, 0xa8,
+	0x05, 0x26, 0x1d, 0x0e, 0x4e, 0x43, 0x64, 0x38, 0x57, 0xf0, 0x72, 0x6b, 0x0c, 0xd3, 0x28, 0xfe,
+	0xc6, 0x04, 0xfe, 0x0c, 0xed, 0xec, 0x21, 0x58, 0xd8, 0x9a, 0x5a, 0x8c, 0xd7, 0xcf, 0x9a, 0x28,
+	0x7d, 0x44, 0xe1, 0x50, 0x68, 0x7b, 0xf1, 0x56, 0x8a, 0x4f, 0xd0, 0x88, 0xe2, 0x6d, 0xf6, 0xee,
+	0xae, 0xed, 0xa2, 0x6b, 0x60, 0xef, 0xa7, 0x06, 0xd0, 0xdf, 0x1c, 0xe3, 0xef, 0x80, 0xfb, 0x59,
+	0x9a, 0xca, 0xcd, 0x64, 0xcb, 0x4c, 0xb0, 0x71, 0xb6, 0x60, 0x09, 0x76, 0x77, 0xe5, 0x2d, 0x85,
+	0x1d, 0x1d, 0x3f, 0xab, 0xca, 0x12, 0xe4, 0xd4, 0xba, 0xda, 0x5b, 0xed, 0xd2, 0x52, 0xbf, 0xe0,
+	0xe3, 0xbf, 0x03, 0x00, 0xbd, 0x21, 0x58, 0x76, 0x96, 0x05, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
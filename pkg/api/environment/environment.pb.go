@@ -176,6 +176,66 @@ func (ExecuteAgentActionResponse_ResponseValue) EnumDescriptor() ([]byte, []int)
 	return fileDescriptor_64e647b85623514a, []int{9, 0}
 }
 
+// What happens at the edges of the world
+type World_Topology int32
+
+const (
+	// Past the edges is rock
+	World_BOUNDED World_Topology = 0
+	// Edges wrap around to the other side
+	World_TORUS World_Topology = 1
+)
+
+var World_Topology_name = map[int32]string{
+	0: "BOUNDED",
+	1: "TORUS",
+}
+
+var World_Topology_value = map[string]int32{
+	"BOUNDED": 0,
+	"TORUS":   1,
+}
+
+func (x World_Topology) String() string {
+	return proto.EnumName(World_Topology_name, int32(x))
+}
+
+func (World_Topology) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{19, 0}
+}
+
+// How the entities are spread over the rectangle
+type SpawnEntitiesRequest_Distribution int32
+
+const (
+	// Anywhere in the rectangle
+	SpawnEntitiesRequest_UNIFORM SpawnEntitiesRequest_Distribution = 0
+	// Clustered around the center, spread cells apart
+	SpawnEntitiesRequest_GAUSSIAN SpawnEntitiesRequest_Distribution = 1
+	// Around a circle of radius cells from the center, spread cells wide
+	SpawnEntitiesRequest_RING SpawnEntitiesRequest_Distribution = 2
+)
+
+var SpawnEntitiesRequest_Distribution_name = map[int32]string{
+	0: "UNIFORM",
+	1: "GAUSSIAN",
+	2: "RING",
+}
+
+var SpawnEntitiesRequest_Distribution_value = map[string]int32{
+	"UNIFORM":  0,
+	"GAUSSIAN": 1,
+	"RING":     2,
+}
+
+func (x SpawnEntitiesRequest_Distribution) String() string {
+	return proto.EnumName(SpawnEntitiesRequest_Distribution_name, int32(x))
+}
+
+func (SpawnEntitiesRequest_Distribution) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{24, 0}
+}
+
 // Taks we have to do
 type Entity struct {
 	// Unique integer identifier of the agent
@@ -671,10 +731,12 @@ func (m *ExecuteAgentActionRequest) GetDirection() ExecuteAgentActionRequest_Dir
 }
 
 type ExecuteAgentActionResponse struct {
-	Value                ExecuteAgentActionResponse_ResponseValue `protobuf:"varint,1,opt,name=value,proto3,enum=endpoints.terrariumai.environment.ExecuteAgentActionResponse_ResponseValue" json:"value,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}                                 `json:"-"`
-	XXX_unrecognized     []byte                                   `json:"-"`
-	XXX_sizecache        int32                                    `json:"-"`
+	Value ExecuteAgentActionResponse_ResponseValue `protobuf:"varint,1,opt,name=value,proto3,enum=endpoints.terrariumai.environment.ExecuteAgentActionResponse_ResponseValue" json:"value,omitempty"`
+	// Tick the action was resolved in, 0 when not running in tick mode
+	Tick                 uint64   `protobuf:"varint,2,opt,name=tick,proto3" json:"tick,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *ExecuteAgentActionResponse) Reset()         { *m = ExecuteAgentActionResponse{} }
@@ -709,6 +771,13 @@ func (m *ExecuteAgentActionResponse) GetValue() ExecuteAgentActionResponse_Respo
 	return ExecuteAgentActionResponse_OK
 }
 
+func (m *ExecuteAgentActionResponse) GetTick() uint64 {
+	if m != nil {
+		return m.Tick
+	}
+	return 0
+}
+
 type GetEntitiesInRegionRequest struct {
 	X                    uint32   `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
 	Y                    uint32   `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
@@ -881,277 +950,1243 @@ func (m *GetEffectsInRegionResponse) GetEffects() []*Effect {
 	return nil
 }
 
-func init() {
-	proto.RegisterEnum("endpoints.terrariumai.environment.Entity_Class", Entity_Class_name, Entity_Class_value)
-	proto.RegisterEnum("endpoints.terrariumai.environment.Effect_Class", Effect_Class_name, Effect_Class_value)
-	proto.RegisterEnum("endpoints.terrariumai.environment.ExecuteAgentActionRequest_Action", ExecuteAgentActionRequest_Action_name, ExecuteAgentActionRequest_Action_value)
-	proto.RegisterEnum("endpoints.terrariumai.environment.ExecuteAgentActionRequest_Direction", ExecuteAgentActionRequest_Direction_name, ExecuteAgentActionRequest_Direction_value)
-	proto.RegisterEnum("endpoints.terrariumai.environment.ExecuteAgentActionResponse_ResponseValue", ExecuteAgentActionResponse_ResponseValue_name, ExecuteAgentActionResponse_ResponseValue_value)
-	proto.RegisterType((*Entity)(nil), "endpoints.terrariumai.environment.Entity")
-	proto.RegisterType((*Effect)(nil), "endpoints.terrariumai.environment.Effect")
-	proto.RegisterType((*CreateEntityRequest)(nil), "endpoints.terrariumai.environment.CreateEntityRequest")
-	proto.RegisterType((*CreateEntityResponse)(nil), "endpoints.terrariumai.environment.CreateEntityResponse")
-	proto.RegisterType((*GetEntityRequest)(nil), "endpoints.terrariumai.environment.GetEntityRequest")
-	proto.RegisterType((*GetEntityResponse)(nil), "endpoints.terrariumai.environment.GetEntityResponse")
-	proto.RegisterType((*DeleteEntityRequest)(nil), "endpoints.terrariumai.environment.DeleteEntityRequest")
-	proto.RegisterType((*DeleteEntityResponse)(nil), "endpoints.terrariumai.environment.DeleteEntityResponse")
-	proto.RegisterType((*ExecuteAgentActionRequest)(nil), "endpoints.terrariumai.environment.ExecuteAgentActionRequest")
-	proto.RegisterType((*ExecuteAgentActionResponse)(nil), "endpoints.terrariumai.environment.ExecuteAgentActionResponse")
-	proto.RegisterType((*GetEntitiesInRegionRequest)(nil), "endpoints.terrariumai.environment.GetEntitiesInRegionRequest")
-	proto.RegisterType((*GetEntitiesInRegionResponse)(nil), "endpoints.terrariumai.environment.GetEntitiesInRegionResponse")
-	proto.RegisterType((*GetEffectsInRegionRequest)(nil), "endpoints.terrariumai.environment.GetEffectsInRegionRequest")
-	proto.RegisterType((*GetEffectsInRegionResponse)(nil), "endpoints.terrariumai.environment.GetEffectsInRegionResponse")
+// Request data to wipe and regenerate the world
+type ResetWorldRequest struct {
+	// Seed used to randomly place food
+	Seed int64 `protobuf:"varint,1,opt,name=seed,proto3" json:"seed,omitempty"`
+	// Number of food entities to randomly place
+	FoodCount uint32 `protobuf:"varint,2,opt,name=foodCount,proto3" json:"foodCount,omitempty"`
+	// Explicit food positions, placed before any random food
+	Food []*Position `protobuf:"bytes,3,rep,name=food,proto3" json:"food,omitempty"`
+	// Generates fresh terrain and food patches from the seed. The terrain is
+	// left as it is when empty.
+	Generator            *WorldGenerator `protobuf:"bytes,4,opt,name=generator,proto3" json:"generator,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
 }
 
-func init() { proto.RegisterFile("environment.proto", fileDescriptor_64e647b85623514a) }
-
-var fileDescriptor_64e647b85623514a = []byte{
-	// 898 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0xc1, 0x6e, 0xdb, 0x46,
-	0x10, 0x15, 0x29, 0x5b, 0x12, 0x27, 0x76, 0xc0, 0xac, 0x0d, 0x83, 0x61, 0x7a, 0x50, 0x17, 0x68,
-	0xe1, 0x5e, 0xe8, 0xc6, 0x29, 0x9a, 0x1e, 0xaa, 0x00, 0x84, 0x48, 0x3b, 0x84, 0x63, 0xc9, 0xd8,
-	0x32, 0x76, 0x8b, 0x1e, 0x02, 0x46, 0x1c, 0xcb, 0x04, 0x24, 0x52, 0x25, 0x57, 0x89, 0x75, 0xec,
-	0x37, 0x14, 0x28, 0xfa, 0x35, 0xfd, 0x89, 0xa2, 0x1f, 0xd3, 0x5b, 0xb1, 0x5c, 0x52, 0x96, 0x64,
-	0xb9, 0x95, 0xec, 0xdc, 0x76, 0x66, 0xe7, 0xbd, 0x7d, 0x33, 0xbb, 0x33, 0x58, 0x78, 0x82, 0xf1,
-	0x87, 0x28, 0x4d, 0xe2, 0x21, 0xc6, 0xdc, 0x1a, 0xa5, 0x09, 0x4f, 0xc8, 0xe7, 0x18, 0x87, 0xa3,
-	0x24, 0x8a, 0x79, 0x66, 0x71, 0x4c, 0xd3, 0x20, 0x8d, 0xc6, 0xc3, 0x20, 0xb2, 0x66, 0x02, 0xcd,
-	0x67, 0xfd, 0x24, 0xe9, 0x0f, 0xf0, 0x20, 0x07, 0xbc, 0x1f, 0x5f, 0x1e, 0xe0, 0x70, 0xc4, 0x27,
-	0x12, 0x4f, 0xff, 0x50, 0xa1, 0xe6, 0xc6, 0x3c, 0xe2, 0x13, 0xf2, 0x18, 0xd4, 0x28, 0x34, 0x94,
-	0xa6, 0xb2, 0xaf, 0x31, 0x35, 0x0a, 0x89, 0x07, 0xf5, 0xde, 0x20, 0xc8, 0x32, 0xcf, 0x31, 0xd4,
-	0xa6, 0xb2, 0xff, 0xf8, 0xf0, 0xc0, 0xfa, 0xdf, 0xc3, 0x2c, 0xc9, 0x65, 0xb5, 0x05, 0x90, 0x95,
-	0x78, 0xb2, 0x05, 0xca, 0xb5, 0x51, 0x6d, 0x2a, 0xfb, 0xdb, 0x4c, 0xb9, 0x16, 0xd6, 0xc4, 0xd8,
-	0x90, 0xd6, 0x84, 0xec, 0x41, 0x0d, 0x63, 0x4c, 0xfb, 0x13, 0x63, 0x33, 0x77, 0x15, 0x96, 0xf0,
-	0x5f, 0x61, 0x30, 0xe0, 0x57, 0x46, 0x4d, 0xfa, 0xa5, 0x45, 0x4c, 0x68, 0x24, 0x1f, 0x63, 0x4c,
-	0xdf, 0x7a, 0x8e, 0x51, 0xcf, 0xc5, 0x4e, 0x6d, 0x62, 0x40, 0x7d, 0x98, 0x84, 0x38, 0xf0, 0x1c,
-	0xa3, 0x91, 0x6f, 0x95, 0x26, 0x7d, 0x0e, 0x9b, 0xb9, 0x26, 0xa2, 0xc1, 0xa6, 0x7b, 0x7a, 0xe6,
-	0xff, 0xa4, 0x57, 0xc4, 0xd2, 0x3e, 0x76, 0x3b, 0xbe, 0xae, 0x90, 0x06, 0x6c, 0xb0, 0x6e, 0xfb,
-	0x44, 0x57, 0xc5, 0xea, 0xa8, 0xdb, 0x75, 0xf4, 0x2a, 0xfd, 0x47, 0x81, 0x9a, 0x7b, 0x79, 0x89,
-	0x3d, 0x2e, 0xf5, 0x2b, 0x73, 0xfa, 0xd5, 0x52, 0xff, 0x67, 0xa0, 0xf1, 0x68, 0x88, 0x19, 0x0f,
-	0x86, 0xa3, 0x3c, 0xc7, 0x2a, 0xbb, 0x71, 0xcc, 0x16, 0x71, 0x63, 0xf5, 0x22, 0xe6, 0xa7, 0x2e,
-	0x16, 0x71, 0x17, 0x36, 0x3f, 0x04, 0x83, 0x31, 0x16, 0x75, 0x92, 0x86, 0xf0, 0x86, 0xd8, 0x0b,
-	0x26, 0x79, 0x95, 0x54, 0x26, 0x0d, 0x21, 0x2a, 0xc4, 0x81, 0x7f, 0x95, 0x62, 0x76, 0x95, 0x57,
-	0x69, 0x9b, 0xdd, 0x38, 0x68, 0xb3, 0x2c, 0x46, 0x03, 0x36, 0x3a, 0xdd, 0x8e, 0xab, 0x57, 0xc8,
-	0x36, 0x68, 0x67, 0xaf, 0x5d, 0xd6, 0x3d, 0x15, 0xa6, 0x42, 0x7f, 0x84, 0x9d, 0x76, 0x8a, 0x01,
-	0x47, 0x79, 0x9f, 0x0c, 0x7f, 0x19, 0x63, 0xc6, 0x89, 0x2d, 0xee, 0x4a, 0x38, 0xf2, 0x62, 0x3c,
-	0x3a, 0xfc, 0x6a, 0xe5, 0x17, 0xc1, 0x0a, 0x20, 0xfd, 0x12, 0x76, 0xe7, 0x99, 0xb3, 0x51, 0x12,
-	0x67, 0xb8, 0xf8, 0xfa, 0x28, 0x05, 0xfd, 0x18, 0xf9, 0xfc, 0xf1, 0x8b, 0x31, 0xe7, 0xf0, 0x64,
-	0x26, 0xa6, 0x20, 0xfa, 0x04, 0x1a, 0xbf, 0x80, 0x1d, 0x07, 0x07, 0xb8, 0x98, 0xfd, 0xe2, 0xf1,
-	0x5f, 0xc3, 0xee, 0x7c, 0x58, 0xa1, 0xc0, 0x80, 0x7a, 0x98, 0xfb, 0x65, 0x70, 0x95, 0x95, 0x26,
-	0xfd, 0x5b, 0x85, 0xa7, 0xee, 0x35, 0xf6, 0xc6, 0x1c, 0xed, 0x3e, 0xc6, 0xdc, 0xee, 0xf1, 0x28,
-	0x89, 0xef, 0xe0, 0x27, 0x3f, 0x43, 0x2d, 0xc8, 0x03, 0x8a, 0xfe, 0x6b, 0xaf, 0x92, 0xc9, 0x5d,
-	0xec, 0x56, 0x61, 0x15, 0x94, 0x24, 0x04, 0x2d, 0x8c, 0x52, 0x94, 0xfc, 0xd5, 0x9c, 0xff, 0xe8,
-	0x41, 0xfc, 0x4e, 0xc9, 0xc6, 0x6e, 0x88, 0xe9, 0x73, 0xa8, 0xc9, 0x28, 0xf1, 0xd4, 0x2e, 0x6c,
-	0xcf, 0xd7, 0x2b, 0x62, 0x75, 0xda, 0x3d, 0x77, 0x75, 0x85, 0xd4, 0xa1, 0xea, 0xda, 0xbe, 0xae,
-	0x12, 0x80, 0x9a, 0xed, 0xfb, 0x76, 0xfb, 0x44, 0xaf, 0xd2, 0x43, 0xd0, 0xa6, 0x54, 0xa4, 0x06,
-	0xea, 0xdb, 0x33, 0x89, 0x71, 0xba, 0x17, 0x1d, 0xd9, 0xa9, 0x6f, 0xdc, 0x23, 0x01, 0xd2, 0x60,
-	0x93, 0x79, 0xc7, 0xaf, 0x7d, 0xbd, 0x4a, 0xff, 0x54, 0xc0, 0x5c, 0xa6, 0xac, 0xb8, 0x90, 0xa0,
-	0xec, 0x1c, 0x25, 0xcf, 0xf3, 0xe4, 0x9e, 0x79, 0x4a, 0x36, 0xab, 0x5c, 0x9c, 0x0b, 0xca, 0xa2,
-	0x0d, 0x69, 0x0b, 0xb6, 0xe7, 0xfc, 0x42, 0x79, 0xf7, 0x44, 0xaf, 0x90, 0x3d, 0x20, 0x2e, 0x63,
-	0xef, 0xbc, 0xce, 0xb9, 0xfd, 0xc6, 0x73, 0xde, 0xf9, 0x36, 0x3b, 0x76, 0xc5, 0xc4, 0xd9, 0x82,
-	0x86, 0xf0, 0x3b, 0x9e, 0xeb, 0xe8, 0x2a, 0xfd, 0x0e, 0xcc, 0xf2, 0x25, 0x47, 0x98, 0x79, 0x31,
-	0xc3, 0xfe, 0xcc, 0xc3, 0xf8, 0x8f, 0xf1, 0x43, 0x43, 0x78, 0xb6, 0x14, 0x59, 0xa4, 0xee, 0x42,
-	0x03, 0x8b, 0x3d, 0x43, 0x69, 0x56, 0xd7, 0xeb, 0x87, 0x29, 0x94, 0xbe, 0x84, 0xa7, 0xe2, 0x94,
-	0x7c, 0x2e, 0xad, 0x25, 0x2f, 0x90, 0x89, 0x2d, 0x02, 0x0b, 0x75, 0x6d, 0xa8, 0xa3, 0xdc, 0x5a,
-	0x47, 0x5c, 0x8e, 0x60, 0x25, 0xf2, 0xf0, 0xaf, 0x3a, 0x3c, 0x72, 0x6f, 0xf6, 0xc9, 0xaf, 0x0a,
-	0x6c, 0xcd, 0x8e, 0x18, 0xf2, 0xed, 0x0a, 0xa4, 0x4b, 0xa6, 0x9d, 0xf9, 0x72, 0x6d, 0x9c, 0x4c,
-	0x8b, 0x56, 0xc8, 0x35, 0x68, 0xd3, 0xc9, 0x44, 0x5e, 0xac, 0xc0, 0xb3, 0x38, 0xeb, 0xcc, 0x6f,
-	0xd6, 0x03, 0x4d, 0x4f, 0x16, 0xd9, 0xcf, 0x4e, 0xa5, 0x95, 0xb2, 0x5f, 0x32, 0xed, 0x56, 0xca,
-	0x7e, 0xd9, 0xf8, 0xa3, 0x15, 0xf2, 0x9b, 0x02, 0xe4, 0x76, 0x03, 0x91, 0xef, 0x1f, 0x32, 0x5f,
-	0xcc, 0xd6, 0x83, 0xba, 0x96, 0x56, 0xc8, 0x2b, 0x00, 0x86, 0x19, 0xf2, 0x8b, 0x24, 0x1d, 0x84,
-	0x64, 0xcf, 0x92, 0xdf, 0x22, 0xab, 0xfc, 0x16, 0x59, 0xae, 0xf8, 0x16, 0x99, 0x77, 0xf8, 0x69,
-	0x85, 0xb4, 0x40, 0xfb, 0x61, 0x14, 0x7c, 0x8c, 0x8f, 0x92, 0xe4, 0x3e, 0xf0, 0xdf, 0x15, 0xd8,
-	0x59, 0xd2, 0xa9, 0xa4, 0xb5, 0xc6, 0x45, 0xdf, 0x9e, 0x0d, 0xe6, 0xab, 0xfb, 0xc2, 0xe7, 0x6e,
-	0xeb, 0x76, 0x8f, 0xae, 0x74, 0x5b, 0x77, 0xce, 0x04, 0xb3, 0x75, 0x4f, 0x74, 0xa9, 0xea, 0x7d,
-	0x2d, 0x2f, 0xe0, 0x8b, 0x7f, 0x03, 0x00, 0x00, 0xff, 0xff, 0x96, 0xab, 0xb3, 0xcf, 0xf4, 0x0a,
-	0x00, 0x00,
+func (m *ResetWorldRequest) Reset()         { *m = ResetWorldRequest{} }
+func (m *ResetWorldRequest) String() string { return proto.CompactTextString(m) }
+func (*ResetWorldRequest) ProtoMessage()    {}
+func (*ResetWorldRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{14}
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *ResetWorldRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResetWorldRequest.Unmarshal(m, b)
+}
+func (m *ResetWorldRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResetWorldRequest.Marshal(b, m, deterministic)
+}
+func (m *ResetWorldRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResetWorldRequest.Merge(m, src)
+}
+func (m *ResetWorldRequest) XXX_Size() int {
+	return xxx_messageInfo_ResetWorldRequest.Size(m)
+}
+func (m *ResetWorldRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResetWorldRequest.DiscardUnknown(m)
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+var xxx_messageInfo_ResetWorldRequest proto.InternalMessageInfo
 
-// EnvironmentClient is the client API for Environment service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type EnvironmentClient interface {
-	// Create new agent
-	CreateEntity(ctx context.Context, in *CreateEntityRequest, opts ...grpc.CallOption) (*CreateEntityResponse, error)
-	// Get data for an entity
-	GetEntity(ctx context.Context, in *GetEntityRequest, opts ...grpc.CallOption) (*GetEntityResponse, error)
-	// Delete an agent
-	DeleteEntity(ctx context.Context, in *DeleteEntityRequest, opts ...grpc.CallOption) (*DeleteEntityResponse, error)
-	// Perform an action for an agent
-	ExecuteAgentAction(ctx context.Context, in *ExecuteAgentActionRequest, opts ...grpc.CallOption) (*ExecuteAgentActionResponse, error)
-	// Reset the world
-	ResetWorld(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
-	// Spawn food in the world
-	SpawnFood(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
-	// Get Region
-	GetEntitiesInRegion(ctx context.Context, in *GetEntitiesInRegionRequest, opts ...grpc.CallOption) (*GetEntitiesInRegionResponse, error)
-	GetEffectsInRegion(ctx context.Context, in *GetEffectsInRegionRequest, opts ...grpc.CallOption) (*GetEffectsInRegionResponse, error)
+func (m *ResetWorldRequest) GetSeed() int64 {
+	if m != nil {
+		return m.Seed
+	}
+	return 0
 }
 
-type environmentClient struct {
-	cc *grpc.ClientConn
+func (m *ResetWorldRequest) GetFoodCount() uint32 {
+	if m != nil {
+		return m.FoodCount
+	}
+	return 0
 }
 
-func NewEnvironmentClient(cc *grpc.ClientConn) EnvironmentClient {
-	return &environmentClient{cc}
+func (m *ResetWorldRequest) GetFood() []*Position {
+	if m != nil {
+		return m.Food
+	}
+	return nil
 }
 
-func (c *environmentClient) CreateEntity(ctx context.Context, in *CreateEntityRequest, opts ...grpc.CallOption) (*CreateEntityResponse, error) {
-	out := new(CreateEntityResponse)
-	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/CreateEntity", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *ResetWorldRequest) GetGenerator() *WorldGenerator {
+	if m != nil {
+		return m.Generator
 	}
-	return out, nil
+	return nil
 }
 
-func (c *environmentClient) GetEntity(ctx context.Context, in *GetEntityRequest, opts ...grpc.CallOption) (*GetEntityResponse, error) {
-	out := new(GetEntityResponse)
-	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/GetEntity", in, out, opts...)
-	if err != nil {
-		return nil, err
+// Parameters of a procedurally generated world, fractions are of the whole
+// world
+type WorldGenerator struct {
+	// Cells across the biggest terrain features, 10 if 0
+	Scale float32 `protobuf:"fixed32,1,opt,name=scale,proto3" json:"scale,omitempty"`
+	// Terrain coverage, water in the lowest ground, walls on the highest and
+	// slow ground on the hills below them
+	Walls      float32 `protobuf:"fixed32,2,opt,name=walls,proto3" json:"walls,omitempty"`
+	Water      float32 `protobuf:"fixed32,3,opt,name=water,proto3" json:"water,omitempty"`
+	SlowGround float32 `protobuf:"fixed32,4,opt,name=slowGround,proto3" json:"slowGround,omitempty"`
+	// Patches of food around random open cells
+	FoodPatches          uint32   `protobuf:"varint,5,opt,name=foodPatches,proto3" json:"foodPatches,omitempty"`
+	FoodPatchRadius      uint32   `protobuf:"varint,6,opt,name=foodPatchRadius,proto3" json:"foodPatchRadius,omitempty"`
+	FoodDensity          float32  `protobuf:"fixed32,7,opt,name=foodDensity,proto3" json:"foodDensity,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WorldGenerator) Reset()         { *m = WorldGenerator{} }
+func (m *WorldGenerator) String() string { return proto.CompactTextString(m) }
+func (*WorldGenerator) ProtoMessage()    {}
+func (*WorldGenerator) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{15}
+}
+
+func (m *WorldGenerator) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WorldGenerator.Unmarshal(m, b)
+}
+func (m *WorldGenerator) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WorldGenerator.Marshal(b, m, deterministic)
+}
+func (m *WorldGenerator) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WorldGenerator.Merge(m, src)
+}
+func (m *WorldGenerator) XXX_Size() int {
+	return xxx_messageInfo_WorldGenerator.Size(m)
+}
+func (m *WorldGenerator) XXX_DiscardUnknown() {
+	xxx_messageInfo_WorldGenerator.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WorldGenerator proto.InternalMessageInfo
+
+func (m *WorldGenerator) GetScale() float32 {
+	if m != nil {
+		return m.Scale
 	}
-	return out, nil
+	return 0
 }
 
-func (c *environmentClient) DeleteEntity(ctx context.Context, in *DeleteEntityRequest, opts ...grpc.CallOption) (*DeleteEntityResponse, error) {
-	out := new(DeleteEntityResponse)
-	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/DeleteEntity", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *WorldGenerator) GetWalls() float32 {
+	if m != nil {
+		return m.Walls
 	}
-	return out, nil
+	return 0
 }
 
-func (c *environmentClient) ExecuteAgentAction(ctx context.Context, in *ExecuteAgentActionRequest, opts ...grpc.CallOption) (*ExecuteAgentActionResponse, error) {
-	out := new(ExecuteAgentActionResponse)
-	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/ExecuteAgentAction", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *WorldGenerator) GetWater() float32 {
+	if m != nil {
+		return m.Water
 	}
-	return out, nil
+	return 0
 }
 
-func (c *environmentClient) ResetWorld(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/ResetWorld", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *WorldGenerator) GetSlowGround() float32 {
+	if m != nil {
+		return m.SlowGround
 	}
-	return out, nil
+	return 0
 }
 
-func (c *environmentClient) SpawnFood(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/SpawnFood", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *WorldGenerator) GetFoodPatches() uint32 {
+	if m != nil {
+		return m.FoodPatches
 	}
-	return out, nil
+	return 0
 }
 
-func (c *environmentClient) GetEntitiesInRegion(ctx context.Context, in *GetEntitiesInRegionRequest, opts ...grpc.CallOption) (*GetEntitiesInRegionResponse, error) {
-	out := new(GetEntitiesInRegionResponse)
-	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/GetEntitiesInRegion", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *WorldGenerator) GetFoodPatchRadius() uint32 {
+	if m != nil {
+		return m.FoodPatchRadius
 	}
-	return out, nil
+	return 0
 }
 
-func (c *environmentClient) GetEffectsInRegion(ctx context.Context, in *GetEffectsInRegionRequest, opts ...grpc.CallOption) (*GetEffectsInRegionResponse, error) {
-	out := new(GetEffectsInRegionResponse)
-	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/GetEffectsInRegion", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *WorldGenerator) GetFoodDensity() float32 {
+	if m != nil {
+		return m.FoodDensity
 	}
-	return out, nil
+	return 0
 }
 
-// EnvironmentServer is the server API for Environment service.
-type EnvironmentServer interface {
-	// Create new agent
-	CreateEntity(context.Context, *CreateEntityRequest) (*CreateEntityResponse, error)
-	// Get data for an entity
-	GetEntity(context.Context, *GetEntityRequest) (*GetEntityResponse, error)
-	// Delete an agent
-	DeleteEntity(context.Context, *DeleteEntityRequest) (*DeleteEntityResponse, error)
-	// Perform an action for an agent
-	ExecuteAgentAction(context.Context, *ExecuteAgentActionRequest) (*ExecuteAgentActionResponse, error)
-	// Reset the world
-	ResetWorld(context.Context, *empty.Empty) (*empty.Empty, error)
-	// Spawn food in the world
-	SpawnFood(context.Context, *empty.Empty) (*empty.Empty, error)
-	// Get Region
-	GetEntitiesInRegion(context.Context, *GetEntitiesInRegionRequest) (*GetEntitiesInRegionResponse, error)
-	GetEffectsInRegion(context.Context, *GetEffectsInRegionRequest) (*GetEffectsInRegionResponse, error)
+// Contains the id of the regenerated world
+type ResetWorldResponse struct {
+	// Incremented every time the world is reset
+	GenerationID         int64    `protobuf:"varint,1,opt,name=generationID,proto3" json:"generationID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-// UnimplementedEnvironmentServer can be embedded to have forward compatible implementations.
-type UnimplementedEnvironmentServer struct {
+func (m *ResetWorldResponse) Reset()         { *m = ResetWorldResponse{} }
+func (m *ResetWorldResponse) String() string { return proto.CompactTextString(m) }
+func (*ResetWorldResponse) ProtoMessage()    {}
+func (*ResetWorldResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{16}
 }
 
-func (*UnimplementedEnvironmentServer) CreateEntity(ctx context.Context, req *CreateEntityRequest) (*CreateEntityResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateEntity not implemented")
+func (m *ResetWorldResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResetWorldResponse.Unmarshal(m, b)
 }
-func (*UnimplementedEnvironmentServer) GetEntity(ctx context.Context, req *GetEntityRequest) (*GetEntityResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetEntity not implemented")
+func (m *ResetWorldResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResetWorldResponse.Marshal(b, m, deterministic)
 }
-func (*UnimplementedEnvironmentServer) DeleteEntity(ctx context.Context, req *DeleteEntityRequest) (*DeleteEntityResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteEntity not implemented")
+func (m *ResetWorldResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResetWorldResponse.Merge(m, src)
 }
-func (*UnimplementedEnvironmentServer) ExecuteAgentAction(ctx context.Context, req *ExecuteAgentActionRequest) (*ExecuteAgentActionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ExecuteAgentAction not implemented")
+func (m *ResetWorldResponse) XXX_Size() int {
+	return xxx_messageInfo_ResetWorldResponse.Size(m)
 }
-func (*UnimplementedEnvironmentServer) ResetWorld(ctx context.Context, req *empty.Empty) (*empty.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ResetWorld not implemented")
+func (m *ResetWorldResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResetWorldResponse.DiscardUnknown(m)
 }
-func (*UnimplementedEnvironmentServer) SpawnFood(ctx context.Context, req *empty.Empty) (*empty.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SpawnFood not implemented")
+
+var xxx_messageInfo_ResetWorldResponse proto.InternalMessageInfo
+
+func (m *ResetWorldResponse) GetGenerationID() int64 {
+	if m != nil {
+		return m.GenerationID
+	}
+	return 0
 }
-func (*UnimplementedEnvironmentServer) GetEntitiesInRegion(ctx context.Context, req *GetEntitiesInRegionRequest) (*GetEntitiesInRegionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetEntitiesInRegion not implemented")
+
+type Position struct {
+	X                    uint32   `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y                    uint32   `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
-func (*UnimplementedEnvironmentServer) GetEffectsInRegion(ctx context.Context, req *GetEffectsInRegionRequest) (*GetEffectsInRegionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetEffectsInRegion not implemented")
+
+func (m *Position) Reset()         { *m = Position{} }
+func (m *Position) String() string { return proto.CompactTextString(m) }
+func (*Position) ProtoMessage()    {}
+func (*Position) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{17}
 }
 
-func RegisterEnvironmentServer(s *grpc.Server, srv EnvironmentServer) {
-	s.RegisterService(&_Environment_serviceDesc, srv)
+func (m *Position) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Position.Unmarshal(m, b)
+}
+func (m *Position) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Position.Marshal(b, m, deterministic)
+}
+func (m *Position) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Position.Merge(m, src)
+}
+func (m *Position) XXX_Size() int {
+	return xxx_messageInfo_Position.Size(m)
+}
+func (m *Position) XXX_DiscardUnknown() {
+	xxx_messageInfo_Position.DiscardUnknown(m)
 }
 
-func _Environment_CreateEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateEntityRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(EnvironmentServer).CreateEntity(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/endpoints.terrariumai.environment.Environment/CreateEntity",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(EnvironmentServer).CreateEntity(ctx, req.(*CreateEntityRequest))
+var xxx_messageInfo_Position proto.InternalMessageInfo
+
+func (m *Position) GetX() uint32 {
+	if m != nil {
+		return m.X
 	}
-	return interceptor(ctx, in, info, handler)
+	return 0
 }
 
-func _Environment_GetEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetEntityRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(EnvironmentServer).GetEntity(ctx, in)
+func (m *Position) GetY() uint32 {
+	if m != nil {
+		return m.Y
+	}
+	return 0
+}
+
+// Rules that balance the ecosystem of the world
+type WorldRules struct {
+	// Energy costs
+	LivingEnergyCost uint32 `protobuf:"varint,1,opt,name=livingEnergyCost,proto3" json:"livingEnergyCost,omitempty"`
+	MoveEnergyCost   uint32 `protobuf:"varint,2,opt,name=moveEnergyCost,proto3" json:"moveEnergyCost,omitempty"`
+	AttackEnergyCost uint32 `protobuf:"varint,3,opt,name=attackEnergyCost,proto3" json:"attackEnergyCost,omitempty"`
+	// Energy gained from eating food
+	EnergyGainOnEat uint32 `protobuf:"varint,4,opt,name=energyGainOnEat,proto3" json:"energyGainOnEat,omitempty"`
+	// Health removed from the target of an attack
+	AttackDamage uint32 `protobuf:"varint,5,opt,name=attackDamage,proto3" json:"attackDamage,omitempty"`
+	// Starting stats for created agents
+	StartingEnergy uint32 `protobuf:"varint,6,opt,name=startingEnergy,proto3" json:"startingEnergy,omitempty"`
+	StartingHealth uint32 `protobuf:"varint,7,opt,name=startingHealth,proto3" json:"startingHealth,omitempty"`
+	// Max agents a model can manually create
+	MaxUserCreatedEntities uint32 `protobuf:"varint,8,opt,name=maxUserCreatedEntities,proto3" json:"maxUserCreatedEntities,omitempty"`
+	// Pheromones left behind by moving agents
+	PheromoneDecay     float32 `protobuf:"fixed32,9,opt,name=pheromoneDecay,proto3" json:"pheromoneDecay,omitempty"`
+	PheromoneDelThresh uint32  `protobuf:"varint,10,opt,name=pheromoneDelThresh,proto3" json:"pheromoneDelThresh,omitempty"`
+	// Extra energy to move onto slow ground
+	SlowGroundEnergyCost uint32 `protobuf:"varint,11,opt,name=slowGroundEnergyCost,proto3" json:"slowGroundEnergyCost,omitempty"`
+	// Food regrowth, eaten food is replaced one for one when foodCapacity is 0
+	FoodCapacity       uint32  `protobuf:"varint,12,opt,name=foodCapacity,proto3" json:"foodCapacity,omitempty"`
+	FoodGrowthRate     float32 `protobuf:"fixed32,13,opt,name=foodGrowthRate,proto3" json:"foodGrowthRate,omitempty"`
+	FoodGrowthInterval uint32  `protobuf:"varint,14,opt,name=foodGrowthInterval,proto3" json:"foodGrowthInterval,omitempty"`
+	// Seasons speeding food growth up and slowing it down
+	SeasonLength         uint32   `protobuf:"varint,15,opt,name=seasonLength,proto3" json:"seasonLength,omitempty"`
+	SeasonAmplitude      float32  `protobuf:"fixed32,16,opt,name=seasonAmplitude,proto3" json:"seasonAmplitude,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WorldRules) Reset()         { *m = WorldRules{} }
+func (m *WorldRules) String() string { return proto.CompactTextString(m) }
+func (*WorldRules) ProtoMessage()    {}
+func (*WorldRules) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{18}
+}
+
+func (m *WorldRules) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WorldRules.Unmarshal(m, b)
+}
+func (m *WorldRules) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WorldRules.Marshal(b, m, deterministic)
+}
+func (m *WorldRules) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WorldRules.Merge(m, src)
+}
+func (m *WorldRules) XXX_Size() int {
+	return xxx_messageInfo_WorldRules.Size(m)
+}
+func (m *WorldRules) XXX_DiscardUnknown() {
+	xxx_messageInfo_WorldRules.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WorldRules proto.InternalMessageInfo
+
+func (m *WorldRules) GetLivingEnergyCost() uint32 {
+	if m != nil {
+		return m.LivingEnergyCost
+	}
+	return 0
+}
+
+func (m *WorldRules) GetMoveEnergyCost() uint32 {
+	if m != nil {
+		return m.MoveEnergyCost
+	}
+	return 0
+}
+
+func (m *WorldRules) GetAttackEnergyCost() uint32 {
+	if m != nil {
+		return m.AttackEnergyCost
+	}
+	return 0
+}
+
+func (m *WorldRules) GetEnergyGainOnEat() uint32 {
+	if m != nil {
+		return m.EnergyGainOnEat
+	}
+	return 0
+}
+
+func (m *WorldRules) GetAttackDamage() uint32 {
+	if m != nil {
+		return m.AttackDamage
+	}
+	return 0
+}
+
+func (m *WorldRules) GetStartingEnergy() uint32 {
+	if m != nil {
+		return m.StartingEnergy
+	}
+	return 0
+}
+
+func (m *WorldRules) GetStartingHealth() uint32 {
+	if m != nil {
+		return m.StartingHealth
+	}
+	return 0
+}
+
+func (m *WorldRules) GetMaxUserCreatedEntities() uint32 {
+	if m != nil {
+		return m.MaxUserCreatedEntities
+	}
+	return 0
+}
+
+func (m *WorldRules) GetPheromoneDecay() float32 {
+	if m != nil {
+		return m.PheromoneDecay
+	}
+	return 0
+}
+
+func (m *WorldRules) GetPheromoneDelThresh() uint32 {
+	if m != nil {
+		return m.PheromoneDelThresh
+	}
+	return 0
+}
+
+func (m *WorldRules) GetSlowGroundEnergyCost() uint32 {
+	if m != nil {
+		return m.SlowGroundEnergyCost
+	}
+	return 0
+}
+
+func (m *WorldRules) GetFoodCapacity() uint32 {
+	if m != nil {
+		return m.FoodCapacity
+	}
+	return 0
+}
+
+func (m *WorldRules) GetFoodGrowthRate() float32 {
+	if m != nil {
+		return m.FoodGrowthRate
+	}
+	return 0
+}
+
+func (m *WorldRules) GetFoodGrowthInterval() uint32 {
+	if m != nil {
+		return m.FoodGrowthInterval
+	}
+	return 0
+}
+
+func (m *WorldRules) GetSeasonLength() uint32 {
+	if m != nil {
+		return m.SeasonLength
+	}
+	return 0
+}
+
+func (m *WorldRules) GetSeasonAmplitude() float32 {
+	if m != nil {
+		return m.SeasonAmplitude
+	}
+	return 0
+}
+
+// A world hosted on the same infrastructure as the others, each has its own
+// entities, effects and environment server
+type World struct {
+	Id                   string         `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Width                uint32         `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height               uint32         `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	Topology             World_Topology `protobuf:"varint,4,opt,name=topology,proto3,enum=endpoints.terrariumai.environment.World_Topology" json:"topology,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *World) Reset()         { *m = World{} }
+func (m *World) String() string { return proto.CompactTextString(m) }
+func (*World) ProtoMessage()    {}
+func (*World) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{19}
+}
+
+func (m *World) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_World.Unmarshal(m, b)
+}
+func (m *World) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_World.Marshal(b, m, deterministic)
+}
+func (m *World) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_World.Merge(m, src)
+}
+func (m *World) XXX_Size() int {
+	return xxx_messageInfo_World.Size(m)
+}
+func (m *World) XXX_DiscardUnknown() {
+	xxx_messageInfo_World.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_World proto.InternalMessageInfo
+
+func (m *World) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *World) GetWidth() uint32 {
+	if m != nil {
+		return m.Width
+	}
+	return 0
+}
+
+func (m *World) GetHeight() uint32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *World) GetTopology() World_Topology {
+	if m != nil {
+		return m.Topology
+	}
+	return World_BOUNDED
+}
+
+type CreateWorldRequest struct {
+	// Lowercase letters, digits and dashes
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Size of the world, left empty for the default size
+	Width                uint32         `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height               uint32         `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	Topology             World_Topology `protobuf:"varint,4,opt,name=topology,proto3,enum=endpoints.terrariumai.environment.World_Topology" json:"topology,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *CreateWorldRequest) Reset()         { *m = CreateWorldRequest{} }
+func (m *CreateWorldRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateWorldRequest) ProtoMessage()    {}
+func (*CreateWorldRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{20}
+}
+
+func (m *CreateWorldRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateWorldRequest.Unmarshal(m, b)
+}
+func (m *CreateWorldRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateWorldRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateWorldRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateWorldRequest.Merge(m, src)
+}
+func (m *CreateWorldRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateWorldRequest.Size(m)
+}
+func (m *CreateWorldRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateWorldRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateWorldRequest proto.InternalMessageInfo
+
+func (m *CreateWorldRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *CreateWorldRequest) GetWidth() uint32 {
+	if m != nil {
+		return m.Width
+	}
+	return 0
+}
+
+func (m *CreateWorldRequest) GetHeight() uint32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *CreateWorldRequest) GetTopology() World_Topology {
+	if m != nil {
+		return m.Topology
+	}
+	return World_BOUNDED
+}
+
+type ListWorldsResponse struct {
+	Worlds               []*World `protobuf:"bytes,1,rep,name=worlds,proto3" json:"worlds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListWorldsResponse) Reset()         { *m = ListWorldsResponse{} }
+func (m *ListWorldsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListWorldsResponse) ProtoMessage()    {}
+func (*ListWorldsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{21}
+}
+
+func (m *ListWorldsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListWorldsResponse.Unmarshal(m, b)
+}
+func (m *ListWorldsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListWorldsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListWorldsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListWorldsResponse.Merge(m, src)
+}
+func (m *ListWorldsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListWorldsResponse.Size(m)
+}
+func (m *ListWorldsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListWorldsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListWorldsResponse proto.InternalMessageInfo
+
+func (m *ListWorldsResponse) GetWorlds() []*World {
+	if m != nil {
+		return m.Worlds
+	}
+	return nil
+}
+
+type DeleteWorldRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteWorldRequest) Reset()         { *m = DeleteWorldRequest{} }
+func (m *DeleteWorldRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteWorldRequest) ProtoMessage()    {}
+func (*DeleteWorldRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{22}
+}
+
+func (m *DeleteWorldRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteWorldRequest.Unmarshal(m, b)
+}
+func (m *DeleteWorldRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteWorldRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteWorldRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteWorldRequest.Merge(m, src)
+}
+func (m *DeleteWorldRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteWorldRequest.Size(m)
+}
+func (m *DeleteWorldRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteWorldRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteWorldRequest proto.InternalMessageInfo
+
+func (m *DeleteWorldRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// A rectangle of cells, corners included
+type Rect struct {
+	X0                   uint32   `protobuf:"varint,1,opt,name=x0,proto3" json:"x0,omitempty"`
+	Y0                   uint32   `protobuf:"varint,2,opt,name=y0,proto3" json:"y0,omitempty"`
+	X1                   uint32   `protobuf:"varint,3,opt,name=x1,proto3" json:"x1,omitempty"`
+	Y1                   uint32   `protobuf:"varint,4,opt,name=y1,proto3" json:"y1,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Rect) Reset()         { *m = Rect{} }
+func (m *Rect) String() string { return proto.CompactTextString(m) }
+func (*Rect) ProtoMessage()    {}
+func (*Rect) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{23}
+}
+
+func (m *Rect) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Rect.Unmarshal(m, b)
+}
+func (m *Rect) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Rect.Marshal(b, m, deterministic)
+}
+func (m *Rect) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Rect.Merge(m, src)
+}
+func (m *Rect) XXX_Size() int {
+	return xxx_messageInfo_Rect.Size(m)
+}
+func (m *Rect) XXX_DiscardUnknown() {
+	xxx_messageInfo_Rect.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Rect proto.InternalMessageInfo
+
+func (m *Rect) GetX0() uint32 {
+	if m != nil {
+		return m.X0
+	}
+	return 0
+}
+
+func (m *Rect) GetY0() uint32 {
+	if m != nil {
+		return m.Y0
+	}
+	return 0
+}
+
+func (m *Rect) GetX1() uint32 {
+	if m != nil {
+		return m.X1
+	}
+	return 0
+}
+
+func (m *Rect) GetY1() uint32 {
+	if m != nil {
+		return m.Y1
+	}
+	return 0
+}
+
+// Entities to place in a part of the world
+type SpawnEntitiesRequest struct {
+	ClassID Entity_Class `protobuf:"varint,1,opt,name=classID,proto3,enum=endpoints.terrariumai.environment.Entity_Class" json:"classID,omitempty"`
+	Count   uint32       `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	// The whole world when left out
+	Bounds       *Rect                             `protobuf:"bytes,3,opt,name=bounds,proto3" json:"bounds,omitempty"`
+	Distribution SpawnEntitiesRequest_Distribution `protobuf:"varint,4,opt,name=distribution,proto3,enum=endpoints.terrariumai.environment.SpawnEntitiesRequest_Distribution" json:"distribution,omitempty"`
+	// Defaults to a sixth of the rectangle's shorter side for gaussian
+	// clusters, and 1 for rings
+	Spread float32 `protobuf:"fixed32,5,opt,name=spread,proto3" json:"spread,omitempty"`
+	// Defaults to a third of the rectangle's shorter side
+	Radius float32 `protobuf:"fixed32,6,opt,name=radius,proto3" json:"radius,omitempty"`
+	// Agents belong to a model
+	ModelID              string   `protobuf:"bytes,7,opt,name=modelID,proto3" json:"modelID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SpawnEntitiesRequest) Reset()         { *m = SpawnEntitiesRequest{} }
+func (m *SpawnEntitiesRequest) String() string { return proto.CompactTextString(m) }
+func (*SpawnEntitiesRequest) ProtoMessage()    {}
+func (*SpawnEntitiesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{24}
+}
+
+func (m *SpawnEntitiesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SpawnEntitiesRequest.Unmarshal(m, b)
+}
+func (m *SpawnEntitiesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SpawnEntitiesRequest.Marshal(b, m, deterministic)
+}
+func (m *SpawnEntitiesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SpawnEntitiesRequest.Merge(m, src)
+}
+func (m *SpawnEntitiesRequest) XXX_Size() int {
+	return xxx_messageInfo_SpawnEntitiesRequest.Size(m)
+}
+func (m *SpawnEntitiesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SpawnEntitiesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SpawnEntitiesRequest proto.InternalMessageInfo
+
+func (m *SpawnEntitiesRequest) GetClassID() Entity_Class {
+	if m != nil {
+		return m.ClassID
+	}
+	return Entity_EMPTY
+}
+
+func (m *SpawnEntitiesRequest) GetCount() uint32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *SpawnEntitiesRequest) GetBounds() *Rect {
+	if m != nil {
+		return m.Bounds
+	}
+	return nil
+}
+
+func (m *SpawnEntitiesRequest) GetDistribution() SpawnEntitiesRequest_Distribution {
+	if m != nil {
+		return m.Distribution
+	}
+	return SpawnEntitiesRequest_UNIFORM
+}
+
+func (m *SpawnEntitiesRequest) GetSpread() float32 {
+	if m != nil {
+		return m.Spread
+	}
+	return 0
+}
+
+func (m *SpawnEntitiesRequest) GetRadius() float32 {
+	if m != nil {
+		return m.Radius
+	}
+	return 0
+}
+
+func (m *SpawnEntitiesRequest) GetModelID() string {
+	if m != nil {
+		return m.ModelID
+	}
+	return ""
+}
+
+// Contains the ids of every entity spawned
+type SpawnEntitiesResponse struct {
+	Ids                  []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SpawnEntitiesResponse) Reset()         { *m = SpawnEntitiesResponse{} }
+func (m *SpawnEntitiesResponse) String() string { return proto.CompactTextString(m) }
+func (*SpawnEntitiesResponse) ProtoMessage()    {}
+func (*SpawnEntitiesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64e647b85623514a, []int{25}
+}
+
+func (m *SpawnEntitiesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SpawnEntitiesResponse.Unmarshal(m, b)
+}
+func (m *SpawnEntitiesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SpawnEntitiesResponse.Marshal(b, m, deterministic)
+}
+func (m *SpawnEntitiesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SpawnEntitiesResponse.Merge(m, src)
+}
+func (m *SpawnEntitiesResponse) XXX_Size() int {
+	return xxx_messageInfo_SpawnEntitiesResponse.Size(m)
+}
+func (m *SpawnEntitiesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SpawnEntitiesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SpawnEntitiesResponse proto.InternalMessageInfo
+
+func (m *SpawnEntitiesResponse) GetIds() []string {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("endpoints.terrariumai.environment.Entity_Class", Entity_Class_name, Entity_Class_value)
+	proto.RegisterEnum("endpoints.terrariumai.environment.Effect_Class", Effect_Class_name, Effect_Class_value)
+	proto.RegisterEnum("endpoints.terrariumai.environment.ExecuteAgentActionRequest_Action", ExecuteAgentActionRequest_Action_name, ExecuteAgentActionRequest_Action_value)
+	proto.RegisterEnum("endpoints.terrariumai.environment.ExecuteAgentActionRequest_Direction", ExecuteAgentActionRequest_Direction_name, ExecuteAgentActionRequest_Direction_value)
+	proto.RegisterEnum("endpoints.terrariumai.environment.ExecuteAgentActionResponse_ResponseValue", ExecuteAgentActionResponse_ResponseValue_name, ExecuteAgentActionResponse_ResponseValue_value)
+	proto.RegisterEnum("endpoints.terrariumai.environment.World_Topology", World_Topology_name, World_Topology_value)
+	proto.RegisterEnum("endpoints.terrariumai.environment.SpawnEntitiesRequest_Distribution", SpawnEntitiesRequest_Distribution_name, SpawnEntitiesRequest_Distribution_value)
+	proto.RegisterType((*Entity)(nil), "endpoints.terrariumai.environment.Entity")
+	proto.RegisterType((*Effect)(nil), "endpoints.terrariumai.environment.Effect")
+	proto.RegisterType((*CreateEntityRequest)(nil), "endpoints.terrariumai.environment.CreateEntityRequest")
+	proto.RegisterType((*CreateEntityResponse)(nil), "endpoints.terrariumai.environment.CreateEntityResponse")
+	proto.RegisterType((*GetEntityRequest)(nil), "endpoints.terrariumai.environment.GetEntityRequest")
+	proto.RegisterType((*GetEntityResponse)(nil), "endpoints.terrariumai.environment.GetEntityResponse")
+	proto.RegisterType((*DeleteEntityRequest)(nil), "endpoints.terrariumai.environment.DeleteEntityRequest")
+	proto.RegisterType((*DeleteEntityResponse)(nil), "endpoints.terrariumai.environment.DeleteEntityResponse")
+	proto.RegisterType((*ExecuteAgentActionRequest)(nil), "endpoints.terrariumai.environment.ExecuteAgentActionRequest")
+	proto.RegisterType((*ExecuteAgentActionResponse)(nil), "endpoints.terrariumai.environment.ExecuteAgentActionResponse")
+	proto.RegisterType((*GetEntitiesInRegionRequest)(nil), "endpoints.terrariumai.environment.GetEntitiesInRegionRequest")
+	proto.RegisterType((*GetEntitiesInRegionResponse)(nil), "endpoints.terrariumai.environment.GetEntitiesInRegionResponse")
+	proto.RegisterType((*GetEffectsInRegionRequest)(nil), "endpoints.terrariumai.environment.GetEffectsInRegionRequest")
+	proto.RegisterType((*GetEffectsInRegionResponse)(nil), "endpoints.terrariumai.environment.GetEffectsInRegionResponse")
+	proto.RegisterType((*ResetWorldRequest)(nil), "endpoints.terrariumai.environment.ResetWorldRequest")
+	proto.RegisterType((*WorldGenerator)(nil), "endpoints.terrariumai.environment.WorldGenerator")
+	proto.RegisterType((*ResetWorldResponse)(nil), "endpoints.terrariumai.environment.ResetWorldResponse")
+	proto.RegisterType((*Position)(nil), "endpoints.terrariumai.environment.Position")
+	proto.RegisterType((*WorldRules)(nil), "endpoints.terrariumai.environment.WorldRules")
+	proto.RegisterType((*World)(nil), "endpoints.terrariumai.environment.World")
+	proto.RegisterType((*CreateWorldRequest)(nil), "endpoints.terrariumai.environment.CreateWorldRequest")
+	proto.RegisterType((*ListWorldsResponse)(nil), "endpoints.terrariumai.environment.ListWorldsResponse")
+	proto.RegisterType((*DeleteWorldRequest)(nil), "endpoints.terrariumai.environment.DeleteWorldRequest")
+	proto.RegisterType((*Rect)(nil), "endpoints.terrariumai.environment.Rect")
+	proto.RegisterType((*SpawnEntitiesRequest)(nil), "endpoints.terrariumai.environment.SpawnEntitiesRequest")
+	proto.RegisterType((*SpawnEntitiesResponse)(nil), "endpoints.terrariumai.environment.SpawnEntitiesResponse")
+}
+
+func init() { proto.RegisterFile("environment.proto", fileDescriptor_64e647b85623514a) }
+
+var fileDescriptor_64e647b85623514a = []byte{
+	// 1743 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x58, 0x5f, 0x6f, 0xdb, 0xc8,
+	0x11, 0x17, 0x29, 0x59, 0x7f, 0x46, 0xb6, 0x8f, 0xd9, 0xb8, 0x01, 0x4f, 0x57, 0x14, 0xee, 0xa2,
+	0x4d, 0x7d, 0x2d, 0xaa, 0xc4, 0xce, 0xdd, 0x25, 0x0f, 0x75, 0xaf, 0xaa, 0x49, 0x2b, 0x42, 0x62,
+	0xcb, 0x58, 0xcb, 0x4e, 0x8b, 0xa2, 0x3d, 0x30, 0xe2, 0x46, 0x22, 0x8e, 0x22, 0x55, 0x72, 0x65,
+	0x4b, 0xe8, 0x53, 0x81, 0x7e, 0x83, 0x02, 0x45, 0x5f, 0xfa, 0x70, 0xdf, 0xe2, 0x3e, 0x47, 0x81,
+	0xa2, 0xaf, 0xfd, 0x1a, 0x7d, 0x2b, 0xf6, 0x0f, 0x25, 0x52, 0x92, 0x53, 0x2a, 0xee, 0x43, 0xdf,
+	0x38, 0xbf, 0xdd, 0x99, 0xfd, 0xcd, 0xec, 0xec, 0xee, 0x0c, 0xe1, 0x01, 0x0d, 0x6e, 0xbc, 0x28,
+	0x0c, 0x46, 0x34, 0x60, 0xcd, 0x71, 0x14, 0xb2, 0x10, 0x7d, 0x9f, 0x06, 0xee, 0x38, 0xf4, 0x02,
+	0x16, 0x37, 0x19, 0x8d, 0x22, 0x27, 0xf2, 0x26, 0x23, 0xc7, 0x6b, 0xa6, 0x26, 0x36, 0x3e, 0x19,
+	0x84, 0xe1, 0xc0, 0xa7, 0x4f, 0x84, 0xc2, 0xdb, 0xc9, 0xbb, 0x27, 0x74, 0x34, 0x66, 0x33, 0xa9,
+	0x8f, 0xff, 0xaa, 0x43, 0xd9, 0x0e, 0x98, 0xc7, 0x66, 0x68, 0x17, 0x74, 0xcf, 0x35, 0xb5, 0x7d,
+	0xed, 0xa0, 0x46, 0x74, 0xcf, 0x45, 0x1d, 0xa8, 0xf4, 0x7d, 0x27, 0x8e, 0x3b, 0x96, 0xa9, 0xef,
+	0x6b, 0x07, 0xbb, 0x47, 0x4f, 0x9a, 0xff, 0x75, 0xb1, 0xa6, 0xb4, 0xd5, 0x3c, 0xe1, 0x8a, 0x24,
+	0xd1, 0x47, 0xdb, 0xa0, 0x4d, 0xcd, 0xe2, 0xbe, 0x76, 0xb0, 0x43, 0xb4, 0x29, 0x97, 0x66, 0x66,
+	0x49, 0x4a, 0x33, 0xf4, 0x08, 0xca, 0x34, 0xa0, 0xd1, 0x60, 0x66, 0x6e, 0x09, 0x48, 0x49, 0x1c,
+	0x1f, 0x52, 0xc7, 0x67, 0x43, 0xb3, 0x2c, 0x71, 0x29, 0xa1, 0x06, 0x54, 0xc3, 0xdb, 0x80, 0x46,
+	0x57, 0x1d, 0xcb, 0xac, 0x08, 0xb2, 0x73, 0x19, 0x99, 0x50, 0x19, 0x85, 0x2e, 0xf5, 0x3b, 0x96,
+	0x59, 0x15, 0x43, 0x89, 0x88, 0x0f, 0x61, 0x4b, 0x70, 0x42, 0x35, 0xd8, 0xb2, 0xcf, 0x2e, 0x7a,
+	0xbf, 0x36, 0x0a, 0xfc, 0xb3, 0xd5, 0xb6, 0xcf, 0x7b, 0x86, 0x86, 0xaa, 0x50, 0x22, 0xdd, 0x93,
+	0x57, 0x86, 0xce, 0xbf, 0x4e, 0xbb, 0x5d, 0xcb, 0x28, 0xe2, 0x7f, 0x6b, 0x50, 0xb6, 0xdf, 0xbd,
+	0xa3, 0x7d, 0x26, 0xf9, 0x6b, 0x19, 0xfe, 0x7a, 0xc2, 0xff, 0xbb, 0x50, 0x63, 0xde, 0x88, 0xc6,
+	0xcc, 0x19, 0x8d, 0x85, 0x8f, 0x45, 0xb2, 0x00, 0xd2, 0x41, 0x2c, 0xe5, 0x0f, 0xa2, 0x58, 0x75,
+	0x39, 0x88, 0x7b, 0xb0, 0x75, 0xe3, 0xf8, 0x13, 0xaa, 0xe2, 0x24, 0x05, 0x8e, 0xba, 0xb4, 0xef,
+	0xcc, 0x44, 0x94, 0x74, 0x22, 0x05, 0x4e, 0xca, 0xa5, 0x7e, 0x6f, 0x18, 0xd1, 0x78, 0x28, 0xa2,
+	0xb4, 0x43, 0x16, 0x00, 0xde, 0x4f, 0x82, 0x51, 0x85, 0xd2, 0x79, 0xf7, 0xdc, 0x36, 0x0a, 0x68,
+	0x07, 0x6a, 0x17, 0x2f, 0x6d, 0xd2, 0x3d, 0xe3, 0xa2, 0x86, 0x7f, 0x05, 0x0f, 0x4f, 0x22, 0xea,
+	0x30, 0x2a, 0xf7, 0x93, 0xd0, 0xdf, 0x4f, 0x68, 0xcc, 0x50, 0x8b, 0xef, 0x15, 0x07, 0x44, 0x30,
+	0xea, 0x47, 0x9f, 0xe6, 0xce, 0x08, 0xa2, 0x14, 0xf1, 0x63, 0xd8, 0xcb, 0x5a, 0x8e, 0xc7, 0x61,
+	0x10, 0xd3, 0xe5, 0xec, 0xc3, 0x18, 0x8c, 0x36, 0x65, 0xd9, 0xe5, 0x97, 0xe7, 0x5c, 0xc3, 0x83,
+	0xd4, 0x1c, 0x65, 0xe8, 0x7f, 0xc0, 0xf1, 0x87, 0xf0, 0xd0, 0xa2, 0x3e, 0x65, 0xf4, 0xfd, 0xcb,
+	0x3f, 0x85, 0xbd, 0xec, 0x34, 0xc5, 0xc0, 0x84, 0x8a, 0x2b, 0x70, 0x39, 0xb9, 0x48, 0x12, 0x11,
+	0xff, 0x43, 0x87, 0x8f, 0xed, 0x29, 0xed, 0x4f, 0x18, 0x6d, 0x0d, 0x68, 0xc0, 0x5a, 0x7d, 0xe6,
+	0x85, 0xc1, 0x1d, 0xf6, 0xd1, 0x6f, 0xa0, 0xec, 0x88, 0x09, 0xea, 0xfc, 0x9d, 0xe4, 0xf1, 0xe4,
+	0x2e, 0xeb, 0x4d, 0x25, 0x29, 0x93, 0xc8, 0x85, 0x9a, 0xeb, 0x45, 0x54, 0xda, 0x2f, 0x0a, 0xfb,
+	0xa7, 0xf7, 0xb2, 0x6f, 0x25, 0xd6, 0xc8, 0xc2, 0x30, 0x3e, 0x84, 0xb2, 0x9c, 0xc5, 0x53, 0xed,
+	0x4d, 0xab, 0xd3, 0x33, 0x0a, 0xfc, 0xeb, 0xac, 0x7b, 0x6d, 0x1b, 0x1a, 0xaa, 0x40, 0xd1, 0x6e,
+	0xf5, 0x0c, 0x1d, 0x01, 0x94, 0x5b, 0xbd, 0x5e, 0xeb, 0xe4, 0x95, 0x51, 0xc4, 0x47, 0x50, 0x9b,
+	0x9b, 0x42, 0x65, 0xd0, 0xaf, 0x2e, 0xa4, 0x8e, 0xd5, 0x7d, 0x73, 0x2e, 0x4f, 0xea, 0x6b, 0xfb,
+	0x94, 0x2b, 0xd5, 0x60, 0x8b, 0x74, 0xda, 0x2f, 0x7b, 0x46, 0x11, 0xff, 0x5d, 0x83, 0xc6, 0x3a,
+	0x66, 0x6a, 0x43, 0x9c, 0xe4, 0xe4, 0x68, 0xc2, 0xcf, 0x57, 0x1f, 0xe8, 0xa7, 0xb4, 0xd6, 0x4c,
+	0x3e, 0xae, 0xb9, 0xc9, 0xe4, 0x18, 0x22, 0x28, 0x31, 0xaf, 0xff, 0xb5, 0xd8, 0xa9, 0x12, 0x11,
+	0xdf, 0xf8, 0x18, 0x76, 0x32, 0x73, 0xb9, 0x37, 0xdd, 0x57, 0x46, 0x01, 0x3d, 0x02, 0x64, 0x13,
+	0xf2, 0x55, 0xe7, 0xfc, 0xba, 0xf5, 0xba, 0x63, 0x7d, 0xd5, 0x6b, 0x91, 0xb6, 0xcd, 0x6f, 0xa1,
+	0x6d, 0xa8, 0x72, 0xdc, 0xea, 0xd8, 0x96, 0xa1, 0xe3, 0x17, 0xd0, 0x48, 0xb2, 0xdb, 0xa3, 0x71,
+	0x27, 0x20, 0x74, 0x90, 0x4a, 0x96, 0xf7, 0x5c, 0x49, 0xd8, 0x85, 0x4f, 0xd6, 0x6a, 0xaa, 0x70,
+	0xd8, 0x50, 0xa5, 0x6a, 0xcc, 0xd4, 0xf6, 0x8b, 0x9b, 0x9d, 0x91, 0xb9, 0x2a, 0x7e, 0x0e, 0x1f,
+	0xf3, 0x55, 0xc4, 0x5d, 0xb5, 0x11, 0x3d, 0x07, 0x1a, 0xeb, 0x14, 0x15, 0xbb, 0x13, 0xa8, 0x50,
+	0x39, 0xb4, 0x09, 0x39, 0xa1, 0x41, 0x12, 0x4d, 0xfc, 0x4f, 0x0d, 0x1e, 0x10, 0x1a, 0x53, 0xf6,
+	0x26, 0x8c, 0x7c, 0x37, 0x21, 0x85, 0xa0, 0x14, 0xd3, 0xf9, 0xa9, 0x14, 0xdf, 0xfc, 0xa6, 0x7c,
+	0x17, 0x86, 0xee, 0x49, 0x38, 0x09, 0x98, 0xa2, 0xb8, 0x00, 0xd0, 0x97, 0x50, 0xe2, 0x82, 0x59,
+	0x14, 0x4c, 0x7e, 0x92, 0x83, 0xc9, 0x45, 0x18, 0x7b, 0x22, 0x5d, 0x84, 0x22, 0xea, 0x42, 0x6d,
+	0x40, 0x03, 0x1a, 0x39, 0x2c, 0x8c, 0xc4, 0x0b, 0x50, 0x3f, 0x3a, 0xcc, 0x61, 0x45, 0xd0, 0x6e,
+	0x27, 0x8a, 0x64, 0x61, 0x03, 0xff, 0x4b, 0x83, 0xdd, 0xec, 0x28, 0x7f, 0x02, 0xe2, 0xbe, 0xe3,
+	0xcb, 0xf4, 0xd6, 0x89, 0x14, 0x38, 0x7a, 0xeb, 0xf8, 0x7e, 0x2c, 0x9c, 0xd2, 0x89, 0x14, 0x24,
+	0xca, 0x68, 0x64, 0x16, 0x13, 0x94, 0xd1, 0x08, 0x7d, 0x0f, 0x20, 0xf6, 0xc3, 0xdb, 0x76, 0x14,
+	0x4e, 0x02, 0x57, 0xd0, 0xd4, 0x49, 0x0a, 0x41, 0xfb, 0x50, 0xe7, 0xde, 0x5c, 0x38, 0xac, 0x3f,
+	0xa4, 0xb1, 0x7a, 0x80, 0xd2, 0x10, 0x3a, 0x80, 0x8f, 0xe6, 0x22, 0x71, 0x5c, 0x6f, 0x12, 0xab,
+	0x67, 0x7b, 0x19, 0x4e, 0x6c, 0x59, 0x34, 0x88, 0xf9, 0x25, 0x5d, 0x11, 0x8b, 0xa5, 0x21, 0xfc,
+	0x02, 0x50, 0x7a, 0xef, 0x54, 0x5e, 0x60, 0xd8, 0x56, 0x51, 0xf0, 0xc2, 0xa0, 0x63, 0xa9, 0x4d,
+	0xcc, 0x60, 0xf8, 0x31, 0x54, 0x93, 0xf8, 0xbf, 0x37, 0x03, 0xbf, 0xdd, 0x02, 0x90, 0xd6, 0x27,
+	0x3e, 0x8d, 0xd1, 0x8f, 0xc1, 0xf0, 0xbd, 0x1b, 0x2f, 0x18, 0xd8, 0xa2, 0xf4, 0x38, 0x09, 0x63,
+	0xa6, 0x34, 0x57, 0x70, 0xf4, 0x18, 0x76, 0x47, 0xe1, 0x0d, 0x4d, 0xcd, 0x94, 0x56, 0x97, 0x50,
+	0x6e, 0xd3, 0x61, 0xcc, 0xe9, 0x7f, 0x9d, 0x9a, 0x29, 0x2b, 0xa0, 0x15, 0x9c, 0x07, 0x4f, 0x16,
+	0x3d, 0x6d, 0xc7, 0x0b, 0xba, 0x81, 0xed, 0x30, 0x55, 0x1e, 0x2d, 0xc3, 0x3c, 0x08, 0x52, 0xdb,
+	0x72, 0x46, 0xce, 0x20, 0x29, 0x05, 0x32, 0x18, 0x67, 0x18, 0x33, 0x27, 0x62, 0x73, 0xde, 0x6a,
+	0x27, 0x96, 0xd0, 0xf4, 0xbc, 0x97, 0xb2, 0xd0, 0xaa, 0x64, 0xe7, 0x49, 0x14, 0x7d, 0x01, 0x8f,
+	0x46, 0xce, 0xf4, 0x2a, 0xa6, 0x91, 0x7c, 0xb8, 0xdd, 0xe4, 0x62, 0x11, 0x35, 0xd6, 0x0e, 0xb9,
+	0x63, 0x94, 0xdb, 0x1f, 0x0f, 0x69, 0x14, 0x8e, 0xc2, 0x80, 0x5a, 0xa2, 0x44, 0xa9, 0x89, 0xbd,
+	0x5e, 0x42, 0x51, 0x13, 0x50, 0x0a, 0x49, 0x8a, 0x16, 0x10, 0xb6, 0xd7, 0x8c, 0xa0, 0x23, 0xd8,
+	0x5b, 0xa4, 0x66, 0x2a, 0xba, 0x75, 0xa1, 0xb1, 0x76, 0x8c, 0xc7, 0x4d, 0x1c, 0x6a, 0x67, 0xec,
+	0xf4, 0x79, 0xd6, 0x6d, 0xcb, 0xb8, 0xa5, 0x31, 0xce, 0x97, 0xcb, 0xed, 0x28, 0xbc, 0x65, 0x43,
+	0xe2, 0x30, 0x6a, 0xee, 0x48, 0xbe, 0x59, 0x94, 0xf3, 0x5d, 0x20, 0x9d, 0x80, 0xd1, 0xe8, 0xc6,
+	0xf1, 0xcd, 0x5d, 0xc9, 0x77, 0x75, 0x84, 0xaf, 0x1d, 0x53, 0x27, 0x0e, 0x83, 0xd7, 0x34, 0x18,
+	0xb0, 0xa1, 0xf9, 0x91, 0x5c, 0x3b, 0x8d, 0xf1, 0x0c, 0x90, 0x72, 0x6b, 0x34, 0xf6, 0x3d, 0x36,
+	0x71, 0xa9, 0x69, 0x88, 0xc5, 0x97, 0x61, 0xfc, 0xad, 0x06, 0x5b, 0x22, 0x75, 0x57, 0xca, 0x05,
+	0x7e, 0xb4, 0x3d, 0x97, 0x0d, 0x55, 0x42, 0x4a, 0x41, 0x96, 0xd1, 0xde, 0x60, 0x98, 0x64, 0x9f,
+	0x92, 0xd0, 0x19, 0x54, 0x59, 0x38, 0x0e, 0xfd, 0x70, 0x30, 0x53, 0x95, 0x69, 0xee, 0x7b, 0xa9,
+	0xd9, 0x53, 0x8a, 0x64, 0x6e, 0x02, 0x63, 0xa8, 0x26, 0x28, 0xaa, 0x43, 0xe5, 0x97, 0xdd, 0xab,
+	0x73, 0xcb, 0xb6, 0x64, 0x91, 0xdd, 0xeb, 0x92, 0xab, 0x4b, 0x43, 0xc3, 0xdf, 0x68, 0x80, 0x64,
+	0x92, 0x64, 0x6e, 0xe5, 0xff, 0x2b, 0x3f, 0xae, 0x01, 0xbd, 0xf6, 0x62, 0x79, 0xf5, 0xc4, 0xf3,
+	0xbb, 0xe7, 0x17, 0x50, 0xbe, 0x15, 0x88, 0x7a, 0x92, 0x0e, 0xf2, 0x2e, 0x41, 0x94, 0x1e, 0xfe,
+	0x01, 0x20, 0x59, 0x2b, 0xbe, 0xcf, 0x75, 0x7c, 0x0a, 0x25, 0xc2, 0xfb, 0x8d, 0x5d, 0xd0, 0xa7,
+	0x4f, 0xd5, 0x15, 0xa4, 0x4f, 0x9f, 0x72, 0x79, 0xf6, 0x54, 0xc5, 0x43, 0x9f, 0x09, 0x79, 0x7a,
+	0xa8, 0x02, 0xa1, 0x4f, 0x0f, 0xc5, 0xf8, 0xa1, 0xba, 0x33, 0xf4, 0xd9, 0x21, 0xfe, 0xa6, 0x08,
+	0x7b, 0x97, 0x63, 0xe7, 0x36, 0x48, 0x0e, 0x63, 0xb2, 0x60, 0xaa, 0x1d, 0xd1, 0xee, 0xd9, 0xd3,
+	0xed, 0xc1, 0x56, 0x3f, 0xf5, 0x68, 0x4a, 0x01, 0x7d, 0x09, 0xe5, 0xb7, 0xfc, 0xec, 0xc5, 0x82,
+	0x5d, 0xfd, 0xe8, 0x47, 0x39, 0xec, 0x73, 0x97, 0x89, 0x52, 0x43, 0x43, 0xd8, 0x76, 0xbd, 0x98,
+	0x45, 0xde, 0xdb, 0x89, 0x28, 0x4d, 0xe5, 0x9e, 0x5a, 0x39, 0xcc, 0xac, 0x73, 0xb8, 0x69, 0xa5,
+	0x6c, 0x91, 0x8c, 0x65, 0x9e, 0x51, 0xf1, 0x38, 0xa2, 0x8e, 0x2b, 0x6e, 0x51, 0x9d, 0x28, 0x89,
+	0xe3, 0xd1, 0xe2, 0x05, 0xd3, 0x89, 0x92, 0xd2, 0xcd, 0x65, 0x25, 0xdb, 0x5c, 0x3e, 0x83, 0xed,
+	0xf4, 0x3a, 0xfc, 0x00, 0x5c, 0x9d, 0x77, 0x4e, 0xbb, 0xe4, 0xcc, 0x28, 0xf0, 0xa2, 0xae, 0xdd,
+	0xba, 0xba, 0xbc, 0xec, 0xb4, 0x54, 0xf9, 0x4a, 0x3a, 0xe7, 0x6d, 0x43, 0xc7, 0x9f, 0xc2, 0x77,
+	0x96, 0x18, 0xab, 0x64, 0x33, 0xa0, 0xe8, 0xa9, 0x4c, 0xab, 0x11, 0xfe, 0x79, 0xf4, 0xb7, 0x6d,
+	0xa8, 0xdb, 0x0b, 0x4f, 0xd1, 0x1f, 0x35, 0xd8, 0x4e, 0x37, 0x51, 0xe8, 0x8b, 0x1c, 0xe1, 0x59,
+	0xd3, 0xcf, 0x35, 0x9e, 0x6f, 0xac, 0x27, 0x39, 0xe2, 0x02, 0x9a, 0x42, 0x6d, 0xde, 0x7b, 0xa1,
+	0x67, 0x39, 0xec, 0x2c, 0x77, 0x73, 0x8d, 0xcf, 0x36, 0x53, 0x9a, 0xaf, 0xcc, 0xbd, 0x4f, 0xf7,
+	0x5d, 0xb9, 0xbc, 0x5f, 0xd3, 0xcf, 0x35, 0x9e, 0x6f, 0xac, 0x37, 0xe7, 0xf0, 0x67, 0x0d, 0xd0,
+	0x6a, 0x8b, 0x80, 0x7e, 0x76, 0x9f, 0x0e, 0xaa, 0x71, 0x7c, 0xaf, 0xbe, 0x04, 0x17, 0xd0, 0x1f,
+	0x00, 0x16, 0x85, 0x13, 0xfa, 0x2c, 0xd7, 0xd1, 0x5b, 0xaa, 0x91, 0x1b, 0x9f, 0x6f, 0xa8, 0x35,
+	0x5f, 0xfc, 0x18, 0x6a, 0x22, 0x9f, 0x4f, 0x79, 0xd9, 0xfb, 0xa8, 0x29, 0x7f, 0x3a, 0x35, 0x93,
+	0x9f, 0x4e, 0x4d, 0x9b, 0xff, 0x74, 0x6a, 0xdc, 0x81, 0xe3, 0x02, 0xfa, 0x93, 0x06, 0x3b, 0x99,
+	0xf3, 0x80, 0x9e, 0x7f, 0xe0, 0x99, 0x6f, 0xbc, 0xd8, 0x5c, 0x71, 0xee, 0xc5, 0x5f, 0x34, 0x78,
+	0xb8, 0xa6, 0x77, 0x42, 0xc7, 0x1b, 0x24, 0xeb, 0x6a, 0xb7, 0xd6, 0xf8, 0xf9, 0x87, 0xaa, 0x67,
+	0x32, 0x6e, 0xb5, 0x6b, 0xca, 0x95, 0x71, 0x77, 0x76, 0x69, 0x8d, 0xe3, 0x0f, 0xd4, 0x9e, 0xb3,
+	0xba, 0x84, 0x6a, 0x9b, 0x32, 0x59, 0x45, 0xdf, 0xb5, 0xe7, 0x3f, 0xcd, 0xfd, 0x58, 0x72, 0x33,
+	0xb8, 0x80, 0xc6, 0x50, 0x4f, 0x95, 0x09, 0xe8, 0xf3, 0xdc, 0x97, 0x54, 0x26, 0x91, 0x73, 0xbf,
+	0xd1, 0xb8, 0x80, 0x7e, 0x0b, 0xb0, 0x78, 0xf5, 0xef, 0x74, 0x24, 0x0f, 0x91, 0xd5, 0xe2, 0x01,
+	0x17, 0xd0, 0xef, 0xa0, 0x9e, 0x7a, 0xfc, 0x73, 0x39, 0xb4, 0x5a, 0x2c, 0xdc, 0x7d, 0x76, 0xde,
+	0x96, 0x05, 0xf2, 0xec, 0x3f, 0x03, 0x00, 0xe1, 0x2c, 0xbc, 0xc5, 0x20, 0x16, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// EnvironmentClient is the client API for Environment service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type EnvironmentClient interface {
+	// Create new agent
+	CreateEntity(ctx context.Context, in *CreateEntityRequest, opts ...grpc.CallOption) (*CreateEntityResponse, error)
+	// Get data for an entity
+	GetEntity(ctx context.Context, in *GetEntityRequest, opts ...grpc.CallOption) (*GetEntityResponse, error)
+	// Delete an agent
+	DeleteEntity(ctx context.Context, in *DeleteEntityRequest, opts ...grpc.CallOption) (*DeleteEntityResponse, error)
+	// Perform an action for an agent
+	ExecuteAgentAction(ctx context.Context, in *ExecuteAgentActionRequest, opts ...grpc.CallOption) (*ExecuteAgentActionResponse, error)
+	// Reset the world
+	ResetWorld(ctx context.Context, in *ResetWorldRequest, opts ...grpc.CallOption) (*ResetWorldResponse, error)
+	// Spawn food in the world
+	SpawnFood(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
+	// Spawn entities in a part of the world
+	SpawnEntities(ctx context.Context, in *SpawnEntitiesRequest, opts ...grpc.CallOption) (*SpawnEntitiesResponse, error)
+	// Get Region
+	GetEntitiesInRegion(ctx context.Context, in *GetEntitiesInRegionRequest, opts ...grpc.CallOption) (*GetEntitiesInRegionResponse, error)
+	GetEffectsInRegion(ctx context.Context, in *GetEffectsInRegionRequest, opts ...grpc.CallOption) (*GetEffectsInRegionResponse, error)
+	// Get the rules the world is running with
+	GetRules(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*WorldRules, error)
+	// Manage the worlds hosted alongside this one
+	CreateWorld(ctx context.Context, in *CreateWorldRequest, opts ...grpc.CallOption) (*World, error)
+	ListWorlds(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListWorldsResponse, error)
+	DeleteWorld(ctx context.Context, in *DeleteWorldRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+}
+
+type environmentClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewEnvironmentClient(cc *grpc.ClientConn) EnvironmentClient {
+	return &environmentClient{cc}
+}
+
+func (c *environmentClient) CreateEntity(ctx context.Context, in *CreateEntityRequest, opts ...grpc.CallOption) (*CreateEntityResponse, error) {
+	out := new(CreateEntityResponse)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/CreateEntity", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) GetEntity(ctx context.Context, in *GetEntityRequest, opts ...grpc.CallOption) (*GetEntityResponse, error) {
+	out := new(GetEntityResponse)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/GetEntity", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) DeleteEntity(ctx context.Context, in *DeleteEntityRequest, opts ...grpc.CallOption) (*DeleteEntityResponse, error) {
+	out := new(DeleteEntityResponse)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/DeleteEntity", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) ExecuteAgentAction(ctx context.Context, in *ExecuteAgentActionRequest, opts ...grpc.CallOption) (*ExecuteAgentActionResponse, error) {
+	out := new(ExecuteAgentActionResponse)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/ExecuteAgentAction", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) ResetWorld(ctx context.Context, in *ResetWorldRequest, opts ...grpc.CallOption) (*ResetWorldResponse, error) {
+	out := new(ResetWorldResponse)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/ResetWorld", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) SpawnFood(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/SpawnFood", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) SpawnEntities(ctx context.Context, in *SpawnEntitiesRequest, opts ...grpc.CallOption) (*SpawnEntitiesResponse, error) {
+	out := new(SpawnEntitiesResponse)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/SpawnEntities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) GetEntitiesInRegion(ctx context.Context, in *GetEntitiesInRegionRequest, opts ...grpc.CallOption) (*GetEntitiesInRegionResponse, error) {
+	out := new(GetEntitiesInRegionResponse)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/GetEntitiesInRegion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) GetEffectsInRegion(ctx context.Context, in *GetEffectsInRegionRequest, opts ...grpc.CallOption) (*GetEffectsInRegionResponse, error) {
+	out := new(GetEffectsInRegionResponse)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/GetEffectsInRegion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) GetRules(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*WorldRules, error) {
+	out := new(WorldRules)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/GetRules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) CreateWorld(ctx context.Context, in *CreateWorldRequest, opts ...grpc.CallOption) (*World, error) {
+	out := new(World)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/CreateWorld", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) ListWorlds(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListWorldsResponse, error) {
+	out := new(ListWorldsResponse)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/ListWorlds", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *environmentClient) DeleteWorld(ctx context.Context, in *DeleteWorldRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/endpoints.terrariumai.environment.Environment/DeleteWorld", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EnvironmentServer is the server API for Environment service.
+type EnvironmentServer interface {
+	// Create new agent
+	CreateEntity(context.Context, *CreateEntityRequest) (*CreateEntityResponse, error)
+	// Get data for an entity
+	GetEntity(context.Context, *GetEntityRequest) (*GetEntityResponse, error)
+	// Delete an agent
+	DeleteEntity(context.Context, *DeleteEntityRequest) (*DeleteEntityResponse, error)
+	// Perform an action for an agent
+	ExecuteAgentAction(context.Context, *ExecuteAgentActionRequest) (*ExecuteAgentActionResponse, error)
+	// Reset the world
+	ResetWorld(context.Context, *ResetWorldRequest) (*ResetWorldResponse, error)
+	// Spawn food in the world
+	SpawnFood(context.Context, *empty.Empty) (*empty.Empty, error)
+	// Spawn entities in a part of the world
+	SpawnEntities(context.Context, *SpawnEntitiesRequest) (*SpawnEntitiesResponse, error)
+	// Get Region
+	GetEntitiesInRegion(context.Context, *GetEntitiesInRegionRequest) (*GetEntitiesInRegionResponse, error)
+	GetEffectsInRegion(context.Context, *GetEffectsInRegionRequest) (*GetEffectsInRegionResponse, error)
+	// Get the rules the world is running with
+	GetRules(context.Context, *empty.Empty) (*WorldRules, error)
+	// Manage the worlds hosted alongside this one
+	CreateWorld(context.Context, *CreateWorldRequest) (*World, error)
+	ListWorlds(context.Context, *empty.Empty) (*ListWorldsResponse, error)
+	DeleteWorld(context.Context, *DeleteWorldRequest) (*empty.Empty, error)
+}
+
+// UnimplementedEnvironmentServer can be embedded to have forward compatible implementations.
+type UnimplementedEnvironmentServer struct {
+}
+
+func (*UnimplementedEnvironmentServer) CreateEntity(ctx context.Context, req *CreateEntityRequest) (*CreateEntityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateEntity not implemented")
+}
+func (*UnimplementedEnvironmentServer) GetEntity(ctx context.Context, req *GetEntityRequest) (*GetEntityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEntity not implemented")
+}
+func (*UnimplementedEnvironmentServer) DeleteEntity(ctx context.Context, req *DeleteEntityRequest) (*DeleteEntityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteEntity not implemented")
+}
+func (*UnimplementedEnvironmentServer) ExecuteAgentAction(ctx context.Context, req *ExecuteAgentActionRequest) (*ExecuteAgentActionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExecuteAgentAction not implemented")
+}
+func (*UnimplementedEnvironmentServer) ResetWorld(ctx context.Context, req *ResetWorldRequest) (*ResetWorldResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetWorld not implemented")
+}
+func (*UnimplementedEnvironmentServer) SpawnFood(ctx context.Context, req *empty.Empty) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SpawnFood not implemented")
+}
+func (*UnimplementedEnvironmentServer) SpawnEntities(ctx context.Context, req *SpawnEntitiesRequest) (*SpawnEntitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SpawnEntities not implemented")
+}
+func (*UnimplementedEnvironmentServer) GetEntitiesInRegion(ctx context.Context, req *GetEntitiesInRegionRequest) (*GetEntitiesInRegionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEntitiesInRegion not implemented")
+}
+func (*UnimplementedEnvironmentServer) GetEffectsInRegion(ctx context.Context, req *GetEffectsInRegionRequest) (*GetEffectsInRegionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEffectsInRegion not implemented")
+}
+func (*UnimplementedEnvironmentServer) GetRules(ctx context.Context, req *empty.Empty) (*WorldRules, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRules not implemented")
+}
+func (*UnimplementedEnvironmentServer) CreateWorld(ctx context.Context, req *CreateWorldRequest) (*World, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWorld not implemented")
+}
+func (*UnimplementedEnvironmentServer) ListWorlds(ctx context.Context, req *empty.Empty) (*ListWorldsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWorlds not implemented")
+}
+func (*UnimplementedEnvironmentServer) DeleteWorld(ctx context.Context, req *DeleteWorldRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWorld not implemented")
+}
+
+func RegisterEnvironmentServer(s *grpc.Server, srv EnvironmentServer) {
+	s.RegisterService(&_Environment_serviceDesc, srv)
+}
+
+func _Environment_CreateEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateEntityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EnvironmentServer).CreateEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/endpoints.terrariumai.environment.Environment/CreateEntity",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EnvironmentServer).CreateEntity(ctx, req.(*CreateEntityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Environment_GetEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEntityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EnvironmentServer).GetEntity(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
@@ -1200,7 +2235,7 @@ func _Environment_ExecuteAgentAction_Handler(srv interface{}, ctx context.Contex
 }
 
 func _Environment_ResetWorld_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(empty.Empty)
+	in := new(ResetWorldRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -1212,7 +2247,7 @@ func _Environment_ResetWorld_Handler(srv interface{}, ctx context.Context, dec f
 		FullMethod: "/endpoints.terrariumai.environment.Environment/ResetWorld",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(EnvironmentServer).ResetWorld(ctx, req.(*empty.Empty))
+		return srv.(EnvironmentServer).ResetWorld(ctx, req.(*ResetWorldRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -1235,6 +2270,24 @@ func _Environment_SpawnFood_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Environment_SpawnEntities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SpawnEntitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EnvironmentServer).SpawnEntities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/endpoints.terrariumai.environment.Environment/SpawnEntities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EnvironmentServer).SpawnEntities(ctx, req.(*SpawnEntitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Environment_GetEntitiesInRegion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetEntitiesInRegionRequest)
 	if err := dec(in); err != nil {
@@ -1271,6 +2324,78 @@ func _Environment_GetEffectsInRegion_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Environment_GetRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EnvironmentServer).GetRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/endpoints.terrariumai.environment.Environment/GetRules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EnvironmentServer).GetRules(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Environment_CreateWorld_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateWorldRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EnvironmentServer).CreateWorld(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/endpoints.terrariumai.environment.Environment/CreateWorld",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EnvironmentServer).CreateWorld(ctx, req.(*CreateWorldRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Environment_ListWorlds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EnvironmentServer).ListWorlds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/endpoints.terrariumai.environment.Environment/ListWorlds",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EnvironmentServer).ListWorlds(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Environment_DeleteWorld_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWorldRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EnvironmentServer).DeleteWorld(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/endpoints.terrariumai.environment.Environment/DeleteWorld",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EnvironmentServer).DeleteWorld(ctx, req.(*DeleteWorldRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Environment_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "endpoints.terrariumai.environment.Environment",
 	HandlerType: (*EnvironmentServer)(nil),
@@ -1299,6 +2424,10 @@ var _Environment_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SpawnFood",
 			Handler:    _Environment_SpawnFood_Handler,
 		},
+		{
+			MethodName: "SpawnEntities",
+			Handler:    _Environment_SpawnEntities_Handler,
+		},
 		{
 			MethodName: "GetEntitiesInRegion",
 			Handler:    _Environment_GetEntitiesInRegion_Handler,
@@ -1307,6 +2436,22 @@ var _Environment_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetEffectsInRegion",
 			Handler:    _Environment_GetEffectsInRegion_Handler,
 		},
+		{
+			MethodName: "GetRules",
+			Handler:    _Environment_GetRules_Handler,
+		},
+		{
+			MethodName: "CreateWorld",
+			Handler:    _Environment_CreateWorld_Handler,
+		},
+		{
+			MethodName: "ListWorlds",
+			Handler:    _Environment_ListWorlds_Handler,
+		},
+		{
+			MethodName: "DeleteWorld",
+			Handler:    _Environment_DeleteWorld_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "environment.proto",
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// UserInfoHeader is the header the endpoints proxy passes the user on in
+const UserInfoHeader = "x-endpoint-api-userinfo"
+
+// ServiceTokenHeader carries the token other services, like the collective,
+// call the environment with
+const ServiceTokenHeader = "x-service-token"
+
+// ErrNoCredentials is returned by authenticators when the caller didn't
+// say who they are at all, so they can carry on anonymously
+var ErrNoCredentials = errors.New("no credentials were provided")
+
+// Authenticator works out which user is calling an rpc
+type Authenticator interface {
+	Authenticate(ctx context.Context) (*UserInfo, error)
+}
+
+// DevUser is who callers are in no-auth mode, unless they say otherwise
+var DevUser = UserInfo{Issuer: "dev", ID: "DEV-UID", Email: "dev@localhost"}
+
+// espAuthenticator trusts the userinfo header the endpoints proxy attaches to
+// each authenticated request. The proxy has to be the only way in.
+type espAuthenticator struct{}
+
+// NewESPAuthenticator trusts the users Cloud Endpoints has already verified
+func NewESPAuthenticator() Authenticator {
+	return espAuthenticator{}
+}
+
+func (espAuthenticator) Authenticate(ctx context.Context) (*UserInfo, error) {
+	return userInfoFromHeader(ctx)
+}
+
+// noAuthAuthenticator lets anyone in, for running locally
+type noAuthAuthenticator struct{}
+
+// NewNoAuthAuthenticator lets every caller in without checking anything, as
+// the user in their userinfo header or DevUser without one. Only for dev.
+func NewNoAuthAuthenticator() Authenticator {
+	return noAuthAuthenticator{}
+}
+
+func (noAuthAuthenticator) Authenticate(ctx context.Context) (*UserInfo, error) {
+	userInfo, err := userInfoFromHeader(ctx)
+	if err == ErrNoCredentials {
+		user := DevUser
+		return &user, nil
+	}
+	return userInfo, err
+}
+
+// userInfoFromHeader parses the base64 json userinfo header
+func userInfoFromHeader(ctx context.Context) (*UserInfo, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	userInfoHeader := md[UserInfoHeader]
+	if len(userInfoHeader) == 0 {
+		return nil, ErrNoCredentials
+	}
+	sDec, err := b64.StdEncoding.DecodeString(userInfoHeader[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid userinfo header: %v", err)
+	}
+	userInfo := UserInfo{}
+	if err := json.Unmarshal(sDec, &userInfo); err != nil {
+		return nil, fmt.Errorf("invalid userinfo header: %v", err)
+	}
+	if len(userInfo.ID) == 0 {
+		return nil, errors.New("invalid userinfo header: missing id")
+	}
+	return &userInfo, nil
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	b64 "encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAuthenticators(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Keys the tokens are signed with, and the files they are verified from
+	secret := []byte("MOCK-SECRET")
+	secretFile := filepath.Join(dir, "secret")
+	if err := ioutil.WriteFile(secretFile, append(secret, '\n'), 0600); err != nil {
+		t.Fatalf("error writing secret: %v", err)
+	}
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("error encoding key: %v", err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	publicFile := filepath.Join(dir, "public.pem")
+	if err := ioutil.WriteFile(publicFile, publicPEM, 0600); err != nil {
+		t.Fatalf("error writing key: %v", err)
+	}
+	hmacAuth, err := LoadJWTAuthenticator("hmac", secretFile, "simulation", "terrarium")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rsaAuth, err := LoadJWTAuthenticator("rsa", publicFile, "simulation", "terrarium")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Calls with each kind of credentials
+	userinfoCtx := func(userinfo string) context.Context {
+		md := metadata.Pairs(UserInfoHeader, b64.StdEncoding.EncodeToString([]byte(userinfo)))
+		return metadata.NewIncomingContext(context.Background(), md)
+	}
+	signedCtx := func(method jwt.SigningMethod, key interface{}, c jwt.Claims) context.Context {
+		token, err := jwt.NewWithClaims(method, c).SignedString(key)
+		if err != nil {
+			t.Fatalf("error signing token: %v", err)
+		}
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(AuthorizationHeader, "Bearer "+token))
+	}
+	tokenCtx := func(method jwt.SigningMethod, key interface{}, c claims) context.Context {
+		return signedCtx(method, key, c)
+	}
+	rawTokenCtx := func(c jwt.MapClaims) context.Context {
+		return signedCtx(jwt.SigningMethodHS256, secret, c)
+	}
+	standard := func(c jwt.StandardClaims) claims {
+		return claims{StandardClaims: c}
+	}
+	user := claims{Email: "player@example.com", StandardClaims: jwt.StandardClaims{Subject: "MOCK-UID", Audience: "simulation", Issuer: "terrarium", ExpiresAt: time.Now().Add(time.Hour).Unix()}}
+	expired := standard(jwt.StandardClaims{Subject: "MOCK-UID", Audience: "simulation", Issuer: "terrarium", ExpiresAt: time.Now().Add(-time.Hour).Unix()})
+	anonymous := standard(jwt.StandardClaims{Audience: "simulation", Issuer: "terrarium", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	forever := standard(jwt.StandardClaims{Subject: "MOCK-UID", Audience: "simulation", Issuer: "terrarium"})
+	otherAudience := standard(jwt.StandardClaims{Subject: "MOCK-UID", Audience: "billing", Issuer: "terrarium", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	noAudience := standard(jwt.StandardClaims{Subject: "MOCK-UID", Issuer: "terrarium", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	otherIssuer := standard(jwt.StandardClaims{Subject: "MOCK-UID", Audience: "simulation", Issuer: "billing", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	tokenUser := &UserInfo{Issuer: "terrarium", ID: "MOCK-UID", Email: "player@example.com"}
+
+	tests := []struct {
+		name    string
+		a       Authenticator
+		ctx     context.Context
+		want    *UserInfo
+		wantErr error
+		// Any error, when wantErr isn't a particular one
+		wantAnyErr bool
+	}{
+		{name: "ESP user", a: NewESPAuthenticator(), ctx: userinfoCtx(`{"id":"MOCK-UID","email":"player@example.com"}`), want: &UserInfo{ID: "MOCK-UID", Email: "player@example.com"}},
+		{name: "ESP without a user", a: NewESPAuthenticator(), ctx: context.Background(), wantErr: ErrNoCredentials},
+		{name: "ESP garbled user", a: NewESPAuthenticator(), ctx: userinfoCtx(`{"id":`), wantAnyErr: true},
+		{name: "ESP user without an id", a: NewESPAuthenticator(), ctx: userinfoCtx(`{"email":"player@example.com"}`), wantAnyErr: true},
+		{name: "ESP ignores tokens", a: NewESPAuthenticator(), ctx: tokenCtx(jwt.SigningMethodHS256, secret, user), wantErr: ErrNoCredentials},
+		{name: "No auth user", a: NewNoAuthAuthenticator(), ctx: userinfoCtx(`{"id":"MOCK-UID"}`), want: &UserInfo{ID: "MOCK-UID"}},
+		{name: "No auth without a user", a: NewNoAuthAuthenticator(), ctx: context.Background(), want: &DevUser},
+		{name: "HMAC token", a: hmacAuth, ctx: tokenCtx(jwt.SigningMethodHS256, secret, user), want: tokenUser},
+		{name: "HMAC token with a stronger hash", a: hmacAuth, ctx: tokenCtx(jwt.SigningMethodHS512, secret, user), want: tokenUser},
+		{name: "HMAC token with the wrong secret", a: hmacAuth, ctx: tokenCtx(jwt.SigningMethodHS256, []byte("WRONG-SECRET"), user), wantAnyErr: true},
+		{name: "HMAC without a token", a: hmacAuth, ctx: context.Background(), wantErr: ErrNoCredentials},
+		{name: "HMAC ignores the userinfo header", a: hmacAuth, ctx: userinfoCtx(`{"id":"MOCK-UID"}`), wantErr: ErrNoCredentials},
+		{name: "Expired token", a: hmacAuth, ctx: tokenCtx(jwt.SigningMethodHS256, secret, expired), wantAnyErr: true},
+		{name: "Token without a subject", a: hmacAuth, ctx: tokenCtx(jwt.SigningMethodHS256, secret, anonymous), wantAnyErr: true},
+		{name: "Token without an expiry", a: hmacAuth, ctx: tokenCtx(jwt.SigningMethodHS256, secret, forever), wantAnyErr: true},
+		{name: "Token for another audience", a: hmacAuth, ctx: tokenCtx(jwt.SigningMethodHS256, secret, otherAudience), wantAnyErr: true},
+		{name: "Token without an audience", a: hmacAuth, ctx: tokenCtx(jwt.SigningMethodHS256, secret, noAudience), wantAnyErr: true},
+		{name: "Token from another issuer", a: hmacAuth, ctx: tokenCtx(jwt.SigningMethodHS256, secret, otherIssuer), wantAnyErr: true},
+		{name: "Token with an audience list", a: hmacAuth, ctx: rawTokenCtx(jwt.MapClaims{"sub": "MOCK-UID", "aud": []string{"billing", "simulation"}, "iss": "terrarium", "exp": time.Now().Add(time.Hour).Unix()}), wantAnyErr: true},
+		{name: "Not a bearer token", a: hmacAuth, ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs(AuthorizationHeader, "Basic dXNlcjpwYXNz")), wantAnyErr: true},
+		{name: "RSA token", a: rsaAuth, ctx: tokenCtx(jwt.SigningMethodRS256, privateKey, user), want: tokenUser},
+		{name: "RSA token signed by someone else", a: rsaAuth, ctx: tokenCtx(jwt.SigningMethodRS256, mustGenerateKey(t), user), wantAnyErr: true},
+		{name: "HMAC token signed with the RSA public key", a: rsaAuth, ctx: tokenCtx(jwt.SigningMethodHS256, publicPEM, user), wantAnyErr: true},
+		{name: "RSA token to an HMAC authenticator", a: hmacAuth, ctx: tokenCtx(jwt.SigningMethodRS256, privateKey, user), wantAnyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Authenticate(tt.ctx)
+			if tt.wantErr != nil || tt.wantAnyErr {
+				if err == nil || (tt.wantErr != nil && err != tt.wantErr) {
+					t.Errorf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadJWTAuthenticator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		method  string
+		content string
+	}{
+		{name: "Empty secret", method: "hmac", content: "\n"},
+		{name: "Not a pem key", method: "rsa", content: "MOCK-SECRET"},
+		{name: "Unknown method", method: "ecdsa", content: "MOCK-SECRET"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.method)
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("error writing key file: %v", err)
+			}
+			if _, err := LoadJWTAuthenticator(tt.method, path, "simulation", "terrarium"); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+	if _, err := LoadJWTAuthenticator("hmac", filepath.Join(dir, "missing"), "simulation", "terrarium"); err == nil {
+		t.Errorf("expected an error for a missing key file")
+	}
+
+	// Without an audience and issuer any token signed with the key would do
+	path := filepath.Join(dir, "secret")
+	if err := ioutil.WriteFile(path, []byte("MOCK-SECRET"), 0600); err != nil {
+		t.Fatalf("error writing key file: %v", err)
+	}
+	if _, err := LoadJWTAuthenticator("hmac", path, "", "terrarium"); err == nil {
+		t.Errorf("expected an error without an audience")
+	}
+	if _, err := LoadJWTAuthenticator("hmac", path, "simulation", ""); err == nil {
+		t.Errorf("expected an error without an issuer")
+	}
+}
+
+// mustGenerateKey makes a new rsa key, for signing tokens nobody trusts
+func mustGenerateKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	return key
+}
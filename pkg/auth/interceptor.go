@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// roleKey is the context key the caller's role is kept under
+type roleKey struct{}
+
+// NewContext returns a context carrying the caller's role
+func NewContext(ctx context.Context, role Role) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// RoleFromContext is the caller's role the interceptor found, a player when
+// it didn't find one
+func RoleFromContext(ctx context.Context) Role {
+	if role, ok := ctx.Value(roleKey{}).(Role); ok {
+		return role
+	}
+	return Player
+}
+
+// UnaryServerInterceptor authenticates every caller, then checks they have
+// the permission the method needs before calling it. Methods that don't need
+// one can be called anonymously, by callers with no credentials at all.
+// Every call carries the caller and their role on for handlers to check.
+func UnaryServerInterceptor(a Authenticator, roles *Roles, methodPermissions map[string]Permission) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorize(ctx, a, roles, methodPermissions, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authorize authenticates the caller and checks their role for a method,
+// returning the context to call it with
+func authorize(ctx context.Context, a Authenticator, roles *Roles, methodPermissions map[string]Permission, method string) (context.Context, error) {
+	permission, needed := methodPermissions[method]
+	userInfo, err := a.Authenticate(ctx)
+	if err == ErrNoCredentials && !needed {
+		return ctx, nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	role := roles.RoleOf(userInfo)
+	if needed && !role.Can(permission) {
+		return nil, status.Errorf(codes.PermissionDenied, "%v can't call %v, it needs %v", role, method, permission)
+	}
+	return NewContext(NewUserContext(ctx, userInfo), role), nil
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streams
+func StreamServerInterceptor(a Authenticator, roles *Roles, methodPermissions map[string]Permission) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(stream.Context(), a, roles, methodPermissions, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Role is what a user is trusted to do
+type Role string
+
+// Permission lets a role call a group of rpcs
+type Permission string
+
+const (
+	// Admin can do anything, including acting on anyone's entities
+	Admin Role = "admin"
+	// Operator runs worlds, creating, resetting and populating them
+	Operator Role = "operator"
+	// Player can only create and act on their own entities. Anyone who
+	// isn't given a role is a player.
+	Player Role = "player"
+)
+
+const (
+	// ManageWorlds creates and deletes worlds
+	ManageWorlds Permission = "manage-worlds"
+	// ResetWorlds wipes and repopulates worlds
+	ResetWorlds Permission = "reset-worlds"
+	// SpawnEntities places entities wherever it likes
+	SpawnEntities Permission = "spawn-entities"
+	// ActOnAnyEntity acts on and deletes entities owned by anyone
+	ActOnAnyEntity Permission = "act-on-any-entity"
+)
+
+// rolePermissions is what each role can do
+var rolePermissions = map[Role][]Permission{
+	Admin:    {ManageWorlds, ResetWorlds, SpawnEntities, ActOnAnyEntity},
+	Operator: {ManageWorlds, ResetWorlds, SpawnEntities},
+	Player:   {},
+}
+
+// Can checks if a role has a permission
+func (r Role) Can(p Permission) bool {
+	for _, permission := range rolePermissions[r] {
+		if permission == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles assigns roles to users by their email or user id
+type Roles struct {
+	users map[string]Role
+}
+
+// rolesFile lists the users with each role
+type rolesFile struct {
+	Roles map[Role][]string `json:"roles" yaml:"roles"`
+}
+
+// NewRoles assigns roles to users, users can be listed by email or user id
+func NewRoles(users map[Role][]string) (*Roles, error) {
+	r := &Roles{users: make(map[string]Role)}
+	for role, list := range users {
+		if _, ok := rolePermissions[role]; !ok {
+			return nil, fmt.Errorf("invalid role %v", role)
+		}
+		for _, user := range list {
+			if len(user) == 0 {
+				return nil, fmt.Errorf("empty user in role %v", role)
+			}
+			if existing, ok := r.users[user]; ok && existing != role {
+				return nil, fmt.Errorf("%v is both %v and %v", user, existing, role)
+			}
+			r.users[user] = role
+		}
+	}
+	return r, nil
+}
+
+// LoadRoles reads the roles from a json or yaml file, picked by extension
+func LoadRoles(path string) (*Roles, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading roles file: %v", err)
+	}
+
+	file := rolesFile{}
+	switch filepath.Ext(path) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&file)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &file)
+	default:
+		err = errors.New("roles file must be .json, .yaml or .yml")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing roles file: %v", err)
+	}
+	return NewRoles(file.Roles)
+}
+
+// RoleOf finds a user's role, by user id first then email
+func (r *Roles) RoleOf(user *UserInfo) Role {
+	if r == nil || user == nil {
+		return Player
+	}
+	if role, ok := r.users[user.ID]; ok && len(user.ID) > 0 {
+		return role
+	}
+	if role, ok := r.users[user.Email]; ok && len(user.Email) > 0 {
+		return role
+	}
+	return Player
+}
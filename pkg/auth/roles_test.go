@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadRoles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		// Role each user is expected to have
+		want    map[UserInfo]Role
+		wantErr bool
+	}{
+		{
+			name:     "Json",
+			filename: "roles.json",
+			content:  `{"roles": {"admin": ["admin@example.com"], "operator": ["OPERATOR-UID"]}}`,
+			want: map[UserInfo]Role{
+				{ID: "ADMIN-UID", Email: "admin@example.com"}:   Admin,
+				{ID: "OPERATOR-UID", Email: "ops@example.com"}:  Operator,
+				{ID: "PLAYER-UID", Email: "player@example.com"}: Player,
+			},
+		},
+		{
+			name:     "Yaml",
+			filename: "roles.yaml",
+			content:  "roles:\n  operator:\n    - ops@example.com\n    - ops2@example.com\n",
+			want: map[UserInfo]Role{
+				{ID: "OPS-UID", Email: "ops@example.com"}:     Operator,
+				{ID: "OPS2-UID", Email: "ops2@example.com"}:   Operator,
+				{ID: "ADMIN-UID", Email: "admin@example.com"}: Player,
+			},
+		},
+		{
+			name:     "User ids come before emails",
+			filename: "ids.yaml",
+			content:  "roles:\n  admin: [ADMIN-UID]\n  operator: [admin@example.com]\n",
+			want: map[UserInfo]Role{
+				{ID: "ADMIN-UID", Email: "admin@example.com"}: Admin,
+			},
+		},
+		{name: "Unknown role", filename: "unknown.yaml", content: "roles:\n  owner: [admin@example.com]\n", wantErr: true},
+		{name: "Two roles", filename: "two.json", content: `{"roles": {"admin": ["a@example.com"], "player": ["a@example.com"]}}`, wantErr: true},
+		{name: "Empty user", filename: "empty.yaml", content: "roles:\n  admin: ['']\n", wantErr: true},
+		{name: "Unknown field", filename: "field.json", content: `{"users": {}}`, wantErr: true},
+		{name: "Unknown extension", filename: "roles.txt", content: "roles: {}\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.filename)
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("error writing roles file: %v", err)
+			}
+			roles, err := LoadRoles(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadRoles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for user, want := range tt.want {
+				user := user
+				if got := roles.RoleOf(&user); got != want {
+					t.Errorf("%v is %v, want %v", user, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoleCan(t *testing.T) {
+	tests := []struct {
+		role       Role
+		permission Permission
+		want       bool
+	}{
+		{Admin, ActOnAnyEntity, true},
+		{Admin, ManageWorlds, true},
+		{Operator, ManageWorlds, true},
+		{Operator, ResetWorlds, true},
+		{Operator, SpawnEntities, true},
+		{Operator, ActOnAnyEntity, false},
+		{Player, ManageWorlds, false},
+		{Role("owner"), ManageWorlds, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.Can(tt.permission); got != tt.want {
+			t.Errorf("%v.Can(%v) = %v, want %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// UserInfo is the struct that will parse the auth response
+type UserInfo struct {
+	Issuer string `json:"issuer"`
+	ID     string `json:"id"`
+	Email  string `json:"email"`
+}
+
+// userKey is the context key the authenticated user is kept under
+type userKey struct{}
+
+// NewUserContext returns a context carrying the authenticated user
+func NewUserContext(ctx context.Context, user *UserInfo) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext is the user the interceptor authenticated, an error when
+// the call didn't come from a user
+func UserFromContext(ctx context.Context) (*UserInfo, error) {
+	user, ok := ctx.Value(userKey{}).(*UserInfo)
+	if !ok || user == nil {
+		return nil, errors.New("not signed in")
+	}
+	return user, nil
+}
@@ -4,16 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
 	b64 "encoding/base64"
 
 	datacom "github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/middleware"
 
 	api "github.com/terrariumai/simulation/pkg/api/collective"
 	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/auth"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
@@ -30,11 +32,42 @@ type collectiveServer struct {
 	// Mutex to ensure data safety
 	m sync.Mutex
 	// Datacom
-	datacom *datacom.Datacom
+	datacom DataAccessLayer
 	// Environment client
 	envClient envApi.EnvironmentClient
 	// Minimum each step should take
 	minStepTimeMilliseconds int64
+	// Token the environment knows the collective by
+	serviceToken string
+	// Logs nowhere without WithLogger
+	logger *zap.Logger
+}
+
+// ServerOption configures optional parts of the collective server
+type ServerOption func(*collectiveServer)
+
+// WithServiceToken calls the environment as a service, so it can drive the
+// agents of every model connected to the collective
+func WithServiceToken(token string) ServerOption {
+	return func(s *collectiveServer) {
+		s.serviceToken = token
+	}
+}
+
+// WithLogger sets the logger the server logs to
+func WithLogger(logger *zap.Logger) ServerOption {
+	return func(s *collectiveServer) {
+		s.logger = logger
+	}
+}
+
+// DataAccessLayer is the data the collective needs, implemented by datacom
+type DataAccessLayer interface {
+	GetRemoteModelMetadataBySecret(modelSecret string) (*datacom.RemoteModel, error)
+	GetRemoteModelMetadataByID(modelID string) (*datacom.RemoteModel, error)
+	UpdateRemoteModelMetadata(remoteModelMD *datacom.RemoteModel, connectCount int) error
+	GetEntitiesForModel(modelID string) ([]envApi.Entity, error)
+	GetObservationsForEntities(entities []envApi.Entity) ([]*api.Observation, error)
 }
 
 // UserInfo is the struct that will parse the auth response
@@ -45,20 +78,7 @@ type UserInfo struct {
 }
 
 // NewCollectiveServer creates a new collective server
-func NewCollectiveServer(env string, redisAddr string, envAddress string, p datacom.PubsubAccessLayer) api.CollectiveServer {
-	// Init datacom
-	datacom, err := datacom.NewDatacom(env, redisAddr, p)
-	if err != nil {
-		log.Fatalf("Error initializing Datacom: %v", err)
-	}
-
-	// Init environment client
-	conn, err := grpc.Dial(envAddress, grpc.WithInsecure())
-	if err != nil {
-		log.Fatalf("Couldn't connect to environment service: %v", err)
-	}
-	envClient := envApi.NewEnvironmentClient(conn)
-
+func NewCollectiveServer(env string, d DataAccessLayer, envAddress string, opts ...ServerOption) api.CollectiveServer {
 	minStepTimeMilliseconds := defaultMinStepTimeMilliseconds
 	// Disavle minimum step time in training, I WANNA GO FAST!
 	if env == "training" {
@@ -68,22 +88,32 @@ func NewCollectiveServer(env string, redisAddr string, envAddress string, p data
 	// Init server
 	s := &collectiveServer{
 		env:                     env,
-		datacom:                 datacom,
-		envClient:               envClient,
+		datacom:                 d,
 		minStepTimeMilliseconds: minStepTimeMilliseconds,
+		logger:                  zap.NewNop(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// Init environment client, request ids are passed on to the environment
+	dialOpts := append([]grpc.DialOption{grpc.WithInsecure()}, middleware.DialOptions()...)
+	conn, err := grpc.Dial(envAddress, dialOpts...)
+	if err != nil {
+		s.logger.Fatal("couldn't connect to environment service", zap.Error(err), zap.String("address", envAddress))
+	}
+	s.envClient = envApi.NewEnvironmentClient(conn)
 
 	return s
 }
 
 func (s *collectiveServer) ConnectRemoteModel(stream api.Collective_ConnectRemoteModelServer) error {
-	log.Println("Remote model has connected! Authorizing...")
 	ctx := stream.Context()
 	// Get metadata and parse userinfo
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
 		err := errors.New("ConnectRemoteModel(): Error getting metadata")
-		log.Printf("Error: %v\n", err)
+		s.logger.Warn("rejected model", zap.Error(err))
 		return err
 	}
 
@@ -91,7 +121,7 @@ func (s *collectiveServer) ConnectRemoteModel(stream api.Collective_ConnectRemot
 	modelSecretHeader := md["model-secret"]
 	if len(modelSecretHeader) == 0 {
 		err := errors.New("ConnectRemoteModel(): authentication or model-secret header are missing")
-		log.Printf("Error: %v\n", err)
+		s.logger.Warn("rejected model", zap.Error(err))
 		return err
 	}
 	modelSecret := modelSecretHeader[0]
@@ -99,12 +129,17 @@ func (s *collectiveServer) ConnectRemoteModel(stream api.Collective_ConnectRemot
 	// Get RM metadata to make sure it exists
 	remoteModelMD, err := s.datacom.GetRemoteModelMetadataBySecret(modelSecret)
 	if err != nil {
-		log.Printf("Error: %v\n", err)
+		s.logger.Warn("rejected model", zap.Error(err))
 		return fmt.Errorf("ConnectRemoteModel(): That model does not exist or invalid secret key: %v", err)
 	}
 
 	defer s.cleanupModel(remoteModelMD.ID)
 
+	// Environment calls are made for this connection, under its request id
+	requestID := middleware.RequestIDFromContext(ctx)
+	logger := s.logger.With(zap.String("model", remoteModelMD.ID), zap.String(middleware.RequestIDTag, requestID))
+	logger.Info("model connected")
+
 	// Update the RM to show that this has connected
 	s.datacom.UpdateRemoteModelMetadata(remoteModelMD, remoteModelMD.ConnectCount+1)
 
@@ -117,7 +152,7 @@ func (s *collectiveServer) ConnectRemoteModel(stream api.Collective_ConnectRemot
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("WARNING: RM client disconnected")
+			logger.Info("model disconnected")
 			return nil
 		default:
 		}
@@ -125,7 +160,7 @@ func (s *collectiveServer) ConnectRemoteModel(stream api.Collective_ConnectRemot
 		// Query db for entities
 		entities, err := s.datacom.GetEntitiesForModel(remoteModelMD.ID)
 		if err != nil {
-			log.Printf("ERROR querying entities: %v\n", err)
+			logger.Error("querying entities", zap.Error(err))
 			return err
 		}
 
@@ -137,24 +172,29 @@ func (s *collectiveServer) ConnectRemoteModel(stream api.Collective_ConnectRemot
 			// Get owner uid from the remote model, generate auth context and make request
 			userinfoJSONString := fmt.Sprintf("{\"id\":\"%s\"}", remoteModelMD.OwnerUID)
 			userinfoEnc := b64.StdEncoding.EncodeToString([]byte(userinfoJSONString))
-			md := metadata.Pairs("x-endpoint-api-userinfo", userinfoEnc)
-			envCtx := metadata.NewOutgoingContext(context.Background(), md)
+			md := metadata.Pairs(auth.UserInfoHeader, userinfoEnc)
+			// Environments that verify their users only take the service token
+			if len(s.serviceToken) > 0 {
+				md = metadata.Join(md, metadata.Pairs(auth.ServiceTokenHeader, s.serviceToken))
+			}
+			envCtx := metadata.NewOutgoingContext(middleware.NewRequestIDContext(context.Background(), requestID), md)
 			s.envClient.CreateEntity(envCtx, &envApi.CreateEntityRequest{Entity: &envApi.Entity{X: 999999999, ModelID: remoteModelMD.ID, OwnerUID: remoteModelMD.OwnerUID, ClassID: envApi.Entity_AGENT}})
 		}
 
 		// Create a new observation packet to send
 		var obsvPacket api.ObservationPacket
 
-		// Generate an observation for each entity
-		for _, e := range entities {
-			obsv, err := s.datacom.GetObservationForEntity(e)
+		// Generate an observation for each entity from one snapshot
+		obsvs, err := s.datacom.GetObservationsForEntities(entities)
+		if err != nil {
+			logger.Error("generating observations", zap.Error(err))
+			return err
+		}
+		for _, obsv := range obsvs {
 			// Check for memory
 			if resp, ok := entityActionResponseMemory[obsv.Id]; ok {
 				obsv.ActionMemory = api.Observation_ResponseValue(resp.Value)
 			}
-			if err != nil {
-				log.Printf("ERROR: %v\n", err)
-			}
 			obsvPacket.Observations = append(obsvPacket.Observations, obsv)
 		}
 
@@ -179,42 +219,64 @@ func (s *collectiveServer) ConnectRemoteModel(stream api.Collective_ConnectRemot
 		if len(obsvPacket.Observations) > 0 {
 			if err := stream.Send(&obsvPacket); err != nil {
 				// TODO - Clean disconnect, remove data from database
-				log.Printf("ERROR sending observation packet: %v\n", err)
+				logger.Error("sending observations", zap.Error(err))
 				return err
 			}
 
 			// Wait for a response
 			actionPacket, err := stream.Recv()
 			if err != nil {
-				fmt.Printf("ERROR: %v\n", err)
+				logger.Error("receiving actions", zap.Error(err))
 				return err
 			}
 
-			// Perform actions
+			// Perform actions all at once, in tick mode the environment holds
+			// each one until the tick is resolved. The environment trusts the
+			// collective with every agent, so models can only act for their own.
 			actions := actionPacket.GetActions()
-			ctx := context.Background()
+			ctx := middleware.NewRequestIDContext(context.Background(), requestID)
+			if len(s.serviceToken) > 0 {
+				ctx = metadata.NewOutgoingContext(ctx, metadata.Pairs(auth.ServiceTokenHeader, s.serviceToken))
+			}
+			owned := make(map[string]bool, len(entities))
+			for _, e := range entities {
+				owned[e.Id] = true
+			}
+			var wg sync.WaitGroup
+			var resultsM sync.Mutex
 			for _, action := range actions {
-				req := envApi.ExecuteAgentActionRequest{
-					Id:        action.Id,
-					Action:    envApi.ExecuteAgentActionRequest_Action(action.Action),
-					Direction: envApi.ExecuteAgentActionRequest_Direction(action.Direction),
-				}
-				resp, err := s.envClient.ExecuteAgentAction(ctx, &req)
-				if err != nil { // Note: Most often due to a message sent to a dead agent
-					log.Printf("ERROR: %v\n", err)
+				if !owned[action.Id] {
+					logger.Warn("model tried to act for an entity it doesn't own", zap.String("entity", action.Id))
 					continue
 				}
-				// Store the response in memory
-				entityActionResponseMemory[action.Id] = *resp
-				// Check if the agent died during this action
-				if resp.Value == envApi.ExecuteAgentActionResponse_ERR_DIED {
-					// Add this observaion to the death obsvs slice to be used in the next loop
-					entityDeathObsvs = append(entityDeathObsvs, api.Observation{
-						Id:      action.Id,
-						IsAlive: false,
-					})
-				}
+				wg.Add(1)
+				go func(action *api.Action) {
+					defer wg.Done()
+					req := envApi.ExecuteAgentActionRequest{
+						Id:        action.Id,
+						Action:    envApi.ExecuteAgentActionRequest_Action(action.Action),
+						Direction: envApi.ExecuteAgentActionRequest_Direction(action.Direction),
+					}
+					resp, err := s.envClient.ExecuteAgentAction(ctx, &req)
+					if err != nil { // Note: Most often due to a message sent to a dead agent
+						logger.Warn("executing action", zap.Error(err), zap.String("entity", action.Id))
+						return
+					}
+					resultsM.Lock()
+					defer resultsM.Unlock()
+					// Store the response in memory
+					entityActionResponseMemory[action.Id] = *resp
+					// Check if the agent died during this action
+					if resp.Value == envApi.ExecuteAgentActionResponse_ERR_DIED {
+						// Add this observaion to the death obsvs slice to be used in the next loop
+						entityDeathObsvs = append(entityDeathObsvs, api.Observation{
+							Id:      action.Id,
+							IsAlive: false,
+						})
+					}
+				}(action)
 			}
+			wg.Wait()
 		}
 
 		// Wait if we got a response too quickly
@@ -232,13 +294,13 @@ func (s *collectiveServer) cleanupModel(id string) {
 	// Get RM metadata to make sure it exists
 	remoteModelMD, err := s.datacom.GetRemoteModelMetadataByID(id)
 	if err != nil {
-		log.Printf("WARNING: Couldn't clean up model because it was not found id=%s\n", id)
+		s.logger.Warn("couldn't clean up model, it wasn't found", zap.Error(err), zap.String("model", id))
 		return
 	}
 	// Update the RM to show that this has disconnected
 	err = s.datacom.UpdateRemoteModelMetadata(remoteModelMD, remoteModelMD.ConnectCount-1)
 	if err != nil {
-		log.Printf("WARNING: Couldn't clean up model, error updating metadata in firebase id=%s\n", id)
+		s.logger.Warn("couldn't clean up model, error updating its metadata", zap.Error(err), zap.String("model", id))
 		return
 	}
 }
@@ -3,11 +3,21 @@ package collective
 import (
 	"context"
 	"log"
+	"net"
 	"testing"
+	"time"
 
 	"google.golang.org/grpc"
 
+	"github.com/stretchr/testify/mock"
 	api "github.com/terrariumai/simulation/pkg/api/collective"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/auth"
+	"github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/datacom/mocks"
+	"github.com/terrariumai/simulation/pkg/environment"
+	"github.com/terrariumai/simulation/pkg/middleware"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -48,3 +58,82 @@ func TestConnectRemoteModel(t *testing.T) {
 		// }
 	})
 }
+
+// Outside training the environment only lets the collective act for agents
+// when it calls with the service token
+func TestExecuteAgentActionInProd(t *testing.T) {
+	tests := []struct {
+		name string
+		// Token the collective calls with, the environment expects MOCK-TOKEN
+		token    string
+		wantMove bool
+	}{
+		{name: "Calls with the service token", token: "MOCK-TOKEN", wantMove: true},
+		{name: "Calls without a token", token: "", wantMove: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPAL := &mocks.PubsubAccessLayer{}
+			mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			dal, err := datacom.NewMemoryDatacom(mockPAL, datacom.DefaultWorldSize, datacom.Bounded)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			agent := envApi.Entity{Id: "MOCK-ENTITY-ID", X: 5, Y: 5, ClassID: envApi.Entity_AGENT, ModelID: "MOCK-MODEL-ID", OwnerUID: "MOCK-UID", Energy: 100, Health: 100}
+			dal.CreateEntity(agent, false)
+
+			// The environment checks users the way it does in prod
+			eListen, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			authenticator := auth.NewESPAuthenticator()
+			eServer := grpc.NewServer(middleware.ServerOptions(zap.NewNop(), auth.UnaryServerInterceptor(authenticator, &auth.Roles{}, environment.MethodPermissions), auth.StreamServerInterceptor(authenticator, &auth.Roles{}, environment.MethodPermissions))...)
+			envApi.RegisterEnvironmentServer(eServer, environment.NewEnvironmentServer("prod", dal, environment.DefaultWorldRules(), environment.WithServiceToken("MOCK-TOKEN")))
+			go eServer.Serve(eListen)
+			defer eServer.Stop()
+
+			cListen, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			cServer := grpc.NewServer(middleware.ServerOptions(zap.NewNop(), nil, nil)...)
+			api.RegisterCollectiveServer(cServer, NewCollectiveServer("prod", dal, eListen.Addr().String(), WithServiceToken(tt.token)))
+			go cServer.Serve(cListen)
+			defer cServer.Stop()
+
+			// Connect a model and move its agent up
+			conn, err := grpc.Dial(cListen.Addr().String(), grpc.WithInsecure())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer conn.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			stream, err := api.NewCollectiveClient(conn).ConnectRemoteModel(metadata.AppendToOutgoingContext(ctx, "model-secret", "MOCK-SECRET"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := stream.Recv(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			actions := &api.ActionPacket{Actions: []*api.Action{{Id: agent.Id, Action: api.Action_MOVE, Direction: api.Action_UP}}}
+			if err := stream.Send(actions); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// The next observation is only sent once the actions are done
+			if _, err := stream.Recv(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			moved, _, err := dal.GetEntity(agent.Id)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := moved.Y == agent.Y+1; got != tt.wantMove {
+				t.Errorf("agent is at %v,%v, moved %v, want %v", moved.X, moved.Y, got, tt.wantMove)
+			}
+		})
+	}
+}
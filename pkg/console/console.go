@@ -3,16 +3,15 @@ package console
 import (
 	"bufio"
 	"context"
-	b64 "encoding/base64"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 
 	"github.com/golang/protobuf/ptypes/empty"
-	"google.golang.org/grpc/metadata"
 
 	api "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/auth"
 )
 
 type command struct {
@@ -43,6 +42,14 @@ var (
 			"Spawns food randomly in the environment",
 			[]string{},
 		},
+		{
+			"resetWorld",
+			"Wipes the environment and randomly places food using the seed",
+			[]string{
+				"seed:int",
+				"foodCount:uint",
+			},
+		},
 	}
 )
 
@@ -81,10 +88,7 @@ func StartConsole(s api.EnvironmentServer) {
 				}
 
 				// Create call context
-				userinfoJSONString := "{\"id\":\"MOCK-UID\",\"isAdmin\":true}"
-				userinfoEnc := b64.StdEncoding.EncodeToString([]byte(userinfoJSONString))
-				md := metadata.Pairs("x-endpoint-api-userinfo", userinfoEnc)
-				ctx := metadata.NewIncomingContext(context.Background(), md)
+				ctx := auth.NewUserContext(context.Background(), &auth.UserInfo{ID: "MOCK-UID"})
 
 				// Execute command
 				switch enteredCmd {
@@ -122,6 +126,26 @@ func StartConsole(s api.EnvironmentServer) {
 					}
 				case "spawnFood":
 					s.SpawnFood(ctx, &empty.Empty{})
+				case "resetWorld":
+					seed, err := strconv.ParseInt(words[1], 10, 64)
+					if err != nil {
+						fmt.Printf("\tError: seed must be a number\n")
+						break
+					}
+					foodCount, err := strconv.ParseUint(words[2], 10, 32)
+					if err != nil {
+						fmt.Printf("\tError: foodCount must be a positive number\n")
+						break
+					}
+					resp, err := s.ResetWorld(ctx, &api.ResetWorldRequest{
+						Seed:      seed,
+						FoodCount: uint32(foodCount),
+					})
+					if err != nil {
+						fmt.Printf("\t%v\n", err)
+						break
+					}
+					fmt.Printf("\t%v\n", resp)
 				default:
 					fmt.Printf("\tUnrecognized command\n")
 				}
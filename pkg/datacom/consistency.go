@@ -0,0 +1,447 @@
+package datacom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+)
+
+// InconsistencyKind is a class of problem in the world's redis keys
+type InconsistencyKind string
+
+// Every kind of inconsistency, in the order they are repaired. Later repairs
+// rely on the earlier ones, removing an entity from a shared cell needs its
+// content to be right first.
+const (
+	// A member or content that can't be parsed
+	CorruptMember InconsistencyKind = "corrupt-member"
+	// A member of the entities set whose id has no content, or a second
+	// member for an entity that is already on the map
+	OrphanedMember InconsistencyKind = "orphaned-member"
+	// Content that doesn't match the entity on the map, usually left behind
+	// with an old position, or content for an entity that isn't on the map
+	StaleContent InconsistencyKind = "stale-content"
+	// An id in a model's entity set that has no content
+	DanglingModelEntity InconsistencyKind = "dangling-model-entity"
+	// More than one entity in the same cell
+	SharedCell InconsistencyKind = "shared-cell"
+	// A cell index entry that doesn't match the entity in the cell, or an
+	// occupied cell that isn't indexed
+	CellIndex InconsistencyKind = "cell-index"
+	// An effect that has decayed past its delete threshold
+	ExpiredEffect InconsistencyKind = "expired-effect"
+)
+
+// InconsistencyKinds lists every kind in repair order
+var InconsistencyKinds = []InconsistencyKind{CorruptMember, OrphanedMember, StaleContent, DanglingModelEntity, SharedCell, CellIndex, ExpiredEffect}
+
+// Inconsistency is a single problem found in the world
+type Inconsistency struct {
+	Kind InconsistencyKind
+	// Redis key holding the problem
+	Key string
+	// Member, field or id within the key
+	Member string
+	// Human readable description
+	Detail string
+}
+
+func (i Inconsistency) String() string {
+	return fmt.Sprintf("%v %v %q: %v", i.Kind, i.Key, i.Member, i.Detail)
+}
+
+// worldState is every entity and effect key read in one go
+type worldState struct {
+	members   []string
+	contents  map[string]string
+	modelSets map[string][]string
+	effects   []string
+	cells     map[string]string
+	// Parsed members, contents and effects, anything corrupt is left out
+	memberEntities  map[string]envApi.Entity
+	memberIndexes   map[string]string
+	contentEntities map[string]envApi.Entity
+	effectContents  map[string]envApi.Effect
+}
+
+func (dc *Datacom) readWorldState() (*worldState, error) {
+	state := &worldState{
+		modelSets: make(map[string][]string),
+	}
+
+	members, err := dc.redisClient.ZRange(dc.key(entitiesKey), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %v: %v", dc.key(entitiesKey), err)
+	}
+	state.members = members
+
+	contents, err := dc.redisClient.HGetAll(dc.key(entitiesContentKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %v: %v", dc.key(entitiesContentKey), err)
+	}
+	state.contents = contents
+
+	var cursor uint64
+	for {
+		keys, nextCursor, err := dc.redisClient.Scan(cursor, dc.modelEntitiesKey("*"), 100).Result()
+		if err != nil {
+			return nil, fmt.Errorf("Error scanning model keys: %v", err)
+		}
+		for _, key := range keys {
+			ids, err := dc.redisClient.SMembers(key).Result()
+			if err != nil {
+				return nil, fmt.Errorf("Error reading %v: %v", key, err)
+			}
+			state.modelSets[key] = ids
+		}
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	effects, err := dc.redisClient.ZRange(dc.key(effectsKey), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %v: %v", dc.key(effectsKey), err)
+	}
+	state.effects = effects
+
+	cells, err := dc.redisClient.HGetAll(dc.key(cellsKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %v: %v", dc.key(cellsKey), err)
+	}
+	state.cells = cells
+
+	state.memberEntities = make(map[string]envApi.Entity)
+	state.memberIndexes = make(map[string]string)
+	for _, member := range state.members {
+		if e, index, err := parseEntityContent(member); err == nil {
+			state.memberEntities[member] = e
+			state.memberIndexes[member] = index
+		}
+	}
+	state.contentEntities = make(map[string]envApi.Entity)
+	for id, content := range state.contents {
+		if e, _, err := parseEntityContent(content); err == nil {
+			state.contentEntities[id] = e
+		}
+	}
+	state.effectContents = make(map[string]envApi.Effect)
+	for _, member := range state.effects {
+		if effect, _, err := parseEffectContent(member); err == nil {
+			state.effectContents[member] = effect
+		}
+	}
+
+	return state, nil
+}
+
+// CheckConsistency scans every key datacom uses and reports anything that
+// doesn't line up. Nothing is changed.
+func (dc *Datacom) CheckConsistency() ([]Inconsistency, error) {
+	state, err := dc.readWorldState()
+	if err != nil {
+		return nil, err
+	}
+	problems := []Inconsistency{}
+
+	// Anything that can't be parsed
+	for _, member := range state.members {
+		if _, ok := state.memberEntities[member]; !ok {
+			problems = append(problems, Inconsistency{CorruptMember, dc.key(entitiesKey), member, "member can't be parsed"})
+		}
+	}
+	for _, id := range sortedKeys(state.contents) {
+		if _, ok := state.contentEntities[id]; !ok {
+			problems = append(problems, Inconsistency{CorruptMember, dc.key(entitiesContentKey), id, "content can't be parsed"})
+		}
+	}
+	for _, member := range state.effects {
+		if _, ok := state.effectContents[member]; !ok {
+			problems = append(problems, Inconsistency{CorruptMember, dc.key(effectsKey), member, "effect can't be parsed"})
+		}
+	}
+
+	// The entities set is the map, so it decides where an entity is
+	inEntities := make(map[string]bool, len(state.members))
+	onMap := make(map[string]bool)
+	for member, e := range state.memberEntities {
+		inEntities[member] = true
+		onMap[e.Id] = true
+	}
+
+	// Members need content, and only one member per entity
+	for _, member := range state.members {
+		e, ok := state.memberEntities[member]
+		if !ok {
+			continue
+		}
+		content, ok := state.contents[e.Id]
+		if !ok {
+			problems = append(problems, Inconsistency{OrphanedMember, dc.key(entitiesKey), member, fmt.Sprintf("entity %v has no content", e.Id)})
+		} else if content != member && inEntities[content] {
+			problems = append(problems, Inconsistency{OrphanedMember, dc.key(entitiesKey), member, fmt.Sprintf("entity %v is already on the map", e.Id)})
+		}
+	}
+
+	// Content needs to match the map
+	for _, id := range sortedKeys(state.contents) {
+		e, ok := state.contentEntities[id]
+		if !ok || inEntities[state.contents[id]] {
+			continue
+		}
+		detail := fmt.Sprintf("content at %v,%v is not on the map", e.X, e.Y)
+		if onMap[id] {
+			detail = fmt.Sprintf("content at %v,%v is not where the entity is on the map", e.X, e.Y)
+		}
+		problems = append(problems, Inconsistency{StaleContent, dc.key(entitiesContentKey), id, detail})
+	}
+
+	// Every model entity needs content
+	modelKeys := []string{}
+	for key := range state.modelSets {
+		modelKeys = append(modelKeys, key)
+	}
+	sort.Strings(modelKeys)
+	for _, key := range modelKeys {
+		ids := state.modelSets[key]
+		sort.Strings(ids)
+		for _, id := range ids {
+			if _, ok := state.contents[id]; !ok {
+				problems = append(problems, Inconsistency{DanglingModelEntity, key, id, "entity has no content"})
+			}
+		}
+	}
+
+	// One entity per cell
+	cells := make(map[string][]string)
+	cellOrder := []string{}
+	for _, member := range state.members {
+		e, ok := state.memberEntities[member]
+		if !ok {
+			continue
+		}
+		index := state.memberIndexes[member]
+		if len(cells[index]) == 0 {
+			cellOrder = append(cellOrder, index)
+		}
+		cells[index] = append(cells[index], e.Id)
+	}
+	for _, index := range cellOrder {
+		ids := cells[index]
+		if len(ids) < 2 {
+			continue
+		}
+		sort.Strings(ids)
+		problems = append(problems, Inconsistency{SharedCell, dc.key(entitiesKey), index, fmt.Sprintf("entities %v share a cell", strings.Join(ids, ", "))})
+	}
+
+	// The cell index has to match the map
+	for _, index := range sortedKeys(state.cells) {
+		id := state.cells[index]
+		ids, ok := cells[index]
+		if !ok {
+			problems = append(problems, Inconsistency{CellIndex, dc.key(cellsKey), index, fmt.Sprintf("cell is indexed to %v but is empty", id)})
+			continue
+		}
+		found := false
+		for _, other := range ids {
+			found = found || other == id
+		}
+		if !found {
+			problems = append(problems, Inconsistency{CellIndex, dc.key(cellsKey), index, fmt.Sprintf("cell is indexed to %v but holds %v", id, strings.Join(ids, ", "))})
+		}
+	}
+	for _, index := range cellOrder {
+		if _, ok := state.cells[index]; !ok {
+			problems = append(problems, Inconsistency{CellIndex, dc.key(cellsKey), index, fmt.Sprintf("cell holding %v isn't indexed", strings.Join(cells[index], ", "))})
+		}
+	}
+
+	// Effects that should have been cleaned up
+	now := time.Now().Unix()
+	for _, member := range state.effects {
+		effect, ok := state.effectContents[member]
+		if ok && isEffectExpired(effect, now) {
+			problems = append(problems, Inconsistency{ExpiredEffect, dc.key(effectsKey), member, fmt.Sprintf("effect at %v,%v has decayed", effect.X, effect.Y)})
+		}
+	}
+
+	return problems, nil
+}
+
+// RepairConsistency repairs the given kinds of inconsistency, or every kind if
+// none are given, and returns what it repaired. Each kind is checked again
+// right before it is repaired since earlier repairs change the world.
+// Note: repairs don't publish events, clients see them on their next query.
+func (dc *Datacom) RepairConsistency(kinds ...InconsistencyKind) ([]Inconsistency, error) {
+	if len(kinds) == 0 {
+		kinds = InconsistencyKinds
+	}
+	wanted := make(map[InconsistencyKind]bool)
+	for _, kind := range kinds {
+		wanted[kind] = true
+	}
+
+	repaired := []Inconsistency{}
+	for _, kind := range InconsistencyKinds {
+		if !wanted[kind] {
+			continue
+		}
+		problems, err := dc.CheckConsistency()
+		if err != nil {
+			return repaired, err
+		}
+		for _, problem := range problems {
+			if problem.Kind != kind {
+				continue
+			}
+			if err := dc.repair(problem); err != nil {
+				return repaired, fmt.Errorf("Error repairing %v: %v", problem, err)
+			}
+			repaired = append(repaired, problem)
+		}
+	}
+
+	return repaired, nil
+}
+
+func (dc *Datacom) repair(problem Inconsistency) error {
+	switch problem.Kind {
+	case CorruptMember:
+		// There is nothing to recover, so it is removed
+		if problem.Key == dc.key(entitiesContentKey) {
+			return dc.redisClient.HDel(problem.Key, problem.Member).Err()
+		}
+		return dc.redisClient.ZRem(problem.Key, problem.Member).Err()
+	case OrphanedMember:
+		// Either a ghost of a removed entity or a leftover from a move
+		return dc.redisClient.ZRem(dc.key(entitiesKey), problem.Member).Err()
+	case StaleContent:
+		// If the entity is on the map, trust the map. Otherwise the entity
+		// isn't anywhere and is removed.
+		content, err := dc.redisClient.HGet(dc.key(entitiesContentKey), problem.Member).Result()
+		if err != nil {
+			return err
+		}
+		e, _, err := parseEntityContent(content)
+		if err != nil {
+			return err
+		}
+		members, err := dc.redisClient.ZRange(dc.key(entitiesKey), 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		for _, member := range members {
+			if other, _, err := parseEntityContent(member); err == nil && other.Id == problem.Member {
+				return dc.redisClient.HSet(dc.key(entitiesContentKey), problem.Member, member).Err()
+			}
+		}
+		return dc.runTx("repair stale content", func(pipe redis.Pipeliner) []txStep {
+			return []txStep{
+				{"remove content", pipe.HDel(dc.key(entitiesContentKey), problem.Member)},
+				{"remove from model", pipe.SRem(dc.modelEntitiesKey(e.ModelID), problem.Member)},
+			}
+		})
+	case DanglingModelEntity:
+		return dc.redisClient.SRem(problem.Key, problem.Member).Err()
+	case SharedCell:
+		// Keep the entity with the lowest id, remove the rest
+		members, err := dc.redisClient.ZRangeByLex(dc.key(entitiesKey), redis.ZRangeBy{
+			Min: "[" + problem.Member + "-",
+			Max: "(" + problem.Member + ".",
+		}).Result()
+		if err != nil {
+			return err
+		}
+		ids := []string{}
+		for _, member := range members {
+			if !strings.HasPrefix(member, problem.Member+"-") {
+				continue
+			}
+			e, _, err := parseEntityContent(member)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, e.Id)
+		}
+		sort.Strings(ids)
+		for i := 1; i < len(ids); i++ {
+			content, err := dc.redisClient.HGet(dc.key(entitiesContentKey), ids[i]).Result()
+			if err != nil {
+				return err
+			}
+			e, _, err := parseEntityContent(content)
+			if err != nil {
+				return err
+			}
+			err = dc.runTx("repair shared cell", func(pipe redis.Pipeliner) []txStep {
+				return []txStep{
+					{"remove content", pipe.HDel(dc.key(entitiesContentKey), e.Id)},
+					{"remove from entities", pipe.ZRem(dc.key(entitiesKey), content)},
+					{"remove from model", pipe.SRem(dc.modelEntitiesKey(e.ModelID), e.Id)},
+				}
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case CellIndex:
+		// Index whatever the map has in the cell
+		return dc.reindexCell(problem.Member)
+	case ExpiredEffect:
+		return dc.redisClient.ZRem(dc.key(effectsKey), problem.Member).Err()
+	}
+	return fmt.Errorf("unknown inconsistency kind %v", problem.Kind)
+}
+
+// reindexCell sets a cell's index entry from the entities in it on the map.
+// A valid entry is kept, otherwise the lowest id is indexed.
+func (dc *Datacom) reindexCell(index string) error {
+	members, err := dc.redisClient.ZRangeByLex(dc.key(entitiesKey), redis.ZRangeBy{
+		Min: "[" + index + "-",
+		Max: "(" + index + ".",
+	}).Result()
+	if err != nil {
+		return err
+	}
+	ids := []string{}
+	for _, member := range members {
+		if !strings.HasPrefix(member, index+"-") {
+			continue
+		}
+		e, _, err := parseEntityContent(member)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, e.Id)
+	}
+	if len(ids) == 0 {
+		return dc.redisClient.HDel(dc.key(cellsKey), index).Err()
+	}
+	current, err := dc.redisClient.HGet(dc.key(cellsKey), index).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if id == current {
+			return nil
+		}
+	}
+	return dc.redisClient.HSet(dc.key(cellsKey), index, ids[0]).Err()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
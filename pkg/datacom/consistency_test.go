@@ -0,0 +1,221 @@
+package datacom_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/mock"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/datacom/mocks"
+)
+
+func TestConsistency(t *testing.T) {
+	entity := func(id string, x uint32, y uint32) envApi.Entity {
+		return envApi.Entity{Id: id, ClassID: envApi.Entity_AGENT, X: x, Y: y, ModelID: "MOCK-MODEL-ID"}
+	}
+
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis)
+		want  []datacom.InconsistencyKind
+		// What gets repaired, if it differs from what was found. Repairs
+		// can fix or cause later kinds.
+		wantRepaired []datacom.InconsistencyKind
+		// Entities that should be left after the repair, by id
+		wantEntities map[string]*envApi.Entity
+	}{
+		{
+			name: "Consistent world",
+			setup: func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				dc.CreateEntity(entity("0", 1, 1), false)
+				dc.CreateEffect(envApi.Effect{X: 1, Y: 1, Timestamp: time.Now().Unix(), Decay: 1.2, DelThresh: 5})
+			},
+			want:         []datacom.InconsistencyKind{},
+			wantEntities: map[string]*envApi.Entity{"0": &envApi.Entity{Id: "0", ClassID: envApi.Entity_AGENT, X: 1, Y: 1, ModelID: "MOCK-MODEL-ID"}},
+		},
+		{
+			name: "Members that can't be parsed are removed",
+			setup: func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				redisServer.ZAdd("v2:entities", 0, "not-an-entity")
+				redisServer.HSet("v2:entities.content", "0", "000000000000000000-\xff")
+				redisServer.ZAdd("v2:effects", 0, "000000000000000000")
+			},
+			want: []datacom.InconsistencyKind{datacom.CorruptMember, datacom.CorruptMember, datacom.CorruptMember},
+		},
+		{
+			name: "Member without content is removed",
+			setup: func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				dc.CreateEntity(entity("0", 1, 1), false)
+				redisServer.HDel("v2:entities.content", "0")
+				redisServer.SRem("v2:model:MOCK-MODEL-ID:entities", "0")
+			},
+			want:         []datacom.InconsistencyKind{datacom.OrphanedMember},
+			wantRepaired: []datacom.InconsistencyKind{datacom.OrphanedMember, datacom.CellIndex},
+			wantEntities: map[string]*envApi.Entity{"0": nil},
+		},
+		{
+			name: "Second member for an entity is removed",
+			setup: func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				dc.CreateEntity(entity("0", 8, 8), false)
+				_, oldContent, _ := dc.GetEntity("0")
+				dc.DeleteEntity("0")
+				dc.CreateEntity(entity("0", 7, 7), false)
+				redisServer.ZAdd("v2:entities", 0, oldContent)
+			},
+			want:         []datacom.InconsistencyKind{datacom.OrphanedMember, datacom.CellIndex},
+			wantRepaired: []datacom.InconsistencyKind{datacom.OrphanedMember},
+			wantEntities: map[string]*envApi.Entity{"0": &envApi.Entity{Id: "0", ClassID: envApi.Entity_AGENT, X: 7, Y: 7, ModelID: "MOCK-MODEL-ID"}},
+		},
+		{
+			name: "Content with an old position follows the map",
+			setup: func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				dc.CreateEntity(entity("0", 2, 2), false)
+				_, oldContent, _ := dc.GetEntity("0")
+				dc.UpdateEntity(oldContent, entity("0", 3, 3))
+				redisServer.HSet("v2:entities.content", "0", oldContent)
+			},
+			want:         []datacom.InconsistencyKind{datacom.StaleContent},
+			wantEntities: map[string]*envApi.Entity{"0": &envApi.Entity{Id: "0", ClassID: envApi.Entity_AGENT, X: 3, Y: 3, ModelID: "MOCK-MODEL-ID"}},
+		},
+		{
+			name: "Content for an entity not on the map is removed",
+			setup: func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				dc.CreateEntity(entity("0", 2, 2), false)
+				_, content, _ := dc.GetEntity("0")
+				redisServer.ZRem("v2:entities", content)
+			},
+			want:         []datacom.InconsistencyKind{datacom.StaleContent, datacom.CellIndex},
+			wantEntities: map[string]*envApi.Entity{"0": nil},
+		},
+		{
+			name: "Model entity without content is removed",
+			setup: func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				redisServer.SetAdd("v2:model:MOCK-MODEL-ID:entities", "0")
+			},
+			want: []datacom.InconsistencyKind{datacom.DanglingModelEntity},
+		},
+		{
+			name: "Shared cell keeps the lowest id",
+			setup: func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				dc.CreateEntity(entity("1", 5, 5), false)
+				dc.CreateEntity(entity("0", 5, 5), false)
+			},
+			want: []datacom.InconsistencyKind{datacom.SharedCell},
+			wantEntities: map[string]*envApi.Entity{
+				"0": &envApi.Entity{Id: "0", ClassID: envApi.Entity_AGENT, X: 5, Y: 5, ModelID: "MOCK-MODEL-ID"},
+				"1": nil,
+			},
+		},
+		{
+			name: "Cell index entry for an empty cell is removed",
+			setup: func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				redisServer.HSet("v2:cells", "000000000000000011", "0")
+			},
+			want: []datacom.InconsistencyKind{datacom.CellIndex},
+		},
+		{
+			name: "Cell index follows the map",
+			setup: func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				dc.CreateEntity(entity("0", 1, 1), false)
+				dc.CreateEntity(entity("1", 2, 2), false)
+				redisServer.HSet("v2:cells", "000000000000000011", "1")
+				redisServer.HDel("v2:cells", "000000000000001100")
+			},
+			want: []datacom.InconsistencyKind{datacom.CellIndex, datacom.CellIndex},
+			wantEntities: map[string]*envApi.Entity{
+				"0": &envApi.Entity{Id: "0", ClassID: envApi.Entity_AGENT, X: 1, Y: 1, ModelID: "MOCK-MODEL-ID"},
+				"1": &envApi.Entity{Id: "1", ClassID: envApi.Entity_AGENT, X: 2, Y: 2, ModelID: "MOCK-MODEL-ID"},
+			},
+		},
+		{
+			name: "Expired effect is removed",
+			setup: func(t *testing.T, dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				dc.CreateEffect(envApi.Effect{X: 1, Y: 1, Timestamp: time.Now().Unix() - 100, Decay: 1.2, DelThresh: 5})
+			},
+			want: []datacom.InconsistencyKind{datacom.ExpiredEffect},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redisServer := setup()
+			defer teardown(redisServer)
+			mockPAL := &mocks.PubsubAccessLayer{}
+			mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			dc, _ := datacom.NewDatacom("training", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
+			tt.setup(t, dc, redisServer)
+
+			problems, err := dc.CheckConsistency()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := []datacom.InconsistencyKind{}
+			for _, problem := range problems {
+				got = append(got, problem.Kind)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckConsistency() = %v, want %v", problems, tt.want)
+			}
+
+			repaired, err := dc.RepairConsistency()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			wantRepaired := tt.wantRepaired
+			if wantRepaired == nil {
+				wantRepaired = tt.want
+			}
+			gotRepaired := []datacom.InconsistencyKind{}
+			for _, problem := range repaired {
+				gotRepaired = append(gotRepaired, problem.Kind)
+			}
+			if !reflect.DeepEqual(gotRepaired, wantRepaired) {
+				t.Errorf("repaired %v, want %v", repaired, wantRepaired)
+			}
+			problems, _ = dc.CheckConsistency()
+			if len(problems) != 0 {
+				t.Errorf("expected no problems after repair, got %v", problems)
+			}
+			for id, want := range tt.wantEntities {
+				got, _, err := dc.GetEntity(id)
+				if want == nil {
+					if err == nil {
+						t.Errorf("%v: expected entity to be gone, got %v", id, got)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%v: got %v, want %v", id, got, want)
+				}
+				if occupied, e, _, _ := dc.IsCellOccupied(want.X, want.Y); !occupied || !reflect.DeepEqual(e, want) {
+					t.Errorf("%v: IsCellOccupied(%v, %v) = %v %v, want %v", id, want.X, want.Y, occupied, e, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRepairConsistencyOnlyRepairsGivenKinds(t *testing.T) {
+	redisServer := setup()
+	defer teardown(redisServer)
+	mockPAL := &mocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dc, _ := datacom.NewDatacom("training", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
+	redisServer.SetAdd("v2:model:MOCK-MODEL-ID:entities", "0")
+	dc.CreateEffect(envApi.Effect{X: 1, Y: 1, Timestamp: time.Now().Unix() - 100, Decay: 1.2, DelThresh: 5})
+
+	repaired, err := dc.RepairConsistency(datacom.ExpiredEffect)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repaired) != 1 || repaired[0].Kind != datacom.ExpiredEffect {
+		t.Errorf("expected only the effect to be repaired, got %v", repaired)
+	}
+	problems, _ := dc.CheckConsistency()
+	if len(problems) != 1 || problems[0].Kind != datacom.DanglingModelEntity {
+		t.Errorf("expected the model entity to be left, got %v", problems)
+	}
+}
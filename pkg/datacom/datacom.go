@@ -2,13 +2,18 @@ package datacom
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	firebase "firebase.google.com/go"
 
 	"github.com/go-redis/redis"
 	"github.com/golang/protobuf/proto"
+	"github.com/terrariumai/simulation/pkg/terrain"
+	"go.uber.org/zap"
 	"google.golang.org/api/option"
 )
 
@@ -18,21 +23,42 @@ const (
 
 	mockSecret = "MOCK-SECRET"
 
-	minPosition             = 0
-	maxPositionCharLength   = 9 // Maximum length a position can be ("10" = 2, "100" = 3, etc.)
-	maxPosition             = 999
 	defaultEntityVisionDist = 5
 	defaultEntitySmellDist  = 2
 
 	regionSize = 10
+
+	// How long a cell lock lives if it is never released
+	cellLockTTL = 5 * time.Second
+
+	// Entity and effect keys are prefixed with the version of their storage
+	// format, so a migration can tell the formats apart
+	keyPrefix          = "v2:"
+	entitiesKey        = keyPrefix + "entities"
+	entitiesContentKey = keyPrefix + "entities.content"
+	effectsKey         = keyPrefix + "effects"
+	// Which entity is in each cell, by position index
+	cellsKey = keyPrefix + "cells"
 )
 
+// modelEntitiesKey is the set of entity ids belonging to a model
+func (dc *Datacom) modelEntitiesKey(modelID string) string {
+	return dc.key(keyPrefix + "model:" + modelID + ":entities")
+}
+
 // Datacom is an object that makes it easy to communicate with our
 // databases. It handles figuring out where each specific
 // bit of data is (redis, firebase, etc.) and how to access it (auth).
 type Datacom struct {
 	// current envirinment
 	env string
+	// world the keys are namespaced to
+	worldID string
+	// size of the world and its position indexes
+	grid grid
+	// terrain observations see, swapped out by generated resets
+	terrain  *terrain.Map
+	terrainM sync.RWMutex
 	// entity vision distance
 	EntityVisionDist int32
 	EntitySmellDist  int32
@@ -42,8 +68,18 @@ type Datacom struct {
 	redisClient *redis.Client
 	// pubnub client
 	pubsub PubsubAccessLayer
+	// logs failures with the world they happened in
+	logger *zap.Logger
 }
 
+// ErrConflict is returned when an entity is written to after it was read,
+// the write is dropped so the other one isn't lost
+var ErrConflict = errors.New("entity changed since it was read")
+
+// ErrEntityExists is returned when an entity is created with an id already in
+// use, the existing entity is left alone
+var ErrEntityExists = errors.New("entity already exists")
+
 // RemoteModel struct for parsing and storing RM data from databases
 type RemoteModel struct {
 	ID           string `firestore:"id,omitempty"`
@@ -66,13 +102,18 @@ type PubsubAccessLayer interface {
 }
 
 // NewDatacom instantiates a new datacom object with proper clients
-// according to the environment
-func NewDatacom(env string, redisAddr string, pubsub PubsubAccessLayer) (*Datacom, error) {
+// according to the environment, reading and writing the given world
+func NewDatacom(env string, worldID string, redisAddr string, pubsub PubsubAccessLayer) (*Datacom, error) {
+	if !ValidWorldID(worldID) {
+		return nil, fmt.Errorf("invalid world id %q", worldID)
+	}
 	dc := &Datacom{
 		env:              env,
+		worldID:          worldID,
 		pubsub:           pubsub,
 		EntityVisionDist: defaultEntityVisionDist,
 		EntitySmellDist:  defaultEntitySmellDist,
+		logger:           zap.NewNop().With(zap.String("world", worldID)),
 	}
 
 	// Setup Firebase
@@ -80,8 +121,7 @@ func NewDatacom(env string, redisAddr string, pubsub PubsubAccessLayer) (*Dataco
 	case "staging":
 		// FIREBASE STAGING
 		if _, err := os.Stat(serviceAccountStagingFileLocation); os.IsNotExist(err) {
-			// path/to/whatever does not exist
-			log.Panic("ERROR: Staging service account file not found")
+			return nil, errors.New("staging service account file not found")
 		}
 		opt := option.WithCredentialsFile(serviceAccountStagingFileLocation)
 		app, err := firebase.NewApp(context.Background(), nil, opt)
@@ -111,5 +151,29 @@ func NewDatacom(env string, redisAddr string, pubsub PubsubAccessLayer) (*Dataco
 	}
 	dc.redisClient = redisClient
 
+	// The world's size decides how positions are indexed
+	size, err := dc.worldSize(worldID)
+	if err != nil {
+		return nil, err
+	}
+	topology, err := dc.worldTopology(worldID)
+	if err != nil {
+		return nil, err
+	}
+	if err := topology.ValidateSize(size); err != nil {
+		return nil, fmt.Errorf("world %v: %v", worldID, err)
+	}
+	dc.grid = newGrid(size)
+	dc.grid.torus = topology == Torus
+	if worldID == DefaultWorldID {
+		dc.grid.bits = legacyIndexBits
+	}
+
 	return dc, nil
 }
+
+// SetLogger sets the logger failures are logged to, nothing is logged
+// without one
+func (dc *Datacom) SetLogger(logger *zap.Logger) {
+	dc.logger = logger.With(zap.String("world", dc.worldID))
+}
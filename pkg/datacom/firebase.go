@@ -4,11 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"go.uber.org/zap"
 )
 
 // GetRemoteModelMetadataBySecret checks the database to see if a remote model exists,
@@ -36,12 +36,12 @@ func (dc *Datacom) GetRemoteModelMetadataBySecret(modelSecret string) (*RemoteMo
 
 	docs, err := q.Documents(ctx).GetAll()
 	if err != nil {
-		log.Println("error iter")
+		dc.logger.Warn("rejected model secret", zap.Error(err))
 		return nil, fmt.Errorf("invalid secret key: %v", err)
 	}
 	if len(docs) == 0 {
 		err := errors.New("remote model does not exist")
-		log.Printf("ERROR: %v\n", err)
+		dc.logger.Warn("rejected model secret", zap.Error(err))
 		return nil, err
 	}
 
@@ -76,7 +76,7 @@ func (dc *Datacom) GetRemoteModelMetadataByID(modelID string) (*RemoteModel, err
 	dsnap, err := client.Collection("remoteModels").Doc(modelID).Get(ctx)
 	if err != nil {
 		err := fmt.Errorf("Invalid model id: %v", err)
-		log.Printf("ERROR: %v\n", err)
+		dc.logger.Warn("getting remote model", zap.Error(err), zap.String("model", modelID))
 		return nil, err
 	}
 
@@ -108,7 +108,7 @@ func (dc *Datacom) UpdateRemoteModelMetadata(remoteModelMD *RemoteModel, connect
 
 	if err != nil {
 		// Handle any errors in an appropriate way, such as returning them.
-		log.Printf("An error has occurred updating model id=%s: %s", remoteModelMD.ID, err)
+		dc.logger.Error("updating remote model", zap.Error(err), zap.String("model", remoteModelMD.ID))
 		return err
 	}
 
@@ -0,0 +1,207 @@
+package datacom
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/terrain"
+)
+
+const (
+	// The default world was indexed with 9 bits per coordinate before worlds
+	// had sizes, it keeps them so its data doesn't move
+	legacyIndexBits = 9
+	// Largest width or height a world can have
+	maxWorldSide = 1 << 15
+)
+
+// WorldSize is how many cells wide and high a world is. Positions run from 0
+// to Width-1 and Height-1.
+type WorldSize struct {
+	Width  uint32
+	Height uint32
+}
+
+// DefaultWorldSize is the size of the default world, and of worlds created
+// without one
+var DefaultWorldSize = WorldSize{Width: 100, Height: 100}
+
+// Validate checks the size can be indexed
+func (size WorldSize) Validate() error {
+	if size.Width == 0 || size.Height == 0 {
+		return errors.New("world width and height must be at least 1")
+	}
+	if size.Width > maxWorldSide || size.Height > maxWorldSide {
+		return fmt.Errorf("world width and height can be at most %v", maxWorldSide)
+	}
+	return nil
+}
+
+// checkTerrain makes sure a terrain map covers the world exactly
+func (g grid) checkTerrain(m *terrain.Map) error {
+	if m != nil && (m.Width != g.size.Width || m.Height != g.size.Height) {
+		return fmt.Errorf("terrain is %vx%v, the world is %vx%v", m.Width, m.Height, g.size.Width, g.size.Height)
+	}
+	return nil
+}
+
+// Topology is what happens at the edges of a world
+type Topology string
+
+const (
+	// Bounded worlds end at their edges, past them is rock
+	Bounded Topology = "bounded"
+	// Torus worlds wrap around, the cell past the right edge is the first
+	// cell of the row and the cell past the top is the bottom of the column
+	Torus Topology = "torus"
+)
+
+// Validate checks the topology is one the datacom knows
+func (t Topology) Validate() error {
+	if t != Bounded && t != Torus {
+		return fmt.Errorf("invalid topology %q", t)
+	}
+	return nil
+}
+
+// ValidateSize checks a world of the size can have the topology. A torus less
+// than 2 cells across wraps a cell's neighbour back round onto the cell.
+func (t Topology) ValidateSize(size WorldSize) error {
+	if t == Torus && (size.Width < 2 || size.Height < 2) {
+		return errors.New("torus worlds must be at least 2 wide and high")
+	}
+	return nil
+}
+
+// grid is the shape of a world, its size, whether it wraps around, its
+// terrain and how many bits of each coordinate go into its position indexes
+type grid struct {
+	size    WorldSize
+	bits    uint
+	torus   bool
+	terrain *terrain.Map
+}
+
+// newGrid sizes the position indexes to fit the world
+func newGrid(size WorldSize) grid {
+	side := size.Width
+	if size.Height > side {
+		side = size.Height
+	}
+	b := uint(bits.Len32(side - 1))
+	if b == 0 {
+		b = 1
+	}
+	return grid{size: size, bits: b}
+}
+
+// contains checks if a position is on the map
+func (g grid) contains(x uint32, y uint32) bool {
+	return x < g.size.Width && y < g.size.Height
+}
+
+// index interlocks an x and y value to use as an index in redis
+func (g grid) index(x uint32, y uint32) (string, error) {
+	if !g.contains(x, y) {
+		return "", errors.New("invalid position")
+	}
+	return g.formatIndex(g.mortonIndex(x, y)), nil
+}
+
+// mortonIndex interlocks x and y, x taking the higher bit of each pair
+func (g grid) mortonIndex(x uint32, y uint32) uint64 {
+	var index uint64
+	for i := int(g.bits) - 1; i >= 0; i-- {
+		index = index<<2 | uint64((x>>uint(i))&1)<<1 | uint64((y>>uint(i))&1)
+	}
+	return index
+}
+
+// formatIndex formats a position index as the binary string stored in redis
+func (g grid) formatIndex(index uint64) string {
+	s := strconv.FormatUint(index, 2)
+	for len(s) < int(g.bits)*2 {
+		s = "0" + s
+	}
+	return s
+}
+
+// wrap finds the cell a position lands on. Off the map positions only land
+// on a cell in a torus world.
+func (g grid) wrap(x int64, y int64) (uint32, uint32, bool) {
+	if g.torus {
+		w, h := int64(g.size.Width), int64(g.size.Height)
+		return uint32((x%w + w) % w), uint32((y%h + h) % h), true
+	}
+	if x < 0 || y < 0 || !g.contains(uint32(x), uint32(y)) {
+		return 0, 0, false
+	}
+	return uint32(x), uint32(y), true
+}
+
+// axisRanges returns the ranges of one axis within dist of v. Bounded worlds
+// clamp to the edge, torus worlds wrap, splitting the range at the seam.
+func axisRanges(v uint32, dist int32, length uint32, torus bool) [][2]uint32 {
+	lo := int64(v) - int64(dist)
+	hi := int64(v) + int64(dist)
+	last := int64(length) - 1
+	if !torus {
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > last {
+			hi = last
+		}
+		return [][2]uint32{{uint32(lo), uint32(hi)}}
+	}
+	if hi-lo >= last {
+		return [][2]uint32{{0, uint32(last)}}
+	}
+	lo = (lo + int64(length)) % int64(length)
+	hi = hi % int64(length)
+	if lo <= hi {
+		return [][2]uint32{{uint32(lo), uint32(hi)}}
+	}
+	return [][2]uint32{{uint32(lo), uint32(last)}, {0, uint32(hi)}}
+}
+
+// spacesAround returns the spaces within dist of a position. It is one space
+// clamped to the map, unless the window crosses the seam of a torus world.
+func (g grid) spacesAround(x uint32, y uint32, dist int32) []space {
+	spaces := []space{}
+	for _, xr := range axisRanges(x, dist, g.size.Width, g.torus) {
+		for _, yr := range axisRanges(y, dist, g.size.Height, g.torus) {
+			spaces = append(spaces, space{xr[0], yr[0], xr[1], yr[1]})
+		}
+	}
+	return spaces
+}
+
+// serializeContent builds a redis member, the position index followed by the
+// binary message. The index keeps members sorted by position for range
+// queries.
+func (g grid) serializeContent(x uint32, y uint32, msg proto.Message) (string, error) {
+	index, err := g.index(x, y)
+	if err != nil {
+		return "", err
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	return index + "-" + string(data), nil
+}
+
+// Serializes an entity to a string
+func (g grid) serializeEntity(e envApi.Entity) (string, error) {
+	return g.serializeContent(e.X, e.Y, &e)
+}
+
+// Serializes a cell to a string
+func (g grid) serializeEffect(p envApi.Effect) (string, error) {
+	return g.serializeContent(p.X, p.Y, &p)
+}
@@ -0,0 +1,118 @@
+package datacom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGrid(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      WorldSize
+		wantBits  uint
+		x, y      uint32
+		wantIndex string
+		wantErr   bool
+	}{
+		{"Single cell world", WorldSize{1, 1}, 1, 0, 0, "00", false},
+		{"Arena origin", WorldSize{20, 20}, 5, 0, 0, "0000000000", false},
+		{"Arena last cell", WorldSize{20, 20}, 5, 19, 19, "1100001111", false},
+		{"Arena width", WorldSize{20, 20}, 5, 20, 0, "", true},
+		{"Arena height", WorldSize{20, 20}, 5, 0, 20, "", true},
+		{"Wide world", WorldSize{1000, 20}, 10, 999, 19, "10101010100100101111", false},
+		{"Wide world height", WorldSize{1000, 20}, 10, 0, 20, "", true},
+		{"Large map", WorldSize{1000, 1000}, 10, 1, 2, "00000000000000000110", false},
+		{"Power of two", WorldSize{512, 512}, 9, 511, 511, "111111111111111111", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGrid(tt.size)
+			if g.bits != tt.wantBits {
+				t.Errorf("bits = %v, want %v", g.bits, tt.wantBits)
+			}
+			index, err := g.index(tt.x, tt.y)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("index() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if index != tt.wantIndex {
+				t.Errorf("index() = %v, want %v", index, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestWorldSizeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    WorldSize
+		wantErr bool
+	}{
+		{"Default", DefaultWorldSize, false},
+		{"Arena", WorldSize{20, 20}, false},
+		{"Largest", WorldSize{maxWorldSide, maxWorldSide}, false},
+		{"No width", WorldSize{0, 20}, true},
+		{"No height", WorldSize{20, 0}, true},
+		{"Too wide", WorldSize{maxWorldSide + 1, 20}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.size.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTopologyValidateSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology Topology
+		size     WorldSize
+		wantErr  bool
+	}{
+		{"Bounded line", Bounded, WorldSize{1, 20}, false},
+		{"Bounded cell", Bounded, WorldSize{1, 1}, false},
+		{"Smallest torus", Torus, WorldSize{2, 2}, false},
+		{"Torus one wide", Torus, WorldSize{1, 20}, true},
+		{"Torus one high", Torus, WorldSize{20, 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.topology.ValidateSize(tt.size); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSpacesAround(t *testing.T) {
+	bounded := newGrid(WorldSize{20, 20})
+	torus := newGrid(WorldSize{20, 20})
+	torus.torus = true
+	tests := []struct {
+		name string
+		g    grid
+		x, y uint32
+		dist int32
+		want []space
+	}{
+		{"Bounded middle", bounded, 10, 10, 2, []space{{8, 8, 12, 12}}},
+		{"Bounded corner is clamped", bounded, 0, 19, 2, []space{{0, 17, 2, 19}}},
+		{"Torus middle", torus, 10, 10, 2, []space{{8, 8, 12, 12}}},
+		{"Torus left edge", torus, 0, 10, 2, []space{{18, 8, 19, 12}, {0, 8, 2, 12}}},
+		{"Torus corner", torus, 19, 0, 1, []space{{18, 19, 19, 19}, {18, 0, 19, 1}, {0, 19, 0, 19}, {0, 0, 0, 1}}},
+		{"Torus window wider than the world", torus, 3, 10, 10, []space{{0, 0, 19, 19}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.g.spacesAround(tt.x, tt.y, tt.dist); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("spacesAround() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
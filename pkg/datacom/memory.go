@@ -0,0 +1,725 @@
+package datacom
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	collectiveApi "github.com/terrariumai/simulation/pkg/api/collective"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/terrain"
+	"go.uber.org/zap"
+)
+
+// MemoryDatacom keeps the whole world in memory, with no redis or firebase
+// behind it. Entities and effects are kept in a grid keyed by cell, so every
+// lookup is a map access instead of a range query. It is only meant for
+// training, where a single process owns the world.
+type MemoryDatacom struct {
+	// entity vision distance
+	EntityVisionDist int32
+	EntitySmellDist  int32
+	// pubnub client
+	pubsub PubsubAccessLayer
+	// size of the world
+	grid grid
+	// logs failures, nothing is logged without one
+	logger *zap.Logger
+
+	m sync.RWMutex
+	// Entities and the content token handed out for them, by id
+	entities map[string]*memEntity
+	// Ids of the entities in each cell
+	cells map[cellKey][]string
+	// Ids of each model's entities
+	models map[string]map[string]bool
+	// Effects in each cell
+	effects map[cellKey][]envApi.Effect
+	// Cell locks
+	locks map[cellKey]memLock
+	// Leases by name, the token is the holder
+	leases map[string]memLock
+	// Food growth steps taken
+	growthSteps uint64
+	// Bumped every write, used to build content tokens
+	revision uint64
+	// World generation
+	generation int64
+	// Other worlds that have been created, only the default world holds
+	// anything in memory
+	worlds map[string]World
+}
+
+type memEntity struct {
+	entity envApi.Entity
+	// Stands in for the redis content, it changes every time the entity is
+	// written so stale updates can be caught
+	content string
+}
+
+type memLock struct {
+	token   string
+	expires time.Time
+}
+
+// NewMemoryDatacom creates an empty in memory world of the given size and
+// topology
+func NewMemoryDatacom(pubsub PubsubAccessLayer, size WorldSize, topology Topology) (*MemoryDatacom, error) {
+	if err := size.Validate(); err != nil {
+		return nil, err
+	}
+	if err := topology.Validate(); err != nil {
+		return nil, err
+	}
+	if err := topology.ValidateSize(size); err != nil {
+		return nil, err
+	}
+	g := newGrid(size)
+	g.torus = topology == Torus
+	return &MemoryDatacom{
+		EntityVisionDist: defaultEntityVisionDist,
+		EntitySmellDist:  defaultEntitySmellDist,
+		pubsub:           pubsub,
+		grid:             g,
+		entities:         make(map[string]*memEntity),
+		cells:            make(map[cellKey][]string),
+		models:           make(map[string]map[string]bool),
+		effects:          make(map[cellKey][]envApi.Effect),
+		locks:            make(map[cellKey]memLock),
+		leases:           make(map[string]memLock),
+		worlds:           make(map[string]World),
+		logger:           zap.NewNop().With(zap.String("world", DefaultWorldID)),
+	}, nil
+}
+
+// SetLogger sets the logger failures are logged to
+func (mc *MemoryDatacom) SetLogger(logger *zap.Logger) {
+	mc.logger = logger.With(zap.String("world", DefaultWorldID))
+}
+
+// WorldSize returns the size of the world
+func (mc *MemoryDatacom) WorldSize() WorldSize {
+	return mc.grid.size
+}
+
+// SetTerrain sets the terrain observations see in empty cells
+func (mc *MemoryDatacom) SetTerrain(m *terrain.Map) error {
+	if err := mc.grid.checkTerrain(m); err != nil {
+		mc.logger.Warn("rejected terrain", zap.Error(err))
+		return err
+	}
+	mc.m.Lock()
+	defer mc.m.Unlock()
+	mc.grid.terrain = m
+	return nil
+}
+
+// observationGrid is a copy of the grid, taken while the terrain can't be
+// swapped out
+func (mc *MemoryDatacom) observationGrid() grid {
+	mc.m.RLock()
+	defer mc.m.RUnlock()
+	return mc.grid
+}
+
+// WorldTopology returns what happens at the edges of the world
+func (mc *MemoryDatacom) WorldTopology() Topology {
+	if mc.grid.torus {
+		return Torus
+	}
+	return Bounded
+}
+
+func (mc *MemoryDatacom) nextContent(id string) string {
+	mc.revision++
+	return id + "@" + strconv.FormatUint(mc.revision, 10)
+}
+
+func (mc *MemoryDatacom) removeFromCell(e envApi.Entity) {
+	key := cellKey{e.X, e.Y}
+	ids := mc.cells[key]
+	for i, id := range ids {
+		if id == e.Id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(mc.cells, key)
+	} else {
+		mc.cells[key] = ids
+	}
+}
+
+// --------------
+// World
+// --------------
+
+// ResetWorld removes every entity and effect, then increments and returns the
+// world generation id
+func (mc *MemoryDatacom) ResetWorld() (int64, error) {
+	mc.m.Lock()
+	defer mc.m.Unlock()
+	mc.entities = make(map[string]*memEntity)
+	mc.cells = make(map[cellKey][]string)
+	mc.models = make(map[string]map[string]bool)
+	mc.effects = make(map[cellKey][]envApi.Effect)
+	mc.generation++
+	return mc.generation, nil
+}
+
+// --------------
+// Locks
+// --------------
+
+// LockCells tries to lock every given cell at once. Invalid positions are
+// skipped, same as redis.
+func (mc *MemoryDatacom) LockCells(cells []envApi.Position) (string, bool, error) {
+	mc.m.Lock()
+	defer mc.m.Unlock()
+	now := time.Now()
+	for _, cell := range cells {
+		if lock, ok := mc.locks[cellKey{cell.X, cell.Y}]; ok && now.Before(lock.expires) {
+			return "", false, nil
+		}
+	}
+	mc.revision++
+	token := "lock@" + strconv.FormatUint(mc.revision, 10)
+	for _, cell := range cells {
+		if !mc.grid.contains(cell.X, cell.Y) {
+			continue
+		}
+		mc.locks[cellKey{cell.X, cell.Y}] = memLock{token, now.Add(cellLockTTL)}
+	}
+	return token, true, nil
+}
+
+// UnlockCells releases the cells held with the token
+func (mc *MemoryDatacom) UnlockCells(token string, cells []envApi.Position) error {
+	mc.m.Lock()
+	defer mc.m.Unlock()
+	for _, cell := range cells {
+		key := cellKey{cell.X, cell.Y}
+		if lock, ok := mc.locks[key]; ok && lock.token == token {
+			delete(mc.locks, key)
+		}
+	}
+	return nil
+}
+
+// AcquireLease makes the holder the only one running a job until the ttl
+// runs out, renewing the lease if they already hold it
+func (mc *MemoryDatacom) AcquireLease(name string, holder string, ttl time.Duration) (bool, error) {
+	mc.m.Lock()
+	defer mc.m.Unlock()
+	now := time.Now()
+	if lease, ok := mc.leases[name]; ok && lease.token != holder && now.Before(lease.expires) {
+		return false, nil
+	}
+	mc.leases[name] = memLock{holder, now.Add(ttl)}
+	return true, nil
+}
+
+// NextGrowthStep counts a food growth step, returning how many were taken
+// before it
+func (mc *MemoryDatacom) NextGrowthStep() (uint64, error) {
+	mc.m.Lock()
+	defer mc.m.Unlock()
+	mc.growthSteps++
+	return mc.growthSteps - 1, nil
+}
+
+// --------------
+// Entities
+// --------------
+
+// IsCellOccupied checks if a cell has an entity in it
+func (mc *MemoryDatacom) IsCellOccupied(x uint32, y uint32) (bool, *envApi.Entity, string, error) {
+	if !mc.grid.contains(x, y) {
+		return true, nil, "", errors.New("invalid position")
+	}
+	mc.m.RLock()
+	defer mc.m.RUnlock()
+	ids := mc.cells[cellKey{x, y}]
+	if len(ids) == 0 {
+		return false, nil, "", nil
+	}
+	me := mc.entities[ids[0]]
+	e := me.entity
+	return true, &e, me.content, nil
+}
+
+// CreateEntity adds an entity to the world
+func (mc *MemoryDatacom) CreateEntity(e envApi.Entity, shouldPublish bool) error {
+	if !mc.grid.contains(e.X, e.Y) {
+		err := errors.New("invalid position")
+		mc.logger.Warn("creating entity", zap.Error(err), zap.String("entity", e.Id), zap.Uint32("x", e.X), zap.Uint32("y", e.Y))
+		return err
+	}
+	mc.m.Lock()
+	if _, ok := mc.entities[e.Id]; ok {
+		mc.m.Unlock()
+		mc.logger.Warn("creating entity", zap.Error(ErrEntityExists), zap.String("entity", e.Id))
+		return ErrEntityExists
+	}
+	mc.entities[e.Id] = &memEntity{e, mc.nextContent(e.Id)}
+	key := cellKey{e.X, e.Y}
+	mc.cells[key] = append(mc.cells[key], e.Id)
+	if mc.models[e.ModelID] == nil {
+		mc.models[e.ModelID] = make(map[string]bool)
+	}
+	mc.models[e.ModelID][e.Id] = true
+	mc.m.Unlock()
+
+	// Send update
+	if shouldPublish {
+		mc.pubsub.QueuePublishEvent("createEntity", &e, e.X, e.Y)
+	}
+
+	return nil
+}
+
+// UpdateEntity replaces an entity, as long as nothing changed it since the
+// origional content was read
+func (mc *MemoryDatacom) UpdateEntity(origionalContent string, e envApi.Entity) error {
+	if !mc.grid.contains(e.X, e.Y) {
+		err := errors.New("invalid position")
+		mc.logger.Warn("updating entity", zap.Error(err), zap.String("entity", e.Id), zap.Uint32("x", e.X), zap.Uint32("y", e.Y))
+		return err
+	}
+	mc.m.Lock()
+	me, ok := mc.entities[e.Id]
+	if !ok {
+		mc.m.Unlock()
+		err := errors.New("UpdateEntity: entity does not exist")
+		mc.logger.Warn("updating entity", zap.Error(err), zap.String("entity", e.Id))
+		return err
+	}
+	if me.content != origionalContent {
+		mc.m.Unlock()
+		mc.logger.Warn("updating entity", zap.Error(ErrConflict), zap.String("entity", e.Id))
+		return ErrConflict
+	}
+	mc.removeFromCell(me.entity)
+	me.entity = e
+	me.content = mc.nextContent(e.Id)
+	key := cellKey{e.X, e.Y}
+	mc.cells[key] = append(mc.cells[key], e.Id)
+	mc.m.Unlock()
+
+	// Send update
+	mc.pubsub.QueuePublishEvent("updateEntity", &e, e.X, e.Y)
+
+	return nil
+}
+
+// GetEntity gets an entity from the environment by id
+func (mc *MemoryDatacom) GetEntity(id string) (*envApi.Entity, string, error) {
+	mc.m.RLock()
+	defer mc.m.RUnlock()
+	me, ok := mc.entities[id]
+	if !ok {
+		return nil, "", errors.New("entity does not exist")
+	}
+	e := me.entity
+	return &e, me.content, nil
+}
+
+// DeleteEntity completely removes an entity from existence from the environment
+func (mc *MemoryDatacom) DeleteEntity(id string) (int64, error) {
+	mc.m.Lock()
+	me, ok := mc.entities[id]
+	if !ok {
+		mc.m.Unlock()
+		err := errors.New("DeleteEntity: get content failed: entity does not exist")
+		mc.logger.Warn("deleting entity", zap.Error(err), zap.String("entity", id))
+		return 0, err
+	}
+	entity := me.entity
+	delete(mc.entities, id)
+	mc.removeFromCell(entity)
+	delete(mc.models[entity.ModelID], id)
+	if len(mc.models[entity.ModelID]) == 0 {
+		delete(mc.models, entity.ModelID)
+	}
+	mc.m.Unlock()
+
+	// Send update
+	mc.pubsub.QueuePublishEvent("deleteEntity", &entity, entity.X, entity.Y)
+
+	return 1, nil
+}
+
+// GetEntitiesForModel gets a list of entities for a specific model
+func (mc *MemoryDatacom) GetEntitiesForModel(modelID string) ([]envApi.Entity, error) {
+	mc.m.RLock()
+	defer mc.m.RUnlock()
+	ids := []string{}
+	for id := range mc.models[modelID] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	entities := []envApi.Entity{}
+	for _, id := range ids {
+		entities = append(entities, mc.entities[id].entity)
+	}
+	return entities, nil
+}
+
+// GetObservationForEntity returns observations for a specific entity
+func (mc *MemoryDatacom) GetObservationForEntity(entity envApi.Entity) (*collectiveApi.Observation, error) {
+	if !mc.grid.contains(entity.X, entity.Y) {
+		err := errors.New("invalid position")
+		mc.logger.Warn("observing entity", zap.Error(err), zap.String("entity", entity.Id), zap.Uint32("x", entity.X), zap.Uint32("y", entity.Y))
+		return nil, err
+	}
+	closeEntities := []*envApi.Entity{}
+	closeEffects := []*envApi.Effect{}
+	for _, sp := range mc.grid.spacesAround(entity.X, entity.Y, mc.EntityVisionDist) {
+		entities, err := mc.GetEntitiesInSpace(sp.x0, sp.y0, sp.x1, sp.y1)
+		if err != nil {
+			mc.logger.Error("observing entity", zap.Error(err), zap.String("entity", entity.Id))
+			return nil, err
+		}
+		closeEntities = append(closeEntities, entities...)
+		effects, err := mc.GetEffectsInSpace(sp.x0, sp.y0, sp.x1, sp.y1)
+		if err != nil {
+			mc.logger.Error("observing entity", zap.Error(err), zap.String("entity", entity.Id))
+			return nil, err
+		}
+		closeEffects = append(closeEffects, effects...)
+	}
+
+	return buildObservation(mc.observationGrid(), entity, mc.EntityVisionDist, mc.EntitySmellDist, entitiesByCell(closeEntities), effectsByCell(closeEffects)), nil
+}
+
+// GetObservationsForEntities returns an observation for each entity, in the
+// same order, all from the same snapshot of the world
+func (mc *MemoryDatacom) GetObservationsForEntities(entities []envApi.Entity) ([]*collectiveApi.Observation, error) {
+	for _, entity := range entities {
+		if !mc.grid.contains(entity.X, entity.Y) {
+			err := errors.New("invalid position")
+			mc.logger.Warn("observing entities", zap.Error(err), zap.String("entity", entity.Id), zap.Uint32("x", entity.X), zap.Uint32("y", entity.Y))
+			return nil, err
+		}
+	}
+
+	dist := observationDist(mc.EntityVisionDist, mc.EntitySmellDist)
+	closeEntities := make(map[cellKey]*envApi.Entity)
+	closeEffects := make(map[cellKey]*envApi.Effect)
+	expired := []envApi.Effect{}
+	seen := make(map[cellKey]bool)
+	now := time.Now().Unix()
+	mc.m.RLock()
+	spaces := []space{}
+	for _, entity := range entities {
+		spaces = append(spaces, mc.grid.spacesAround(entity.X, entity.Y, dist)...)
+	}
+	for _, sp := range spaces {
+		for y := sp.y0; y <= sp.y1; y++ {
+			for x := sp.x0; x <= sp.x1; x++ {
+				key := cellKey{x, y}
+				// Windows overlap, each cell is only read once
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if len(mc.cells[key]) > 0 {
+					ids := append([]string{}, mc.cells[key]...)
+					sort.Strings(ids)
+					e := mc.entities[ids[len(ids)-1]].entity
+					closeEntities[key] = &e
+				}
+				for _, effect := range mc.effects[key] {
+					effect := effect
+					if isEffectExpired(effect, now) {
+						expired = append(expired, effect)
+						continue
+					}
+					closeEffects[key] = &effect
+				}
+			}
+		}
+	}
+	g := mc.grid
+	mc.m.RUnlock()
+
+	// Clean up decayed effects
+	for _, effect := range expired {
+		mc.DeleteEffect(effect)
+	}
+
+	observations := make([]*collectiveApi.Observation, len(entities))
+	for i, entity := range entities {
+		observations[i] = buildObservation(g, entity, mc.EntityVisionDist, mc.EntitySmellDist, closeEntities, closeEffects)
+	}
+	return observations, nil
+}
+
+// cellsInSpace returns the occupied cells within a space. Small spaces are
+// walked cell by cell, big ones by going through what is in the grid.
+func (g grid) cellsInSpace(x0 uint32, y0 uint32, x1 uint32, y1 uint32, occupied int, has func(key cellKey) bool, each func(func(key cellKey))) []cellKey {
+	keys := []cellKey{}
+	// Nothing can be past the edge of the map
+	if x1 > g.size.Width-1 {
+		x1 = g.size.Width - 1
+	}
+	if y1 > g.size.Height-1 {
+		y1 = g.size.Height - 1
+	}
+	if x1 < x0 || y1 < y0 {
+		return keys
+	}
+	if uint64(x1-x0+1)*uint64(y1-y0+1) <= uint64(occupied) {
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				if has(cellKey{x, y}) {
+					keys = append(keys, cellKey{x, y})
+				}
+			}
+		}
+	} else {
+		each(func(key cellKey) {
+			if key.x >= x0 && key.x <= x1 && key.y >= y0 && key.y <= y1 {
+				keys = append(keys, key)
+			}
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return g.mortonIndex(keys[i].x, keys[i].y) < g.mortonIndex(keys[j].x, keys[j].y)
+	})
+	return keys
+}
+
+// GetEntitiesInSpace returns the entities in a specific region
+func (mc *MemoryDatacom) GetEntitiesInSpace(x0 uint32, y0 uint32, x1 uint32, y1 uint32) ([]*envApi.Entity, error) {
+	if !mc.grid.contains(x0, y0) {
+		return nil, fmt.Errorf("Error converting min/max positions to index: invalid position")
+	}
+	mc.m.RLock()
+	defer mc.m.RUnlock()
+	keys := mc.grid.cellsInSpace(x0, y0, x1, y1, len(mc.cells), func(key cellKey) bool {
+		return len(mc.cells[key]) > 0
+	}, func(f func(key cellKey)) {
+		for key := range mc.cells {
+			f(key)
+		}
+	})
+
+	entities := []*envApi.Entity{}
+	for _, key := range keys {
+		ids := append([]string{}, mc.cells[key]...)
+		sort.Strings(ids)
+		for _, id := range ids {
+			e := mc.entities[id].entity
+			entities = append(entities, &e)
+		}
+	}
+	return entities, nil
+}
+
+// --------------
+// Effects
+// --------------
+
+// CreateEffect sets an effect at a specific position
+func (mc *MemoryDatacom) CreateEffect(effect envApi.Effect) error {
+	if effect.Timestamp == 0 {
+		effect.Timestamp = time.Now().Unix()
+	}
+	if !mc.grid.contains(effect.X, effect.Y) {
+		err := errors.New("invalid position")
+		mc.logger.Warn("creating effect", zap.Error(err), zap.Uint32("x", effect.X), zap.Uint32("y", effect.Y))
+		return err
+	}
+	mc.m.Lock()
+	key := cellKey{effect.X, effect.Y}
+	exists := false
+	for i := range mc.effects[key] {
+		exists = exists || proto.Equal(&mc.effects[key][i], &effect)
+	}
+	// An identical effect is only kept once, same as the redis set
+	if !exists {
+		mc.effects[key] = append(mc.effects[key], effect)
+	}
+	mc.m.Unlock()
+
+	// Send update
+	mc.pubsub.QueuePublishEvent("createEffect", &effect, effect.X, effect.Y)
+
+	return nil
+}
+
+// GetEffectsInSpace returns the effects in a specific region, cleaning up
+// any that have decayed
+func (mc *MemoryDatacom) GetEffectsInSpace(x0 uint32, y0 uint32, x1 uint32, y1 uint32) ([]*envApi.Effect, error) {
+	if !mc.grid.contains(x0, y0) {
+		return nil, fmt.Errorf("Error converting min/max positions to index: invalid position")
+	}
+	mc.m.RLock()
+	keys := mc.grid.cellsInSpace(x0, y0, x1, y1, len(mc.effects), func(key cellKey) bool {
+		return len(mc.effects[key]) > 0
+	}, func(f func(key cellKey)) {
+		for key := range mc.effects {
+			f(key)
+		}
+	})
+	effects := []*envApi.Effect{}
+	expired := []envApi.Effect{}
+	now := time.Now().Unix()
+	for _, key := range keys {
+		for _, effect := range mc.effects[key] {
+			effect := effect
+			if isEffectExpired(effect, now) {
+				expired = append(expired, effect)
+				continue
+			}
+			effects = append(effects, &effect)
+		}
+	}
+	mc.m.RUnlock()
+
+	// Clean up decayed effects
+	for _, effect := range expired {
+		mc.DeleteEffect(effect)
+	}
+
+	return effects, nil
+}
+
+// DeleteEffect removes an effect
+func (mc *MemoryDatacom) DeleteEffect(effect envApi.Effect) (int64, error) {
+	mc.m.Lock()
+	key := cellKey{effect.X, effect.Y}
+	var removed int64
+	kept := []envApi.Effect{}
+	for i := range mc.effects[key] {
+		if proto.Equal(&mc.effects[key][i], &effect) {
+			removed++
+			continue
+		}
+		kept = append(kept, mc.effects[key][i])
+	}
+	if len(kept) == 0 {
+		delete(mc.effects, key)
+	} else {
+		mc.effects[key] = kept
+	}
+	mc.m.Unlock()
+
+	// Send update
+	mc.pubsub.QueuePublishEvent("deleteEffect", &effect, effect.X, effect.Y)
+
+	return removed, nil
+}
+
+// --------------
+// Firebase
+// --------------
+// Note: there is no firebase in memory, these behave like datacom does in
+//   training.
+
+// GetRemoteModelMetadataBySecret returns the training model for any secret
+func (mc *MemoryDatacom) GetRemoteModelMetadataBySecret(modelSecret string) (*RemoteModel, error) {
+	return &RemoteModel{
+		ID:           "MOCK-MODEL-ID",
+		OwnerUID:     "MOCK-UID",
+		ConnectCount: 1,
+	}, nil
+}
+
+// GetRemoteModelMetadataByID returns the training model for any id
+func (mc *MemoryDatacom) GetRemoteModelMetadataByID(modelID string) (*RemoteModel, error) {
+	return &RemoteModel{
+		ID:           "MOCK-MODEL-ID",
+		OwnerUID:     "MOCK-UID",
+		ConnectCount: 1,
+	}, nil
+}
+
+// UpdateRemoteModelMetadata does nothing in memory
+func (mc *MemoryDatacom) UpdateRemoteModelMetadata(remoteModelMD *RemoteModel, connectCount int) error {
+	return nil
+}
+
+// AddEntityMetadataToFireabase does nothing in memory
+func (mc *MemoryDatacom) AddEntityMetadataToFireabase(e envApi.Entity) error {
+	return nil
+}
+
+// RemoveEntityMetadataFromFirebase does nothing in memory
+func (mc *MemoryDatacom) RemoveEntityMetadataFromFirebase(id string) error {
+	return nil
+}
+
+// --------------
+// Worlds
+// --------------
+
+// CreateWorld registers a new, empty world with the given size and topology
+func (mc *MemoryDatacom) CreateWorld(worldID string, size WorldSize, topology Topology) error {
+	if !ValidWorldID(worldID) {
+		err := fmt.Errorf("invalid world id %q", worldID)
+		mc.logger.Warn("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	if err := size.Validate(); err != nil {
+		mc.logger.Warn("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	if err := topology.Validate(); err != nil {
+		mc.logger.Warn("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	if err := topology.ValidateSize(size); err != nil {
+		mc.logger.Warn("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	mc.m.Lock()
+	defer mc.m.Unlock()
+	if _, ok := mc.worlds[worldID]; ok || worldID == DefaultWorldID {
+		err := errors.New("world already exists")
+		mc.logger.Warn("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	mc.worlds[worldID] = World{worldID, size, topology}
+	return nil
+}
+
+// ListWorlds returns every world, starting with the default one
+func (mc *MemoryDatacom) ListWorlds() ([]World, error) {
+	mc.m.RLock()
+	defer mc.m.RUnlock()
+	ids := []string{}
+	for id := range mc.worlds {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	worlds := []World{{DefaultWorldID, mc.grid.size, mc.WorldTopology()}}
+	for _, id := range ids {
+		worlds = append(worlds, mc.worlds[id])
+	}
+	return worlds, nil
+}
+
+// DeleteWorld removes a world
+func (mc *MemoryDatacom) DeleteWorld(worldID string) error {
+	if worldID == DefaultWorldID {
+		err := errors.New("the default world can't be deleted")
+		mc.logger.Warn("deleting world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	mc.m.Lock()
+	defer mc.m.Unlock()
+	if _, ok := mc.worlds[worldID]; !ok {
+		err := errors.New("world does not exist")
+		mc.logger.Warn("deleting world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	delete(mc.worlds, worldID)
+	return nil
+}
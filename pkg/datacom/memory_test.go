@@ -0,0 +1,110 @@
+package datacom_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/datacom/mocks"
+	"github.com/terrariumai/simulation/pkg/environment"
+	"github.com/terrariumai/simulation/pkg/environment/daltest"
+)
+
+func newMemoryDatacom() *datacom.MemoryDatacom {
+	mockPAL := &mocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mc, _ := datacom.NewMemoryDatacom(mockPAL, datacom.DefaultWorldSize, datacom.Bounded)
+	return mc
+}
+
+// The memory world has to answer every query the same way redis does
+func TestMemoryDatacomMatchesRedis(t *testing.T) {
+	redisServer := setup()
+	defer teardown(redisServer)
+	mockPAL := &mocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dc, _ := datacom.NewDatacom("training", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
+	mc := newMemoryDatacom()
+
+	entities := []envApi.Entity{
+		{Id: "0", X: 0, Y: 0, ClassID: envApi.Entity_AGENT, ModelID: "MOCK-MODEL-ID", Health: 100, Energy: 100},
+		{Id: "1", X: 1, Y: 1, ClassID: envApi.Entity_AGENT, ModelID: "MOCK-MODEL-ID", Health: 50, Energy: 20},
+		{Id: "2", X: 3, Y: 2, ClassID: envApi.Entity_FOOD},
+		{Id: "3", X: 9, Y: 10, ClassID: envApi.Entity_ROCK},
+		{Id: "4", X: 10, Y: 9, ClassID: envApi.Entity_AGENT, ModelID: "MOCK-MODEL-ID-2"},
+		{Id: "5", X: 99, Y: 99, ClassID: envApi.Entity_FOOD},
+	}
+	effects := []envApi.Effect{
+		{X: 0, Y: 1, ClassID: envApi.Effect_PHEROMONE, Value: 1, Decay: 1.2, DelThresh: 5, Timestamp: time.Now().Unix()},
+		{X: 2, Y: 2, ClassID: envApi.Effect_PHEROMONE, Value: 2, Decay: 1.2, DelThresh: 5, Timestamp: time.Now().Unix()},
+		{X: 9, Y: 9, ClassID: envApi.Effect_PHEROMONE, Value: 3, Decay: 1.2, DelThresh: 5, Timestamp: time.Now().Unix() - 100},
+	}
+	for _, e := range entities {
+		dc.CreateEntity(e, true)
+		mc.CreateEntity(e, true)
+	}
+	for _, effect := range effects {
+		dc.CreateEffect(effect)
+		mc.CreateEffect(effect)
+	}
+
+	spaces := [][4]uint32{{0, 0, 9, 9}, {1, 1, 3, 2}, {5, 5, 15, 15}, {0, 0, 100, 100}, {50, 50, 60, 60}}
+	for _, space := range spaces {
+		want, err := dc.GetEntitiesInSpace(space[0], space[1], space[2], space[3])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := mc.GetEntitiesInSpace(space[0], space[1], space[2], space[3])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetEntitiesInSpace(%v) = %v, want %v", space, got, want)
+		}
+
+		wantEffects, _ := dc.GetEffectsInSpace(space[0], space[1], space[2], space[3])
+		gotEffects, _ := mc.GetEffectsInSpace(space[0], space[1], space[2], space[3])
+		if !reflect.DeepEqual(gotEffects, wantEffects) {
+			t.Errorf("GetEffectsInSpace(%v) = %v, want %v", space, gotEffects, wantEffects)
+		}
+	}
+
+	for _, e := range entities {
+		want, _ := dc.GetObservationForEntity(e)
+		got, _ := mc.GetObservationForEntity(e)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetObservationForEntity(%v) = %v, want %v", e.Id, got, want)
+		}
+
+		wantOccupied, wantEntity, _, _ := dc.IsCellOccupied(e.X, e.Y)
+		gotOccupied, gotEntity, _, _ := mc.IsCellOccupied(e.X, e.Y)
+		if gotOccupied != wantOccupied || !reflect.DeepEqual(gotEntity, wantEntity) {
+			t.Errorf("IsCellOccupied(%v, %v) = %v %v, want %v %v", e.X, e.Y, gotOccupied, gotEntity, wantOccupied, wantEntity)
+		}
+	}
+
+	want, _ := dc.GetObservationsForEntities(entities)
+	got, _ := mc.GetObservationsForEntities(entities)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetObservationsForEntities() = %v, want %v", got, want)
+	}
+
+	for _, modelID := range []string{"MOCK-MODEL-ID", "MOCK-MODEL-ID-2", "MISSING"} {
+		want, _ := dc.GetEntitiesForModel(modelID)
+		sort.Slice(want, func(i, j int) bool { return want[i].Id < want[j].Id })
+		got, _ := mc.GetEntitiesForModel(modelID)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetEntitiesForModel(%v) = %v, want %v", modelID, got, want)
+		}
+	}
+}
+
+func TestMemoryDatacomContract(t *testing.T) {
+	daltest.Run(t, func(t *testing.T) (environment.DataAccessLayer, func()) {
+		return newMemoryDatacom(), func() {}
+	})
+}
@@ -0,0 +1,197 @@
+package datacom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis"
+	"github.com/golang/protobuf/proto"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"go.uber.org/zap"
+)
+
+// Keys used before storage was versioned, entities and effects were stored
+// as text protos with newlines replaced by %n
+const (
+	textEntitiesKey        = "entities"
+	textEntitiesContentKey = "entities.content"
+	textEffectsKey         = "effects"
+	textModelEntitiesKeys  = "model:*:entities"
+	// Members that couldn't be converted are kept under this prefix and the
+	// old key, instead of being dropped with it
+	unmigratedKeyPrefix = "v1:unmigrated:"
+)
+
+// MigrationResult is what MigrateTextStorage converted
+type MigrationResult struct {
+	// Keys that were converted
+	Keys []string
+	// Number of members or fields converted
+	Converted int
+	// Members or fields that couldn't be parsed, moved to v1:unmigrated: keys
+	Skipped []string
+}
+
+// parseTextContent parses a member from the text storage format
+func parseTextContent(content string, msg proto.Message) error {
+	values := strings.SplitN(content, "-", 2)
+	if len(values) != 2 {
+		return fmt.Errorf("invalid content %q: missing position index", content)
+	}
+	text := strings.ReplaceAll(values[1], "%n", "\n")
+	if err := proto.UnmarshalText(text, msg); err != nil {
+		return fmt.Errorf("invalid content %q: %v", content, err)
+	}
+	return nil
+}
+
+// MigrateTextStorage converts entities and effects from the old text format
+// to the current one, moving them to the versioned keys. Each old key is
+// converted and removed in a single transaction, so running it again after a
+// failure picks up where it left off. Anything that can't be converted is
+// moved to a v1:unmigrated: key so it can be fixed by hand. The world should
+// be stopped while it runs.
+func (dc *Datacom) MigrateTextStorage() (*MigrationResult, error) {
+	result := &MigrationResult{Keys: []string{}, Skipped: []string{}}
+
+	// Sorted sets of entities and effects
+	convertMembers := func(oldKey string, newKey string, convert func(member string) (string, error)) error {
+		members, err := dc.redisClient.ZRange(oldKey, 0, -1).Result()
+		if err != nil {
+			return fmt.Errorf("Error reading %v: %v", oldKey, err)
+		}
+		if len(members) == 0 {
+			return nil
+		}
+		converted := []redis.Z{}
+		skipped := []redis.Z{}
+		for _, member := range members {
+			content, err := convert(member)
+			if err != nil {
+				dc.logger.Warn("skipping unconvertible member", zap.Error(err), zap.String("key", oldKey))
+				result.Skipped = append(result.Skipped, oldKey+" "+member)
+				skipped = append(skipped, redis.Z{Score: 0, Member: member})
+				continue
+			}
+			converted = append(converted, redis.Z{Score: 0, Member: content})
+		}
+		err = dc.runTx("MigrateTextStorage", func(pipe redis.Pipeliner) []txStep {
+			steps := []txStep{}
+			if len(converted) > 0 {
+				steps = append(steps, txStep{"add to " + newKey, pipe.ZAdd(newKey, converted...)})
+			}
+			if len(skipped) > 0 {
+				steps = append(steps, txStep{"add to " + unmigratedKeyPrefix + oldKey, pipe.ZAdd(unmigratedKeyPrefix+oldKey, skipped...)})
+			}
+			return append(steps, txStep{"remove " + oldKey, pipe.Del(oldKey)})
+		})
+		if err != nil {
+			return err
+		}
+		result.Keys = append(result.Keys, oldKey)
+		result.Converted += len(converted)
+		return nil
+	}
+	if err := convertMembers(textEntitiesKey, dc.key(entitiesKey), dc.convertTextEntity); err != nil {
+		return result, err
+	}
+	if err := convertMembers(textEffectsKey, dc.key(effectsKey), dc.convertTextEffect); err != nil {
+		return result, err
+	}
+
+	// Entity content hash
+	contents, err := dc.redisClient.HGetAll(textEntitiesContentKey).Result()
+	if err != nil {
+		return result, fmt.Errorf("Error reading %v: %v", textEntitiesContentKey, err)
+	}
+	if len(contents) > 0 {
+		converted := make(map[string]interface{})
+		skipped := make(map[string]interface{})
+		for _, id := range sortedKeys(contents) {
+			content, err := dc.convertTextEntity(contents[id])
+			if err != nil {
+				dc.logger.Warn("skipping unconvertible entity", zap.Error(err), zap.String("entity", id))
+				result.Skipped = append(result.Skipped, textEntitiesContentKey+" "+id)
+				skipped[id] = contents[id]
+				continue
+			}
+			converted[id] = content
+		}
+		err = dc.runTx("MigrateTextStorage", func(pipe redis.Pipeliner) []txStep {
+			steps := []txStep{}
+			if len(converted) > 0 {
+				steps = append(steps, txStep{"add to " + dc.key(entitiesContentKey), pipe.HMSet(dc.key(entitiesContentKey), converted)})
+			}
+			if len(skipped) > 0 {
+				steps = append(steps, txStep{"add to " + unmigratedKeyPrefix + textEntitiesContentKey, pipe.HMSet(unmigratedKeyPrefix+textEntitiesContentKey, skipped)})
+			}
+			return append(steps, txStep{"remove " + textEntitiesContentKey, pipe.Del(textEntitiesContentKey)})
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Keys = append(result.Keys, textEntitiesContentKey)
+		result.Converted += len(converted)
+	}
+
+	// Model entity sets only hold ids, they just move
+	modelKeys := []string{}
+	var cursor uint64
+	for {
+		keys, nextCursor, err := dc.redisClient.Scan(cursor, textModelEntitiesKeys, 100).Result()
+		if err != nil {
+			return result, fmt.Errorf("Error scanning model keys: %v", err)
+		}
+		modelKeys = append(modelKeys, keys...)
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+	for _, oldKey := range modelKeys {
+		ids, err := dc.redisClient.SMembers(oldKey).Result()
+		if err != nil {
+			return result, fmt.Errorf("Error reading %v: %v", oldKey, err)
+		}
+		newKey := dc.key(keyPrefix + oldKey)
+		err = dc.runTx("MigrateTextStorage", func(pipe redis.Pipeliner) []txStep {
+			members := make([]interface{}, len(ids))
+			for i, id := range ids {
+				members[i] = id
+			}
+			steps := []txStep{}
+			if len(members) > 0 {
+				steps = append(steps, txStep{"add to " + newKey, pipe.SAdd(newKey, members...)})
+			}
+			return append(steps, txStep{"remove " + oldKey, pipe.Del(oldKey)})
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Keys = append(result.Keys, oldKey)
+		result.Converted += len(ids)
+	}
+
+	// The text format had no cell index, build it from the converted map
+	if _, err := dc.RepairConsistency(CellIndex); err != nil {
+		return result, fmt.Errorf("Error indexing cells: %v", err)
+	}
+
+	return result, nil
+}
+
+func (dc *Datacom) convertTextEntity(content string) (string, error) {
+	e := envApi.Entity{}
+	if err := parseTextContent(content, &e); err != nil {
+		return "", err
+	}
+	return dc.grid.serializeEntity(e)
+}
+
+func (dc *Datacom) convertTextEffect(content string) (string, error) {
+	effect := envApi.Effect{}
+	if err := parseTextContent(content, &effect); err != nil {
+		return "", err
+	}
+	return dc.grid.serializeEffect(effect)
+}
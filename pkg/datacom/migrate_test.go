@@ -0,0 +1,82 @@
+package datacom_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/datacom/mocks"
+)
+
+func TestMigrateTextStorage(t *testing.T) {
+	redisServer := setup()
+	defer teardown(redisServer)
+	mockPAL := &mocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dc, _ := datacom.NewDatacom("training", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
+
+	// A world stored in the text format
+	now := time.Now().Unix()
+	entity := `000000000000000011-id: "0"%nclassID: AGENT%nx: 1%ny: 1%nenergy: 100%nhealth: 100%nownerUID: "MOCK-UID"%nmodelID: "MOCK-MODEL-ID"%n`
+	effect := `000000000000000011-x: 1%ny: 1%ntimestamp: ` + strconv.FormatInt(now, 10) + `%nclassID: PHEROMONE%nvalue: 1%ndecay: 1.2%ndelThresh: 5%n`
+	redisServer.ZAdd("entities", 0, entity)
+	redisServer.ZAdd("entities", 0, `000000000000000000-not a proto`)
+	redisServer.HSet("entities.content", "0", entity)
+	redisServer.HSet("entities.content", "1", "not a proto")
+	redisServer.SetAdd("model:MOCK-MODEL-ID:entities", "0")
+	redisServer.ZAdd("effects", 0, effect)
+
+	result, err := dc.MigrateTextStorage()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantKeys := []string{"entities", "effects", "entities.content", "model:MOCK-MODEL-ID:entities"}
+	if !reflect.DeepEqual(result.Keys, wantKeys) {
+		t.Errorf("converted keys %v, want %v", result.Keys, wantKeys)
+	}
+	if result.Converted != 4 || len(result.Skipped) != 2 {
+		t.Errorf("got %v converted and %v skipped, want 4 and 2", result.Converted, result.Skipped)
+	}
+	for _, key := range wantKeys {
+		if redisServer.Exists(key) {
+			t.Errorf("expected %v to be removed", key)
+		}
+	}
+
+	// What couldn't be converted is kept to fix by hand
+	if members, _ := redisServer.ZMembers("v1:unmigrated:entities"); !reflect.DeepEqual(members, []string{"000000000000000000-not a proto"}) {
+		t.Errorf("got unmigrated entities %v, want the malformed member", members)
+	}
+	if content := redisServer.HGet("v1:unmigrated:entities.content", "1"); content != "not a proto" {
+		t.Errorf("got unmigrated content %q, want the malformed content", content)
+	}
+
+	// Everything reads back in the new format
+	want := &envApi.Entity{Id: "0", ClassID: envApi.Entity_AGENT, X: 1, Y: 1, Energy: 100, Health: 100, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID"}
+	if got, _, err := dc.GetEntity("0"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetEntity() = %v %v, want %v", got, err, want)
+	}
+	if got, _ := dc.GetEntitiesInSpace(0, 0, 9, 9); len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("GetEntitiesInSpace() = %v, want %v", got, want)
+	}
+	if got, _ := dc.GetEntitiesForModel("MOCK-MODEL-ID"); len(got) != 1 || got[0].Id != "0" {
+		t.Errorf("GetEntitiesForModel() = %v, want entity 0", got)
+	}
+	wantEffect := &envApi.Effect{X: 1, Y: 1, Timestamp: now, ClassID: envApi.Effect_PHEROMONE, Value: 1, Decay: 1.2, DelThresh: 5}
+	if got, _ := dc.GetEffectsInSpace(0, 0, 9, 9); len(got) != 1 || !reflect.DeepEqual(got[0], wantEffect) {
+		t.Errorf("GetEffectsInSpace() = %v, want %v", got, wantEffect)
+	}
+	if problems, _ := dc.CheckConsistency(); len(problems) != 0 {
+		t.Errorf("expected a consistent world, got %v", problems)
+	}
+
+	// Running it again has nothing to do
+	result, err = dc.MigrateTextStorage()
+	if err != nil || len(result.Keys) != 0 {
+		t.Errorf("expected nothing to migrate, got %v %v", result.Keys, err)
+	}
+}
@@ -3,11 +3,11 @@ package datacom
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	pubnub "github.com/pubnub/go"
+	"go.uber.org/zap"
 )
 
 const (
@@ -27,11 +27,14 @@ type batchPubMsg struct {
 type PubnubPAL struct {
 	pubnubClient *pubnub.PubNub
 	env          string
+	worldID      string
 	pubChan      chan pubMsg
+	logger       *zap.Logger
 }
 
-// NewPubnubPAL Creates a new pubnub specific Pubsub Access Layer
-func NewPubnubPAL(env string, subkey string, pubkey string) PubsubAccessLayer {
+// NewPubnubPAL Creates a new pubnub specific Pubsub Access Layer, publishing
+// events for the given world and logging failed publishes to the logger
+func NewPubnubPAL(env string, worldID string, subkey string, pubkey string, logger *zap.Logger) PubsubAccessLayer {
 	// Setup pubnub
 	config := pubnub.NewConfig()
 	config.SubscribeKey = subkey
@@ -39,7 +42,9 @@ func NewPubnubPAL(env string, subkey string, pubkey string) PubsubAccessLayer {
 	p := PubnubPAL{
 		pubnubClient: pubnub.NewPubNub(config),
 		env:          env,
+		worldID:      worldID,
 		pubChan:      make(chan pubMsg, 99),
+		logger:       logger.With(zap.String("world", worldID)),
 	}
 
 	// Start publish loop
@@ -60,7 +65,7 @@ func (p *PubnubPAL) QueuePublishEvent(eventName string, protoMsg proto.Message,
 	// marshal
 	b, err := json.Marshal(protoMsg)
 	if err != nil {
-		log.Printf("PublishEvent(): error: %v\n", err)
+		p.logger.Error("marshalling event", zap.Error(err), zap.String("event", eventName))
 		return err
 	}
 
@@ -71,6 +76,11 @@ func (p *PubnubPAL) QueuePublishEvent(eventName string, protoMsg proto.Message,
 
 	regionX, regionY := getRegionForPos(x, y)
 	channel := fmt.Sprintf("%v-%v", regionX, regionY)
+	// Worlds share the pubnub keys, only the default world's channels are
+	// left as they were
+	if p.worldID != DefaultWorldID {
+		channel = p.worldID + ":" + channel
+	}
 
 	p.pubChan <- pubMsg{
 		channel,
@@ -114,7 +124,7 @@ func (p *PubnubPAL) BatchPublish() {
 		err := p.PublishMessage(channel, batch)
 
 		if err != nil {
-			log.Printf("ERROR: issue publishing batch: %v\n", err)
+			p.logger.Error("publishing batch", zap.Error(err), zap.String("channel", channel), zap.Int("events", len(batch.Events)))
 		}
 	}
 }
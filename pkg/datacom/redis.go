@@ -3,92 +3,344 @@ package datacom
 import (
 	"errors"
 	"fmt"
-	"log"
-	"math"
-	"strconv"
-	"strings"
+	"sort"
 	"time"
 
 	"github.com/go-redis/redis"
+	uuid "github.com/satori/go.uuid"
 	collectiveApi "github.com/terrariumai/simulation/pkg/api/collective"
 	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"go.uber.org/zap"
 )
 
-func zipStrings(str1 string, str2 string) string {
-	res := ""
-	for i := 0; i < len(str1); i++ {
-		res = res + str1[i:i+1] + str2[i:i+1]
-	}
-	return res
+// maxSpacequeryRanges caps how many ranges a space query is split into. Past
+// it, blocks that are only partly in the space are read whole and filtered.
+const maxSpacequeryRanges = 32
+
+// zBlock is a square block of cells that is a contiguous run of position
+// indexes, 2^level cells wide
+type zBlock struct {
+	x     uint32
+	y     uint32
+	level uint
+}
+
+func (b zBlock) size() uint32 {
+	return 1 << b.level
+}
+
+// overlaps checks if any of the block is in the space
+func (b zBlock) overlaps(x0 uint32, y0 uint32, x1 uint32, y1 uint32) bool {
+	return b.x <= x1 && b.x+b.size()-1 >= x0 && b.y <= y1 && b.y+b.size()-1 >= y0
+}
+
+// within checks if all of the block is in the space
+func (b zBlock) within(x0 uint32, y0 uint32, x1 uint32, y1 uint32) bool {
+	return b.x >= x0 && b.x+b.size()-1 <= x1 && b.y >= y0 && b.y+b.size()-1 <= y1
+}
+
+// indexRange is the first and last position index in a block
+func (b zBlock) indexRange(g grid) [2]uint64 {
+	start := g.mortonIndex(b.x, b.y)
+	return [2]uint64{start, start + uint64(b.size())*uint64(b.size()) - 1}
+}
+
+// space is a rectangle of cells, inclusive of both corners
+type space struct {
+	x0 uint32
+	y0 uint32
+	x1 uint32
+	y1 uint32
 }
 
-func rjust(s string, filler byte, max int) string {
-	if len(s) < max {
-		return rjust(string(filler)+s, filler, max)
+// spaceRanges covers a space with position index ranges. The world is split
+// like a quadtree, blocks inside the space are read whole and blocks on its
+// edge are split until the cells match or there would be more than
+// maxSpacequeryRanges ranges.
+func (g grid) spaceRanges(x0 uint32, y0 uint32, x1 uint32, y1 uint32) [][2]uint64 {
+	if x1 > g.size.Width-1 {
+		x1 = g.size.Width - 1
+	}
+	if y1 > g.size.Height-1 {
+		y1 = g.size.Height - 1
+	}
+	if x0 > x1 || y0 > y1 {
+		return [][2]uint64{}
+	}
+
+	ranges := [][2]uint64{}
+	edges := []zBlock{{0, 0, g.bits}}
+	for len(edges) > 0 && len(ranges)+len(edges)*4 <= maxSpacequeryRanges {
+		next := []zBlock{}
+		for _, block := range edges {
+			half := block.size() / 2
+			for _, child := range []zBlock{
+				{block.x, block.y, block.level - 1},
+				{block.x, block.y + half, block.level - 1},
+				{block.x + half, block.y, block.level - 1},
+				{block.x + half, block.y + half, block.level - 1},
+			} {
+				if !child.overlaps(x0, y0, x1, y1) {
+					continue
+				}
+				if child.within(x0, y0, x1, y1) {
+					ranges = append(ranges, child.indexRange(g))
+				} else {
+					next = append(next, child)
+				}
+			}
+		}
+		edges = next
 	}
-	return s
+	for _, block := range edges {
+		ranges = append(ranges, block.indexRange(g))
+	}
+
+	return ranges
 }
 
-// constructSpacequeryCalls constructs zrangeby calls for zrangebylex queries
-func constructSpacequeryCalls(x0 uint32, y0 uint32, x1 uint32, y1 uint32, exp float64) []redis.ZRangeBy {
+// rangesToCalls merges position index ranges that overlap or are next to
+// each other and constructs a zrangebylex call for each
+func (g grid) rangesToCalls(ranges [][2]uint64) []redis.ZRangeBy {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
 	calls := []redis.ZRangeBy{}
-
-	bits := int(exp * 2)
-	xStart := x0 / uint32(math.Pow(2, exp))
-	xEnd := x1 / uint32(math.Pow(2, exp))
-	yStart := y0 / uint32(math.Pow(2, exp))
-	yEnd := y1 / uint32(math.Pow(2, exp))
-	for x := xStart; x <= xEnd; x++ {
-		for y := yStart; y <= yEnd; y++ {
-			xRangeStart := x * uint32(math.Pow(2, exp))
-			yRangeStart := y * uint32(math.Pow(2, exp))
-
-			xBin := strconv.FormatUint(uint64(xRangeStart), 2)
-			xBin = rjust(xBin, '0', 9)
-			yBin := strconv.FormatUint(uint64(yRangeStart), 2)
-			yBin = rjust(yBin, '0', 9)
-
-			s := zipStrings(xBin, yBin)
-			e := s[:len(s)-bits] + strings.Repeat("1", bits)
-			calls = append(calls, redis.ZRangeBy{
-				Min: "[" + s,
-				Max: "[" + e,
-			})
+	for i := 0; i < len(ranges); {
+		start, end := ranges[i][0], ranges[i][1]
+		for i++; i < len(ranges) && ranges[i][0] <= end+1; i++ {
+			if ranges[i][1] > end {
+				end = ranges[i][1]
+			}
 		}
+		// Members are "<index>-<content>" and '-' sorts before '.'
+		calls = append(calls, redis.ZRangeBy{
+			Min: "[" + g.formatIndex(start),
+			Max: "(" + g.formatIndex(end) + ".",
+		})
 	}
 
 	return calls
 }
 
-// spacequery will make ain INACCURATE query within a space. It is possible,
-// and very likely that there will be elements included that are outside the
-// space, but will always include all elements within the space.
-func (dc *Datacom) spacequery(key string, x0 uint32, y0 uint32, x1 uint32, y1 uint32) ([]string, error) {
-	calls := constructSpacequeryCalls(x0, y0, x1, y1, maxPositionCharLength)
-
-	// Perform queries to get the content array
-	combinedContentArray := []string{}
-	for _, call := range calls {
-		// Perform the query
-		rangeQuery := dc.redisClient.ZRangeByLex(key, call)
-		if err := rangeQuery.Err(); err != nil {
+// constructSpacequeryCalls constructs zrangebylex calls covering a space
+func (g grid) constructSpacequeryCalls(x0 uint32, y0 uint32, x1 uint32, y1 uint32) []redis.ZRangeBy {
+	return g.rangesToCalls(g.spaceRanges(x0, y0, x1, y1))
+}
+
+// spacequery will make an INACCURATE query of each key within the spaces. It
+// is possible that there will be elements included that are outside the
+// spaces, but will always include all elements within them. The range
+// queries for every key are sent in one pipeline, and the contents come back
+// in the same order as the keys.
+func (dc *Datacom) spacequery(keys []string, spaces []space) ([][]string, error) {
+	ranges := [][2]uint64{}
+	for _, s := range spaces {
+		ranges = append(ranges, dc.grid.spaceRanges(s.x0, s.y0, s.x1, s.y1)...)
+	}
+	calls := dc.grid.rangesToCalls(ranges)
+
+	pipe := dc.redisClient.Pipeline()
+	defer pipe.Close()
+	cmds := make([][]*redis.StringSliceCmd, len(keys))
+	for i, key := range keys {
+		for _, call := range calls {
+			cmds[i] = append(cmds[i], pipe.ZRangeByLex(key, call))
+		}
+	}
+	if len(calls) > 0 && len(keys) > 0 {
+		if _, err := pipe.Exec(); err != nil {
 			return nil, fmt.Errorf("Error in range query: %v", err)
 		}
-		contentArray := rangeQuery.Val()
-		combinedContentArray = append(combinedContentArray, contentArray...)
 	}
 
-	return combinedContentArray, nil
+	// Collect the content arrays
+	contents := make([][]string, len(keys))
+	for i := range keys {
+		contents[i] = []string{}
+		for j, cmd := range cmds[i] {
+			// Note: only trust members in the range, some redis implementations
+			//   return members below the range when nothing is in it
+			min, max := calls[j].Min[1:], calls[j].Max[1:]
+			for _, content := range cmd.Val() {
+				if content >= min && content < max {
+					contents[i] = append(contents[i], content)
+				}
+			}
+		}
+	}
+
+	return contents, nil
+}
+
+// --------------
+// World
+// --------------
+
+// ResetWorld removes every entity, effect and model entity set from the
+// environment, then increments and returns the world generation id
+func (dc *Datacom) ResetWorld() (int64, error) {
+	// Find all the model entity sets
+	keys := []string{dc.key(entitiesKey), dc.key(entitiesContentKey), dc.key(effectsKey), dc.key(cellsKey)}
+	var cursor uint64
+	for {
+		modelKeys, nextCursor, err := dc.redisClient.Scan(cursor, dc.modelEntitiesKey("*"), 100).Result()
+		if err != nil {
+			return 0, fmt.Errorf("Error scanning model keys: %v", err)
+		}
+		keys = append(keys, modelKeys...)
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	// Remove everything and bump the generation together
+	var generation *redis.IntCmd
+	_, err := dc.redisClient.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(keys...)
+		generation = pipe.Incr(dc.key("world.generation"))
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Error resetting world: %v", err)
+	}
+
+	return generation.Val(), nil
+}
+
+// --------------
+// Locks
+// --------------
+
+// lockCellsScript sets every lock key, or none of them if any is already held
+var lockCellsScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+	if not redis.call("SET", key, ARGV[1], "NX", "PX", ARGV[2]) then
+		for j = 1, i - 1 do
+			redis.call("DEL", KEYS[j])
+		end
+		return 0
+	end
+end
+return 1
+`)
+
+// unlockCellsScript only removes the lock keys still held with the token
+var unlockCellsScript = redis.NewScript(`
+local removed = 0
+for _, key in ipairs(KEYS) do
+	if redis.call("GET", key) == ARGV[1] then
+		removed = removed + redis.call("DEL", key)
+	end
+end
+return removed
+`)
+
+// cellLockKeys converts cells to their lock keys, skipping duplicates. Cells
+// outside the world can't hold an entity so there is nothing to lock.
+func (dc *Datacom) cellLockKeys(cells []envApi.Position) []string {
+	keys := []string{}
+	seen := make(map[string]bool)
+	for _, cell := range cells {
+		index, err := dc.grid.index(cell.X, cell.Y)
+		if err != nil || seen[index] {
+			continue
+		}
+		seen[index] = true
+		keys = append(keys, dc.key("lock:cell:"+index))
+	}
+	return keys
+}
+
+// LockCells tries to lock every given cell at once, so no other environment
+// sharing this redis can change them. Returns the token needed to unlock, and
+// false if any of the cells is already locked. Locks expire on their own in
+// case the holder dies.
+func (dc *Datacom) LockCells(cells []envApi.Position) (string, bool, error) {
+	newUUID, err := uuid.NewV4()
+	if err != nil {
+		return "", false, fmt.Errorf("Error generating lock token: %v", err)
+	}
+	token := newUUID.String()
+
+	keys := dc.cellLockKeys(cells)
+	if len(keys) == 0 {
+		return token, true, nil
+	}
+	locked, err := lockCellsScript.Run(dc.redisClient, keys, token, cellLockTTL.Nanoseconds()/int64(time.Millisecond)).Int64()
+	if err != nil {
+		dc.logger.Error("locking cells", zap.Error(err))
+		return "", false, err
+	}
+
+	return token, locked == 1, nil
+}
+
+// UnlockCells releases cells locked with the given token
+func (dc *Datacom) UnlockCells(token string, cells []envApi.Position) error {
+	keys := dc.cellLockKeys(cells)
+	if len(keys) == 0 {
+		return nil
+	}
+	err := unlockCellsScript.Run(dc.redisClient, keys, token).Err()
+	if err != nil {
+		dc.logger.Error("unlocking cells", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// leaseScript takes a lease, or renews it when the holder already has it
+var leaseScript = redis.NewScript(`
+local holder = redis.call("GET", KEYS[1])
+if holder and holder ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+// AcquireLease makes the holder the only one running a job for the world
+// until the ttl runs out, renewing the lease if they already hold it. Returns
+// false while someone else holds it.
+func (dc *Datacom) AcquireLease(name string, holder string, ttl time.Duration) (bool, error) {
+	held, err := leaseScript.Run(dc.redisClient, []string{dc.key("lease:" + name)}, holder, ttl.Nanoseconds()/int64(time.Millisecond)).Int64()
+	if err != nil {
+		dc.logger.Error("acquiring lease", zap.Error(err), zap.String("lease", name))
+		return false, err
+	}
+	return held == 1, nil
+}
+
+// NextGrowthStep counts a food growth step for the world, returning how many
+// were taken before it. Kept with the world so seasons carry on whichever
+// replica grows the food.
+func (dc *Datacom) NextGrowthStep() (uint64, error) {
+	steps, err := dc.redisClient.Incr(dc.key("growth.steps")).Result()
+	if err != nil {
+		dc.logger.Error("counting growth step", zap.Error(err))
+		return 0, err
+	}
+	return uint64(steps - 1), nil
 }
 
 // --------------
 // Entities
 // --------------
 
+// cellContentScript looks up the entity in a cell through the cell index and
+// returns its content, all in one round trip
+var cellContentScript = redis.NewScript(`
+local id = redis.call("HGET", KEYS[1], ARGV[1])
+if not id then
+	return false
+end
+return redis.call("HGET", KEYS[2], id)
+`)
+
 // IsCellOccupied checks the env to see if a cell has an entity by converting
-// the cell position to an index then querying redis
+// the cell position to an index then looking it up in the cell index
 func (dc *Datacom) IsCellOccupied(x uint32, y uint32) (bool, *envApi.Entity, string, error) {
-	index, err := posToRedisIndex(x, y)
+	index, err := dc.grid.index(x, y)
 	if err != nil {
 		// TODO - Returning an empty string here may cause errors down the line
 		return true, nil, "", err
@@ -96,42 +348,86 @@ func (dc *Datacom) IsCellOccupied(x uint32, y uint32) (bool, *envApi.Entity, str
 
 	// Now we can assume positions are correct sizes
 	// (would have thrown an error above if not)
-	keys, _, err := dc.redisClient.ZScan("entities", 0, index+"-*", 0).Result()
-	if len(keys) > 0 {
-		e, _ := parseEntityContent(keys[0])
-		return true, &e, keys[0], nil
+	content, err := cellContentScript.Run(dc.redisClient, []string{dc.key(cellsKey), dc.key(entitiesContentKey)}, index).String()
+	if err == redis.Nil {
+		return false, nil, "", nil
+	}
+	if err != nil {
+		dc.logger.Error("reading cell", zap.Error(err), zap.Uint32("x", x), zap.Uint32("y", y))
+		return true, nil, "", err
 	}
+	e, _, err := parseEntityContent(content)
+	if err != nil {
+		dc.logger.Error("reading cell", zap.Error(err), zap.Uint32("x", x), zap.Uint32("y", y))
+		return true, nil, "", err
+	}
+
+	return true, &e, content, nil
+}
+
+// txStep is a command queued in a transaction, named so a failure can say
+// which step broke
+type txStep struct {
+	name string
+	cmd  redis.Cmder
+}
 
-	return false, nil, "", nil
+// runTx queues the steps in a single MULTI/EXEC so they are written together.
+// Note: redis doesn't roll back, a step failing inside EXEC (like a key
+// holding the wrong type) doesn't undo the others.
+func (dc *Datacom) runTx(op string, queue func(pipe redis.Pipeliner) []txStep) error {
+	var steps []txStep
+	_, err := dc.redisClient.TxPipelined(func(pipe redis.Pipeliner) error {
+		steps = queue(pipe)
+		return nil
+	})
+	if err == nil {
+		return nil
+	}
+	for _, step := range steps {
+		if step.cmd.Err() != nil {
+			return fmt.Errorf("%v: %v failed: %v", op, step.name, step.cmd.Err())
+		}
+	}
+	return fmt.Errorf("%v: %v", op, err)
 }
 
+// createEntityScript adds an entity to every key it goes in, as long as its
+// id isn't taken. Returns 0 if it is.
+var createEntityScript = redis.NewScript(`
+if redis.call("HSETNX", KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call("ZADD", KEYS[2], 0, ARGV[2])
+redis.call("SADD", KEYS[3], ARGV[1])
+redis.call("HSET", KEYS[4], ARGV[3], ARGV[1])
+return 1
+`)
+
 // CreateEntity sets entity data in the environment. It assumes that
 // the location is open and that the owner and model have already been checked.
+// It fails with ErrEntityExists if the id is already in use.
 func (dc *Datacom) CreateEntity(e envApi.Entity, shouldPublish bool) error {
 	// Serialized entity content
-	content, err := serializeEntity(e)
+	content, err := dc.grid.serializeEntity(e)
 	if err != nil {
-		log.Println("ERROR: ", err)
+		dc.logger.Warn("creating entity", zap.Error(err), zap.String("entity", e.Id), zap.Uint32("x", e.X), zap.Uint32("y", e.Y))
 		return err
 	}
+	index, _ := dc.grid.index(e.X, e.Y)
 
-	// Add the entity to entities sorted set
-	err = dc.redisClient.ZAdd("entities", redis.Z{
-		Score:  float64(0),
-		Member: content,
-	}).Err()
+	// Claim the id and add the entity to the entities, its model and its
+	// cell in one script
+	keys := []string{dc.key(entitiesContentKey), dc.key(entitiesKey), dc.modelEntitiesKey(e.ModelID), dc.key(cellsKey)}
+	created, err := createEntityScript.Run(dc.redisClient, keys, e.Id, content, index).Int64()
 	if err != nil {
+		err := fmt.Errorf("CreateEntity: %v", err)
+		dc.logger.Error("creating entity", zap.Error(err), zap.String("entity", e.Id))
 		return err
 	}
-	// Add the content for later easy indexing
-	err = dc.redisClient.HSet("entities.content", e.Id, content).Err()
-	if err != nil {
-		return err
-	}
-	// Add the entitiy to the model's data
-	err = dc.redisClient.SAdd("model:"+e.ModelID+":entities", e.Id).Err()
-	if err != nil {
-		return err
+	if created == 0 {
+		dc.logger.Warn("creating entity", zap.Error(ErrEntityExists), zap.String("entity", e.Id))
+		return ErrEntityExists
 	}
 
 	// Send update
@@ -142,29 +438,62 @@ func (dc *Datacom) CreateEntity(e envApi.Entity, shouldPublish bool) error {
 	return nil
 }
 
-// UpdateEntity updates an entity. It first removes the origional entity
-// data then creates new entity data and index from the given params and
-// writes those.
+// updateEntityScript swaps an entity's content, as long as it is still the
+// content the caller read. Returns -1 if the entity is gone and 0 if it
+// changed, so a concurrent write can't be overwritten between the check and
+// the write.
+var updateEntityScript = redis.NewScript(`
+local current = redis.call("HGET", KEYS[1], ARGV[1])
+if not current then
+	return -1
+end
+if current ~= ARGV[2] then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[3])
+redis.call("ZREM", KEYS[2], ARGV[2])
+redis.call("ZADD", KEYS[2], 0, ARGV[3])
+if ARGV[4] ~= ARGV[5] then
+	redis.call("HDEL", KEYS[3], ARGV[4])
+end
+redis.call("HSET", KEYS[3], ARGV[5], ARGV[1])
+return 1
+`)
+
+// UpdateEntity updates an entity. It replaces the origional entity data and
+// index with new ones built from the given params, failing with ErrConflict
+// if anything changed the entity since the origional content was read.
 func (dc *Datacom) UpdateEntity(origionalContent string, e envApi.Entity) error {
-	content, err := serializeEntity(e)
+	content, err := dc.grid.serializeEntity(e)
 	if err != nil {
-		log.Println("ERROR: ", err)
+		dc.logger.Warn("updating entity", zap.Error(err), zap.String("entity", e.Id), zap.Uint32("x", e.X), zap.Uint32("y", e.Y))
 		return err
 	}
-	err = dc.redisClient.HSet("entities.content", e.Id, content).Err()
+	_, oldIndex, err := parseEntityContent(origionalContent)
 	if err != nil {
-		log.Println("ERROR: ", err)
+		err := fmt.Errorf("UpdateEntity: %v", err)
+		dc.logger.Warn("updating entity", zap.Error(err), zap.String("entity", e.Id))
 		return err
 	}
-	err = dc.redisClient.ZRem("entities", origionalContent).Err()
+	index, _ := dc.grid.index(e.X, e.Y)
+
+	// Compare and write in one script, moving it in the cell index
+	keys := []string{dc.key(entitiesContentKey), dc.key(entitiesKey), dc.key(cellsKey)}
+	updated, err := updateEntityScript.Run(dc.redisClient, keys, e.Id, origionalContent, content, oldIndex, index).Int64()
 	if err != nil {
-		log.Println("ERROR: ", err)
+		err := fmt.Errorf("UpdateEntity: %v", err)
+		dc.logger.Error("updating entity", zap.Error(err), zap.String("entity", e.Id))
 		return err
 	}
-	err = dc.redisClient.ZAdd("entities", redis.Z{
-		Score:  float64(0),
-		Member: content,
-	}).Err()
+	switch updated {
+	case -1:
+		err := errors.New("UpdateEntity: entity does not exist")
+		dc.logger.Warn("updating entity", zap.Error(err), zap.String("entity", e.Id))
+		return err
+	case 0:
+		dc.logger.Warn("updating entity", zap.Error(ErrConflict), zap.String("entity", e.Id))
+		return ErrConflict
+	}
 
 	// Send update
 	dc.pubsub.QueuePublishEvent("updateEntity", &e, e.X, e.Y)
@@ -175,63 +504,83 @@ func (dc *Datacom) UpdateEntity(origionalContent string, e envApi.Entity) error
 // GetEntity gets an entity from the environment by id
 func (dc *Datacom) GetEntity(id string) (*envApi.Entity, string, error) {
 	// Get the content
-	hGetEntityContent := dc.redisClient.HGet("entities.content", id)
+	hGetEntityContent := dc.redisClient.HGet(dc.key(entitiesContentKey), id)
 	if hGetEntityContent.Err() != nil {
 		return nil, "", errors.New("entity does not exist")
 	}
 	content := hGetEntityContent.Val()
-	entity, _ := parseEntityContent(content)
+	entity, _, err := parseEntityContent(content)
+	if err != nil {
+		dc.logger.Error("reading entity", zap.Error(err), zap.String("entity", id))
+		return nil, "", err
+	}
 
 	return &entity, content, nil
 }
 
-// DeleteEntity completely removes an entity from existence from the environment
+// deleteEntityScript removes an entity from every key it is in, as long as
+// its content is still the content the caller read. Returns 0 if it changed.
+var deleteEntityScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call("HDEL", KEYS[1], ARGV[1])
+redis.call("ZREM", KEYS[2], ARGV[2])
+redis.call("SREM", KEYS[3], ARGV[1])
+redis.call("HDEL", KEYS[4], ARGV[3])
+return 1
+`)
+
+// DeleteEntity completely removes an entity from existence from the
+// environment. It fails with ErrConflict if the entity is written to while
+// it is being deleted.
 func (dc *Datacom) DeleteEntity(id string) (int64, error) {
 	// Get the content
-	hGetEntityContent := dc.redisClient.HGet("entities.content", id)
+	hGetEntityContent := dc.redisClient.HGet(dc.key(entitiesContentKey), id)
 	if err := hGetEntityContent.Err(); err != nil {
-		log.Printf("ERROR: %v\n", err)
+		err := fmt.Errorf("DeleteEntity: get content failed: %v", err)
+		dc.logger.Warn("deleting entity", zap.Error(err), zap.String("entity", id))
 		return 0, err
 	}
 	content := hGetEntityContent.Val()
 	// Parse the content
-	entity, _ := parseEntityContent(content)
-	// Remove from hash
-	delete := dc.redisClient.HDel("entities.content", entity.Id)
-	if err := delete.Err(); err != nil {
-		log.Printf("ERROR: %v\n", err)
-		return 0, err
-	}
-	// Remove from SS
-	remove := dc.redisClient.ZRem("entities", content)
-	if err := remove.Err(); err != nil {
-		log.Printf("ERROR: %v\n", err)
+	entity, index, err := parseEntityContent(content)
+	if err != nil {
+		err := fmt.Errorf("DeleteEntity: %v", err)
+		dc.logger.Error("deleting entity", zap.Error(err), zap.String("entity", id))
 		return 0, err
 	}
-	// Remove from model
-	err := dc.redisClient.SRem("model:"+entity.ModelID+":entities", entity.Id).Err()
+
+	// Compare and remove in one script
+	keys := []string{dc.key(entitiesContentKey), dc.key(entitiesKey), dc.modelEntitiesKey(entity.ModelID), dc.key(cellsKey)}
+	deleted, err := deleteEntityScript.Run(dc.redisClient, keys, entity.Id, content, index).Int64()
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		err := fmt.Errorf("DeleteEntity: %v", err)
+		dc.logger.Error("deleting entity", zap.Error(err), zap.String("entity", id))
 		return 0, err
 	}
+	if deleted == 0 {
+		dc.logger.Warn("deleting entity", zap.Error(ErrConflict), zap.String("entity", id))
+		return 0, ErrConflict
+	}
 
 	// Send update
 	dc.pubsub.QueuePublishEvent("deleteEntity", &entity, entity.X, entity.Y)
 
-	return delete.Val(), nil
+	return deleted, nil
 }
 
 // GetEntitiesForModel gets a list of entities for a specific model
 func (dc *Datacom) GetEntitiesForModel(modelID string) ([]envApi.Entity, error) {
 	// Get the entitiy IDs for this model
-	entityIdsRequest := dc.redisClient.SMembers("model:" + modelID + ":entities")
+	entityIdsRequest := dc.redisClient.SMembers(dc.modelEntitiesKey(modelID))
 	if err := entityIdsRequest.Err(); err != nil {
-		log.Fatalf("ConnectRemoteModel(): %v", err)
+		dc.logger.Error("reading model entities", zap.Error(err), zap.String("model", modelID))
 		return nil, errors.New("ConnectRemoteModel(): Couldn't access the database to get the entity ids for this model")
 	}
 	entityIds := entityIdsRequest.Val()
 	// Get the entities for this model
-	entitiesContentRequest := dc.redisClient.HMGet("entities.content", entityIds...)
+	entitiesContentRequest := dc.redisClient.HMGet(dc.key(entitiesContentKey), entityIds...)
 	// Get the content for each entity
 	entitiesContent := make([]interface{}, 0)
 	if len(entityIds) > 0 {
@@ -243,7 +592,16 @@ func (dc *Datacom) GetEntitiesForModel(modelID string) ([]envApi.Entity, error)
 	// Convert content to entities
 	entities := []envApi.Entity{}
 	for _, content := range entitiesContent {
-		entity, _ := parseEntityContent(content.(string))
+		// Ids without content are skipped, the consistency check reports them
+		contentStr, ok := content.(string)
+		if !ok {
+			continue
+		}
+		entity, _, err := parseEntityContent(contentStr)
+		if err != nil {
+			dc.logger.Error("reading model entities", zap.Error(err), zap.String("model", modelID))
+			return nil, err
+		}
 		entities = append(entities, entity)
 	}
 	return entities, nil
@@ -251,97 +609,84 @@ func (dc *Datacom) GetEntitiesForModel(modelID string) ([]envApi.Entity, error)
 
 // GetObservationForEntity returns observations for a specific entity
 func (dc *Datacom) GetObservationForEntity(entity envApi.Entity) (*collectiveApi.Observation, error) {
-	index, err := posToRedisIndex(entity.X, entity.Y)
-	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+	if _, err := dc.grid.index(entity.X, entity.Y); err != nil {
+		dc.logger.Warn("observing entity", zap.Error(err), zap.String("entity", entity.Id), zap.Uint32("x", entity.X), zap.Uint32("y", entity.Y))
 		return nil, err
 	}
 
-	obsv := collectiveApi.Observation{
-		Id:      entity.Id,
-		Energy:  entity.Energy,
-		Health:  entity.Health,
-		IsAlive: true,
+	// Query for entities and effects near this position, the window is split
+	// in pieces where it wraps around the edge of a torus world
+	// Note: we handle grabbing specific entities below, can ignore extras
+	closeEntities := []*envApi.Entity{}
+	closeEffects := []*envApi.Effect{}
+	for _, sp := range dc.grid.spacesAround(entity.X, entity.Y, dc.EntityVisionDist) {
+		entities, err := dc.GetEntitiesInSpace(sp.x0, sp.y0, sp.x1, sp.y1)
+		if err != nil {
+			dc.logger.Error("observing entity", zap.Error(err), zap.String("entity", entity.Id))
+			return nil, err
+		}
+		closeEntities = append(closeEntities, entities...)
+		effects, err := dc.GetEffectsInSpace(sp.x0, sp.y0, sp.x1, sp.y1)
+		if err != nil {
+			dc.logger.Error("observing entity", zap.Error(err), zap.String("entity", entity.Id))
+			return nil, err
+		}
+		closeEffects = append(closeEffects, effects...)
 	}
 
-	// Query for entities near this position
-	// Note: we handle grabbing specific entities below, can ignore extras
-	x0, y0, x1, y1 := calcSpaceAroundPoint(int32(entity.X), int32(entity.Y), dc.EntityVisionDist)
-	closeEntities, err := dc.GetEntitiesInSpace(uint32(x0), uint32(y0), uint32(x1), uint32(y1))
-	if err != nil {
-		log.Printf("ERROR: %v\n", err)
-		return nil, err
+	return buildObservation(dc.observationGrid(), entity, dc.EntityVisionDist, dc.EntitySmellDist, entitiesByCell(closeEntities), effectsByCell(closeEffects)), nil
+}
+
+// GetObservationsForEntities returns an observation for each entity, in the
+// same order. Everything the entities can see or smell is read in one
+// pipeline, so they all observe the same snapshot of the world.
+func (dc *Datacom) GetObservationsForEntities(entities []envApi.Entity) ([]*collectiveApi.Observation, error) {
+	dist := observationDist(dc.EntityVisionDist, dc.EntitySmellDist)
+	spaces := make([]space, 0, len(entities))
+	for _, entity := range entities {
+		if _, err := dc.grid.index(entity.X, entity.Y); err != nil {
+			dc.logger.Warn("observing entities", zap.Error(err), zap.String("entity", entity.Id), zap.Uint32("x", entity.X), zap.Uint32("y", entity.Y))
+			return nil, err
+		}
+		spaces = append(spaces, dc.grid.spacesAround(entity.X, entity.Y, dist)...)
 	}
-	// Query for effects near this position
-	// Note: we handle grabbing specific entities below, can ignore extras
-	x0, y0, x1, y1 = calcSpaceAroundPoint(int32(entity.X), int32(entity.Y), dc.EntityVisionDist)
-	closeEffects, err := dc.GetEffectsInSpace(uint32(x0), uint32(y0), uint32(x1), uint32(y1))
+
+	contents, err := dc.spacequery([]string{dc.key(entitiesKey), dc.key(effectsKey)}, spaces)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		dc.logger.Error("observing entities", zap.Error(err))
 		return nil, err
 	}
-
-	// Add all the other entities to the indexEntityMap
-	// Match them up with the correct positions
-	indexEntityMap := make(map[string]envApi.Entity)
-	for _, otherEntity := range closeEntities {
-		index, _ := posToRedisIndex(otherEntity.X, otherEntity.Y)
-		indexEntityMap[index] = *otherEntity
-	}
-	var x int32
-	var y int32
-	for y = int32(entity.Y) + dc.EntityVisionDist; y >= int32(entity.Y)-dc.EntityVisionDist; y-- {
-		for x = int32(entity.X) - dc.EntityVisionDist; x <= int32(entity.X)+dc.EntityVisionDist; x++ {
-			// If position is invalid, set it to untraversable entity (rock)
-			if x < minPosition || x > maxPosition || y < minPosition || y > maxPosition {
-				obsv.Sight = append(obsv.Sight, &collectiveApi.Entity{Id: "", ClassID: 2})
-				continue
-			}
-			// Attempt to get redis index from position
-			otherIndex, err := posToRedisIndex(uint32(x), uint32(y))
-			if err != nil { // If it errors here, something went wrong with logic above
-				return nil, err
-			}
-			// If the index is the same as our current entity
-			if otherIndex == index {
-				continue
-			}
-			if otherEntity, ok := indexEntityMap[otherIndex]; ok {
-				obsv.Sight = append(obsv.Sight, &collectiveApi.Entity{Id: otherEntity.Id, ClassID: collectiveApi.Entity_Class(otherEntity.ClassID)})
-			} else {
-				obsv.Sight = append(obsv.Sight, &collectiveApi.Entity{Id: "", ClassID: 0})
-			}
+	closeEntities := make(map[cellKey]*envApi.Entity)
+	for _, content := range contents[0] {
+		e, _, err := parseEntityContent(content)
+		if err != nil {
+			dc.logger.Error("observing entities", zap.Error(err))
+			return nil, err
+		}
+		closeEntities[cellKey{e.X, e.Y}] = &e
+	}
+	closeEffects := make(map[cellKey]*envApi.Effect)
+	now := time.Now().Unix()
+	for _, content := range contents[1] {
+		effect, _, err := parseEffectContent(content)
+		if err != nil {
+			dc.logger.Error("observing entities", zap.Error(err))
+			return nil, err
 		}
+		// Clean up decayed effects
+		if isEffectExpired(effect, now) {
+			dc.DeleteEffect(effect)
+			continue
+		}
+		closeEffects[cellKey{effect.X, effect.Y}] = &effect
 	}
 
-	// Add all the other entities to the indexEntityMap
-	// Match them up with the correct positions
-	indexEffectMap := make(map[string]envApi.Effect)
-	for _, effect := range closeEffects {
-		index, _ := posToRedisIndex(effect.X, effect.Y)
-		indexEffectMap[index] = *effect
-	}
-	for y = int32(entity.Y) + dc.EntitySmellDist; y >= int32(entity.Y)-dc.EntitySmellDist; y-- {
-		for x = int32(entity.X) - dc.EntitySmellDist; x <= int32(entity.X)+dc.EntitySmellDist; x++ {
-			// If position is invalid, set it to untraversable entity (rock)
-			if x < minPosition || x > maxPosition || y < minPosition || y > maxPosition {
-				obsv.Smell = append(obsv.Smell, &collectiveApi.Effect{ClassID: collectiveApi.Effect_Class(0)})
-				continue
-			}
-			// Attempt to get redis index from position
-			otherIndex, err := posToRedisIndex(uint32(x), uint32(y))
-			if err != nil { // If it errors here, something went wrong with logic above
-				return nil, err
-			}
-			if effect, ok := indexEffectMap[otherIndex]; ok {
-				strength := uint32(100 / math.Pow(float64(effect.Decay), float64(time.Now().Unix()-effect.Timestamp)))
-				obsv.Smell = append(obsv.Smell, &collectiveApi.Effect{ClassID: collectiveApi.Effect_Class(effect.ClassID), Value: effect.Value, Strength: strength})
-			} else {
-				obsv.Smell = append(obsv.Smell, &collectiveApi.Effect{ClassID: collectiveApi.Effect_Class(0)})
-			}
-		}
+	g := dc.observationGrid()
+	observations := make([]*collectiveApi.Observation, len(entities))
+	for i, entity := range entities {
+		observations[i] = buildObservation(g, entity, dc.EntityVisionDist, dc.EntitySmellDist, closeEntities, closeEffects)
 	}
-	return &obsv, nil
+	return observations, nil
 }
 
 // GetEntitiesInSpace returns the entities in a specific region
@@ -349,13 +694,17 @@ func (dc *Datacom) GetEntitiesInSpace(x0 uint32, y0 uint32, x1 uint32, y1 uint32
 	entities := []*envApi.Entity{}
 
 	// Perform the query
-	contentArray, err := dc.spacequery("entities", x0, y0, x1, y1)
+	contents, err := dc.spacequery([]string{dc.key(entitiesKey)}, []space{{x0, y0, x1, y1}})
 	if err != nil {
 		return nil, fmt.Errorf("Error converting min/max positions to index: %v", err)
 	}
 
-	for _, content := range contentArray {
-		entity, _ := parseEntityContent(content)
+	for _, content := range contents[0] {
+		entity, _, err := parseEntityContent(content)
+		if err != nil {
+			dc.logger.Error("reading entities", zap.Error(err))
+			return nil, err
+		}
 		// Ignore entities outside space
 		if entity.X < x0 || entity.X > x1 || entity.Y < y0 || entity.Y > y1 {
 			continue
@@ -375,12 +724,12 @@ func (dc *Datacom) CreateEffect(effect envApi.Effect) error {
 	if effect.Timestamp == 0 {
 		effect.Timestamp = time.Now().Unix()
 	}
-	content, err := serializeEffect(effect)
+	content, err := dc.grid.serializeEffect(effect)
 	if err != nil {
-		log.Println("ERROR: ", err)
+		dc.logger.Warn("creating effect", zap.Error(err), zap.Uint32("x", effect.X), zap.Uint32("y", effect.Y))
 		return err
 	}
-	err = dc.redisClient.ZAdd("effects", redis.Z{
+	err = dc.redisClient.ZAdd(dc.key(effectsKey), redis.Z{
 		Score:  float64(0),
 		Member: content,
 	}).Err()
@@ -396,20 +745,23 @@ func (dc *Datacom) GetEffectsInSpace(x0 uint32, y0 uint32, x1 uint32, y1 uint32)
 	effects := []*envApi.Effect{}
 
 	// Perform the query
-	contentArray, err := dc.spacequery("effects", x0, y0, x1, y1)
+	contents, err := dc.spacequery([]string{dc.key(effectsKey)}, []space{{x0, y0, x1, y1}})
 	if err != nil {
 		return nil, fmt.Errorf("Error converting min/max positions to index: %v", err)
 	}
 
-	for _, content := range contentArray {
-		effect, _ := parseEffectContent(content)
+	for _, content := range contents[0] {
+		effect, _, err := parseEffectContent(content)
+		if err != nil {
+			dc.logger.Error("reading effects", zap.Error(err))
+			return nil, err
+		}
 		// Ignore outside
 		if effect.X < x0 || effect.X > x1 || effect.Y < y0 || effect.Y > y1 {
 			continue
 		}
-		// Calculate strength
-		strength := uint32(100 / math.Pow(float64(effect.Decay), float64(time.Now().Unix()-effect.Timestamp)))
-		if strength <= effect.DelThresh {
+		// Clean up decayed effects
+		if isEffectExpired(effect, time.Now().Unix()) {
 			dc.DeleteEffect(effect)
 			continue
 		}
@@ -421,11 +773,11 @@ func (dc *Datacom) GetEffectsInSpace(x0 uint32, y0 uint32, x1 uint32, y1 uint32)
 
 // DeleteEffect removes an effect
 func (dc *Datacom) DeleteEffect(effect envApi.Effect) (int64, error) {
-	content, _ := serializeEffect(effect)
+	content, _ := dc.grid.serializeEffect(effect)
 	// Remove from SS
-	remove := dc.redisClient.ZRem("effects", content)
+	remove := dc.redisClient.ZRem(dc.key(effectsKey), content)
 	if err := remove.Err(); err != nil {
-		log.Printf("ERROR: %v\n", err)
+		dc.logger.Error("deleting effect", zap.Error(err), zap.Uint32("x", effect.X), zap.Uint32("y", effect.Y))
 		return 0, err
 	}
 
@@ -2,9 +2,8 @@ package datacom_test
 
 import (
 	"errors"
-	"fmt"
 	"reflect"
-	"strings"
+	"strconv"
 	"testing"
 	"time"
 
@@ -18,6 +17,11 @@ import (
 	envApi "github.com/terrariumai/simulation/pkg/api/environment"
 	"github.com/terrariumai/simulation/pkg/datacom"
 	"github.com/terrariumai/simulation/pkg/datacom/mocks"
+	"github.com/terrariumai/simulation/pkg/environment"
+	"github.com/terrariumai/simulation/pkg/environment/daltest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 type mockFuncCall struct {
@@ -31,6 +35,24 @@ type numOfCallsAssertion struct {
 	num  int
 }
 
+// member builds a redis member the way datacom stores it
+func member(index string, msg proto.Message) string {
+	data, _ := proto.Marshal(msg)
+	return index + "-" + string(data)
+}
+
+// zscan lists a sorted set the way ZSCAN does, each member followed by its
+// score. Note: miniredis can't glob match binary members, so ZSCAN itself
+// can't be used.
+func zscan(redisClient *redis.Client, key string) ([]string, uint64, error) {
+	members, err := redisClient.ZRangeWithScores(key, 0, -1).Result()
+	keys := []string{}
+	for _, z := range members {
+		keys = append(keys, z.Member.(string), strconv.FormatFloat(z.Score, 'f', -1, 64))
+	}
+	return keys, 0, err
+}
+
 func setup() *miniredis.Miniredis {
 	// Redis Setup
 	redisServer, err := miniredis.Run()
@@ -44,6 +66,22 @@ func teardown(redisServer *miniredis.Miniredis) {
 	redisServer.Close()
 }
 
+// -------------------------------------
+// DATA ACCESS LAYER CONTRACT
+// -------------------------------------
+func TestDatacomContract(t *testing.T) {
+	daltest.Run(t, func(t *testing.T) (environment.DataAccessLayer, func()) {
+		redisServer := setup()
+		mockPAL := &mocks.PubsubAccessLayer{}
+		mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		dc, err := datacom.NewDatacom("training", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
+		if err != nil {
+			t.Fatalf("error creating datacom: %v", err)
+		}
+		return dc, func() { teardown(redisServer) }
+	})
+}
+
 // -------------------------------------
 // CREATE ENTITY
 // -------------------------------------
@@ -66,8 +104,8 @@ func TestCreateEntity(t *testing.T) {
 			name: "Test succesful creation",
 			args: args{
 				entity: envApi.Entity{
-					X:        123,
-					Y:        456,
+					X:        12,
+					Y:        45,
 					OwnerUID: "MOCK-UID",
 					ModelID:  "MOCK-MODEL-ID",
 					Energy:   100,
@@ -78,14 +116,14 @@ func TestCreateEntity(t *testing.T) {
 				shouldPublish: true,
 			},
 			expectedPublishCount: 1,
-			expected:             `010111101011001010-id: "1"%nclassID: AGENT%nx: 123%ny: 456%nenergy: 100%nhealth: 100%nownerUID: "MOCK-UID"%nmodelID: "MOCK-MODEL-ID"%n`,
+			expected:             member("000000010011110001", &envApi.Entity{Id: "1", ClassID: envApi.Entity_AGENT, X: 12, Y: 45, Energy: 100, Health: 100, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID"}),
 		},
 		{
 			name: "Test invalid position error",
 			args: args{
 				entity: envApi.Entity{
-					X:        512,
-					Y:        456,
+					X:        100,
+					Y:        45,
 					OwnerUID: "MOCK-UID",
 					ModelID:  "MOCK-MODEL-ID",
 					Energy:   100,
@@ -102,8 +140,8 @@ func TestCreateEntity(t *testing.T) {
 			name: "Test no publish",
 			args: args{
 				entity: envApi.Entity{
-					X:        123,
-					Y:        456,
+					X:        12,
+					Y:        45,
 					OwnerUID: "MOCK-UID",
 					ModelID:  "MOCK-MODEL-ID",
 					Energy:   100,
@@ -113,7 +151,7 @@ func TestCreateEntity(t *testing.T) {
 				},
 				shouldPublish: false,
 			},
-			expected: `010111101011001010-id: "1"%nclassID: AGENT%nx: 123%ny: 456%nenergy: 100%nhealth: 100%nownerUID: "MOCK-UID"%nmodelID: "MOCK-MODEL-ID"%n`,
+			expected: member("000000010011110001", &envApi.Entity{Id: "1", ClassID: envApi.Entity_AGENT, X: 12, Y: 45, Energy: 100, Health: 100, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID"}),
 		},
 	}
 
@@ -122,7 +160,7 @@ func TestCreateEntity(t *testing.T) {
 			// Setup pubsub mock
 			redisServer.FlushDB()
 			mockPAL := &mocks.PubsubAccessLayer{}
-			dc, _ := datacom.NewDatacom("testing", redisServer.Addr(), mockPAL)
+			dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
 			mockPAL.On("QueuePublishEvent", "createEntity", &tt.args.entity, tt.args.entity.X, tt.args.entity.Y).Return(nil)
 
 			err := dc.CreateEntity(tt.args.entity, tt.args.shouldPublish)
@@ -146,9 +184,9 @@ func TestCreateEntity(t *testing.T) {
 
 			// Check publish calls
 			mockPAL.AssertNumberOfCalls(t, "QueuePublishEvent", tt.expectedPublishCount)
-			keys, cursor := redisClient.ZScan("entities", 0, "*", 0).Val()
+			keys, cursor, _ := zscan(redisClient, "v2:entities")
 
-			keys, cursor, err = redisClient.ZScan("entities", 0, "*", 0).Result()
+			keys, cursor, err = zscan(redisClient, "v2:entities")
 			if err != nil {
 				t.Errorf("error in scan: %v", err)
 			}
@@ -164,6 +202,88 @@ func TestCreateEntity(t *testing.T) {
 	}
 }
 
+// -------------------------------------
+// Reset World
+// -------------------------------------
+func TestResetWorld(t *testing.T) {
+	redisServer := setup()
+	defer teardown(redisServer)
+	// Setup pubsub mock
+	mockPAL := &mocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("uint32"), mock.AnythingOfType("uint32")).Return(nil)
+	dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
+
+	dc.CreateEntity(envApi.Entity{X: 0, Y: 0, ClassID: envApi.Entity_AGENT, ModelID: "MOCK-MODEL-ID", Id: "0"}, true)
+	dc.CreateEntity(envApi.Entity{X: 1, Y: 1, ClassID: envApi.Entity_FOOD, Id: "1"}, true)
+	dc.CreateEffect(envApi.Effect{X: 1, Y: 1, ClassID: envApi.Effect_PHEROMONE, Decay: 1.2, DelThresh: 5})
+	redisServer.Set("unrelated", "value")
+
+	generation, err := dc.ResetWorld()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if generation != 1 {
+		t.Errorf("expected generation 1, got %v", generation)
+	}
+	for _, key := range []string{"v2:entities", "v2:entities.content", "v2:effects", "v2:model:MOCK-MODEL-ID:entities"} {
+		if redisServer.Exists(key) {
+			t.Errorf("expected %v to be removed", key)
+		}
+	}
+	if !redisServer.Exists("unrelated") {
+		t.Errorf("expected unrelated keys to be kept")
+	}
+
+	// Every reset gets a new generation
+	generation, _ = dc.ResetWorld()
+	if generation != 2 {
+		t.Errorf("expected generation 2, got %v", generation)
+	}
+}
+
+// -------------------------------------
+// Cell Locks
+// -------------------------------------
+func TestCellLocks(t *testing.T) {
+	redisServer := setup()
+	defer teardown(redisServer)
+	dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), &mocks.PubsubAccessLayer{})
+
+	cells := []envApi.Position{{X: 1, Y: 1}, {X: 2, Y: 1}}
+	token, locked, err := dc.LockCells(cells)
+	if err != nil || !locked {
+		t.Fatalf("expected to lock cells, got locked=%v err=%v", locked, err)
+	}
+
+	// Overlapping cells can't be locked, and nothing is left half locked
+	_, locked, err = dc.LockCells([]envApi.Position{{X: 5, Y: 5}, {X: 2, Y: 1}})
+	if err != nil || locked {
+		t.Errorf("expected overlapping lock to fail, got locked=%v err=%v", locked, err)
+	}
+	_, locked, _ = dc.LockCells([]envApi.Position{{X: 5, Y: 5}})
+	if !locked {
+		t.Errorf("expected failed lock to release the cells it took")
+	}
+
+	// Locks expire in case the holder dies
+	if ttl := redisServer.TTL("lock:cell:000000000000000011"); ttl <= 0 {
+		t.Errorf("expected lock to expire, got ttl %v", ttl)
+	}
+
+	// Only the holder can unlock
+	dc.UnlockCells("not-the-token", cells)
+	if _, locked, _ = dc.LockCells(cells); locked {
+		t.Errorf("expected cells to stay locked with the wrong token")
+	}
+	if err := dc.UnlockCells(token, cells); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, locked, _ = dc.LockCells(cells); !locked {
+		t.Errorf("expected cells to be unlocked")
+	}
+}
+
 // -------------------------------------
 // Is Cell Occupied
 // -------------------------------------
@@ -173,7 +293,7 @@ func TestIsCellOccupied(t *testing.T) {
 	// Setup pubsub mock
 	mockPAL := &mocks.PubsubAccessLayer{}
 	mockPAL.On("QueuePublishEvent", "createEntity", mock.AnythingOfType("*endpoints_terrariumai_environment.Entity"), mock.AnythingOfType("uint32"), mock.AnythingOfType("uint32")).Return(nil)
-	dc, _ := datacom.NewDatacom("testing", redisServer.Addr(), mockPAL)
+	dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
 	entities := []envApi.Entity{
 		envApi.Entity{
 			X: 0, Y: 0, ClassID: envApi.Entity_AGENT, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID", Health: 100, Energy: 100, Id: "0",
@@ -269,7 +389,7 @@ func TestUpdateEntity(t *testing.T) {
 	// Setup pubsub mock
 	mockPAL := &mocks.PubsubAccessLayer{}
 	mockPAL.On("QueuePublishEvent", "createEntity", mock.AnythingOfType("*endpoints_terrariumai_environment.Entity"), mock.AnythingOfType("uint32"), mock.AnythingOfType("uint32")).Return(nil)
-	dc, _ := datacom.NewDatacom("testing", redisServer.Addr(), mockPAL)
+	dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
 
 	dc.CreateEntity(envApi.Entity{
 		X: 0, Y: 0, ClassID: 1, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID", Health: 100, Energy: 100, Id: "0",
@@ -299,7 +419,7 @@ func TestUpdateEntity(t *testing.T) {
 					Id:       "0",
 				},
 			},
-			`000000000000000011-id: "0"%nclassID: ROCK%nx: 1%ny: 1%nenergy: 90%nhealth: 90%nownerUID: "MOCK-UID-2"%nmodelID: "MOCK-MODEL-ID-2"%n`,
+			member("000000000000000011", &envApi.Entity{Id: "0", ClassID: envApi.Entity_ROCK, X: 1, Y: 1, Energy: 90, Health: 90, OwnerUID: "MOCK-UID-2", ModelID: "MOCK-MODEL-ID-2"}),
 			false,
 		},
 	}
@@ -317,7 +437,7 @@ func TestUpdateEntity(t *testing.T) {
 			})
 
 			// Get the origional content
-			keys, cursor, err := redisClient.ZScan("entities", 0, "*", 0).Result()
+			keys, cursor, err := zscan(redisClient, "v2:entities")
 
 			err = dc.UpdateEntity(keys[cursor], tt.args.entity)
 			if err != nil && tt.expectErr {
@@ -327,7 +447,7 @@ func TestUpdateEntity(t *testing.T) {
 				return
 			}
 
-			keys, cursor, err = redisClient.ZScan("entities", 0, "*", 0).Result()
+			keys, cursor, err = zscan(redisClient, "v2:entities")
 
 			if keys[cursor] != tt.expected {
 				t.Errorf("expected %v, \n\t got: %v", tt.expected, keys[cursor])
@@ -336,6 +456,93 @@ func TestUpdateEntity(t *testing.T) {
 	}
 }
 
+// -------------------------------------
+// Entity Write Errors
+// -------------------------------------
+func TestEntityWriteErrors(t *testing.T) {
+	e := envApi.Entity{
+		X: 0, Y: 0, ClassID: 1, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID", Health: 100, Energy: 100, Id: "0",
+	}
+	content := member("000000000000000000", &envApi.Entity{Id: "0", ClassID: envApi.Entity_AGENT, Energy: 100, Health: 100, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID"})
+
+	tests := []struct {
+		name    string
+		setup   func(dc *datacom.Datacom, redisServer *miniredis.Miniredis)
+		write   func(dc *datacom.Datacom) error
+		wantErr error
+	}{
+		{
+			name: "Create with a taken id",
+			setup: func(dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				dc.CreateEntity(e, false)
+			},
+			write: func(dc *datacom.Datacom) error {
+				return dc.CreateEntity(envApi.Entity{Id: "0", ClassID: envApi.Entity_FOOD, X: 5, Y: 5}, false)
+			},
+			wantErr: datacom.ErrEntityExists,
+		},
+		{
+			name: "Update with stale content fails before writing",
+			setup: func(dc *datacom.Datacom, redisServer *miniredis.Miniredis) {
+				dc.CreateEntity(e, false)
+				// Someone else writes the entity after it was read
+				redisServer.HSet("v2:entities.content", "0", "changed")
+			},
+			write: func(dc *datacom.Datacom) error {
+				return dc.UpdateEntity(content, e)
+			},
+			wantErr: datacom.ErrConflict,
+		},
+		{
+			name:  "Delete of a missing entity names the failed step",
+			setup: func(dc *datacom.Datacom, redisServer *miniredis.Miniredis) {},
+			write: func(dc *datacom.Datacom) error {
+				_, err := dc.DeleteEntity("0")
+				return err
+			},
+			wantErr: errors.New("DeleteEntity: get content failed: redis: nil"),
+		},
+		{
+			name:  "Update of a missing entity",
+			setup: func(dc *datacom.Datacom, redisServer *miniredis.Miniredis) {},
+			write: func(dc *datacom.Datacom) error {
+				return dc.UpdateEntity(content, e)
+			},
+			wantErr: errors.New("UpdateEntity: entity does not exist"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redisServer := setup()
+			defer teardown(redisServer)
+			mockPAL := &mocks.PubsubAccessLayer{}
+			dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
+			tt.setup(dc, redisServer)
+
+			err := tt.write(dc)
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The original content survives a stale update and a create with its id
+	redisServer := setup()
+	defer teardown(redisServer)
+	dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), &mocks.PubsubAccessLayer{})
+	dc.CreateEntity(e, false)
+	dc.UpdateEntity("stale-content", envApi.Entity{Id: "0", X: 5, Y: 5})
+	dc.CreateEntity(envApi.Entity{Id: "0", ClassID: envApi.Entity_FOOD, X: 5, Y: 5}, false)
+	if occupied, _, _, _ := dc.IsCellOccupied(5, 5); occupied {
+		t.Errorf("expected a create with a taken id to leave its cell empty")
+	}
+	members, _ := redisServer.ZMembers("v2:entities")
+	if !reflect.DeepEqual(members, []string{content}) {
+		t.Errorf("got entities %v, want %v", members, []string{content})
+	}
+}
+
 // -------------------------------------
 // Get Entity
 // -------------------------------------
@@ -345,7 +552,7 @@ func TestGetEntity(t *testing.T) {
 	// Setup pubsub mock
 	mockPAL := &mocks.PubsubAccessLayer{}
 	mockPAL.On("QueuePublishEvent", "createEntity", mock.AnythingOfType("*endpoints_terrariumai_environment.Entity"), mock.AnythingOfType("uint32"), mock.AnythingOfType("uint32")).Return(nil)
-	dc, _ := datacom.NewDatacom("testing", redisServer.Addr(), mockPAL)
+	dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
 
 	dc.CreateEntity(envApi.Entity{
 		X: 0, Y: 0, ClassID: 1, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID", Health: 100, Energy: 100, Id: "0",
@@ -365,7 +572,7 @@ func TestGetEntity(t *testing.T) {
 			args{
 				id: "0",
 			},
-			`000000000000000000-id: "0"%nclassID: AGENT%nenergy: 100%nhealth: 100%nownerUID: "MOCK-UID"%nmodelID: "MOCK-MODEL-ID"%n`,
+			member("000000000000000000", &envApi.Entity{Id: "0", ClassID: envApi.Entity_AGENT, Energy: 100, Health: 100, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID"}),
 			false,
 		},
 		{
@@ -388,7 +595,7 @@ func TestGetEntity(t *testing.T) {
 			})
 
 			// Get the origional content
-			keys, cursor, err := redisClient.ZScan("entities", 0, "*", 0).Result()
+			keys, cursor, err := zscan(redisClient, "v2:entities")
 
 			_, content, err := dc.GetEntity(tt.args.id)
 			if err != nil && tt.expectErr {
@@ -410,7 +617,7 @@ func TestDeleteEntity(t *testing.T) {
 	defer teardown(redisServer)
 	// Setup pubsub mock
 	mockPAL := &mocks.PubsubAccessLayer{}
-	dc, _ := datacom.NewDatacom("testing", redisServer.Addr(), mockPAL)
+	dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
 
 	e := envApi.Entity{
 		X: 0, Y: 0, ClassID: 1, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID", Health: 100, Energy: 100, Id: "0",
@@ -458,7 +665,7 @@ func TestGetEntitiesForModel(t *testing.T) {
 	// Setup pubsub mock
 	mockPAL := &mocks.PubsubAccessLayer{}
 	mockPAL.On("QueuePublishEvent", "createEntity", mock.AnythingOfType("*endpoints_terrariumai_environment.Entity"), mock.AnythingOfType("uint32"), mock.AnythingOfType("uint32")).Return(nil)
-	dc, _ := datacom.NewDatacom("testing", redisServer.Addr(), mockPAL)
+	dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
 
 	dc.CreateEntity(envApi.Entity{
 		X: 0, Y: 0, ClassID: 1, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID", Health: 100, Energy: 100, Id: "0",
@@ -532,7 +739,7 @@ func TestGetEntitiesInSpace(t *testing.T) {
 	mockPAL := &mocks.PubsubAccessLayer{}
 	mockPAL.On("QueuePublishEvent", "createEntity", mock.AnythingOfType("*endpoints_terrariumai_environment.Entity"), mock.AnythingOfType("uint32"), mock.AnythingOfType("uint32")).Return(nil)
 
-	dc, _ := datacom.NewDatacom("testing", redisServer.Addr(), mockPAL)
+	dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
 
 	dc.CreateEntity(envApi.Entity{
 		X: 0, Y: 0, ClassID: 1, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID", Health: 100, Energy: 100, Id: "0",
@@ -542,6 +749,10 @@ func TestGetEntitiesInSpace(t *testing.T) {
 		X: 11, Y: 5, ClassID: 1, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID", Health: 100, Energy: 100, Id: "1",
 	}, true)
 
+	dc.CreateEntity(envApi.Entity{
+		X: 15, Y: 15, ClassID: 1, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID", Health: 100, Energy: 100, Id: "2",
+	}, true)
+
 	type args struct {
 		x0 uint32
 		y0 uint32
@@ -584,6 +795,21 @@ func TestGetEntitiesInSpace(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"Last cell of a block",
+			args{
+				x0: 8,
+				y0: 8,
+				x1: 15,
+				y1: 15,
+			},
+			[]*envApi.Entity{
+				&envApi.Entity{
+					X: 15, Y: 15, ClassID: 1, OwnerUID: "MOCK-UID", ModelID: "MOCK-MODEL-ID", Health: 100, Energy: 100, Id: "2",
+				},
+			},
+			false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -614,7 +840,7 @@ func TestGetObservationsForEntity(t *testing.T) {
 	mockPAL.On("QueuePublishEvent", "createEntity", mock.AnythingOfType("*endpoints_terrariumai_environment.Entity"), mock.AnythingOfType("uint32"), mock.AnythingOfType("uint32")).Return(nil)
 	mockPAL.On("QueuePublishEvent", "createEffect", mock.AnythingOfType("*endpoints_terrariumai_environment.Effect"), mock.AnythingOfType("uint32"), mock.AnythingOfType("uint32")).Return(nil)
 
-	dc, _ := datacom.NewDatacom("testing", redisServer.Addr(), mockPAL)
+	dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
 	dc.EntityVisionDist = 2
 
 	dc.CreateEntity(envApi.Entity{
@@ -731,7 +957,7 @@ func TestCreateEffect(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup pubsub mock
 			mockPAL := &mocks.PubsubAccessLayer{}
-			dc, _ := datacom.NewDatacom("testing", redisServer.Addr(), mockPAL)
+			dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
 
 			// Setup mock
 			for _, mockFuncCall := range tt.PALMockFuncCalls {
@@ -756,16 +982,13 @@ func TestCreateEffect(t *testing.T) {
 				Password: "", // no password set
 				DB:       0,  // use default DB
 			})
-			keys, cursor := redisClient.ZScan("effects", 0, "*", 0).Val()
+			keys, cursor, _ := zscan(redisClient, "v2:effects")
 			if len(keys) != tt.want {
 				t.Errorf("got %v, want %v", len(keys), tt.want)
 			}
 
 			effect := envApi.Effect{}
-			values := strings.Split(keys[cursor], "-")
-			content := strings.ReplaceAll(values[1], "%n", "\n")
-			proto.UnmarshalText(content, &effect)
-			fmt.Println(content)
+			proto.Unmarshal([]byte(keys[cursor][19:]), &effect)
 
 			if !reflect.DeepEqual(effect, tt.args.effect) {
 				t.Errorf("got %v, expected %v", effect, tt.args.effect)
@@ -939,7 +1162,7 @@ func TestGetEffectsInSpace(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup pubsub mock
 			mockPAL := &mocks.PubsubAccessLayer{}
-			dc, _ := datacom.NewDatacom("testing", redisServer.Addr(), mockPAL)
+			dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
 
 			// Setup mock
 			for _, mockFuncCall := range tt.PALMockFuncCalls {
@@ -1047,7 +1270,7 @@ func TestDeleteEffect(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup pubsub mock
 			mockPAL := &mocks.PubsubAccessLayer{}
-			dc, _ := datacom.NewDatacom("testing", redisServer.Addr(), mockPAL)
+			dc, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
 
 			// Setup mock
 			for _, mockFuncCall := range tt.PALMockFuncCalls {
@@ -1090,9 +1313,81 @@ func TestDeleteEffect(t *testing.T) {
 	}
 }
 func TestPubnubPAL(t *testing.T) {
-	p := datacom.NewPubnubPAL("testing", "sub-c-b4ba4e28-a647-11e9-ad2c-6ad2737329fc", "pub-c-83ed11c2-81e1-4d7f-8e94-0abff2b85825")
+	p := datacom.NewPubnubPAL("testing", datacom.DefaultWorldID, "sub-c-b4ba4e28-a647-11e9-ad2c-6ad2737329fc", "pub-c-83ed11c2-81e1-4d7f-8e94-0abff2b85825", zap.NewNop())
 	p.QueuePublishEvent("updateEntity", &envApi.Entity{Id: "test-id", Y: 0}, 0, 0)
 	p.QueuePublishEvent("updateEntity", &envApi.Entity{Id: "test-id-2", X: 5, Y: 0}, 5, 0)
 	t.Log("Queued publish message, batching...")
 	p.BatchPublish()
 }
+
+// Failures are logged with the world and entity they happened to
+func TestLogsFailures(t *testing.T) {
+	redisServer := setup()
+	defer teardown(redisServer)
+	mockPAL := &mocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dc, _ := datacom.NewDatacom("training", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
+	mc := newMemoryDatacom()
+
+	tests := []struct {
+		name string
+		call func(dal environment.DataAccessLayer) error
+		want map[string]interface{}
+	}{
+		{
+			name: "Create outside the world",
+			call: func(dal environment.DataAccessLayer) error {
+				return dal.CreateEntity(envApi.Entity{Id: "MOCK-ENTITY-ID", X: 1000, Y: 0}, false)
+			},
+			want: map[string]interface{}{"entity": "MOCK-ENTITY-ID", "x": uint32(1000), "y": uint32(0)},
+		},
+		{
+			name: "Update a missing entity",
+			call: func(dal environment.DataAccessLayer) error {
+				return dal.UpdateEntity("MOCK-CONTENT", envApi.Entity{Id: "MOCK-ENTITY-ID"})
+			},
+			want: map[string]interface{}{"entity": "MOCK-ENTITY-ID"},
+		},
+		{
+			name: "Delete a missing entity",
+			call: func(dal environment.DataAccessLayer) error {
+				_, err := dal.DeleteEntity("MOCK-ENTITY-ID")
+				return err
+			},
+			want: map[string]interface{}{"entity": "MOCK-ENTITY-ID"},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, kind := range []string{"redis", "memory"} {
+			t.Run(tt.name+" "+kind, func(t *testing.T) {
+				core, logs := observer.New(zapcore.DebugLevel)
+				var dal environment.DataAccessLayer = dc
+				dc.SetLogger(zap.New(core))
+				if kind == "memory" {
+					dal = mc
+					mc.SetLogger(zap.New(core))
+				}
+
+				if err := tt.call(dal); err == nil {
+					t.Fatalf("expected an error")
+				}
+				if logs.Len() != 1 {
+					t.Fatalf("got %v log lines, want 1: %v", logs.Len(), logs.All())
+				}
+				fields := logs.All()[0].ContextMap()
+				if fields["world"] != datacom.DefaultWorldID {
+					t.Errorf("logged world %v, want %v", fields["world"], datacom.DefaultWorldID)
+				}
+				if _, ok := fields["error"]; !ok {
+					t.Errorf("the error wasn't logged: %v", fields)
+				}
+				for k, v := range tt.want {
+					if fields[k] != v {
+						t.Errorf("logged %v %v, want %v", k, fields[k], v)
+					}
+				}
+			})
+		}
+	}
+}
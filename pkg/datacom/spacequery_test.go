@@ -0,0 +1,71 @@
+package datacom
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestConstructSpacequeryCalls(t *testing.T) {
+	legacy := grid{size: WorldSize{512, 512}, bits: legacyIndexBits}
+	arena := newGrid(WorldSize{20, 20})
+	large := newGrid(WorldSize{1000, 1000})
+	tests := []struct {
+		name           string
+		g              grid
+		x0, y0, x1, y1 uint32
+		// How many cells the ranges can cover at most, thin spaces cut across
+		// the most blocks so they read the most extra cells
+		maxCells uint64
+	}{
+		{"Single cell", legacy, 5, 7, 5, 7, 1},
+		{"Aligned block", legacy, 8, 8, 15, 15, 64},
+		{"Observation square", legacy, 37, 12, 47, 22, 2 * 121},
+		{"Strip", legacy, 0, 100, 511, 100, 512 * 512 / 16},
+		{"Large region", legacy, 3, 3, 300, 250, 2 * 298 * 248},
+		{"Whole world", legacy, 0, 0, 999, 999, 512 * 512},
+		{"Past the edge", legacy, 600, 600, 700, 700, 0},
+		{"Whole arena", arena, 0, 0, 19, 19, 32 * 32},
+		{"Arena corner", arena, 15, 15, 30, 30, 2 * 25},
+		{"Past the arena", arena, 20, 0, 25, 5, 0},
+		{"Large map corner", large, 990, 990, 999, 999, 2 * 100},
+		{"Past the large map", large, 1000, 0, 1023, 5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := tt.g.constructSpacequeryCalls(tt.x0, tt.y0, tt.x1, tt.y1)
+			if len(calls) > maxSpacequeryRanges {
+				t.Errorf("got %v calls, want at most %v", len(calls), maxSpacequeryRanges)
+			}
+
+			ranges := [][2]uint64{}
+			var cells uint64
+			for _, call := range calls {
+				start, _ := strconv.ParseUint(call.Min[1:], 2, 64)
+				end, _ := strconv.ParseUint(call.Max[1:len(call.Max)-1], 2, 64)
+				if len(ranges) > 0 && start <= ranges[len(ranges)-1][1] {
+					t.Errorf("ranges overlap or are out of order: %v", calls)
+				}
+				ranges = append(ranges, [2]uint64{start, end})
+				cells += end - start + 1
+			}
+			if cells > tt.maxCells {
+				t.Errorf("ranges cover %v cells, want at most %v", cells, tt.maxCells)
+			}
+
+			// Every cell in the space has to be covered
+			for x := tt.x0; x <= tt.x1 && x < tt.g.size.Width; x++ {
+				for y := tt.y0; y <= tt.y1 && y < tt.g.size.Height; y++ {
+					index := tt.g.mortonIndex(x, y)
+					covered := false
+					for _, r := range ranges {
+						covered = covered || (index >= r[0] && index <= r[1])
+					}
+					if !covered {
+						t.Fatalf("cell %v,%v is not covered by %v", x, y, calls)
+					}
+				}
+			}
+		})
+	}
+}
@@ -1,109 +1,147 @@
 package datacom
 
 import (
-	"errors"
 	"fmt"
-	"log"
 	"math"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang/protobuf/proto"
+	collectiveApi "github.com/terrariumai/simulation/pkg/api/collective"
 	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/terrain"
 )
 
-// PosToRedisIndex interlocks an x and y value to use as an index in redis
-func posToRedisIndex(x uint32, y uint32) (string, error) {
-	xString := strconv.FormatUint(uint64(x), 2)
-	yString := strconv.FormatUint(uint64(y), 2)
-	interlocked := ""
-	// make sure x and y are the correct length when converted to str
-	if len(xString) > maxPositionCharLength || len(yString) > maxPositionCharLength {
-		return "", errors.New("invalid position")
+// parseContent splits a redis member into its index and message. The index
+// is only ones and zeros, so it ends at the first dash.
+func parseContent(content string, msg proto.Message) (string, error) {
+	indexLength := strings.IndexByte(content, '-')
+	if indexLength <= 0 || strings.Trim(content[:indexLength], "01") != "" {
+		return "", fmt.Errorf("invalid content %q: missing position index", content)
 	}
-	// add padding
-	for len(xString) < maxPositionCharLength {
-		xString = "0" + xString
+	if err := proto.Unmarshal([]byte(content[indexLength+1:]), msg); err != nil {
+		return "", fmt.Errorf("invalid content %q: %v", content, err)
 	}
-	for len(yString) < maxPositionCharLength {
-		yString = "0" + yString
-	}
-	// interlock
-	for i := 0; i < maxPositionCharLength; i++ {
-		interlocked = interlocked + xString[i:i+1] + yString[i:i+1]
-	}
-
-	return interlocked, nil
-}
-
-// Serializes an entity to a string
-func serializeEntity(e envApi.Entity) (string, error) {
-	index, err := posToRedisIndex(e.X, e.Y)
-	if err != nil {
-		log.Println("ERROR: ", err)
-		return "", err
-	}
-	entityStr := proto.MarshalTextString(&e)
-	entityStr = strings.ReplaceAll(entityStr, "\n", "%n")
-	return fmt.Sprintf("%s-%s", index, entityStr), nil
-}
-
-// Serializes a cell to a string
-func serializeEffect(p envApi.Effect) (string, error) {
-	index, err := posToRedisIndex(p.X, p.Y)
-	if err != nil {
-		log.Println("ERROR: ", err)
-		return "", err
-	}
-	effectString := proto.MarshalTextString(&p)
-	effectString = strings.ReplaceAll(effectString, "\n", "%n")
-	return fmt.Sprintf("%s-%s", index, effectString), nil
+	return content[:indexLength], nil
 }
 
 // parseEntityContent takes entity string and parses it out to an entity
-func parseEntityContent(content string) (envApi.Entity, string) {
-	values := strings.SplitN(content, "-", 2)
-	entityStr := values[1]
-	entityStr = strings.ReplaceAll(entityStr, "%n", "\n")
+func parseEntityContent(content string) (envApi.Entity, string, error) {
 	entity := envApi.Entity{}
-	proto.UnmarshalText(entityStr, &entity)
-	return entity, values[0]
+	index, err := parseContent(content, &entity)
+	return entity, index, err
 }
 
 // parseCellContent takes  a cell string and converts it to a cell struct
-func parseEffectContent(content string) (p envApi.Effect, index string) {
-	values := strings.SplitN(content, "-", 2)
-	effectString := values[1]
-	effectString = strings.ReplaceAll(effectString, "%n", "\n")
+func parseEffectContent(content string) (envApi.Effect, string, error) {
 	effect := envApi.Effect{}
-	proto.UnmarshalText(effectString, &effect)
-	return effect, values[0]
+	index, err := parseContent(content, &effect)
+	return effect, index, err
 }
 
-func getRegionForPos(x uint32, y uint32) (uint32, uint32) {
-	regionX := uint32(math.Floor(float64(x) / regionSize))
-	regionY := uint32(math.Floor(float64(y) / regionSize))
+// isEffectExpired checks if an effect has decayed down to its delete threshold
+func isEffectExpired(effect envApi.Effect, now int64) bool {
+	strength := uint32(100 / math.Pow(float64(effect.Decay), float64(now-effect.Timestamp)))
+	return strength <= effect.DelThresh
+}
 
-	return regionX, regionY
+// cellKey is a position that can be used as a map key
+type cellKey struct {
+	x uint32
+	y uint32
+}
+
+// entitiesByCell matches entities up with their positions
+func entitiesByCell(entities []*envApi.Entity) map[cellKey]*envApi.Entity {
+	cells := make(map[cellKey]*envApi.Entity)
+	for _, e := range entities {
+		cells[cellKey{e.X, e.Y}] = e
+	}
+	return cells
+}
+
+// effectsByCell matches effects up with their positions
+func effectsByCell(effects []*envApi.Effect) map[cellKey]*envApi.Effect {
+	cells := make(map[cellKey]*envApi.Effect)
+	for _, effect := range effects {
+		cells[cellKey{effect.X, effect.Y}] = effect
+	}
+	return cells
 }
 
-func calcSpaceAroundPoint(x int32, y int32, dist int32) (int32, int32, int32, int32) {
-	x0 := int32(x) - dist
-	x1 := int32(x) + dist
-	y0 := int32(y) - dist
-	y1 := int32(y) + dist
-	if x0 < minPosition {
-		x0 = minPosition
+// observationDist is how far around an entity has to be read to build its
+// observation
+func observationDist(visionDist int32, smellDist int32) int32 {
+	if smellDist > visionDist {
+		return smellDist
 	}
-	if y0 < minPosition {
-		y0 = minPosition
+	return visionDist
+}
+
+// terrainClasses is how each terrain is seen in a cell with no entity on it
+var terrainClasses = map[terrain.Type]collectiveApi.Entity_Class{
+	terrain.Ground: collectiveApi.Entity_EMPTY,
+	terrain.Wall:   collectiveApi.Entity_WALL,
+	terrain.Water:  collectiveApi.Entity_WATER,
+	terrain.Slow:   collectiveApi.Entity_SLOW_GROUND,
+}
+
+// buildObservation lays out what an entity sees and smells from the entities
+// and effects around it, by position. Cells off the map are seen as rocks,
+// unless the world wraps around, and empty cells as their terrain.
+func buildObservation(g grid, entity envApi.Entity, visionDist int32, smellDist int32, cellEntityMap map[cellKey]*envApi.Entity, cellEffectMap map[cellKey]*envApi.Effect) *collectiveApi.Observation {
+	obsv := collectiveApi.Observation{
+		Id:      entity.Id,
+		Energy:  entity.Energy,
+		Health:  entity.Health,
+		IsAlive: true,
 	}
-	if x1 > maxPosition {
-		x1 = maxPosition
+
+	var x int64
+	var y int64
+	for y = int64(entity.Y) + int64(visionDist); y >= int64(entity.Y)-int64(visionDist); y-- {
+		for x = int64(entity.X) - int64(visionDist); x <= int64(entity.X)+int64(visionDist); x++ {
+			// Skip the entity's own cell
+			if x == int64(entity.X) && y == int64(entity.Y) {
+				continue
+			}
+			// If position is invalid, set it to untraversable entity (rock)
+			cellX, cellY, ok := g.wrap(x, y)
+			if !ok {
+				obsv.Sight = append(obsv.Sight, &collectiveApi.Entity{Id: "", ClassID: 2})
+				continue
+			}
+			if otherEntity, ok := cellEntityMap[cellKey{cellX, cellY}]; ok {
+				obsv.Sight = append(obsv.Sight, &collectiveApi.Entity{Id: otherEntity.Id, ClassID: collectiveApi.Entity_Class(otherEntity.ClassID)})
+			} else {
+				obsv.Sight = append(obsv.Sight, &collectiveApi.Entity{Id: "", ClassID: terrainClasses[g.terrain.At(cellX, cellY)]})
+			}
+		}
 	}
-	if y1 > maxPosition {
-		y1 = maxPosition
+
+	now := time.Now().Unix()
+	for y = int64(entity.Y) + int64(smellDist); y >= int64(entity.Y)-int64(smellDist); y-- {
+		for x = int64(entity.X) - int64(smellDist); x <= int64(entity.X)+int64(smellDist); x++ {
+			// If position is invalid, there is nothing to smell
+			cellX, cellY, ok := g.wrap(x, y)
+			if !ok {
+				obsv.Smell = append(obsv.Smell, &collectiveApi.Effect{ClassID: collectiveApi.Effect_Class(0)})
+				continue
+			}
+			if effect, ok := cellEffectMap[cellKey{cellX, cellY}]; ok {
+				strength := uint32(100 / math.Pow(float64(effect.Decay), float64(now-effect.Timestamp)))
+				obsv.Smell = append(obsv.Smell, &collectiveApi.Effect{ClassID: collectiveApi.Effect_Class(effect.ClassID), Value: effect.Value, Strength: strength})
+			} else {
+				obsv.Smell = append(obsv.Smell, &collectiveApi.Effect{ClassID: collectiveApi.Effect_Class(0)})
+			}
+		}
 	}
+	return &obsv
+}
+
+func getRegionForPos(x uint32, y uint32) (uint32, uint32) {
+	regionX := uint32(math.Floor(float64(x) / regionSize))
+	regionY := uint32(math.Floor(float64(y) / regionSize))
 
-	return x0, y0, x1, y1
+	return regionX, regionY
 }
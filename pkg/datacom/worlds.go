@@ -0,0 +1,270 @@
+package datacom
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/go-redis/redis"
+	"github.com/terrariumai/simulation/pkg/terrain"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultWorldID is the world that always exists. It keeps the keys from
+	// before there were worlds so existing data doesn't move.
+	DefaultWorldID = "default"
+
+	// Ids of every created world besides the default one
+	worldsKey = "worlds"
+	// Width and height of a world, within the world's keys
+	worldSizeKey = "world.size"
+	// Topology of a world, within the world's keys
+	worldTopologyKey = "world.topology"
+)
+
+// World is a world, its size and what happens at its edges
+type World struct {
+	ID       string
+	Size     WorldSize
+	Topology Topology
+}
+
+var worldIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ValidWorldID checks a world id is lowercase letters, digits and dashes, at
+// most 32 long. Ids end up in redis keys and pubsub channels.
+func ValidWorldID(worldID string) bool {
+	return worldIDPattern.MatchString(worldID)
+}
+
+// worldKeyPrefix is what every key of a world starts with
+func worldKeyPrefix(worldID string) string {
+	if worldID == DefaultWorldID {
+		return ""
+	}
+	return "world:" + worldID + ":"
+}
+
+// key namespaces a key to the datacom's world
+func (dc *Datacom) key(name string) string {
+	return worldKeyPrefix(dc.worldID) + name
+}
+
+// WorldID returns the world the datacom reads and writes
+func (dc *Datacom) WorldID() string {
+	return dc.worldID
+}
+
+// WorldSize returns the size of the world the datacom reads and writes
+func (dc *Datacom) WorldSize() WorldSize {
+	return dc.grid.size
+}
+
+// SetTerrain sets the terrain observations see in empty cells. It isn't
+// stored, every service of a world loads the same map, which is why generated
+// resets are only allowed in single process training.
+func (dc *Datacom) SetTerrain(m *terrain.Map) error {
+	if err := dc.grid.checkTerrain(m); err != nil {
+		dc.logger.Warn("rejected terrain", zap.Error(err))
+		return err
+	}
+	dc.terrainM.Lock()
+	defer dc.terrainM.Unlock()
+	dc.terrain = m
+	return nil
+}
+
+// observationGrid is the grid with the current terrain laid over it
+func (dc *Datacom) observationGrid() grid {
+	dc.terrainM.RLock()
+	defer dc.terrainM.RUnlock()
+	g := dc.grid
+	g.terrain = dc.terrain
+	return g
+}
+
+// WorldTopology returns what happens at the edges of the world the datacom
+// reads and writes
+func (dc *Datacom) WorldTopology() Topology {
+	if dc.grid.torus {
+		return Torus
+	}
+	return Bounded
+}
+
+// worldTopology reads the topology a world was created with, worlds without
+// one are bounded
+func (dc *Datacom) worldTopology(worldID string) (Topology, error) {
+	value, err := dc.redisClient.Get(worldKeyPrefix(worldID) + worldTopologyKey).Result()
+	if err == redis.Nil {
+		return Bounded, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	topology := Topology(value)
+	if err := topology.Validate(); err != nil {
+		return "", fmt.Errorf("world %v: %v", worldID, err)
+	}
+	return topology, nil
+}
+
+// worldSize reads the size a world was created with. The default world
+// hasn't got one stored unless it was set by hand.
+func (dc *Datacom) worldSize(worldID string) (WorldSize, error) {
+	values, err := dc.redisClient.HGetAll(worldKeyPrefix(worldID) + worldSizeKey).Result()
+	if err != nil {
+		return WorldSize{}, err
+	}
+	if len(values) == 0 {
+		return DefaultWorldSize, nil
+	}
+	width, err := strconv.ParseUint(values["width"], 10, 32)
+	if err != nil {
+		return WorldSize{}, fmt.Errorf("invalid width for world %v: %v", worldID, err)
+	}
+	height, err := strconv.ParseUint(values["height"], 10, 32)
+	if err != nil {
+		return WorldSize{}, fmt.Errorf("invalid height for world %v: %v", worldID, err)
+	}
+	size := WorldSize{Width: uint32(width), Height: uint32(height)}
+	if err := size.Validate(); err != nil {
+		return WorldSize{}, fmt.Errorf("world %v: %v", worldID, err)
+	}
+	if worldID == DefaultWorldID && newGrid(size).bits > legacyIndexBits {
+		return WorldSize{}, fmt.Errorf("the default world can be at most %v wide and high", 1<<legacyIndexBits)
+	}
+	return size, nil
+}
+
+// CreateWorld registers a new, empty world with the given size and topology
+func (dc *Datacom) CreateWorld(worldID string, size WorldSize, topology Topology) error {
+	if !ValidWorldID(worldID) {
+		err := fmt.Errorf("invalid world id %q", worldID)
+		dc.logger.Warn("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	if err := size.Validate(); err != nil {
+		dc.logger.Warn("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	if err := topology.Validate(); err != nil {
+		dc.logger.Warn("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	if err := topology.ValidateSize(size); err != nil {
+		dc.logger.Warn("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	if worldID == DefaultWorldID {
+		err := errors.New("world already exists")
+		dc.logger.Warn("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	added, err := dc.redisClient.SAdd(worldsKey, worldID).Result()
+	if err != nil {
+		dc.logger.Error("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	if added == 0 {
+		err := errors.New("world already exists")
+		dc.logger.Warn("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	_, err = dc.redisClient.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HMSet(worldKeyPrefix(worldID)+worldSizeKey, map[string]interface{}{
+			"width":  size.Width,
+			"height": size.Height,
+		})
+		pipe.Set(worldKeyPrefix(worldID)+worldTopologyKey, string(topology), 0)
+		return nil
+	})
+	if err != nil {
+		dc.logger.Error("creating world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	return nil
+}
+
+// ListWorlds returns every world, starting with the default one
+func (dc *Datacom) ListWorlds() ([]World, error) {
+	ids, err := dc.redisClient.SMembers(worldsKey).Result()
+	if err != nil {
+		dc.logger.Error("listing worlds", zap.Error(err))
+		return nil, err
+	}
+	sort.Strings(ids)
+	worlds := []World{}
+	for _, id := range append([]string{DefaultWorldID}, ids...) {
+		size, err := dc.worldSize(id)
+		if err != nil {
+			dc.logger.Error("listing worlds", zap.Error(err), zap.String("target", id))
+			return nil, err
+		}
+		topology, err := dc.worldTopology(id)
+		if err != nil {
+			dc.logger.Error("listing worlds", zap.Error(err), zap.String("target", id))
+			return nil, err
+		}
+		worlds = append(worlds, World{id, size, topology})
+	}
+	return worlds, nil
+}
+
+// WorldExists checks if a world has been created
+func (dc *Datacom) WorldExists(worldID string) (bool, error) {
+	if worldID == DefaultWorldID {
+		return true, nil
+	}
+	return dc.redisClient.SIsMember(worldsKey, worldID).Result()
+}
+
+// DeleteWorld removes a world and everything in it. The default world can
+// only be reset, not deleted.
+func (dc *Datacom) DeleteWorld(worldID string) error {
+	if worldID == DefaultWorldID {
+		err := errors.New("the default world can't be deleted")
+		dc.logger.Warn("deleting world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	exists, err := dc.WorldExists(worldID)
+	if err != nil {
+		dc.logger.Error("deleting world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	if !exists {
+		err := errors.New("world does not exist")
+		dc.logger.Warn("deleting world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+
+	// Remove every key in the world
+	var cursor uint64
+	for {
+		keys, nextCursor, err := dc.redisClient.Scan(cursor, worldKeyPrefix(worldID)+"*", 100).Result()
+		if err != nil {
+			dc.logger.Error("deleting world", zap.Error(err), zap.String("target", worldID))
+			return err
+		}
+		if len(keys) > 0 {
+			if err := dc.redisClient.Del(keys...).Err(); err != nil {
+				dc.logger.Error("deleting world", zap.Error(err), zap.String("target", worldID))
+				return err
+			}
+		}
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	// Unregister it last, so a failed delete can be run again
+	if err := dc.redisClient.SRem(worldsKey, worldID).Err(); err != nil {
+		dc.logger.Error("deleting world", zap.Error(err), zap.String("target", worldID))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package datacom_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	collectiveApi "github.com/terrariumai/simulation/pkg/api/collective"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/datacom/mocks"
+	"github.com/terrariumai/simulation/pkg/environment"
+	"github.com/terrariumai/simulation/pkg/terrain"
+)
+
+func TestWorlds(t *testing.T) {
+	redisServer := setup()
+	defer teardown(redisServer)
+	mockPAL := &mocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	defaultWorld, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
+
+	if _, err := datacom.NewDatacom("testing", "Not A World", redisServer.Addr(), mockPAL); err == nil {
+		t.Errorf("expected an error for an invalid world id")
+	}
+	arena := datacom.WorldSize{Width: 20, Height: 20}
+	large := datacom.WorldSize{Width: 1000, Height: 1000}
+	if err := defaultWorld.CreateWorld("sandbox", arena, datacom.Bounded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := defaultWorld.CreateWorld("sandbox", arena, datacom.Bounded); err == nil {
+		t.Errorf("expected an error creating a world twice")
+	}
+	if err := defaultWorld.CreateWorld(datacom.DefaultWorldID, arena, datacom.Bounded); err == nil {
+		t.Errorf("expected an error creating the default world")
+	}
+	if err := defaultWorld.CreateWorld("empty", datacom.WorldSize{}, datacom.Bounded); err == nil {
+		t.Errorf("expected an error creating a world without a size")
+	}
+	if err := defaultWorld.CreateWorld("ring", datacom.WorldSize{Width: 20, Height: 1}, datacom.Torus); err == nil {
+		t.Errorf("expected an error creating a torus one cell high")
+	}
+	if err := defaultWorld.CreateWorld("tournament", large, datacom.Bounded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	worlds, err := defaultWorld.ListWorlds()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []datacom.World{{ID: "default", Size: datacom.DefaultWorldSize, Topology: datacom.Bounded}, {ID: "sandbox", Size: arena, Topology: datacom.Bounded}, {ID: "tournament", Size: large, Topology: datacom.Bounded}}
+	if !reflect.DeepEqual(worlds, want) {
+		t.Errorf("ListWorlds() = %v, want %v", worlds, want)
+	}
+
+	// The same cell holds a different entity in each world
+	sandbox, _ := datacom.NewDatacom("testing", "sandbox", redisServer.Addr(), mockPAL)
+	if sandbox.WorldSize() != arena {
+		t.Errorf("WorldSize() = %v, want %v", sandbox.WorldSize(), arena)
+	}
+	defaultWorld.CreateEntity(envApi.Entity{Id: "0", X: 1, Y: 1, ModelID: "MOCK-MODEL-ID"}, false)
+	sandbox.CreateEntity(envApi.Entity{Id: "1", X: 1, Y: 1, ModelID: "MOCK-MODEL-ID"}, false)
+	for _, world := range []struct {
+		dc *datacom.Datacom
+		id string
+	}{{defaultWorld, "0"}, {sandbox, "1"}} {
+		entities, _ := world.dc.GetEntitiesInSpace(0, 0, 5, 5)
+		if len(entities) != 1 || entities[0].Id != world.id {
+			t.Errorf("%v: GetEntitiesInSpace() = %v, want only %v", world.dc.WorldID(), entities, world.id)
+		}
+		modelEntities, _ := world.dc.GetEntitiesForModel("MOCK-MODEL-ID")
+		if len(modelEntities) != 1 || modelEntities[0].Id != world.id {
+			t.Errorf("%v: GetEntitiesForModel() = %v, want only %v", world.dc.WorldID(), modelEntities, world.id)
+		}
+	}
+	if !redisServer.Exists("v2:entities") || !redisServer.Exists("world:sandbox:v2:entities") {
+		t.Errorf("expected the default world's keys unprefixed and the sandbox's namespaced, got %v", redisServer.Keys())
+	}
+
+	// Deleting a world leaves the others alone
+	if err := defaultWorld.DeleteWorld("sandbox"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := defaultWorld.DeleteWorld("sandbox"); err == nil {
+		t.Errorf("expected an error deleting a missing world")
+	}
+	if err := defaultWorld.DeleteWorld(datacom.DefaultWorldID); err == nil {
+		t.Errorf("expected an error deleting the default world")
+	}
+	for _, key := range redisServer.Keys() {
+		if strings.HasPrefix(key, "world:sandbox:") {
+			t.Errorf("expected %v to be deleted", key)
+		}
+	}
+	if _, _, err := defaultWorld.GetEntity("0"); err != nil {
+		t.Errorf("expected the default world's entity to be left, got %v", err)
+	}
+
+	// Positions are checked against each world's own size
+	tournament, _ := datacom.NewDatacom("testing", "tournament", redisServer.Addr(), mockPAL)
+	if err := tournament.CreateEntity(envApi.Entity{Id: "2", X: 999, Y: 999}, false); err != nil {
+		t.Errorf("unexpected error creating an entity in the corner of a large map: %v", err)
+	}
+	if err := tournament.CreateEntity(envApi.Entity{Id: "3", X: 1000, Y: 0}, false); err == nil {
+		t.Errorf("expected an error creating an entity past the edge of the map")
+	}
+	entities, _ := tournament.GetEntitiesInSpace(990, 990, 1010, 1010)
+	if len(entities) != 1 || entities[0].Id != "2" {
+		t.Errorf("GetEntitiesInSpace() = %v, want only 2", entities)
+	}
+	worlds, _ = defaultWorld.ListWorlds()
+	if want := []datacom.World{{ID: "default", Size: datacom.DefaultWorldSize, Topology: datacom.Bounded}, {ID: "tournament", Size: large, Topology: datacom.Bounded}}; !reflect.DeepEqual(worlds, want) {
+		t.Errorf("ListWorlds() = %v, want %v", worlds, want)
+	}
+}
+
+func TestTorusObservations(t *testing.T) {
+	redisServer := setup()
+	defer teardown(redisServer)
+	mockPAL := &mocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	defaultWorld, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
+	size := datacom.WorldSize{Width: 20, Height: 20}
+	defaultWorld.CreateWorld("donut", size, datacom.Torus)
+	defaultWorld.CreateWorld("box", size, datacom.Bounded)
+	donut, _ := datacom.NewDatacom("testing", "donut", redisServer.Addr(), mockPAL)
+	box, _ := datacom.NewDatacom("testing", "box", redisServer.Addr(), mockPAL)
+	memoryDonut, _ := datacom.NewMemoryDatacom(mockPAL, size, datacom.Torus)
+	if donut.WorldTopology() != datacom.Torus || box.WorldTopology() != datacom.Bounded {
+		t.Errorf("got topologies %v and %v, want torus and bounded", donut.WorldTopology(), box.WorldTopology())
+	}
+
+	tests := []struct {
+		name     string
+		dal      environment.DataAccessLayer
+		wantSeen bool
+	}{
+		{"Torus", donut, true},
+		{"Memory torus", memoryDonut, true},
+		{"Bounded", box, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The food and pheromone are just across the seam from the agent
+			e := envApi.Entity{Id: "0", X: 0, Y: 0, ClassID: envApi.Entity_AGENT, ModelID: "MOCK-MODEL-ID"}
+			tt.dal.CreateEntity(e, false)
+			tt.dal.CreateEntity(envApi.Entity{Id: "1", X: 19, Y: 19, ClassID: envApi.Entity_FOOD}, false)
+			tt.dal.CreateEffect(envApi.Effect{X: 19, Y: 0, ClassID: envApi.Effect_PHEROMONE, Value: 7, Decay: 1.2, DelThresh: 5, Timestamp: time.Now().Unix()})
+
+			obsv, err := tt.dal.GetObservationForEntity(e)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			seen, rocks, smelled := false, 0, false
+			for _, cell := range obsv.Sight {
+				seen = seen || cell.Id == "1"
+				if cell.ClassID == collectiveApi.Entity_ROCK {
+					rocks++
+				}
+			}
+			for _, effect := range obsv.Smell {
+				smelled = smelled || effect.Value == 7
+			}
+			if seen != tt.wantSeen || smelled != tt.wantSeen {
+				t.Errorf("seen = %v, smelled = %v, want %v", seen, smelled, tt.wantSeen)
+			}
+			if tt.wantSeen && rocks > 0 {
+				t.Errorf("expected no rocks in a torus world, saw %v", rocks)
+			}
+			if !tt.wantSeen && rocks == 0 {
+				t.Errorf("expected rocks past the edge of a bounded world")
+			}
+
+			obsvs, err := tt.dal.GetObservationsForEntities([]envApi.Entity{e})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(obsvs[0], obsv) {
+				t.Errorf("GetObservationsForEntities() = %v, want %v", obsvs[0], obsv)
+			}
+		})
+	}
+}
+
+func TestTerrainObservations(t *testing.T) {
+	redisServer := setup()
+	defer teardown(redisServer)
+	mockPAL := &mocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	size := datacom.WorldSize{Width: 20, Height: 20}
+	defaultWorld, _ := datacom.NewDatacom("testing", datacom.DefaultWorldID, redisServer.Addr(), mockPAL)
+	defaultWorld.CreateWorld("island", size, datacom.Bounded)
+	island, _ := datacom.NewDatacom("testing", "island", redisServer.Addr(), mockPAL)
+	memoryIsland, _ := datacom.NewMemoryDatacom(mockPAL, size, datacom.Bounded)
+
+	// A wall to the left of 5,5, water to the right and slow ground below
+	m := terrain.New(20, 20)
+	m.Set(4, 5, terrain.Wall)
+	m.Set(6, 5, terrain.Water)
+	m.Set(5, 4, terrain.Slow)
+
+	tests := []struct {
+		name string
+		dal  environment.DataAccessLayer
+	}{
+		{"Redis", island},
+		{"Memory", memoryIsland},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dal.SetTerrain(terrain.New(10, 10)); err == nil {
+				t.Errorf("expected an error for terrain that doesn't match the world")
+			}
+			if err := tt.dal.SetTerrain(m); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			e := envApi.Entity{Id: "0", X: 5, Y: 5, ClassID: envApi.Entity_AGENT, ModelID: "MOCK-MODEL-ID"}
+			tt.dal.CreateEntity(e, false)
+			// Food on slow ground is still seen as food
+			tt.dal.CreateEntity(envApi.Entity{Id: "1", X: 5, Y: 4, ClassID: envApi.Entity_FOOD}, false)
+
+			obsv, err := tt.dal.GetObservationForEntity(e)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// Sight runs row by row from the top left, skipping the center
+			sightAt := func(x, y int) collectiveApi.Entity_Class {
+				i := (10-y)*11 + x
+				if i > 60 {
+					i--
+				}
+				return obsv.Sight[i].ClassID
+			}
+			got := []collectiveApi.Entity_Class{sightAt(4, 5), sightAt(6, 5), sightAt(5, 4), sightAt(0, 0)}
+			want := []collectiveApi.Entity_Class{collectiveApi.Entity_WALL, collectiveApi.Entity_WATER, collectiveApi.Entity_FOOD, collectiveApi.Entity_EMPTY}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+
+			// Terrain swapped by a generated reset while agents observe, the
+			// race detector catches it being read unlocked
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 50; i++ {
+					tt.dal.SetTerrain(m)
+				}
+			}()
+			for i := 0; i < 50; i++ {
+				tt.dal.GetObservationForEntity(e)
+				tt.dal.GetObservationsForEntities([]envApi.Entity{e})
+			}
+			<-done
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package environment
+
+import (
+	"context"
+	"crypto/subtle"
+
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/auth"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodPrefix starts the full name of every environment rpc
+const methodPrefix = "/endpoints.terrariumai.environment.Environment/"
+
+// MethodPermissions is the permission each rpc needs, for the auth
+// interceptor. Rpcs not listed are open to everyone.
+var MethodPermissions = map[string]auth.Permission{
+	methodPrefix + "CreateWorld":   auth.ManageWorlds,
+	methodPrefix + "DeleteWorld":   auth.ManageWorlds,
+	methodPrefix + "ResetWorld":    auth.ResetWorlds,
+	methodPrefix + "SpawnFood":     auth.SpawnEntities,
+	methodPrefix + "SpawnEntities": auth.SpawnEntities,
+}
+
+// caller is who an rpc was called by, either a user or one of our services
+type caller struct {
+	user    *auth.UserInfo
+	service bool
+}
+
+// WithServiceToken lets services calling with the token act on any entity.
+// Without it only users can call.
+func WithServiceToken(token string) ServerOption {
+	return func(s *environmentServer) {
+		s.serviceToken = token
+	}
+}
+
+// getCaller works out who called an rpc from its metadata
+func (s *environmentServer) getCaller(ctx context.Context) (*caller, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if tokens := md[auth.ServiceTokenHeader]; len(tokens) > 0 && len(s.serviceToken) > 0 {
+		if subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(s.serviceToken)) != 1 {
+			err := status.Error(codes.Unauthenticated, "invalid service token")
+			s.logger.Warn("rejected service token", zap.Error(err))
+			return nil, err
+		}
+		return &caller{service: true}, nil
+	}
+	userInfo, err := auth.UserFromContext(ctx)
+	if err != nil {
+		err := status.Error(codes.Unauthenticated, err.Error())
+		s.logger.Warn("rejected caller", zap.Error(err))
+		return nil, err
+	}
+	return &caller{user: userInfo}, nil
+}
+
+// authorizeEntity makes sure the caller can act on an entity. Services and
+// roles allowed to can act on any entity, users only on their own. Anyone can in
+// training and testing.
+func (s *environmentServer) authorizeEntity(ctx context.Context, e *envApi.Entity) error {
+	if s.env == "training" || s.env == "testing" {
+		return nil
+	}
+	c, err := s.getCaller(ctx)
+	if err != nil {
+		return err
+	}
+	if c.service || auth.RoleFromContext(ctx).Can(auth.ActOnAnyEntity) {
+		return nil
+	}
+	if len(e.OwnerUID) == 0 || e.OwnerUID != c.user.ID {
+		err := status.Errorf(codes.PermissionDenied, "you do not own entity %v", e.Id)
+		s.logger.Warn("rejected caller", zap.Error(err), zap.String("entity", e.Id), zap.String("user", c.user.ID))
+		return err
+	}
+	return nil
+}
+
+// authorizeEntityID is authorizeEntity for an entity that hasn't been read
+func (s *environmentServer) authorizeEntityID(ctx context.Context, id string) error {
+	if s.env == "training" || s.env == "testing" {
+		return nil
+	}
+	entity, _, err := s.datacomDAL.GetEntity(id)
+	if err != nil {
+		s.logger.Error("getting entity", zap.Error(err), zap.String("entity", id))
+		return err
+	}
+	return s.authorizeEntity(ctx, entity)
+}
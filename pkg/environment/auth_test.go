@@ -0,0 +1,125 @@
+package environment
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"testing"
+	"time"
+
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/auth"
+	"github.com/terrariumai/simulation/pkg/environment/mocks"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestOwnership(t *testing.T) {
+	// userCtx calls as a user, the way the auth interceptor passes them on
+	userCtx := func(id string) context.Context {
+		return auth.NewUserContext(context.Background(), &auth.UserInfo{ID: id})
+	}
+	serviceCtx := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(auth.ServiceTokenHeader, token))
+	}
+	owned := &envApi.Entity{Id: "mock-entity-id", ClassID: envApi.Entity_AGENT, X: 1, Y: 1, Energy: 100, Health: 100, OwnerUID: "MOCK-UID", ModelID: "mock-model-id"}
+	deleteEntity := func(s envApi.EnvironmentServer, ctx context.Context) error {
+		_, err := s.DeleteEntity(ctx, &envApi.DeleteEntityRequest{Id: "mock-entity-id"})
+		return err
+	}
+	executeAction := func(s envApi.EnvironmentServer, ctx context.Context) error {
+		_, err := s.ExecuteAgentAction(ctx, &envApi.ExecuteAgentActionRequest{Id: "mock-entity-id", Action: envApi.ExecuteAgentActionRequest_WAIT})
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		call     func(s envApi.EnvironmentServer, ctx context.Context) error
+		opts     []ServerOption
+		wantCode codes.Code
+	}{
+		{name: "Owner can delete", ctx: userCtx("MOCK-UID"), call: deleteEntity, wantCode: codes.OK},
+		{name: "Admin can delete anyone's", ctx: auth.NewContext(userCtx("ADMIN-UID"), auth.Admin), call: deleteEntity, wantCode: codes.OK},
+		{name: "Operators can't delete anyone's", ctx: auth.NewContext(userCtx("OPERATOR-UID"), auth.Operator), call: deleteEntity, wantCode: codes.PermissionDenied},
+		{name: "Service can delete anyone's", ctx: serviceCtx("MOCK-TOKEN"), call: deleteEntity, opts: []ServerOption{WithServiceToken("MOCK-TOKEN")}, wantCode: codes.OK},
+		{name: "Others can't delete", ctx: userCtx("OTHER-UID"), call: deleteEntity, wantCode: codes.PermissionDenied},
+		{name: "Others can't act", ctx: userCtx("OTHER-UID"), call: executeAction, wantCode: codes.PermissionDenied},
+		{name: "Others can't queue actions", ctx: userCtx("OTHER-UID"), call: executeAction, opts: []ServerOption{WithTickInterval(time.Hour)}, wantCode: codes.PermissionDenied},
+		{name: "Wrong service token", ctx: serviceCtx("WRONG-TOKEN"), call: executeAction, opts: []ServerOption{WithServiceToken("MOCK-TOKEN")}, wantCode: codes.Unauthenticated},
+		{name: "Service tokens aren't accepted without one", ctx: serviceCtx(""), call: deleteEntity, wantCode: codes.Unauthenticated},
+		{name: "Nobody", ctx: context.Background(), call: executeAction, wantCode: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDAL := &mocks.DataAccessLayer{}
+			mockDAL.On("GetEntity", "mock-entity-id").Return(owned, "mock-original-content", nil)
+			mockDAL.On("DeleteEntity", "mock-entity-id").Return(int64(1), nil)
+			mockDAL.On("RemoveEntityMetadataFromFirebase", "mock-entity-id").Return(nil)
+			mockCellLocks(mockDAL)
+			s := NewEnvironmentServer("prod", mockDAL, DefaultWorldRules(), tt.opts...)
+
+			err := tt.call(s, tt.ctx)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("got %v (%v), want %v", code, err, tt.wantCode)
+			}
+			if tt.wantCode != codes.OK {
+				mockDAL.AssertNotCalled(t, "DeleteEntity", "mock-entity-id")
+				mockDAL.AssertNotCalled(t, "UpdateEntity", "mock-original-content", *owned)
+			}
+		})
+	}
+}
+
+func TestMethodPermissions(t *testing.T) {
+	userCtx := func(userinfo string) context.Context {
+		md := metadata.Pairs(auth.UserInfoHeader, b64.StdEncoding.EncodeToString([]byte(userinfo)))
+		return metadata.NewIncomingContext(context.Background(), md)
+	}
+	roles, err := auth.NewRoles(map[auth.Role][]string{
+		auth.Admin:    {"admin@example.com"},
+		auth.Operator: {"OPERATOR-UID"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	interceptor := auth.UnaryServerInterceptor(auth.NewESPAuthenticator(), roles, MethodPermissions)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		method   string
+		wantCode codes.Code
+	}{
+		{name: "Admins create worlds", ctx: userCtx(`{"id":"ADMIN-UID","email":"admin@example.com"}`), method: "CreateWorld", wantCode: codes.OK},
+		{name: "Operators create worlds", ctx: userCtx(`{"id":"OPERATOR-UID"}`), method: "CreateWorld", wantCode: codes.OK},
+		{name: "Operators reset worlds", ctx: userCtx(`{"id":"OPERATOR-UID"}`), method: "ResetWorld", wantCode: codes.OK},
+		{name: "Operators spawn food", ctx: userCtx(`{"id":"OPERATOR-UID"}`), method: "SpawnFood", wantCode: codes.OK},
+		{name: "Players can't create worlds", ctx: userCtx(`{"id":"PLAYER-UID"}`), method: "CreateWorld", wantCode: codes.PermissionDenied},
+		{name: "Players can't delete worlds", ctx: userCtx(`{"id":"PLAYER-UID"}`), method: "DeleteWorld", wantCode: codes.PermissionDenied},
+		{name: "Players can't spawn entities", ctx: userCtx(`{"id":"PLAYER-UID"}`), method: "SpawnEntities", wantCode: codes.PermissionDenied},
+		{name: "Players list worlds", ctx: userCtx(`{"id":"PLAYER-UID"}`), method: "ListWorlds", wantCode: codes.OK},
+		{name: "Anonymous callers can't reset worlds", ctx: context.Background(), method: "ResetWorld", wantCode: codes.Unauthenticated},
+		{name: "Anonymous callers get the rules", ctx: context.Background(), method: "GetRules", wantCode: codes.OK},
+		{name: "Garbled userinfo", ctx: userCtx(`not json`), method: "GetRules", wantCode: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return nil, nil
+			}
+			_, err := interceptor(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: methodPrefix + tt.method}, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("got %v (%v), want %v", code, err, tt.wantCode)
+			}
+			if called != (tt.wantCode == codes.OK) {
+				t.Errorf("handler called: %v, want %v", called, tt.wantCode == codes.OK)
+			}
+		})
+	}
+}
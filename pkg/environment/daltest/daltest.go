@@ -0,0 +1,407 @@
+// Package daltest is a contract test suite for environment.DataAccessLayer
+// implementations. It covers the rules the environment server relies on, so
+// every backend behaves the same way.
+package daltest
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/environment"
+)
+
+// NewDAL returns a data access layer holding an empty world, along with a func
+// that cleans it up once the test is done
+type NewDAL func(t *testing.T) (environment.DataAccessLayer, func())
+
+// Run runs every contract test against fresh worlds from newDAL
+func Run(t *testing.T, newDAL NewDAL) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, dal environment.DataAccessLayer)
+	}{
+		{"Occupancy", testOccupancy},
+		{"Get and delete", testGetAndDelete},
+		{"Duplicate ids", testDuplicateIDs},
+		{"Update", testUpdate},
+		{"Space queries", testSpaceQueries},
+		{"Model membership", testModelMembership},
+		{"Effect decay", testEffectDecay},
+		{"Observations", testObservations},
+		{"Cell locks", testCellLocks},
+		{"Leases", testLeases},
+		{"Growth steps", testGrowthSteps},
+		{"Reset world", testResetWorld},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dal, cleanup := newDAL(t)
+			defer cleanup()
+			tt.test(t, dal)
+		})
+	}
+}
+
+func agent(id string, x uint32, y uint32, modelID string) envApi.Entity {
+	return envApi.Entity{Id: id, ClassID: envApi.Entity_AGENT, X: x, Y: y, Energy: 100, Health: 100, ModelID: modelID}
+}
+
+func create(t *testing.T, dal environment.DataAccessLayer, entities ...envApi.Entity) {
+	for _, e := range entities {
+		if err := dal.CreateEntity(e, true); err != nil {
+			t.Fatalf("CreateEntity(%v): unexpected error: %v", e.Id, err)
+		}
+	}
+}
+
+func ids(entities []*envApi.Entity) []string {
+	got := []string{}
+	for _, e := range entities {
+		got = append(got, e.Id)
+	}
+	sort.Strings(got)
+	return got
+}
+
+func testOccupancy(t *testing.T, dal environment.DataAccessLayer) {
+	e := agent("0", 3, 4, "MOCK-MODEL-ID")
+	create(t, dal, e)
+
+	occupied, got, content, err := dal.IsCellOccupied(3, 4)
+	if err != nil || !occupied {
+		t.Fatalf("expected cell to be occupied, got %v %v", occupied, err)
+	}
+	if !reflect.DeepEqual(got, &e) {
+		t.Errorf("got %v, want %v", got, &e)
+	}
+	if _, wantContent, _ := dal.GetEntity("0"); content != wantContent {
+		t.Errorf("expected the same content as GetEntity, got %q want %q", content, wantContent)
+	}
+
+	// Neighbours aren't occupied
+	for _, cell := range [][2]uint32{{2, 4}, {4, 4}, {3, 3}, {3, 5}, {4, 3}} {
+		if occupied, _, _, err := dal.IsCellOccupied(cell[0], cell[1]); occupied || err != nil {
+			t.Errorf("expected %v to be empty, got %v %v", cell, occupied, err)
+		}
+	}
+}
+
+func testGetAndDelete(t *testing.T, dal environment.DataAccessLayer) {
+	e := agent("0", 1, 1, "MOCK-MODEL-ID")
+	create(t, dal, e)
+
+	got, _, err := dal.GetEntity("0")
+	if err != nil || !reflect.DeepEqual(got, &e) {
+		t.Errorf("GetEntity() = %v %v, want %v", got, err, &e)
+	}
+	if _, _, err := dal.GetEntity("missing"); err == nil {
+		t.Errorf("expected an error getting a missing entity")
+	}
+
+	if deleted, err := dal.DeleteEntity("0"); err != nil || deleted != 1 {
+		t.Errorf("DeleteEntity() = %v %v, want 1", deleted, err)
+	}
+	if _, _, err := dal.GetEntity("0"); err == nil {
+		t.Errorf("expected the entity to be gone")
+	}
+	if occupied, _, _, _ := dal.IsCellOccupied(1, 1); occupied {
+		t.Errorf("expected the cell to be empty")
+	}
+	if _, err := dal.DeleteEntity("0"); err == nil {
+		t.Errorf("expected an error deleting a missing entity")
+	}
+}
+
+func testDuplicateIDs(t *testing.T, dal environment.DataAccessLayer) {
+	create(t, dal, agent("0", 1, 1, "A"))
+	if err := dal.CreateEntity(envApi.Entity{Id: "0", ClassID: envApi.Entity_FOOD, X: 2, Y: 2}, false); err != datacom.ErrEntityExists {
+		t.Errorf("got %v, want %v", err, datacom.ErrEntityExists)
+	}
+	if e, _, err := dal.GetEntity("0"); err != nil || e.X != 1 || e.Y != 1 {
+		t.Errorf("expected the first entity to stay, got %v, %v", e, err)
+	}
+	if occupied, _, _, _ := dal.IsCellOccupied(2, 2); occupied {
+		t.Errorf("expected the second entity's cell to stay empty")
+	}
+}
+
+func testUpdate(t *testing.T, dal environment.DataAccessLayer) {
+	create(t, dal, agent("0", 1, 1, "MOCK-MODEL-ID"))
+	_, content, _ := dal.GetEntity("0")
+
+	moved := agent("0", 2, 1, "MOCK-MODEL-ID")
+	moved.Energy = 90
+	if err := dal.UpdateEntity(content, moved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, newContent, _ := dal.GetEntity("0")
+	if !reflect.DeepEqual(got, &moved) {
+		t.Errorf("got %v, want %v", got, &moved)
+	}
+	if newContent == content {
+		t.Errorf("expected the content to change with the entity")
+	}
+
+	// Moving leaves the old cell empty
+	if occupied, _, _, _ := dal.IsCellOccupied(1, 1); occupied {
+		t.Errorf("expected the old cell to be empty")
+	}
+	if occupied, e, _, _ := dal.IsCellOccupied(2, 1); !occupied || e.Id != "0" {
+		t.Errorf("expected the entity in the new cell, got %v", e)
+	}
+	if entities, _ := dal.GetEntitiesInSpace(0, 0, 9, 9); len(entities) != 1 {
+		t.Errorf("expected one entity after the move, got %v", entities)
+	}
+
+	// Updating with stale content fails and changes nothing
+	if err := dal.UpdateEntity(content, agent("0", 3, 1, "MOCK-MODEL-ID")); err == nil {
+		t.Errorf("expected an error updating with stale content")
+	}
+	if got, _, _ := dal.GetEntity("0"); !reflect.DeepEqual(got, &moved) {
+		t.Errorf("expected a stale update to change nothing, got %v", got)
+	}
+}
+
+func testSpaceQueries(t *testing.T, dal environment.DataAccessLayer) {
+	create(t, dal,
+		agent("0", 0, 0, "A"),
+		agent("1", 9, 9, "A"),
+		agent("2", 10, 9, "A"),
+		agent("3", 9, 10, "A"),
+		agent("4", 5, 7, "A"),
+		agent("5", 99, 99, "A"),
+	)
+	// The default world is 100x100
+	if err := dal.CreateEntity(agent("6", 100, 0, "A"), false); err == nil {
+		t.Errorf("expected an error creating an entity off the map")
+	}
+
+	tests := []struct {
+		space [4]uint32
+		want  []string
+	}{
+		{[4]uint32{0, 0, 9, 9}, []string{"0", "1", "4"}},
+		{[4]uint32{0, 0, 99, 99}, []string{"0", "1", "2", "3", "4", "5"}},
+		{[4]uint32{0, 0, 500, 500}, []string{"0", "1", "2", "3", "4", "5"}},
+		{[4]uint32{5, 7, 5, 7}, []string{"4"}},
+		{[4]uint32{9, 9, 10, 10}, []string{"1", "2", "3"}},
+		{[4]uint32{1, 1, 4, 4}, []string{}},
+		{[4]uint32{10, 10, 98, 98}, []string{}},
+	}
+	for _, tt := range tests {
+		entities, err := dal.GetEntitiesInSpace(tt.space[0], tt.space[1], tt.space[2], tt.space[3])
+		if err != nil {
+			t.Errorf("GetEntitiesInSpace(%v): unexpected error: %v", tt.space, err)
+			continue
+		}
+		if got := ids(entities); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetEntitiesInSpace(%v) = %v, want %v", tt.space, got, tt.want)
+		}
+	}
+}
+
+func testModelMembership(t *testing.T, dal environment.DataAccessLayer) {
+	create(t, dal, agent("0", 1, 1, "A"), agent("1", 2, 2, "A"), agent("2", 3, 3, "B"))
+
+	modelIDs := func(modelID string) []string {
+		entities, err := dal.GetEntitiesForModel(modelID)
+		if err != nil {
+			t.Fatalf("GetEntitiesForModel(%v): unexpected error: %v", modelID, err)
+		}
+		got := []string{}
+		for _, e := range entities {
+			got = append(got, e.Id)
+		}
+		sort.Strings(got)
+		return got
+	}
+	if got := modelIDs("A"); !reflect.DeepEqual(got, []string{"0", "1"}) {
+		t.Errorf("got %v for model A, want [0 1]", got)
+	}
+	if got := modelIDs("missing"); len(got) != 0 {
+		t.Errorf("got %v for a missing model, want none", got)
+	}
+
+	// Model entities follow updates and deletes
+	_, content, _ := dal.GetEntity("1")
+	dal.UpdateEntity(content, agent("1", 4, 4, "A"))
+	entities, _ := dal.GetEntitiesForModel("A")
+	for _, e := range entities {
+		if e.Id == "1" && (e.X != 4 || e.Y != 4) {
+			t.Errorf("expected the model's entity to be updated, got %v", e)
+		}
+	}
+	dal.DeleteEntity("0")
+	if got := modelIDs("A"); !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("got %v for model A after delete, want [1]", got)
+	}
+}
+
+func testEffectDecay(t *testing.T, dal environment.DataAccessLayer) {
+	now := time.Now().Unix()
+	fresh := envApi.Effect{X: 1, Y: 1, ClassID: envApi.Effect_PHEROMONE, Value: 1, Decay: 1.2, DelThresh: 5, Timestamp: now}
+	decayed := envApi.Effect{X: 2, Y: 2, ClassID: envApi.Effect_PHEROMONE, Value: 2, Decay: 1.2, DelThresh: 5, Timestamp: now - 100}
+	outside := envApi.Effect{X: 20, Y: 20, ClassID: envApi.Effect_PHEROMONE, Value: 3, Decay: 1.2, DelThresh: 5, Timestamp: now}
+	for _, effect := range []envApi.Effect{fresh, decayed, outside} {
+		if err := dal.CreateEffect(effect); err != nil {
+			t.Fatalf("CreateEffect(): unexpected error: %v", err)
+		}
+	}
+
+	effects, err := dal.GetEffectsInSpace(0, 0, 9, 9)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(effects) != 1 || !reflect.DeepEqual(effects[0], &fresh) {
+		t.Errorf("GetEffectsInSpace() = %v, want only %v", effects, &fresh)
+	}
+
+	// Effects without a timestamp are stamped when created
+	dal.CreateEffect(envApi.Effect{X: 3, Y: 3, Decay: 1.2, DelThresh: 5})
+	effects, _ = dal.GetEffectsInSpace(3, 3, 3, 3)
+	if len(effects) != 1 || effects[0].Timestamp < now {
+		t.Errorf("expected a timestamped effect, got %v", effects)
+	}
+}
+
+func testObservations(t *testing.T, dal environment.DataAccessLayer) {
+	e := agent("0", 0, 0, "A")
+	create(t, dal, e, envApi.Entity{Id: "1", ClassID: envApi.Entity_FOOD, X: 1, Y: 0})
+	dal.CreateEffect(envApi.Effect{X: 0, Y: 1, ClassID: envApi.Effect_PHEROMONE, Value: 7, Decay: 1.2, DelThresh: 5})
+
+	obsv, err := dal.GetObservationForEntity(e)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obsv.Id != "0" || !obsv.IsAlive || obsv.Energy != e.Energy || obsv.Health != e.Health {
+		t.Errorf("unexpected observation header %v", obsv)
+	}
+
+	// Sight is every cell around the entity, top row first, without its own
+	sawFood := false
+	offMap := 0
+	for _, seen := range obsv.Sight {
+		if seen.Id == "1" {
+			sawFood = true
+		}
+		if seen.ClassID == 2 && seen.Id == "" {
+			offMap++
+		}
+	}
+	if !sawFood {
+		t.Errorf("expected to see the food next to the entity")
+	}
+	if offMap == 0 {
+		t.Errorf("expected cells off the map to be seen as rocks")
+	}
+	smelled := false
+	for _, smell := range obsv.Smell {
+		if smell.Value == 7 && smell.Strength > 0 {
+			smelled = true
+		}
+	}
+	if !smelled {
+		t.Errorf("expected to smell the effect next to the entity")
+	}
+
+	// Batched observations match one at a time, near and far apart
+	others := []envApi.Entity{agent("2", 3, 2, "A"), agent("3", 90, 95, "A")}
+	create(t, dal, others...)
+	entities := append([]envApi.Entity{e}, others...)
+	obsvs, err := dal.GetObservationsForEntities(entities)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(obsvs) != len(entities) {
+		t.Fatalf("got %v observations, want %v", len(obsvs), len(entities))
+	}
+	for i, entity := range entities {
+		want, _ := dal.GetObservationForEntity(entity)
+		if !reflect.DeepEqual(obsvs[i], want) {
+			t.Errorf("GetObservationsForEntities()[%v] = %v, want %v", i, obsvs[i], want)
+		}
+	}
+	if obsvs, err := dal.GetObservationsForEntities(nil); err != nil || len(obsvs) != 0 {
+		t.Errorf("expected no observations for no entities, got %v %v", obsvs, err)
+	}
+	if _, err := dal.GetObservationsForEntities([]envApi.Entity{agent("4", 999999, 0, "A")}); err == nil {
+		t.Errorf("expected an error for an invalid position")
+	}
+}
+
+func testCellLocks(t *testing.T, dal environment.DataAccessLayer) {
+	cells := []envApi.Position{{X: 1, Y: 1}, {X: 2, Y: 1}}
+	token, locked, err := dal.LockCells(cells)
+	if err != nil || !locked {
+		t.Fatalf("expected to lock cells, got locked=%v err=%v", locked, err)
+	}
+
+	// Overlapping cells can't be locked, and nothing is left half locked
+	if _, locked, _ = dal.LockCells([]envApi.Position{{X: 5, Y: 5}, {X: 2, Y: 1}}); locked {
+		t.Errorf("expected overlapping lock to fail")
+	}
+	if _, locked, _ = dal.LockCells([]envApi.Position{{X: 5, Y: 5}}); !locked {
+		t.Errorf("expected failed lock to release the cells it took")
+	}
+
+	// Only the holder can unlock
+	dal.UnlockCells("not-the-token", cells)
+	if _, locked, _ = dal.LockCells(cells); locked {
+		t.Errorf("expected cells to stay locked with the wrong token")
+	}
+	if err := dal.UnlockCells(token, cells); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, locked, _ = dal.LockCells(cells); !locked {
+		t.Errorf("expected cells to be unlocked")
+	}
+}
+
+func testLeases(t *testing.T, dal environment.DataAccessLayer) {
+	if held, err := dal.AcquireLease("growth", "a", time.Minute); err != nil || !held {
+		t.Fatalf("expected to take the lease, got held=%v err=%v", held, err)
+	}
+	if held, _ := dal.AcquireLease("growth", "a", time.Minute); !held {
+		t.Errorf("expected the holder to renew the lease")
+	}
+	if held, _ := dal.AcquireLease("growth", "b", time.Minute); held {
+		t.Errorf("expected the lease to stay with its holder")
+	}
+	if held, _ := dal.AcquireLease("other", "b", time.Minute); !held {
+		t.Errorf("expected leases to be independent")
+	}
+}
+
+func testGrowthSteps(t *testing.T, dal environment.DataAccessLayer) {
+	for want := uint64(0); want < 3; want++ {
+		if step, err := dal.NextGrowthStep(); err != nil || step != want {
+			t.Errorf("got step %v err=%v, want %v", step, err, want)
+		}
+	}
+}
+
+func testResetWorld(t *testing.T, dal environment.DataAccessLayer) {
+	create(t, dal, agent("0", 1, 1, "A"))
+	dal.CreateEffect(envApi.Effect{X: 1, Y: 1, Decay: 1.2, DelThresh: 5})
+
+	generation, err := dal.ResetWorld()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := dal.GetEntity("0"); err == nil {
+		t.Errorf("expected entities to be removed")
+	}
+	if entities, _ := dal.GetEntitiesForModel("A"); len(entities) != 0 {
+		t.Errorf("expected model entities to be removed, got %v", entities)
+	}
+	if effects, _ := dal.GetEffectsInSpace(0, 0, 9, 9); len(effects) != 0 {
+		t.Errorf("expected effects to be removed, got %v", effects)
+	}
+	if next, _ := dal.ResetWorld(); next != generation+1 {
+		t.Errorf("expected every reset to bump the generation, got %v then %v", generation, next)
+	}
+}
@@ -2,57 +2,82 @@ package environment
 
 import (
 	"context"
-	b64 "encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"strconv"
 	"sync"
 	"time"
 
-	"google.golang.org/grpc/metadata"
-
-	uuid "github.com/satori/go.uuid"
-
 	datacom "github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/terrain"
+	"github.com/terrariumai/simulation/pkg/worldgen"
 
 	"github.com/golang/protobuf/ptypes/empty"
+	uuid "github.com/satori/go.uuid"
 	collectiveApi "github.com/terrariumai/simulation/pkg/api/collective"
 	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	maxPosition            = 100
-	minPosition            = 0
-	regionSize             = 10
-	maxUserCreatedEntities = 5
-
-	agentLivingEnergyCost = 1
-	agentMoveEnergyCost   = 2
-	agentAttackEnergyCost = 5
-	agentEnergyGainOnEat  = 10
-	agentAttackDmg        = 10
-	startingEnergy        = 100
-	startingHealth        = 100
+	regionSize = 10
+
+	// Cell locks are retried until the timeout runs out
+	lockTimeout   = time.Second
+	lockRetryWait = 5 * time.Millisecond
+	// Times to chase an entity that moves while its cell is being locked
+	maxLockAttempts = 3
+	// Random cells to try before searching the whole world for one
+	maxRandomCellAttempts = 100
 )
 
+// position is a comparable x,y pair for tracking cells
+type position struct {
+	x uint32
+	y uint32
+}
+
 // toDoServiceServer is implementation of api.ToDoServiceServer proto interface
 type environmentServer struct {
 	// Environment the server is running in
 	env string
+	// World the server is running, its size and topology
+	worldID   string
+	worldSize datacom.WorldSize
+	topology  datacom.Topology
+	// Token services like the collective call with, only users can call
+	// when empty
+	serviceToken string
+	// Walls, water and slow ground, all ground when nil. Generated resets
+	// swap it out.
+	terrain  *terrain.Map
+	terrainM sync.RWMutex
+	// Generates every reset's terrain and food, resets keep the terrain and
+	// only place the food they are given when nil
+	generator *worldgen.Params
 	// Datacom
 	datacomDAL DataAccessLayer
-	// Mutex to ensure data safety
-	m sync.Mutex
-}
-
-// UserInfo is the struct that will parse the auth response
-type UserInfo struct {
-	Issuer string `json:"issuer"`
-	ID     string `json:"id"`
-	Email  string `json:"email"`
+	// Rules that balance the ecosystem
+	rules WorldRules
+	// Seeded randomness for the world, and the ids it hands out
+	src  *lockedSource
+	rand *rand.Rand
+	ids  IDGenerator
+	// Tick mode, actions are queued and resolved together every interval.
+	// Realtime when 0.
+	tickInterval time.Duration
+	tick         uint64
+	queued       map[string]*queuedAction
+	tickM        sync.Mutex
+	// Held while a tick is resolved, so a reset never lands in the middle
+	resolveM sync.Mutex
+	// Identifies this process when taking the growth lease
+	instanceID string
+	// Logs with the world on every line, nowhere without WithLogger
+	logger *zap.Logger
 }
 
 // DataAccessLayer interface for all data access, specificly plugs in from datacom
@@ -66,138 +91,232 @@ type DataAccessLayer interface {
 	GetEntity(id string) (*envApi.Entity, string, error)
 	GetEntitiesForModel(modelID string) ([]envApi.Entity, error)
 	GetObservationForEntity(entity envApi.Entity) (*collectiveApi.Observation, error)
+	GetObservationsForEntities(entities []envApi.Entity) ([]*collectiveApi.Observation, error)
 	GetEntitiesInSpace(x0 uint32, y0 uint32, x1 uint32, y1 uint32) ([]*envApi.Entity, error)
 	GetEffectsInSpace(x0 uint32, y0 uint32, x1 uint32, y1 uint32) ([]*envApi.Effect, error)
+	ResetWorld() (int64, error)
+	LockCells(cells []envApi.Position) (string, bool, error)
+	UnlockCells(token string, cells []envApi.Position) error
+	AcquireLease(name string, holder string, ttl time.Duration) (bool, error)
+	NextGrowthStep() (uint64, error)
 	// Firebase
 	GetRemoteModelMetadataBySecret(modelSecret string) (*datacom.RemoteModel, error)
 	GetRemoteModelMetadataByID(modelID string) (*datacom.RemoteModel, error)
 	UpdateRemoteModelMetadata(remoteModelMD *datacom.RemoteModel, connectCount int) error
 	AddEntityMetadataToFireabase(envApi.Entity) error
 	RemoveEntityMetadataFromFirebase(id string) error
+	// Terrain observations see, swapped out by generated resets
+	SetTerrain(m *terrain.Map) error
+	// Worlds
+	CreateWorld(worldID string, size datacom.WorldSize, topology datacom.Topology) error
+	ListWorlds() ([]datacom.World, error)
+	DeleteWorld(worldID string) error
+}
+
+// ServerOption configures optional parts of the environment server
+type ServerOption func(*environmentServer)
+
+// WithLogger sets the logger the server logs to
+func WithLogger(logger *zap.Logger) ServerOption {
+	return func(s *environmentServer) {
+		s.logger = logger
+	}
 }
 
-// NewEnvironmentServer creates simulation service
-func NewEnvironmentServer(env string, d DataAccessLayer) envApi.EnvironmentServer {
+// NewEnvironmentServer creates simulation service. Without WithSeed the world
+// is seeded from the clock. Entity ids come from the seed in training and
+// testing, anywhere else replicas and resets share a world and ids are random
+// uuids.
+func NewEnvironmentServer(env string, d DataAccessLayer, rules WorldRules, opts ...ServerOption) envApi.EnvironmentServer {
 	// initialize server
+	src := newLockedSource(time.Now().UnixNano())
+	r := rand.New(src)
+	var ids IDGenerator = UUIDGenerator{}
+	if env == "training" || env == "testing" {
+		ids = randIDGenerator{r}
+	}
 	s := &environmentServer{
 		env:        env,
+		worldID:    datacom.DefaultWorldID,
+		worldSize:  datacom.DefaultWorldSize,
+		topology:   datacom.Bounded,
 		datacomDAL: d,
+		rules:      rules,
+		src:        src,
+		rand:       r,
+		ids:        ids,
+		instanceID: uuid.Must(uuid.NewV4()).String(),
+		logger:     zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.logger = s.logger.With(zap.String("world", s.worldID))
+
+	// Start resolving ticks, food grows at the end of them
+	if s.tickInterval > 0 {
+		s.queued = make(map[string]*queuedAction)
+		go s.runTicks()
+	} else if s.rules.FoodCapacity > 0 {
+		go s.runGrowth()
 	}
 
 	return s
 }
 
-func randomPosition() (uint32, uint32) {
-	return uint32(rand.Intn(maxPosition)), uint32(rand.Intn(maxPosition))
+// randomPosition picks a random cell that can be stood on. Mostly walled in
+// worlds are searched for their open cells instead of guessing forever.
+func (s *environmentServer) randomPosition() (uint32, uint32, error) {
+	terrainMap := s.currentTerrain()
+	for i := 0; i < maxRandomCellAttempts; i++ {
+		x, y := uint32(s.rand.Intn(int(s.worldSize.Width))), uint32(s.rand.Intn(int(s.worldSize.Height)))
+		if terrainMap.At(x, y).Passable() {
+			return x, y, nil
+		}
+	}
+	open := []position{}
+	for y := uint32(0); y < s.worldSize.Height; y++ {
+		for x := uint32(0); x < s.worldSize.Width; x++ {
+			if terrainMap.At(x, y).Passable() {
+				open = append(open, position{x, y})
+			}
+		}
+	}
+	if len(open) == 0 {
+		err := errors.New("no cell in the world can be stood on")
+		s.logger.Error("picking a random cell", zap.Error(err))
+		return 0, 0, err
+	}
+	pos := open[s.rand.Intn(len(open))]
+	return pos.x, pos.y, nil
+}
+
+// onMap checks if a position is inside the world. Positions left of or below
+// the map turn into huge values, so they are caught too.
+func (s *environmentServer) onMap(x uint32, y uint32) bool {
+	return x < s.worldSize.Width && y < s.worldSize.Height
 }
 
 // Get data for an entity
 func (s *environmentServer) CreateEntity(ctx context.Context, req *envApi.CreateEntityRequest) (*envApi.CreateEntityResponse, error) {
-	// Lock the data, defer unlock until end of call
-	s.m.Lock()
-	defer s.m.Unlock()
-
-	// Get user info from metadata
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		err := errors.New("Incorrect or no headers were provided")
-		log.Printf("ERROR: %v\n", err)
+	// Find out who is calling, services create entities for any model
+	c, err := s.getCaller(ctx)
+	if err != nil {
 		return nil, err
 	}
-	userInfoHeader := md["x-endpoint-api-userinfo"]
-	sDec, _ := b64.StdEncoding.DecodeString(userInfoHeader[0])
-	userInfo := UserInfo{}
-	json.Unmarshal(sDec, &userInfo)
 
 	// Make sure the user has supplied data
 	if req.Entity == nil {
 		err := errors.New("entity not in request")
-		log.Printf("ERROR: %v\n", err)
+		s.logger.Warn("rejected entity", zap.Error(err))
 		return nil, err
 	}
 
 	// Validate entity class
 	if req.Entity.ClassID > 3 {
 		err := errors.New("invalid class")
-		log.Printf("ERROR: %v\n", err)
+		s.logger.Warn("rejected entity", zap.Error(err))
 		return nil, err
 	}
 
 	// Validate modelID
 	if len(req.Entity.ModelID) == 0 {
 		err := errors.New("missing model id")
-		log.Printf("ERROR: %v\n", err)
+		s.logger.Warn("rejected entity", zap.Error(err))
 		return nil, err
 	}
 	remoteModelMD, err := s.datacomDAL.GetRemoteModelMetadataByID(req.Entity.ModelID)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		s.logger.Error("getting remote model", zap.Error(err), zap.String("model", req.Entity.ModelID))
 		return nil, err
 	}
-	if remoteModelMD.OwnerUID != userInfo.ID {
+	if !c.service && remoteModelMD.OwnerUID != c.user.ID {
 		err := errors.New("you do not own that remote model")
-		log.Printf("Error validating modelID. Metadata owner=%s, but userinfo id=%s\n", remoteModelMD.OwnerUID, userInfo.ID)
+		s.logger.Warn("rejected entity for a model the user doesn't own", zap.String("model", remoteModelMD.ID), zap.String("owner", remoteModelMD.OwnerUID), zap.String("user", c.user.ID))
 		return nil, err
 	}
 	if remoteModelMD.ConnectCount == 0 {
 		err := errors.New("rm is offline")
-		log.Printf("ERROR: %v\n", err)
+		s.logger.Warn("rejected entity", zap.Error(err), zap.String("model", remoteModelMD.ID))
 		return nil, err
 	}
 
 	// Make sure user can't create more than limit
 	entities, err := s.datacomDAL.GetEntitiesForModel(remoteModelMD.ID)
 	if err != nil {
-		log.Printf("ERROR querying entities: %v\n", err)
+		s.logger.Error("querying entities", zap.Error(err), zap.String("model", remoteModelMD.ID))
 		return nil, err
 	}
-	if len(entities) >= maxUserCreatedEntities && s.env != "training" {
-		err := errors.New("you can only manually create 5 entities at a time")
+	if len(entities) >= int(s.rules.MaxUserCreatedEntities) && s.env != "training" {
+		err := fmt.Errorf("you can only manually create %v entities at a time", s.rules.MaxUserCreatedEntities)
 		return nil, err
 	}
 
 	// If invalid posiiton, create new random position in the range
-	if req.Entity.X < minPosition || req.Entity.Y < minPosition || req.Entity.X > maxPosition || req.Entity.Y > maxPosition {
-		x, y := randomPosition()
+	if !s.onMap(req.Entity.X, req.Entity.Y) {
+		x, y, err := s.randomPosition()
+		if err != nil {
+			return nil, err
+		}
 		req.Entity.X = x
 		req.Entity.Y = y
 	}
+	if !s.passable(req.Entity.X, req.Entity.Y) {
+		err := errors.New("can't create an entity on a wall or water")
+		s.logger.Warn("rejected entity", zap.Error(err), zap.String("model", remoteModelMD.ID))
+		return nil, err
+	}
+
+	// Lock the cell, defer unlock until end of call
+	unlock, err := s.lockCells(position{req.Entity.X, req.Entity.Y})
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	// Make sure the cell is not occupied
 	isCellOccupied, _, _, err := s.datacomDAL.IsCellOccupied(req.Entity.X, req.Entity.Y)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		s.logger.Error("checking cell", zap.Error(err), zap.Uint32("x", req.Entity.X), zap.Uint32("y", req.Entity.Y))
 		return nil, err
 	}
 	if isCellOccupied {
 		err := errors.New("cell is already occupied")
-		log.Printf("ERROR: %v\n", err)
+		s.logger.Warn("rejected entity", zap.Error(err), zap.String("model", remoteModelMD.ID))
 		return nil, err
 	}
 
 	// Create an id for the entity
-	newUUID, err := uuid.NewV4()
+	entityID, err := s.ids.NewID()
 	if err != nil {
 		err := errors.New("Error generating id")
-		log.Printf("ERROR CreateEntity(): %v\n", err)
+		s.logger.Error("generating id", zap.Error(err))
 		return nil, err
 	}
-	entityID := newUUID.String()
 	// Or... use given ID for testing
 	if s.env == "testing" {
 		entityID = req.Entity.Id
 	}
 
 	// Set values for the entity
-	req.Entity.OwnerUID = userInfo.ID
-	req.Entity.Energy = startingEnergy
-	req.Entity.Health = startingHealth
+	req.Entity.OwnerUID = remoteModelMD.OwnerUID
+	req.Entity.Energy = s.rules.StartingEnergy
+	req.Entity.Health = s.rules.StartingHealth
 	req.Entity.Id = entityID
 
 	// Add the entity to the environment
-	err = s.datacomDAL.CreateEntity(*req.Entity, true)
-	// Add metadata to firebase
-	err = s.datacomDAL.AddEntityMetadataToFireabase(*req.Entity)
+	if err := s.datacomDAL.CreateEntity(*req.Entity, true); err != nil {
+		s.logger.Error("creating entity", zap.Error(err), zap.String("entity", entityID))
+		return nil, err
+	}
+	// Add metadata to firebase, the entity goes again without it
+	if err := s.datacomDAL.AddEntityMetadataToFireabase(*req.Entity); err != nil {
+		s.logger.Error("adding entity metadata", zap.Error(err), zap.String("entity", entityID))
+		if _, err := s.datacomDAL.DeleteEntity(entityID); err != nil {
+			s.logger.Error("removing entity", zap.Error(err), zap.String("entity", entityID))
+		}
+		return nil, err
+	}
 
 	// Return the data for the agent
 	return &envApi.CreateEntityResponse{
@@ -207,14 +326,10 @@ func (s *environmentServer) CreateEntity(ctx context.Context, req *envApi.Create
 
 // Get data for an entity
 func (s *environmentServer) GetEntity(ctx context.Context, req *envApi.GetEntityRequest) (*envApi.GetEntityResponse, error) {
-	// Lock the data, defer unlock until end of call
-	s.m.Lock()
-	defer s.m.Unlock()
-
 	// Get the entity
 	entity, _, err := s.datacomDAL.GetEntity(req.Id)
 	if err != nil {
-		fmt.Printf("ERROR: %v\n", err)
+		s.logger.Error("getting entity", zap.Error(err), zap.String("entity", req.Id))
 		return nil, err
 	}
 
@@ -226,20 +341,29 @@ func (s *environmentServer) GetEntity(ctx context.Context, req *envApi.GetEntity
 
 // Get data for an entity
 func (s *environmentServer) DeleteEntity(ctx context.Context, req *envApi.DeleteEntityRequest) (*envApi.DeleteEntityResponse, error) {
-	// Lock the data, defer unlock until end of call
-	s.m.Lock()
-	defer s.m.Unlock()
+	// Lock the entity's cell, defer unlock until end of call
+	entity, _, unlock, err := s.getEntityLocked(req.Id, nil)
+	if err != nil {
+		s.logger.Error("getting entity", zap.Error(err), zap.String("entity", req.Id))
+		return nil, err
+	}
+	defer unlock()
+
+	// Only the entity's owner can delete it
+	if err := s.authorizeEntity(ctx, entity); err != nil {
+		return nil, err
+	}
 
 	// Remove the entity from the environment
 	deleted, err := s.datacomDAL.DeleteEntity(req.Id)
 	if err != nil {
-		log.Printf("ERROR: %v", err)
+		s.logger.Error("deleting entity", zap.Error(err), zap.String("entity", req.Id))
 		return nil, err
 	}
 	// Remove entity from firebase
 	err = s.datacomDAL.RemoveEntityMetadataFromFirebase(req.Id)
 	if err != nil {
-		log.Printf("ERROR: %v", err)
+		s.logger.Error("removing entity metadata", zap.Error(err), zap.String("entity", req.Id))
 		return nil, err
 	}
 
@@ -249,55 +373,216 @@ func (s *environmentServer) DeleteEntity(ctx context.Context, req *envApi.Delete
 	}, nil
 }
 
+// targetCell returns the cell an agent is facing in the given direction. In a
+// torus world it wraps around the edges.
+func (s *environmentServer) targetCell(e *envApi.Entity, direction envApi.ExecuteAgentActionRequest_Direction) (uint32, uint32) {
+	var targetX, targetY = int64(e.X), int64(e.Y)
+	switch direction {
+	case envApi.ExecuteAgentActionRequest_UP: // UP
+		targetY++
+	case envApi.ExecuteAgentActionRequest_DOWN: // DOWN
+		targetY--
+	case envApi.ExecuteAgentActionRequest_LEFT: // LEFT
+		targetX--
+	case envApi.ExecuteAgentActionRequest_RIGHT: // RIGHT
+		targetX++
+	}
+	if s.topology == datacom.Torus {
+		w, h := int64(s.worldSize.Width), int64(s.worldSize.Height)
+		return uint32((targetX%w + w) % w), uint32((targetY%h + h) % h)
+	}
+	return uint32(targetX), uint32(targetY)
+}
+
+// spendEnergy takes the cost out of an entity's energy, and out of its health
+// once the energy runs dry. Returns false if the entity died paying.
+func spendEnergy(e *envApi.Entity, cost uint32) bool {
+	if e.Energy >= cost {
+		e.Energy -= cost
+		return true
+	}
+	diff := cost - e.Energy
+	if e.Health >= diff {
+		e.Health -= diff
+		return true
+	}
+	return false
+}
+
+// leavePheromone drops the scent of an entity's model on the cell it is in
+func (s *environmentServer) leavePheromone(entity *envApi.Entity) {
+	// Calculate scent
+	// TODO: This is temporary and should probably be replaced with a generated number
+	// on model creation
+	scentString := ""
+	added := 0 // limit on how many numbers to add
+	for i := 0; i < len(entity.ModelID); i++ {
+		if added == 5 {
+			break
+		}
+		i, err := strconv.ParseInt("0x"+string(entity.ModelID[i]), 0, 32)
+		if err == nil {
+			scentString += strconv.Itoa(int(i))
+			added++
+		}
+	}
+	scentNum, _ := strconv.ParseInt(scentString, 0, 32)
+
+	// Create pheromone effect
+	s.datacomDAL.CreateEffect(envApi.Effect{
+		X:         entity.X,
+		Y:         entity.Y,
+		ClassID:   envApi.Effect_Class(1),
+		Value:     uint32(scentNum),
+		Decay:     s.rules.PheromoneDecay,
+		DelThresh: s.rules.PheromoneDelThresh,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// spawnRandomFood places a food entity in a random empty cell
+func (s *environmentServer) spawnRandomFood(shouldPublish bool) error {
+	// Create an id for the entity
+	entityID, err := s.ids.NewID()
+	if err != nil {
+		err := errors.New("Error generating id")
+		s.logger.Error("generating id", zap.Error(err))
+		return err
+	}
+	// Random cells are nearly always free, a crowded world is searched for
+	// the cells that are and each is tried once
+	for i := 0; i < maxRandomCellAttempts; i++ {
+		x, y, err := s.randomPosition()
+		if err != nil {
+			return err
+		}
+		e := envApi.Entity{Id: entityID, ClassID: envApi.Entity_FOOD, X: x, Y: y}
+		placed, err := s.spawnEntityAt(e, shouldPublish)
+		if placed || err != nil {
+			return err
+		}
+	}
+	free, err := s.freeCells()
+	if err != nil {
+		return err
+	}
+	for len(free) > 0 {
+		j := s.rand.Intn(len(free))
+		pos := free[j]
+		free[j] = free[len(free)-1]
+		free = free[:len(free)-1]
+		e := envApi.Entity{Id: entityID, ClassID: envApi.Entity_FOOD, X: pos.x, Y: pos.y}
+		placed, err := s.spawnEntityAt(e, shouldPublish)
+		if placed || err != nil {
+			return err
+		}
+	}
+	err = errors.New("no free cell for food")
+	s.logger.Warn("spawning food", zap.Error(err))
+	return err
+}
+
+// freeCells lists the cells that can be stood on and have nothing in them
+func (s *environmentServer) freeCells() ([]position, error) {
+	entities, err := s.datacomDAL.GetEntitiesInSpace(0, 0, s.worldSize.Width-1, s.worldSize.Height-1)
+	if err != nil {
+		s.logger.Error("getting entities", zap.Error(err))
+		return nil, err
+	}
+	occupied := make(map[position]bool, len(entities))
+	for _, e := range entities {
+		occupied[position{e.X, e.Y}] = true
+	}
+	free := []position{}
+	for y := uint32(0); y < s.worldSize.Height; y++ {
+		for x := uint32(0); x < s.worldSize.Width; x++ {
+			if s.passable(x, y) && !occupied[position{x, y}] {
+				free = append(free, position{x, y})
+			}
+		}
+	}
+	return free, nil
+}
+
+// spawnEntityAt places an entity in its cell, unless the cell is taken or
+// someone else is using it
+func (s *environmentServer) spawnEntityAt(e envApi.Entity, shouldPublish bool) (bool, error) {
+	// Move on if someone else is using the cell
+	unlock, err := s.tryLockCells(position{e.X, e.Y})
+	if err != nil {
+		return false, err
+	}
+	if unlock == nil {
+		return false, nil
+	}
+	defer unlock()
+	isCellOccupied, _, _, err := s.datacomDAL.IsCellOccupied(e.X, e.Y)
+	if err != nil || isCellOccupied {
+		return false, nil
+	}
+	if err := s.datacomDAL.CreateEntity(e, shouldPublish); err != nil {
+		s.logger.Error("creating entity", zap.Error(err), zap.String("entity", e.Id))
+		return false, err
+	}
+	return true, nil
+}
+
 // Get data for an entity
 func (s *environmentServer) ExecuteAgentAction(ctx context.Context, req *envApi.ExecuteAgentActionRequest) (*envApi.ExecuteAgentActionResponse, error) {
-	// Lock the data, defer unlock until end of call
-	s.m.Lock()
-	defer s.m.Unlock()
+	// In tick mode the action waits to be resolved with everyone else's, once
+	// the caller is known to own the agent
+	if s.tickInterval > 0 {
+		if err := s.authorizeEntityID(ctx, req.Id); err != nil {
+			return nil, err
+		}
+		return s.queueAction(ctx, req)
+	}
 
-	// Get the entity
-	entity, origionalContent, err := s.datacomDAL.GetEntity(req.Id)
+	// Get the entity, locking it and the cell it is facing until the end of call
+	entity, origionalContent, unlock, err := s.getEntityLocked(req.Id, func(e *envApi.Entity) []position {
+		if req.Action == envApi.ExecuteAgentActionRequest_WAIT {
+			return nil
+		}
+		x, y := s.targetCell(e, req.Direction)
+		return []position{{x, y}}
+	})
 	if err != nil {
 		// Note: returning an error here seems correct, but completely stops a model if an agent was deleted
 		// mid session.
 		// An error here essentially means the agent was removed manually.
 		return nil, err
 	}
+	defer unlock()
 
-	var targetX, targetY = entity.X, entity.Y
-	switch req.Direction {
-	case envApi.ExecuteAgentActionRequest_UP: // UP
-		targetY++
-	case envApi.ExecuteAgentActionRequest_DOWN: // DOWN
-		targetY--
-	case envApi.ExecuteAgentActionRequest_LEFT: // LEFT
-		targetX--
-	case envApi.ExecuteAgentActionRequest_RIGHT: // RIGHT
-		targetX++
+	// Only the agent's owner can drive it
+	if err := s.authorizeEntity(ctx, entity); err != nil {
+		return nil, err
 	}
 
+	targetX, targetY := s.targetCell(entity, req.Direction)
+
 	// Living energy cost
-	// Note: we will handle negative energy as overflow later
-	if entity.Energy >= agentLivingEnergyCost {
-		entity.Energy -= agentLivingEnergyCost
-	} else {
-		diff := agentLivingEnergyCost - entity.Energy
-		if entity.Health >= diff {
-			entity.Health -= diff
-		} else {
-			// KILL
-			s.datacomDAL.DeleteEntity(entity.Id)
-			s.datacomDAL.RemoveEntityMetadataFromFirebase(entity.Id)
-			return &envApi.ExecuteAgentActionResponse{
-				Value: envApi.ExecuteAgentActionResponse_ERR_DIED,
-			}, nil
-		}
+	if !spendEnergy(entity, s.rules.LivingEnergyCost) {
+		// KILL
+		s.datacomDAL.DeleteEntity(entity.Id)
+		s.datacomDAL.RemoveEntityMetadataFromFirebase(entity.Id)
+		return &envApi.ExecuteAgentActionResponse{
+			Value: envApi.ExecuteAgentActionResponse_ERR_DIED,
+		}, nil
+	}
+
+	// Nothing can be done to a cell off the map
+	if req.Action != envApi.ExecuteAgentActionRequest_WAIT && !s.onMap(targetX, targetY) {
+		return &envApi.ExecuteAgentActionResponse{
+			Value: envApi.ExecuteAgentActionResponse_ERR_INVALID_TARGET,
+		}, nil
 	}
 
 	switch req.Action {
 	case 0: // REST
 	case 1: // MOVE
-		if targetX < minPosition || targetY < minPosition {
+		// Walls and water can't be walked into
+		if !s.passable(targetX, targetY) {
 			return &envApi.ExecuteAgentActionResponse{
 				Value: envApi.ExecuteAgentActionResponse_ERR_INVALID_TARGET,
 			}, nil
@@ -310,50 +595,18 @@ func (s *environmentServer) ExecuteAgentAction(ctx context.Context, req *envApi.
 				Value: envApi.ExecuteAgentActionResponse_ERR_INVALID_TARGET,
 			}, nil
 		}
-		// Adjust energy
-		if entity.Energy >= agentMoveEnergyCost {
-			entity.Energy -= agentMoveEnergyCost
-		} else {
-			diff := agentMoveEnergyCost - entity.Energy
-			if entity.Health >= diff {
-				entity.Health -= diff
-			} else {
-				// KILL
-				s.datacomDAL.DeleteEntity(entity.Id)
-				s.datacomDAL.RemoveEntityMetadataFromFirebase(entity.Id)
-				return &envApi.ExecuteAgentActionResponse{
-					Value: envApi.ExecuteAgentActionResponse_ERR_DIED,
-				}, nil
-			}
+		// Adjust energy, slow ground costs extra
+		if !spendEnergy(entity, s.moveEnergyCost(targetX, targetY)) {
+			// KILL
+			s.datacomDAL.DeleteEntity(entity.Id)
+			s.datacomDAL.RemoveEntityMetadataFromFirebase(entity.Id)
+			return &envApi.ExecuteAgentActionResponse{
+				Value: envApi.ExecuteAgentActionResponse_ERR_DIED,
+			}, nil
 		}
 
-		// Calculate scent
-		// TODO: This is temporary and should probably be replaced with a generated number
-		// on model creation
-		scentString := ""
-		added := 0 // limit on how many numbers to add
-		for i := 0; i < len(entity.ModelID); i++ {
-			if added == 5 {
-				break
-			}
-			i, err := strconv.ParseInt("0x"+string(entity.ModelID[i]), 0, 32)
-			if err == nil {
-				scentString += strconv.Itoa(int(i))
-				added++
-			}
-		}
-		scentNum, _ := strconv.ParseInt(scentString, 0, 32)
-
-		// Create pheromone effect
-		s.datacomDAL.CreateEffect(envApi.Effect{
-			X:         entity.X,
-			Y:         entity.Y,
-			ClassID:   envApi.Effect_Class(1),
-			Value:     uint32(scentNum),
-			Decay:     1.2,
-			DelThresh: 5,
-			Timestamp: time.Now().Unix(),
-		})
+		// Leave a trail behind
+		s.leavePheromone(entity)
 		// Finally, adjust the position
 		entity.X = targetX
 		entity.Y = targetY
@@ -372,32 +625,15 @@ func (s *environmentServer) ExecuteAgentAction(ctx context.Context, req *envApi.
 			}, nil
 		}
 		// Update entity
-		entity.Energy += agentEnergyGainOnEat
+		entity.Energy += s.rules.EnergyGainOnEat
 		// Delete food
 		s.datacomDAL.DeleteEntity(other.Id)
-		// Spawn another random food entity (loop to ensure another is spawned)
-		// this artificially keeps the ecosystem in check
-		for {
-			// Create an id for the entity
-			newUUID, err := uuid.NewV4()
-			if err != nil {
-				err := errors.New("Error generating id")
-				log.Printf("ERROR CreateEntity(): %v\n", err)
-				return nil, err
-			}
-			entityID := newUUID.String()
-			// Create entity
-			x, y := randomPosition()
-			e := envApi.Entity{
-				Id:      entityID,
-				ClassID: 3,
-				X:       x,
-				Y:       y,
-			}
-			// Create entity silently (no publish)
-			err = s.datacomDAL.CreateEntity(e, true)
-			if err == nil {
-				break
+		// Without regrowth another random food entity is spawned, this
+		// artificially keeps the ecosystem in check. A full world goes
+		// without, the agent still ate.
+		if s.rules.FoodCapacity == 0 {
+			if err := s.spawnRandomFood(true); err != nil {
+				s.logger.Error("spawning food", zap.Error(err))
 			}
 		}
 	case 3: // ATTACK
@@ -416,28 +652,21 @@ func (s *environmentServer) ExecuteAgentAction(ctx context.Context, req *envApi.
 			}, nil
 		}
 		// Update this entity's energy
-		if entity.Energy >= agentAttackEnergyCost {
-			entity.Energy -= agentAttackEnergyCost
-		} else {
-			diff := agentAttackEnergyCost - entity.Energy
-			if entity.Health >= diff {
-				entity.Health -= diff
-			} else {
-				// KILL
-				s.datacomDAL.DeleteEntity(entity.Id)
-				s.datacomDAL.RemoveEntityMetadataFromFirebase(entity.Id)
-				return &envApi.ExecuteAgentActionResponse{
-					Value: envApi.ExecuteAgentActionResponse_ERR_DIED,
-				}, nil
-			}
+		if !spendEnergy(entity, s.rules.AttackEnergyCost) {
+			// KILL
+			s.datacomDAL.DeleteEntity(entity.Id)
+			s.datacomDAL.RemoveEntityMetadataFromFirebase(entity.Id)
+			return &envApi.ExecuteAgentActionResponse{
+				Value: envApi.ExecuteAgentActionResponse_ERR_DIED,
+			}, nil
 		}
 		// Update other entity's health
-		if other.Health > agentAttackDmg {
-			other.Health -= agentAttackDmg
+		if other.Health > s.rules.AttackDamage {
+			other.Health -= s.rules.AttackDamage
 			// Update the entity
 			err = s.datacomDAL.UpdateEntity(otherOrigionalContent, *other)
 			if err != nil {
-				fmt.Printf("ERROR: %v\n", err)
+				s.logger.Error("updating entity", zap.Error(err), zap.String("entity", other.Id))
 			}
 		} else {
 			// KILL
@@ -463,66 +692,149 @@ func (s *environmentServer) ExecuteAgentAction(ctx context.Context, req *envApi.
 	}, nil
 }
 
-func (s *environmentServer) ResetWorld(ctx context.Context, req *empty.Empty) (*empty.Empty, error) {
-	// Lock the data, defer unlock until end of call
-	s.m.Lock()
-	defer s.m.Unlock()
+// ResetWorld wipes every entity and effect from the world, then repopulates
+// food from the given layout and seed. With a generator the terrain and food
+// patches are generated from the seed too.
+func (s *environmentServer) ResetWorld(ctx context.Context, req *envApi.ResetWorldRequest) (*envApi.ResetWorldResponse, error) {
+	// Note: the whole world is wiped at once so no cells are locked, an action
+	// landing mid reset can leave an entity behind in the new generation
+
+	// Generate the new layout first, the food is checked against its terrain
+	terrainMap := s.currentTerrain()
+	var generated *worldgen.World
+	if params := s.generatorParams(req.Generator); params != nil {
+		// Generated terrain isn't stored, only this process and its datacom
+		// see it. Other replicas and the collective would keep the old map.
+		if s.env != "training" && s.env != "testing" {
+			err := status.Error(codes.FailedPrecondition, "generated resets only work in training, every service of a live world loads the same terrain file")
+			s.logger.Warn("rejected reset", zap.Error(err))
+			return nil, err
+		}
+		var err error
+		generated, err = worldgen.Generate(req.Seed, *params)
+		if err != nil {
+			s.logger.Warn("rejected reset", zap.Error(err))
+			return nil, err
+		}
+		terrainMap = generated.Terrain
+	}
 
-	// Return
-	return &empty.Empty{}, nil
-}
+	// Validate the layout before touching anything
+	occupied := make(map[position]bool)
+	positions := make([]position, 0, len(req.Food)+int(req.FoodCount))
+	for _, p := range req.Food {
+		pos := position{p.X, p.Y}
+		if !s.onMap(pos.x, pos.y) || !terrainMap.At(pos.x, pos.y).Passable() {
+			err := fmt.Errorf("invalid food position %v,%v", pos.x, pos.y)
+			s.logger.Warn("rejected reset", zap.Error(err))
+			return nil, err
+		}
+		if occupied[pos] {
+			err := fmt.Errorf("duplicate food position %v,%v", pos.x, pos.y)
+			s.logger.Warn("rejected reset", zap.Error(err))
+			return nil, err
+		}
+		occupied[pos] = true
+		positions = append(positions, pos)
+	}
+	if generated != nil {
+		for _, p := range generated.Food {
+			pos := position{p.X, p.Y}
+			if !occupied[pos] {
+				occupied[pos] = true
+				positions = append(positions, pos)
+			}
+		}
+	}
+	if uint64(len(positions))+uint64(req.FoodCount) > s.openCells(terrainMap) {
+		err := errors.New("too much food for the world")
+		s.logger.Warn("rejected reset", zap.Error(err))
+		return nil, err
+	}
 
-func (s *environmentServer) SpawnFood(ctx context.Context, req *empty.Empty) (*empty.Empty, error) {
-	// Lock the data, defer unlock until end of call
-	s.m.Lock()
-	defer s.m.Unlock()
+	// No tick resolves while the world is swapped out, and actions queued
+	// for the old world are dropped
+	s.resolveM.Lock()
+	defer s.resolveM.Unlock()
+	s.dropQueuedActions()
 
-	// Get user info from metadata
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		err := errors.New("Incorrect or no headers were provided")
-		log.Printf("ERROR: %v\n", err)
+	// Entities with a model have metadata in firebase, it goes with them
+	previous, err := s.datacomDAL.GetEntitiesInSpace(0, 0, s.worldSize.Width-1, s.worldSize.Height-1)
+	if err != nil {
+		s.logger.Error("getting entities", zap.Error(err))
 		return nil, err
 	}
-	userInfoHeader := md["x-endpoint-api-userinfo"]
-	sDec, _ := b64.StdEncoding.DecodeString(userInfoHeader[0])
-	userInfo := UserInfo{}
-	json.Unmarshal(sDec, &userInfo)
-	if userInfo.Email != "zacharyholland@gmail.com" {
-		err := errors.New("must be zac to perform this action")
-		log.Printf("ERROR: %v\n", err)
+
+	// Wipe the world
+	generationID, err := s.datacomDAL.ResetWorld()
+	if err != nil {
+		s.logger.Error("wiping world", zap.Error(err))
 		return nil, err
 	}
+	for _, e := range previous {
+		if len(e.ModelID) == 0 {
+			continue
+		}
+		if err := s.datacomDAL.RemoveEntityMetadataFromFirebase(e.Id); err != nil {
+			s.logger.Error("removing entity metadata", zap.Error(err), zap.String("entity", e.Id))
+		}
+	}
+
+	// Observations and moves see the generated terrain from now on
+	if generated != nil {
+		if err := s.datacomDAL.SetTerrain(generated.Terrain); err != nil {
+			s.logger.Error("setting terrain", zap.Error(err))
+			return nil, err
+		}
+		s.setTerrain(generated.Terrain)
+	}
 
-	foodCount := 500
-	for i := 0; i < foodCount; i++ {
+	// Reseed the world so the new generation plays out the same every time
+	s.src.Seed(req.Seed)
+
+	// The explicit layout and generated patches go first, then fill up with
+	// seeded random food
+	for i := uint32(0); i < req.FoodCount; {
+		x, y, err := s.randomPosition()
+		if err != nil {
+			return nil, err
+		}
+		pos := position{x, y}
+		if occupied[pos] {
+			continue
+		}
+		occupied[pos] = true
+		positions = append(positions, pos)
+		i++
+	}
+	for _, pos := range positions {
 		// Create an id for the entity
-		newUUID, err := uuid.NewV4()
+		entityID, err := s.ids.NewID()
 		if err != nil {
 			err := errors.New("Error generating id")
-			log.Printf("ERROR CreateEntity(): %v\n", err)
+			s.logger.Error("generating id", zap.Error(err))
 			return nil, err
 		}
-		entityID := newUUID.String()
-		// Create entity
 		e := envApi.Entity{
 			Id:      entityID,
-			ClassID: 3,
-			X:       uint32(rand.Intn(100)),
-			Y:       uint32(rand.Intn(100)),
+			ClassID: envApi.Entity_FOOD,
+			X:       pos.x,
+			Y:       pos.y,
 		}
 		// Create entity silently (no publish)
-		s.datacomDAL.CreateEntity(e, false)
+		if err := s.datacomDAL.CreateEntity(e, false); err != nil {
+			s.logger.Error("creating food", zap.Error(err), zap.String("entity", e.Id))
+			return nil, err
+		}
 	}
 
 	// Return
-	return &empty.Empty{}, nil
+	return &envApi.ResetWorldResponse{
+		GenerationID: generationID,
+	}, nil
 }
 
 func (s *environmentServer) GetEntitiesInRegion(ctx context.Context, req *envApi.GetEntitiesInRegionRequest) (*envApi.GetEntitiesInRegionResponse, error) {
-	// Lock the data, defer unlock until end of call
-	s.m.Lock()
-	defer s.m.Unlock()
 	entities := []*envApi.Entity{}
 
 	x0 := req.X * regionSize
@@ -532,7 +844,7 @@ func (s *environmentServer) GetEntitiesInRegion(ctx context.Context, req *envApi
 
 	entities, err := s.datacomDAL.GetEntitiesInSpace(x0, y0, x1, y1)
 	if err != nil {
-		log.Printf("ERROR: %v", err)
+		s.logger.Error("getting entities in region", zap.Error(err))
 		return nil, err
 	}
 
@@ -542,9 +854,6 @@ func (s *environmentServer) GetEntitiesInRegion(ctx context.Context, req *envApi
 }
 
 func (s *environmentServer) GetEffectsInRegion(ctx context.Context, req *envApi.GetEffectsInRegionRequest) (*envApi.GetEffectsInRegionResponse, error) {
-	// Lock the data, defer unlock until end of call
-	s.m.Lock()
-	defer s.m.Unlock()
 	effects := []*envApi.Effect{}
 
 	x0 := req.X * regionSize
@@ -554,7 +863,7 @@ func (s *environmentServer) GetEffectsInRegion(ctx context.Context, req *envApi.
 
 	effects, err := s.datacomDAL.GetEffectsInSpace(x0, y0, x1, y1)
 	if err != nil {
-		log.Printf("ERROR: %v", err)
+		s.logger.Error("getting effects in region", zap.Error(err))
 		return nil, err
 	}
 
@@ -562,3 +871,8 @@ func (s *environmentServer) GetEffectsInRegion(ctx context.Context, req *envApi.
 		Effects: effects,
 	}, nil
 }
+
+// GetRules returns the rules the world is running with
+func (s *environmentServer) GetRules(ctx context.Context, req *empty.Empty) (*envApi.WorldRules, error) {
+	return s.rules.toProto(), nil
+}
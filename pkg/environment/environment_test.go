@@ -2,7 +2,6 @@ package environment
 
 import (
 	"context"
-	b64 "encoding/base64"
 	"errors"
 	"reflect"
 	"testing"
@@ -13,9 +12,13 @@ import (
 	"github.com/alicebob/miniredis/v2"
 	"github.com/golang/protobuf/ptypes/empty"
 	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/auth"
 	datacom "github.com/terrariumai/simulation/pkg/datacom"
 	"github.com/terrariumai/simulation/pkg/environment/mocks"
+	"github.com/terrariumai/simulation/pkg/terrain"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type mockFuncCall struct {
@@ -30,11 +33,8 @@ type numOfCallsAssertion struct {
 }
 
 func setup() (context.Context, *miniredis.Miniredis) {
-	// Context setup
-	userinfoJSONString := "{\"id\":\"MOCK-UID\"}"
-	userinfoEnc := b64.StdEncoding.EncodeToString([]byte(userinfoJSONString))
-	md := metadata.Pairs("x-endpoint-api-userinfo", userinfoEnc)
-	ctxValidUserInfo := metadata.NewIncomingContext(context.Background(), md)
+	// Context setup, as the auth interceptor passes the user on
+	ctxValidUserInfo := auth.NewUserContext(context.Background(), &auth.UserInfo{ID: "MOCK-UID"})
 	// Redis Setup
 	redisServer, err := miniredis.Run()
 	if err != nil {
@@ -47,6 +47,18 @@ func teardown(redisServer *miniredis.Miniredis) {
 	redisServer.Close()
 }
 
+// mockCellLocks lets every cell lock through, add it after the test's own calls
+func mockCellLocks(mockDAL *mocks.DataAccessLayer) {
+	mockDAL.On("LockCells", mock.Anything).Return("MOCK-TOKEN", true, nil)
+	mockDAL.On("UnlockCells", "MOCK-TOKEN", mock.Anything).Return(nil)
+}
+
+// mockEmptyWorld has the world hold nothing before it is reset, add it after
+// the test's own calls
+func mockEmptyWorld(mockDAL *mocks.DataAccessLayer) {
+	mockDAL.On("GetEntitiesInSpace", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*envApi.Entity{}, nil)
+}
+
 func TestCreateEntity(t *testing.T) {
 	ctx, redisServer := setup()
 	defer teardown(redisServer)
@@ -150,6 +162,26 @@ func TestCreateEntity(t *testing.T) {
 			},
 			wantErr: errors.New("rm is offline"),
 		},
+		{
+			name: "Services can create for any model",
+			args: args{
+				ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs(auth.ServiceTokenHeader, "MOCK-TOKEN")),
+				req: &envApi.CreateEntityRequest{
+					Entity: &envApi.Entity{
+						ModelID: "mock-model-id",
+					},
+				},
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{ // Get the metadata for the RM
+					name: "GetRemoteModelMetadataByID",
+					args: []interface{}{"mock-model-id"},
+					resp: []interface{}{&datacom.RemoteModel{ID: "mock-model-id", OwnerUID: "OTHER-UID", ConnectCount: 0}, nil},
+				},
+			},
+			// Past the owner check
+			wantErr: errors.New("rm is offline"),
+		},
 		{
 			name: "Cannot create more than 5 entities manually",
 			args: args{
@@ -240,6 +272,94 @@ func TestCreateEntity(t *testing.T) {
 			},
 			wantErr: errors.New("cell is already occupied"),
 		},
+		{
+			name: "Fails if the datacom can't create the entity",
+			args: args{
+				ctx: ctx,
+				req: &envApi.CreateEntityRequest{
+					Entity: &envApi.Entity{
+						ModelID:  "mock-model-id",
+						OwnerUID: "MOCK-UID",
+						X:        25,
+						Y:        25,
+						Id:       "0",
+						ClassID:  1,
+					},
+				},
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{ // Get the metadata for the RM
+					name: "GetRemoteModelMetadataByID",
+					args: []interface{}{"mock-model-id"},
+					resp: []interface{}{&datacom.RemoteModel{ID: "mock-model-id", OwnerUID: "MOCK-UID", ConnectCount: 1}, nil},
+				},
+				{ // Get entities for the RM
+					name: "GetEntitiesForModel",
+					args: []interface{}{"mock-model-id"},
+					resp: []interface{}{[]envApi.Entity{}, nil},
+				},
+				{ // Check if the cell is occupied in the target position
+					name: "IsCellOccupied",
+					args: []interface{}{uint32(25), uint32(25)},
+					resp: []interface{}{false, nil, "", nil},
+				},
+				{ // Create the entity
+					name: "CreateEntity",
+					args: []interface{}{envApi.Entity{Id: "0", ClassID: 1, X: uint32(25), Y: uint32(25), Energy: uint32(100), Health: uint32(100), OwnerUID: "MOCK-UID", ModelID: "mock-model-id"}, true},
+					resp: []interface{}{datacom.ErrEntityExists},
+				},
+			},
+			wantErr: datacom.ErrEntityExists,
+		},
+		{
+			name: "Removes the entity if its metadata can't be stored",
+			args: args{
+				ctx: ctx,
+				req: &envApi.CreateEntityRequest{
+					Entity: &envApi.Entity{
+						ModelID:  "mock-model-id",
+						OwnerUID: "MOCK-UID",
+						X:        25,
+						Y:        25,
+						Id:       "0",
+						ClassID:  1,
+					},
+				},
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{ // Get the metadata for the RM
+					name: "GetRemoteModelMetadataByID",
+					args: []interface{}{"mock-model-id"},
+					resp: []interface{}{&datacom.RemoteModel{ID: "mock-model-id", OwnerUID: "MOCK-UID", ConnectCount: 1}, nil},
+				},
+				{ // Get entities for the RM
+					name: "GetEntitiesForModel",
+					args: []interface{}{"mock-model-id"},
+					resp: []interface{}{[]envApi.Entity{}, nil},
+				},
+				{ // Check if the cell is occupied in the target position
+					name: "IsCellOccupied",
+					args: []interface{}{uint32(25), uint32(25)},
+					resp: []interface{}{false, nil, "", nil},
+				},
+				{ // Create the entity
+					name: "CreateEntity",
+					args: []interface{}{envApi.Entity{Id: "0", ClassID: 1, X: uint32(25), Y: uint32(25), Energy: uint32(100), Health: uint32(100), OwnerUID: "MOCK-UID", ModelID: "mock-model-id"}, true},
+					resp: []interface{}{nil},
+				},
+				{ // Add entity to firebase
+					name: "AddEntityMetadataToFireabase",
+					args: []interface{}{envApi.Entity{Id: "0", ClassID: 1, X: uint32(25), Y: uint32(25), Energy: uint32(100), Health: uint32(100), OwnerUID: "MOCK-UID", ModelID: "mock-model-id"}},
+					resp: []interface{}{errors.New("firebase is down")},
+				},
+				{ // Remove the entity again
+					name: "DeleteEntity",
+					args: []interface{}{"0"},
+					resp: []interface{}{int64(1), nil},
+				},
+			},
+			wantErr: errors.New("firebase is down"),
+		},
 		{
 			name: "Succesful in middle position",
 			args: args{
@@ -337,10 +457,11 @@ func TestCreateEntity(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup mock
 			mockDAL := &mocks.DataAccessLayer{}
-			s := NewEnvironmentServer("testing", mockDAL)
+			s := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules(), WithServiceToken("MOCK-TOKEN"))
 			for _, mockFuncCall := range tt.DALMockFuncCalls {
 				mockDAL.On(mockFuncCall.name, mockFuncCall.args...).Return(mockFuncCall.resp...)
 			}
+			mockCellLocks(mockDAL)
 			// Call function
 			got, err := s.CreateEntity(tt.args.ctx, tt.args.req)
 			// Check results
@@ -410,7 +531,7 @@ func TestGetEntity(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup mock
 			mockDAL := &mocks.DataAccessLayer{}
-			s := NewEnvironmentServer("testing", mockDAL)
+			s := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules())
 
 			mockDAL.On("GetEntity", tt.args.req.Id).Return(tt.mockGetEntityResponse, tt.mockGetEntityResponse2, tt.mockGetEntityErr)
 
@@ -456,9 +577,9 @@ func TestDeleteEntity(t *testing.T) {
 			},
 			DALMockFuncCalls: []mockFuncCall{
 				{
-					name: "DeleteEntity",
+					name: "GetEntity",
 					args: []interface{}{""},
-					resp: []interface{}{int64(0), errors.New("entity does not exist")},
+					resp: []interface{}{nil, "", errors.New("entity does not exist")},
 				},
 			},
 			wantErr: errors.New("entity does not exist"),
@@ -470,6 +591,11 @@ func TestDeleteEntity(t *testing.T) {
 				req: &envApi.DeleteEntityRequest{},
 			},
 			DALMockFuncCalls: []mockFuncCall{
+				{ // Get the entity to lock its cell
+					name: "GetEntity",
+					args: []interface{}{""},
+					resp: []interface{}{&envApi.Entity{X: 1, Y: 1}, "mock-content", nil},
+				},
 				{
 					name: "DeleteEntity",
 					args: []interface{}{""},
@@ -491,11 +617,12 @@ func TestDeleteEntity(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup mock
 			mockDAL := &mocks.DataAccessLayer{}
-			s := NewEnvironmentServer("testing", mockDAL)
+			s := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules())
 
 			for _, mockFunc := range tt.DALMockFuncCalls {
 				mockDAL.On(mockFunc.name, mockFunc.args...).Return(mockFunc.resp...)
 			}
+			mockCellLocks(mockDAL)
 
 			got, err := s.DeleteEntity(tt.args.ctx, tt.args.req)
 			if err != nil {
@@ -702,6 +829,11 @@ func TestExecuteAgentAction(t *testing.T) {
 					args: []interface{}{"mock-food-id"},
 					resp: []interface{}{int64(1), nil},
 				},
+				{ // Find an empty cell for the new food
+					name: "IsCellOccupied",
+					args: []interface{}{mock.Anything, mock.Anything},
+					resp: []interface{}{false, nil, "", nil},
+				},
 				{ // Create a new food entity somewhere
 					name: "CreateEntity",
 					args: []interface{}{mock.AnythingOfType("Entity"), true},
@@ -898,10 +1030,11 @@ func TestExecuteAgentAction(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup mock
 			mockDAL := &mocks.DataAccessLayer{}
-			s := NewEnvironmentServer("testing", mockDAL)
+			s := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules())
 			for _, mockFuncCall := range tt.DALMockFuncCalls {
 				mockDAL.On(mockFuncCall.name, mockFuncCall.args...).Return(mockFuncCall.resp...)
 			}
+			mockCellLocks(mockDAL)
 
 			got, err := s.ExecuteAgentAction(tt.args.ctx, tt.args.req)
 
@@ -933,22 +1066,138 @@ func TestResetWorld(t *testing.T) {
 
 	type args struct {
 		ctx context.Context
-		req *empty.Empty
+		req *envApi.ResetWorldRequest
 	}
 	tests := []struct {
-		name           string
-		args           args
-		want           *empty.Empty
-		wantErr        bool
-		wantErrMessage string
+		name                 string
+		args                 args
+		DALMockFuncCalls     []mockFuncCall
+		numOfCallsAssertions []numOfCallsAssertion
+		want                 *envApi.ResetWorldResponse
+		wantErr              error
 	}{
 		{
-			name: "Success",
+			name: "Invalid food position fails before wiping",
 			args: args{
 				ctx: ctx,
-				req: &empty.Empty{},
+				req: &envApi.ResetWorldRequest{
+					Food: []*envApi.Position{{X: 101, Y: 0}},
+				},
+			},
+			numOfCallsAssertions: []numOfCallsAssertion{
+				{"ResetWorld", 0},
+			},
+			wantErr: errors.New("invalid food position 101,0"),
+		},
+		{
+			name: "Duplicate food position fails before wiping",
+			args: args{
+				ctx: ctx,
+				req: &envApi.ResetWorldRequest{
+					Food: []*envApi.Position{{X: 1, Y: 1}, {X: 1, Y: 1}},
+				},
+			},
+			numOfCallsAssertions: []numOfCallsAssertion{
+				{"ResetWorld", 0},
+			},
+			wantErr: errors.New("duplicate food position 1,1"),
+		},
+		{
+			name: "Wipes the world and places the food layout",
+			args: args{
+				ctx: ctx,
+				req: &envApi.ResetWorldRequest{
+					Food: []*envApi.Position{{X: 1, Y: 1}, {X: 2, Y: 3}},
+				},
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{
+					name: "ResetWorld",
+					args: []interface{}{},
+					resp: []interface{}{int64(4), nil},
+				},
+				{
+					name: "CreateEntity",
+					args: []interface{}{mock.MatchedBy(func(e envApi.Entity) bool {
+						return e.ClassID == envApi.Entity_FOOD && e.X == 1 && e.Y == 1
+					}), false},
+					resp: []interface{}{nil},
+				},
+				{
+					name: "CreateEntity",
+					args: []interface{}{mock.MatchedBy(func(e envApi.Entity) bool {
+						return e.ClassID == envApi.Entity_FOOD && e.X == 2 && e.Y == 3
+					}), false},
+					resp: []interface{}{nil},
+				},
+			},
+			numOfCallsAssertions: []numOfCallsAssertion{
+				{"ResetWorld", 1},
+				{"CreateEntity", 2},
+			},
+			want: &envApi.ResetWorldResponse{
+				GenerationID: 4,
+			},
+		},
+		{
+			name: "Removes the old world's entity metadata",
+			args: args{
+				ctx: ctx,
+				req: &envApi.ResetWorldRequest{},
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{
+					name: "GetEntitiesInSpace",
+					args: []interface{}{uint32(0), uint32(0), uint32(99), uint32(99)},
+					resp: []interface{}{[]*envApi.Entity{{Id: "agent", ClassID: envApi.Entity_AGENT, ModelID: "mock-model-id"}, {Id: "food", ClassID: envApi.Entity_FOOD}}, nil},
+				},
+				{
+					name: "ResetWorld",
+					args: []interface{}{},
+					resp: []interface{}{int64(2), nil},
+				},
+				{
+					name: "RemoveEntityMetadataFromFirebase",
+					args: []interface{}{"agent"},
+					resp: []interface{}{nil},
+				},
+			},
+			numOfCallsAssertions: []numOfCallsAssertion{
+				{"ResetWorld", 1},
+				{"RemoveEntityMetadataFromFirebase", 1},
+			},
+			want: &envApi.ResetWorldResponse{
+				GenerationID: 2,
+			},
+		},
+		{
+			name: "Places seeded random food",
+			args: args{
+				ctx: ctx,
+				req: &envApi.ResetWorldRequest{
+					Seed:      42,
+					FoodCount: 25,
+				},
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{
+					name: "ResetWorld",
+					args: []interface{}{},
+					resp: []interface{}{int64(1), nil},
+				},
+				{
+					name: "CreateEntity",
+					args: []interface{}{mock.AnythingOfType("Entity"), false},
+					resp: []interface{}{nil},
+				},
+			},
+			numOfCallsAssertions: []numOfCallsAssertion{
+				{"ResetWorld", 1},
+				{"CreateEntity", 25},
+			},
+			want: &envApi.ResetWorldResponse{
+				GenerationID: 1,
 			},
-			want: &empty.Empty{},
 		},
 	}
 
@@ -956,16 +1205,25 @@ func TestResetWorld(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup mock
 			mockDAL := &mocks.DataAccessLayer{}
-			s := NewEnvironmentServer("testing", mockDAL)
+			s := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules())
+			for _, mockFuncCall := range tt.DALMockFuncCalls {
+				mockDAL.On(mockFuncCall.name, mockFuncCall.args...).Return(mockFuncCall.resp...)
+			}
+			mockEmptyWorld(mockDAL)
 
 			got, err := s.ResetWorld(tt.args.ctx, tt.args.req)
+
+			for _, assertion := range tt.numOfCallsAssertions {
+				mockDAL.AssertNumberOfCalls(t, assertion.name, assertion.num)
+			}
+
 			if err != nil {
-				if !tt.wantErr {
+				if tt.wantErr == nil {
 					t.Errorf("error: %v, wantErr: %v", err, tt.wantErr)
 					return
 				}
-				if err.Error() != tt.wantErrMessage {
-					t.Errorf("error message: '%v', want error message: '%v'", err, tt.wantErrMessage)
+				if err.Error() != tt.wantErr.Error() {
+					t.Errorf("error message: '%v', want error message: '%v'", err, tt.wantErr.Error())
 					return
 				}
 			}
@@ -1045,7 +1303,7 @@ func TestGetEntitiesInRegion(t *testing.T) {
 			mockDAL := &mocks.DataAccessLayer{}
 			mockDAL.On("GetEntitiesInSpace", uint32(10), uint32(20), uint32(19), uint32(29)).Return(tt.mockGetEntitiesInRegionResp.entities, tt.mockGetEntitiesInRegionResp.err)
 
-			s := NewEnvironmentServer("testing", mockDAL)
+			s := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules())
 
 			got, err := s.GetEntitiesInRegion(tt.args.ctx, tt.args.req)
 			if err != nil {
@@ -1065,3 +1323,523 @@ func TestGetEntitiesInRegion(t *testing.T) {
 	}
 
 }
+
+func TestGetRules(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+
+	mockDAL := &mocks.DataAccessLayer{}
+	rules := DefaultWorldRules()
+	rules.AttackDamage = 30
+	s := NewEnvironmentServer("testing", mockDAL, rules)
+
+	got, err := s.GetRules(ctx, &empty.Empty{})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	want := &envApi.WorldRules{
+		LivingEnergyCost:       1,
+		MoveEnergyCost:         2,
+		AttackEnergyCost:       5,
+		EnergyGainOnEat:        10,
+		AttackDamage:           30,
+		StartingEnergy:         100,
+		StartingHealth:         100,
+		MaxUserCreatedEntities: 5,
+		PheromoneDecay:         1.2,
+		PheromoneDelThresh:     5,
+		SlowGroundEnergyCost:   2,
+		FoodGrowthRate:         0.2,
+		FoodGrowthInterval:     10,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWorlds(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+
+	tests := []struct {
+		name             string
+		env              string
+		call             func(s envApi.EnvironmentServer) (interface{}, error)
+		DALMockFuncCalls []mockFuncCall
+		want             interface{}
+		wantErr          error
+	}{
+		{
+			name: "Creates a world",
+			env:  "testing",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.CreateWorld(ctx, &envApi.CreateWorldRequest{Id: "sandbox"})
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{name: "CreateWorld", args: []interface{}{"sandbox", datacom.DefaultWorldSize, datacom.Bounded}, resp: []interface{}{nil}},
+			},
+			want: &envApi.World{Id: "sandbox", Width: 100, Height: 100},
+		},
+		{
+			name: "Creates a sized world",
+			env:  "testing",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.CreateWorld(ctx, &envApi.CreateWorldRequest{Id: "arena", Width: 20, Height: 20})
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{name: "CreateWorld", args: []interface{}{"arena", datacom.WorldSize{Width: 20, Height: 20}, datacom.Bounded}, resp: []interface{}{nil}},
+			},
+			want: &envApi.World{Id: "arena", Width: 20, Height: 20},
+		},
+		{
+			name: "Lists worlds",
+			env:  "prod",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.ListWorlds(ctx, &empty.Empty{})
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{name: "ListWorlds", args: []interface{}{}, resp: []interface{}{[]datacom.World{{ID: "default", Size: datacom.DefaultWorldSize, Topology: datacom.Bounded}, {ID: "sandbox", Size: datacom.WorldSize{Width: 1000, Height: 1000}, Topology: datacom.Torus}}, nil}},
+			},
+			want: &envApi.ListWorldsResponse{Worlds: []*envApi.World{{Id: "default", Width: 100, Height: 100}, {Id: "sandbox", Width: 1000, Height: 1000, Topology: envApi.World_TORUS}}},
+		},
+		{
+			name: "Deletes a world",
+			env:  "testing",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.DeleteWorld(ctx, &envApi.DeleteWorldRequest{Id: "sandbox"})
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{name: "DeleteWorld", args: []interface{}{"sandbox"}, resp: []interface{}{nil}},
+			},
+			want: &empty.Empty{},
+		},
+		{
+			name: "Can't delete the world being served",
+			env:  "testing",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.DeleteWorld(ctx, &envApi.DeleteWorldRequest{Id: "default"})
+			},
+			wantErr: errors.New("can't delete the world this server is running"),
+		},
+		{
+			name: "Passes datacom errors on",
+			env:  "testing",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.DeleteWorld(ctx, &envApi.DeleteWorldRequest{Id: "missing"})
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{name: "DeleteWorld", args: []interface{}{"missing"}, resp: []interface{}{errors.New("world does not exist")}},
+			},
+			wantErr: errors.New("world does not exist"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDAL := &mocks.DataAccessLayer{}
+			s := NewEnvironmentServer(tt.env, mockDAL, DefaultWorldRules())
+			for _, mockFuncCall := range tt.DALMockFuncCalls {
+				mockDAL.On(mockFuncCall.name, mockFuncCall.args...).Return(mockFuncCall.resp...)
+			}
+
+			got, err := tt.call(s)
+			if err != nil {
+				if tt.wantErr == nil || err.Error() != tt.wantErr.Error() {
+					t.Errorf("error: %v, wantErr: %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				t.Errorf("expected error %v", tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorldSize(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+
+	arena := datacom.WorldSize{Width: 20, Height: 20}
+	tests := []struct {
+		name             string
+		call             func(s envApi.EnvironmentServer) (interface{}, error)
+		DALMockFuncCalls []mockFuncCall
+		want             interface{}
+		wantErr          error
+	}{
+		{
+			name: "Can't move off the edge of the arena",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.ExecuteAgentAction(ctx, &envApi.ExecuteAgentActionRequest{Id: "mock-entity-id", Action: 1, Direction: 3})
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{
+					name: "GetEntity",
+					args: []interface{}{"mock-entity-id"},
+					resp: []interface{}{&envApi.Entity{Id: "mock-entity-id", ModelID: "mock-model-id", X: 19, Y: 5, Energy: 100, Health: 100}, "mock-original-content", nil},
+				},
+			},
+			want: &envApi.ExecuteAgentActionResponse{Value: envApi.ExecuteAgentActionResponse_ERR_INVALID_TARGET},
+		},
+		{
+			name: "Can't eat off the edge of the arena",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.ExecuteAgentAction(ctx, &envApi.ExecuteAgentActionRequest{Id: "mock-entity-id", Action: 2, Direction: 0})
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				{
+					name: "GetEntity",
+					args: []interface{}{"mock-entity-id"},
+					resp: []interface{}{&envApi.Entity{Id: "mock-entity-id", ModelID: "mock-model-id", X: 5, Y: 19, Energy: 100, Health: 100}, "mock-original-content", nil},
+				},
+			},
+			want: &envApi.ExecuteAgentActionResponse{Value: envApi.ExecuteAgentActionResponse_ERR_INVALID_TARGET},
+		},
+		{
+			name: "Food has to be in the arena",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.ResetWorld(ctx, &envApi.ResetWorldRequest{Food: []*envApi.Position{{X: 20, Y: 0}}})
+			},
+			wantErr: errors.New("invalid food position 20,0"),
+		},
+		{
+			name: "Food has to fit in the arena",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.ResetWorld(ctx, &envApi.ResetWorldRequest{FoodCount: 401})
+			},
+			wantErr: errors.New("too much food for the world"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDAL := &mocks.DataAccessLayer{}
+			s := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules(), WithWorldSize(arena))
+			for _, mockFuncCall := range tt.DALMockFuncCalls {
+				mockDAL.On(mockFuncCall.name, mockFuncCall.args...).Return(mockFuncCall.resp...)
+			}
+			mockCellLocks(mockDAL)
+
+			got, err := tt.call(s)
+			mockDAL.AssertNotCalled(t, "IsCellOccupied", mock.Anything, mock.Anything)
+			mockDAL.AssertNotCalled(t, "ResetWorld")
+			if err != nil {
+				if tt.wantErr == nil || err.Error() != tt.wantErr.Error() {
+					t.Errorf("error: %v, wantErr: %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				t.Errorf("expected error %v", tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTorus(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+
+	tests := []struct {
+		name             string
+		req              *envApi.ExecuteAgentActionRequest
+		DALMockFuncCalls []mockFuncCall
+		wantTarget       [2]uint32
+		want             *envApi.ExecuteAgentActionResponse
+	}{
+		{
+			name: "Moves across the right edge",
+			req:  &envApi.ExecuteAgentActionRequest{Id: "mock-entity-id", Action: 1, Direction: 3},
+			DALMockFuncCalls: []mockFuncCall{
+				{
+					name: "GetEntity",
+					args: []interface{}{"mock-entity-id"},
+					resp: []interface{}{&envApi.Entity{Id: "mock-entity-id", ModelID: "mock-model-id", X: 19, Y: 5, Energy: 100, Health: 100}, "mock-original-content", nil},
+				},
+				{
+					name: "IsCellOccupied",
+					args: []interface{}{uint32(0), uint32(5)},
+					resp: []interface{}{false, nil, "", nil},
+				},
+				{
+					name: "UpdateEntity",
+					args: []interface{}{"mock-original-content", envApi.Entity{Id: "mock-entity-id", ModelID: "mock-model-id", X: 0, Y: 5, Energy: 97, Health: 100}},
+					resp: []interface{}{nil},
+				},
+				{
+					name: "CreateEffect",
+					args: []interface{}{mock.Anything},
+					resp: []interface{}{nil},
+				},
+			},
+			wantTarget: [2]uint32{0, 5},
+			want:       &envApi.ExecuteAgentActionResponse{Value: envApi.ExecuteAgentActionResponse_OK},
+		},
+		{
+			name: "Eats across the bottom edge",
+			req:  &envApi.ExecuteAgentActionRequest{Id: "mock-entity-id", Action: 2, Direction: 1},
+			DALMockFuncCalls: []mockFuncCall{
+				{
+					name: "GetEntity",
+					args: []interface{}{"mock-entity-id"},
+					resp: []interface{}{&envApi.Entity{Id: "mock-entity-id", ModelID: "mock-model-id", X: 5, Y: 0, Energy: 100, Health: 100}, "mock-original-content", nil},
+				},
+				{
+					name: "IsCellOccupied",
+					args: []interface{}{uint32(5), uint32(19)},
+					resp: []interface{}{false, nil, "", nil},
+				},
+			},
+			wantTarget: [2]uint32{5, 19},
+			want:       &envApi.ExecuteAgentActionResponse{Value: envApi.ExecuteAgentActionResponse_ERR_INVALID_TARGET},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDAL := &mocks.DataAccessLayer{}
+			s := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules(), WithWorldSize(datacom.WorldSize{Width: 20, Height: 20}), WithWorldTopology(datacom.Torus))
+			for _, mockFuncCall := range tt.DALMockFuncCalls {
+				mockDAL.On(mockFuncCall.name, mockFuncCall.args...).Return(mockFuncCall.resp...)
+			}
+			mockCellLocks(mockDAL)
+
+			got, err := s.ExecuteAgentAction(ctx, tt.req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			mockDAL.AssertCalled(t, "IsCellOccupied", tt.wantTarget[0], tt.wantTarget[1])
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTerrain(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+
+	// A wall to the right of 1,1, water above it and slow ground below it
+	m := terrain.New(100, 100)
+	m.Set(2, 1, terrain.Wall)
+	m.Set(1, 2, terrain.Water)
+	m.Set(1, 0, terrain.Slow)
+	// The agent is changed by each action, so every case gets its own
+	getAgent := func() mockFuncCall {
+		return mockFuncCall{
+			name: "GetEntity",
+			args: []interface{}{"mock-entity-id"},
+			resp: []interface{}{&envApi.Entity{Id: "mock-entity-id", ModelID: "mock-model-id", X: 1, Y: 1, Energy: 100, Health: 100}, "mock-original-content", nil},
+		}
+	}
+	tests := []struct {
+		name             string
+		call             func(s envApi.EnvironmentServer) (interface{}, error)
+		DALMockFuncCalls []mockFuncCall
+		want             interface{}
+		wantErr          error
+	}{
+		{
+			name: "Can't move into a wall",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.ExecuteAgentAction(ctx, &envApi.ExecuteAgentActionRequest{Id: "mock-entity-id", Action: 1, Direction: 3})
+			},
+			DALMockFuncCalls: []mockFuncCall{getAgent()},
+			want:             &envApi.ExecuteAgentActionResponse{Value: envApi.ExecuteAgentActionResponse_ERR_INVALID_TARGET},
+		},
+		{
+			name: "Can't move into water",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.ExecuteAgentAction(ctx, &envApi.ExecuteAgentActionRequest{Id: "mock-entity-id", Action: 1, Direction: 0})
+			},
+			DALMockFuncCalls: []mockFuncCall{getAgent()},
+			want:             &envApi.ExecuteAgentActionResponse{Value: envApi.ExecuteAgentActionResponse_ERR_INVALID_TARGET},
+		},
+		{
+			name: "Moving onto slow ground costs extra energy",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.ExecuteAgentAction(ctx, &envApi.ExecuteAgentActionRequest{Id: "mock-entity-id", Action: 1, Direction: 1})
+			},
+			DALMockFuncCalls: []mockFuncCall{
+				getAgent(),
+				{
+					name: "IsCellOccupied",
+					args: []interface{}{uint32(1), uint32(0)},
+					resp: []interface{}{false, nil, "", nil},
+				},
+				{
+					name: "UpdateEntity",
+					args: []interface{}{"mock-original-content", envApi.Entity{Id: "mock-entity-id", ModelID: "mock-model-id", X: 1, Y: 0, Energy: 95, Health: 100}},
+					resp: []interface{}{nil},
+				},
+				{
+					name: "CreateEffect",
+					args: []interface{}{mock.Anything},
+					resp: []interface{}{nil},
+				},
+			},
+			want: &envApi.ExecuteAgentActionResponse{Value: envApi.ExecuteAgentActionResponse_OK},
+		},
+		{
+			name: "Food can't be placed in a wall",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.ResetWorld(ctx, &envApi.ResetWorldRequest{Food: []*envApi.Position{{X: 2, Y: 1}}})
+			},
+			wantErr: errors.New("invalid food position 2,1"),
+		},
+		{
+			name: "Food has to fit around the walls and water",
+			call: func(s envApi.EnvironmentServer) (interface{}, error) {
+				return s.ResetWorld(ctx, &envApi.ResetWorldRequest{FoodCount: 100*100 - 1})
+			},
+			wantErr: errors.New("too much food for the world"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDAL := &mocks.DataAccessLayer{}
+			s := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules(), WithTerrain(m))
+			for _, mockFuncCall := range tt.DALMockFuncCalls {
+				mockDAL.On(mockFuncCall.name, mockFuncCall.args...).Return(mockFuncCall.resp...)
+			}
+			mockCellLocks(mockDAL)
+
+			got, err := tt.call(s)
+			mockDAL.AssertNotCalled(t, "ResetWorld")
+			if err != nil {
+				if tt.wantErr == nil || err.Error() != tt.wantErr.Error() {
+					t.Errorf("error: %v, wantErr: %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				t.Errorf("expected error %v", tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeededWorldIsReproducible(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+
+	// Build a world from the given server seed and record every entity created
+	run := func(env string, serverSeed int64) []envApi.Entity {
+		created := []envApi.Entity{}
+		mockDAL := &mocks.DataAccessLayer{}
+		mockDAL.On("ResetWorld").Return(int64(1), nil)
+		mockEmptyWorld(mockDAL)
+		mockDAL.On("CreateEntity", mock.AnythingOfType("Entity"), false).Return(nil).Run(func(args mock.Arguments) {
+			created = append(created, args.Get(0).(envApi.Entity))
+		})
+		s := NewEnvironmentServer(env, mockDAL, DefaultWorldRules(), WithSeed(serverSeed))
+		_, err := s.ResetWorld(ctx, &envApi.ResetWorldRequest{Seed: 3, FoodCount: 10})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return created
+	}
+
+	first := run("testing", 1)
+	second := run("testing", 2)
+	if len(first) != 10 {
+		t.Fatalf("got %v entities, want 10", len(first))
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("same reset seed built different worlds: %v, %v", first, second)
+	}
+
+	// Live replicas with the same seed lay food out the same, but never hand
+	// out the same ids
+	first = run("prod", 1)
+	second = run("prod", 1)
+	for i := range first {
+		if first[i].X != second[i].X || first[i].Y != second[i].Y {
+			t.Errorf("same seed put food %v at %v,%v and %v,%v", i, first[i].X, first[i].Y, second[i].X, second[i].Y)
+		}
+		if first[i].Id == second[i].Id {
+			t.Errorf("same seed handed out id %v twice", first[i].Id)
+		}
+	}
+}
+
+func TestGeneratedReset(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+	generator := &envApi.WorldGenerator{Walls: 0.2, Water: 0.2, SlowGround: 0.1, FoodPatches: 4, FoodPatchRadius: 3, FoodDensity: 0.5}
+
+	// Reset a world and record the terrain and every entity created
+	run := func(req *envApi.ResetWorldRequest, opts ...ServerOption) (*terrain.Map, []envApi.Entity, *environmentServer) {
+		var generated *terrain.Map
+		created := []envApi.Entity{}
+		mockDAL := &mocks.DataAccessLayer{}
+		mockDAL.On("ResetWorld").Return(int64(1), nil)
+		mockEmptyWorld(mockDAL)
+		mockDAL.On("SetTerrain", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			generated = args.Get(0).(*terrain.Map)
+		})
+		mockDAL.On("CreateEntity", mock.AnythingOfType("Entity"), false).Return(nil).Run(func(args mock.Arguments) {
+			created = append(created, args.Get(0).(envApi.Entity))
+		})
+		s := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules(), opts...).(*environmentServer)
+		if _, err := s.ResetWorld(ctx, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return generated, created, s
+	}
+
+	terrainMap, food, s := run(&envApi.ResetWorldRequest{Seed: 3, FoodCount: 5, Generator: generator})
+	if terrainMap == nil || s.currentTerrain() != terrainMap {
+		t.Fatalf("expected the generated terrain to be handed to the datacom and the server")
+	}
+	if len(food) <= 5 {
+		t.Errorf("got %v food, want the patches and 5 more", len(food))
+	}
+	for _, e := range food {
+		if !terrainMap.At(e.X, e.Y).Passable() {
+			t.Errorf("food at %v,%v is on %v", e.X, e.Y, terrainMap.At(e.X, e.Y))
+		}
+	}
+
+	// The same seed lays the world out the same, from the request or the server
+	againMap, againFood, _ := run(&envApi.ResetWorldRequest{Seed: 3, FoodCount: 5}, WithGenerator(*s.generatorParams(generator)))
+	if !reflect.DeepEqual(terrainMap, againMap) || !reflect.DeepEqual(food, againFood) {
+		t.Errorf("same reset seed generated different worlds")
+	}
+	otherMap, _, _ := run(&envApi.ResetWorldRequest{Seed: 4, Generator: generator})
+	if reflect.DeepEqual(terrainMap, otherMap) {
+		t.Errorf("different seeds generated the same terrain")
+	}
+
+	// Bad generators fail before the world is wiped
+	mockDAL := &mocks.DataAccessLayer{}
+	bad := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules())
+	if _, err := bad.ResetWorld(ctx, &envApi.ResetWorldRequest{Generator: &envApi.WorldGenerator{Walls: 0.6, Water: 0.6}}); err == nil {
+		t.Errorf("expected an error for a generator that leaves nowhere to stand")
+	}
+	mockDAL.AssertNotCalled(t, "ResetWorld")
+
+	// Live worlds don't share generated terrain between services
+	for _, env := range []string{"prod", "staging"} {
+		mockDAL := &mocks.DataAccessLayer{}
+		live := NewEnvironmentServer(env, mockDAL, DefaultWorldRules())
+		_, err := live.ResetWorld(ctx, &envApi.ResetWorldRequest{Seed: 3, Generator: generator})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("%v: got %v, want FailedPrecondition", env, err)
+		}
+		mockDAL.AssertNotCalled(t, "ResetWorld")
+	}
+}
@@ -0,0 +1,148 @@
+package environment
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"go.uber.org/zap"
+)
+
+// Food grows back on its own when the rules give regions a carrying capacity.
+// Every growth step each region of the world grows logistically towards its
+// capacity: a region with little food grows slowly, one nearly at capacity
+// barely grows, and one stripped bare grows as if it had a single food left
+// (seeds blowing in from outside). Seasons swing the growth rate up and down
+// over a year of steps, so scarce winters and plentiful summers take turns.
+//
+// The capacity is for a region that is open all the way through, regions cut
+// short by the edge of the world or covered in walls and water hold less.
+//
+// Every replica of a world shares its entities, so only the replica holding
+// the world's growth lease grows food. The lease outlives a couple of growth
+// intervals, if its holder goes away another replica takes over. The growth
+// steps are counted with the world, so the seasons carry on across a handover.
+
+// growthLease is the name of the lease for growing a world's food
+const growthLease = "growth"
+
+// runGrowth grows food every growth interval, forever
+func (s *environmentServer) runGrowth() {
+	interval := time.Duration(s.rules.FoodGrowthInterval) * time.Second
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		s.growIfOwner(interval)
+	}
+}
+
+// growIfOwner grows food if this replica holds the growth lease, renewing it
+// for a couple more intervals
+func (s *environmentServer) growIfOwner(interval time.Duration) {
+	held, err := s.datacomDAL.AcquireLease(growthLease, s.instanceID, 2*interval)
+	if err != nil {
+		s.logger.Error("acquiring growth lease", zap.Error(err))
+		return
+	}
+	if !held {
+		return
+	}
+	if err := s.growFood(); err != nil {
+		s.logger.Error("growing food", zap.Error(err))
+	}
+}
+
+// growthDue checks if food grows at the end of a tick
+func (s *environmentServer) growthDue(tick uint64) bool {
+	return s.rules.FoodCapacity > 0 && tick%uint64(s.rules.FoodGrowthInterval) == 0
+}
+
+// seasonModifier is how much the season speeds up or slows down growth on a
+// growth step, 1 without seasons
+func (s *environmentServer) seasonModifier(step uint64) float64 {
+	if s.rules.SeasonLength == 0 {
+		return 1
+	}
+	yearFraction := float64(step%uint64(s.rules.SeasonLength)) / float64(s.rules.SeasonLength)
+	return 1 + float64(s.rules.SeasonAmplitude)*math.Sin(2*math.Pi*yearFraction)
+}
+
+// growFood runs a growth step, growing food in every region towards its
+// carrying capacity
+func (s *environmentServer) growFood() error {
+	// The step is counted with the world, the season carries on when another
+	// replica takes over growing
+	step, err := s.datacomDAL.NextGrowthStep()
+	if err != nil {
+		return err
+	}
+
+	// Find out what is in every cell
+	entities, err := s.datacomDAL.GetEntitiesInSpace(0, 0, s.worldSize.Width-1, s.worldSize.Height-1)
+	if err != nil {
+		s.logger.Error("getting entities", zap.Error(err))
+		return err
+	}
+	occupied := make(map[position]bool, len(entities))
+	food := make(map[position]int)
+	for _, e := range entities {
+		occupied[position{e.X, e.Y}] = true
+		if e.ClassID == envApi.Entity_FOOD {
+			food[position{e.X / regionSize, e.Y / regionSize}]++
+		}
+	}
+
+	rate := float64(s.rules.FoodGrowthRate) * s.seasonModifier(step)
+	for ry := uint32(0); ry*regionSize < s.worldSize.Height; ry++ {
+		for rx := uint32(0); rx*regionSize < s.worldSize.Width; rx++ {
+			// The region holds food in proportion to how much of it is open
+			passable := 0
+			empty := []position{}
+			for y := ry * regionSize; y < (ry+1)*regionSize && y < s.worldSize.Height; y++ {
+				for x := rx * regionSize; x < (rx+1)*regionSize && x < s.worldSize.Width; x++ {
+					if !s.passable(x, y) {
+						continue
+					}
+					passable++
+					if !occupied[position{x, y}] {
+						empty = append(empty, position{x, y})
+					}
+				}
+			}
+			capacity := float64(s.rules.FoodCapacity) * float64(passable) / (regionSize * regionSize)
+			n := float64(food[position{rx, ry}])
+			if n >= capacity {
+				continue
+			}
+
+			// Logistic growth, the fraction left over is grown by chance
+			growth := rate * math.Max(n, 1) * (1 - n/capacity)
+			count := int(growth)
+			if s.rand.Float64() < growth-float64(count) {
+				count++
+			}
+			if count > int(capacity-n) {
+				count = int(capacity - n)
+			}
+
+			for i := 0; i < count && len(empty) > 0; i++ {
+				j := s.rand.Intn(len(empty))
+				pos := empty[j]
+				empty[j] = empty[len(empty)-1]
+				empty = empty[:len(empty)-1]
+				entityID, err := s.ids.NewID()
+				if err != nil {
+					err := errors.New("Error generating id")
+					s.logger.Error("generating id", zap.Error(err))
+					return err
+				}
+				// A cell in use is skipped, the food grows next step instead
+				e := envApi.Entity{Id: entityID, ClassID: envApi.Entity_FOOD, X: pos.x, Y: pos.y}
+				if _, err := s.spawnEntityAt(e, true); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package environment
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/datacom"
+	datacomMocks "github.com/terrariumai/simulation/pkg/datacom/mocks"
+	"github.com/terrariumai/simulation/pkg/terrain"
+)
+
+func TestGrowFood(t *testing.T) {
+	size := datacom.WorldSize{Width: 20, Height: 20}
+	// The top right region is walled off and the bottom right is half water
+	m := terrain.New(20, 20)
+	for y := uint32(0); y < 20; y++ {
+		for x := uint32(10); x < 20; x++ {
+			if y >= 10 {
+				m.Set(x, y, terrain.Wall)
+			} else if y < 5 {
+				m.Set(x, y, terrain.Water)
+			}
+		}
+	}
+
+	// Count the food in each region, keyed by the region's position
+	regionFood := func(dal DataAccessLayer) map[position]int {
+		entities, _ := dal.GetEntitiesInSpace(0, 0, 19, 19)
+		food := make(map[position]int)
+		for _, e := range entities {
+			if e.ClassID == envApi.Entity_FOOD {
+				food[position{e.X / regionSize, e.Y / regionSize}]++
+			}
+		}
+		return food
+	}
+
+	tests := []struct {
+		name   string
+		steps  int
+		modify func(r *WorldRules)
+		want   map[position]int
+	}{
+		{
+			name:  "Bare regions grow a little",
+			steps: 1,
+			modify: func(r *WorldRules) {
+				r.FoodCapacity = 20
+				r.FoodGrowthRate = 1
+			},
+			want: map[position]int{{0, 0}: 1, {1, 0}: 1, {0, 1}: 1},
+		},
+		{
+			name:  "Regions fill up to what their open cells can hold",
+			steps: 200,
+			modify: func(r *WorldRules) {
+				r.FoodCapacity = 20
+				r.FoodGrowthRate = 1
+			},
+			want: map[position]int{{0, 0}: 20, {1, 0}: 10, {0, 1}: 20},
+		},
+		{
+			name:  "Nothing grows without a growth rate",
+			steps: 10,
+			modify: func(r *WorldRules) {
+				r.FoodCapacity = 20
+				r.FoodGrowthRate = 0
+			},
+			want: map[position]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPAL := &datacomMocks.PubsubAccessLayer{}
+			mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			dal, _ := datacom.NewMemoryDatacom(mockPAL, size, datacom.Bounded)
+			rules := DefaultWorldRules()
+			tt.modify(&rules)
+			s := NewEnvironmentServer("testing", dal, rules, WithSeed(1), WithWorldSize(size), WithTerrain(m)).(*environmentServer)
+
+			for i := 0; i < tt.steps; i++ {
+				if err := s.growFood(); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			got := regionFood(dal)
+			for region, want := range tt.want {
+				if got[region] != want {
+					t.Errorf("region %v has %v food, want %v", region, got[region], want)
+				}
+			}
+			if got[position{1, 1}] != 0 {
+				t.Errorf("expected nothing to grow in the walls, got %v", got[position{1, 1}])
+			}
+		})
+	}
+}
+
+func TestEatingWithRegrowth(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+	mockPAL := &datacomMocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dal, _ := datacom.NewMemoryDatacom(mockPAL, datacom.DefaultWorldSize, datacom.Bounded)
+	rules := DefaultWorldRules()
+	rules.FoodCapacity = 20
+	s := NewEnvironmentServer("testing", dal, rules)
+
+	dal.CreateEntity(envApi.Entity{Id: "agent", ClassID: envApi.Entity_AGENT, X: 1, Y: 1, Energy: 50, Health: 100}, false)
+	dal.CreateEntity(envApi.Entity{Id: "food", ClassID: envApi.Entity_FOOD, X: 1, Y: 2}, false)
+	resp, err := s.ExecuteAgentAction(ctx, &envApi.ExecuteAgentActionRequest{Id: "agent", Action: envApi.ExecuteAgentActionRequest_EAT, Direction: 0})
+	if err != nil || resp.Value != envApi.ExecuteAgentActionResponse_OK {
+		t.Fatalf("got %v, %v, want OK", resp, err)
+	}
+	// The food waits for the next growth step instead of being replaced
+	entities, _ := dal.GetEntitiesInSpace(0, 0, 99, 99)
+	if len(entities) != 1 {
+		t.Errorf("got %v, want only the agent left", entities)
+	}
+}
+
+func TestEatingInAFullWorld(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+	mockPAL := &datacomMocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	size := datacom.WorldSize{Width: 2, Height: 2}
+	dal, _ := datacom.NewMemoryDatacom(mockPAL, size, datacom.Bounded)
+	s := NewEnvironmentServer("testing", dal, DefaultWorldRules(), WithSeed(1), WithWorldSize(size)).(*environmentServer)
+
+	// The eaten food's cell is locked by the agent eating it, so the
+	// replacement has nowhere to go
+	dal.CreateEntity(envApi.Entity{Id: "agent", ClassID: envApi.Entity_AGENT, X: 0, Y: 0, Energy: 50, Health: 100}, false)
+	dal.CreateEntity(envApi.Entity{Id: "food", ClassID: envApi.Entity_FOOD, X: 0, Y: 1}, false)
+	dal.CreateEntity(envApi.Entity{Id: "rock-1", ClassID: envApi.Entity_ROCK, X: 1, Y: 0}, false)
+	dal.CreateEntity(envApi.Entity{Id: "rock-2", ClassID: envApi.Entity_ROCK, X: 1, Y: 1}, false)
+	resp, err := s.ExecuteAgentAction(ctx, &envApi.ExecuteAgentActionRequest{Id: "agent", Action: envApi.ExecuteAgentActionRequest_EAT, Direction: 0})
+	if err != nil || resp.Value != envApi.ExecuteAgentActionResponse_OK {
+		t.Fatalf("got %v, %v, want OK", resp, err)
+	}
+
+	// Once the cell is free the replacement finds it
+	if err := s.spawnRandomFood(false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if occupied, e, _, _ := dal.IsCellOccupied(0, 1); !occupied || e.ClassID != envApi.Entity_FOOD {
+		t.Errorf("expected food in the last free cell, got %v", e)
+	}
+	if err := s.spawnRandomFood(false); err == nil {
+		t.Errorf("expected an error with no free cells")
+	}
+}
+
+func TestRandomPositionWithoutOpenCells(t *testing.T) {
+	size := datacom.WorldSize{Width: 3, Height: 3}
+	m := terrain.New(3, 3)
+	for y := uint32(0); y < 3; y++ {
+		for x := uint32(0); x < 3; x++ {
+			m.Set(x, y, terrain.Wall)
+		}
+	}
+	s := NewEnvironmentServer("testing", nil, DefaultWorldRules(), WithWorldSize(size), WithTerrain(m)).(*environmentServer)
+	if _, _, err := s.randomPosition(); err == nil {
+		t.Errorf("expected an error with nowhere to stand")
+	}
+
+	// A single open cell is found even when guessing misses it
+	m.Set(2, 1, terrain.Ground)
+	x, y, err := s.randomPosition()
+	if err != nil || x != 2 || y != 1 {
+		t.Errorf("got %v,%v %v, want 2,1", x, y, err)
+	}
+}
+
+func TestSeasonModifier(t *testing.T) {
+	rules := DefaultWorldRules()
+	rules.SeasonLength = 8
+	rules.SeasonAmplitude = 0.5
+	s := NewEnvironmentServer("testing", nil, rules).(*environmentServer)
+	for step, want := range map[uint64]float64{0: 1, 2: 1.5, 4: 1, 6: 0.5, 10: 1.5} {
+		if got := s.seasonModifier(step); math.Abs(got-want) > 1e-9 {
+			t.Errorf("seasonModifier(%v) = %v, want %v", step, got, want)
+		}
+	}
+
+	// Growth is steady without seasons
+	s = NewEnvironmentServer("testing", nil, DefaultWorldRules()).(*environmentServer)
+	if got := s.seasonModifier(3); got != 1 {
+		t.Errorf("seasonModifier(3) = %v, want 1", got)
+	}
+}
+
+func TestGrowthLease(t *testing.T) {
+	mockPAL := &datacomMocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dal, _ := datacom.NewMemoryDatacom(mockPAL, datacom.DefaultWorldSize, datacom.Bounded)
+	rules := DefaultWorldRules()
+	rules.FoodCapacity = 20
+	rules.FoodGrowthRate = 1
+	// Two replicas of the same world, only the first to take the lease grows
+	first := NewEnvironmentServer("testing", dal, rules, WithSeed(1)).(*environmentServer)
+	second := NewEnvironmentServer("testing", dal, rules, WithSeed(2)).(*environmentServer)
+
+	for i := 0; i < 3; i++ {
+		first.growIfOwner(time.Minute)
+		second.growIfOwner(time.Minute)
+	}
+	// Only one replica grew each round
+	if step, _ := dal.NextGrowthStep(); step != 3 {
+		t.Errorf("got %v growth steps, want 3", step)
+	}
+
+	// Once the lease runs out the other replica takes over, carrying on the
+	// same seasons
+	first.growIfOwner(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	second.growIfOwner(time.Minute)
+	if step, _ := dal.NextGrowthStep(); step != 6 {
+		t.Errorf("got %v growth steps, want 6 with the second replica carrying on", step)
+	}
+}
@@ -0,0 +1,94 @@
+package environment
+
+import (
+	"errors"
+	"time"
+
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"go.uber.org/zap"
+)
+
+// Cells are locked in redis instead of in memory, so any number of
+// environments can share a world. Anything that changes a cell has to hold
+// its lock, while reads go straight through.
+
+func toPositions(cells []position) []envApi.Position {
+	positions := make([]envApi.Position, len(cells))
+	for i, cell := range cells {
+		positions[i] = envApi.Position{X: cell.x, Y: cell.y}
+	}
+	return positions
+}
+
+// tryLockCells makes a single attempt at locking the cells. The unlock func is
+// only returned when the lock was taken.
+func (s *environmentServer) tryLockCells(cells ...position) (func(), error) {
+	positions := toPositions(cells)
+	token, locked, err := s.datacomDAL.LockCells(positions)
+	if err != nil {
+		s.logger.Error("locking cells", zap.Error(err))
+		return nil, err
+	}
+	if !locked {
+		return nil, nil
+	}
+	return func() {
+		if err := s.datacomDAL.UnlockCells(token, positions); err != nil {
+			s.logger.Error("unlocking cells", zap.Error(err))
+		}
+	}, nil
+}
+
+// lockCells waits until it holds the lock on every cell, then returns a func
+// to unlock them
+func (s *environmentServer) lockCells(cells ...position) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		unlock, err := s.tryLockCells(cells...)
+		if err != nil {
+			return nil, err
+		}
+		if unlock != nil {
+			return unlock, nil
+		}
+		if time.Now().After(deadline) {
+			err := errors.New("cells are busy, try again")
+			s.logger.Warn("locking cells", zap.Error(err))
+			return nil, err
+		}
+		time.Sleep(lockRetryWait)
+	}
+}
+
+// getEntityLocked gets an entity while holding the lock on its cell, along
+// with any cells around it that the caller needs. The entity is read again
+// once the lock is held in case it moved in the meantime.
+func (s *environmentServer) getEntityLocked(id string, around func(e *envApi.Entity) []position) (*envApi.Entity, string, func(), error) {
+	for attempt := 0; attempt < maxLockAttempts; attempt++ {
+		entity, _, err := s.datacomDAL.GetEntity(id)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		cells := []position{{entity.X, entity.Y}}
+		if around != nil {
+			cells = append(cells, around(entity)...)
+		}
+		unlock, err := s.lockCells(cells...)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		locked, content, err := s.datacomDAL.GetEntity(id)
+		if err != nil {
+			unlock()
+			return nil, "", nil, err
+		}
+		if locked.X == entity.X && locked.Y == entity.Y {
+			return locked, content, unlock, nil
+		}
+		// It moved before we got the lock, go again
+		unlock()
+	}
+	err := errors.New("entity kept moving, try again")
+	s.logger.Warn("locking entity", zap.Error(err), zap.String("entity", id))
+	return nil, "", nil, err
+}
@@ -0,0 +1,53 @@
+package environment
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+)
+
+func TestReplicasRacingForACell(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		_, redisServer := setup()
+		// Two environments sharing one world
+		s1, dc := tickWorld(t, redisServer.Addr(), []envApi.Entity{agent("A", 4, 5, 100), agent("B", 6, 5, 100)})
+		s2, _ := tickWorld(t, redisServer.Addr(), nil)
+
+		var wg sync.WaitGroup
+		responses := make([]*envApi.ExecuteAgentActionResponse, 2)
+		move := func(i int, s *environmentServer, id string, direction envApi.ExecuteAgentActionRequest_Direction) {
+			defer wg.Done()
+			resp, err := s.ExecuteAgentAction(context.Background(), &envApi.ExecuteAgentActionRequest{
+				Id:        id,
+				Action:    envApi.ExecuteAgentActionRequest_MOVE,
+				Direction: direction,
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			responses[i] = resp
+		}
+		wg.Add(2)
+		go move(0, s1, "A", envApi.ExecuteAgentActionRequest_RIGHT)
+		go move(1, s2, "B", envApi.ExecuteAgentActionRequest_LEFT)
+		wg.Wait()
+
+		moved := 0
+		for _, resp := range responses {
+			if resp != nil && resp.Value == envApi.ExecuteAgentActionResponse_OK {
+				moved++
+			}
+		}
+		if moved != 1 {
+			t.Errorf("expected exactly one agent to get the cell, %v did", moved)
+		}
+		entities, _ := dc.GetEntitiesInSpace(5, 5, 5, 5)
+		if len(entities) != 1 {
+			t.Errorf("expected one entity in the cell, got %v", len(entities))
+		}
+		teardown(redisServer)
+	}
+}
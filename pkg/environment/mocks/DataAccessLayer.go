@@ -7,12 +7,35 @@ import endpoints_terrariumai_collective "github.com/terrariumai/simulation/pkg/a
 import endpoints_terrariumai_environment "github.com/terrariumai/simulation/pkg/api/environment"
 
 import mock "github.com/stretchr/testify/mock"
+import terrain "github.com/terrariumai/simulation/pkg/terrain"
+import time "time"
 
 // DataAccessLayer is an autogenerated mock type for the DataAccessLayer type
 type DataAccessLayer struct {
 	mock.Mock
 }
 
+// AcquireLease provides a mock function with given fields: name, holder, ttl
+func (_m *DataAccessLayer) AcquireLease(name string, holder string, ttl time.Duration) (bool, error) {
+	ret := _m.Called(name, holder, ttl)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string, time.Duration) bool); ok {
+		r0 = rf(name, holder, ttl)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, time.Duration) error); ok {
+		r1 = rf(name, holder, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // AddEntityMetadataToFireabase provides a mock function with given fields: _a0
 func (_m *DataAccessLayer) AddEntityMetadataToFireabase(_a0 endpoints_terrariumai_environment.Entity) error {
 	ret := _m.Called(_a0)
@@ -55,6 +78,20 @@ func (_m *DataAccessLayer) CreateEntity(e endpoints_terrariumai_environment.Enti
 	return r0
 }
 
+// CreateWorld provides a mock function with given fields: worldID, size, topology
+func (_m *DataAccessLayer) CreateWorld(worldID string, size datacom.WorldSize, topology datacom.Topology) error {
+	ret := _m.Called(worldID, size, topology)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, datacom.WorldSize, datacom.Topology) error); ok {
+		r0 = rf(worldID, size, topology)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteEntity provides a mock function with given fields: id
 func (_m *DataAccessLayer) DeleteEntity(id string) (int64, error) {
 	ret := _m.Called(id)
@@ -76,6 +113,20 @@ func (_m *DataAccessLayer) DeleteEntity(id string) (int64, error) {
 	return r0, r1
 }
 
+// DeleteWorld provides a mock function with given fields: worldID
+func (_m *DataAccessLayer) DeleteWorld(worldID string) error {
+	ret := _m.Called(worldID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(worldID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetEffectsInSpace provides a mock function with given fields: x0, y0, x1, y1
 func (_m *DataAccessLayer) GetEffectsInSpace(x0 uint32, y0 uint32, x1 uint32, y1 uint32) ([]*endpoints_terrariumai_environment.Effect, error) {
 	ret := _m.Called(x0, y0, x1, y1)
@@ -198,6 +249,29 @@ func (_m *DataAccessLayer) GetObservationForEntity(entity endpoints_terrariumai_
 	return r0, r1
 }
 
+// GetObservationsForEntities provides a mock function with given fields: entities
+func (_m *DataAccessLayer) GetObservationsForEntities(entities []endpoints_terrariumai_environment.Entity) ([]*endpoints_terrariumai_collective.Observation, error) {
+	ret := _m.Called(entities)
+
+	var r0 []*endpoints_terrariumai_collective.Observation
+	if rf, ok := ret.Get(0).(func([]endpoints_terrariumai_environment.Entity) []*endpoints_terrariumai_collective.Observation); ok {
+		r0 = rf(entities)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*endpoints_terrariumai_collective.Observation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]endpoints_terrariumai_environment.Entity) error); ok {
+		r1 = rf(entities)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRemoteModelMetadataByID provides a mock function with given fields: modelID
 func (_m *DataAccessLayer) GetRemoteModelMetadataByID(modelID string) (*datacom.RemoteModel, error) {
 	ret := _m.Called(modelID)
@@ -281,6 +355,78 @@ func (_m *DataAccessLayer) IsCellOccupied(x uint32, y uint32) (bool, *endpoints_
 	return r0, r1, r2, r3
 }
 
+// ListWorlds provides a mock function with given fields:
+func (_m *DataAccessLayer) ListWorlds() ([]datacom.World, error) {
+	ret := _m.Called()
+
+	var r0 []datacom.World
+	if rf, ok := ret.Get(0).(func() []datacom.World); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]datacom.World)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LockCells provides a mock function with given fields: cells
+func (_m *DataAccessLayer) LockCells(cells []endpoints_terrariumai_environment.Position) (string, bool, error) {
+	ret := _m.Called(cells)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func([]endpoints_terrariumai_environment.Position) string); ok {
+		r0 = rf(cells)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func([]endpoints_terrariumai_environment.Position) bool); ok {
+		r1 = rf(cells)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func([]endpoints_terrariumai_environment.Position) error); ok {
+		r2 = rf(cells)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// NextGrowthStep provides a mock function with given fields:
+func (_m *DataAccessLayer) NextGrowthStep() (uint64, error) {
+	ret := _m.Called()
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func() uint64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RemoveEntityMetadataFromFirebase provides a mock function with given fields: id
 func (_m *DataAccessLayer) RemoveEntityMetadataFromFirebase(id string) error {
 	ret := _m.Called(id)
@@ -295,6 +441,55 @@ func (_m *DataAccessLayer) RemoveEntityMetadataFromFirebase(id string) error {
 	return r0
 }
 
+// ResetWorld provides a mock function with given fields:
+func (_m *DataAccessLayer) ResetWorld() (int64, error) {
+	ret := _m.Called()
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetTerrain provides a mock function with given fields: m
+func (_m *DataAccessLayer) SetTerrain(m *terrain.Map) error {
+	ret := _m.Called(m)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*terrain.Map) error); ok {
+		r0 = rf(m)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnlockCells provides a mock function with given fields: token, cells
+func (_m *DataAccessLayer) UnlockCells(token string, cells []endpoints_terrariumai_environment.Position) error {
+	ret := _m.Called(token, cells)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []endpoints_terrariumai_environment.Position) error); ok {
+		r0 = rf(token, cells)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateEntity provides a mock function with given fields: origionalContent, e
 func (_m *DataAccessLayer) UpdateEntity(origionalContent string, e endpoints_terrariumai_environment.Entity) error {
 	ret := _m.Called(origionalContent, e)
@@ -0,0 +1,81 @@
+package environment
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sync"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// IDGenerator creates ids for new entities
+type IDGenerator interface {
+	NewID() (string, error)
+}
+
+// UUIDGenerator creates random v4 uuids. Ids from it can not be reproduced,
+// so only use it when determinism doesn't matter.
+type UUIDGenerator struct{}
+
+// NewID returns a new v4 uuid
+func (UUIDGenerator) NewID() (string, error) {
+	newUUID, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return newUUID.String(), nil
+}
+
+// randIDGenerator creates v4 shaped uuids from the world's random source, so
+// the same seed always hands out the same ids in the same order
+type randIDGenerator struct {
+	r *rand.Rand
+}
+
+// NewID returns the next uuid from the random source
+func (g randIDGenerator) NewID() (string, error) {
+	// Uint64 only touches the locked source, unlike Read which buffers
+	var u uuid.UUID
+	binary.BigEndian.PutUint64(u[:8], g.r.Uint64())
+	binary.BigEndian.PutUint64(u[8:], g.r.Uint64())
+	u.SetVersion(uuid.V4)
+	u.SetVariant(uuid.VariantRFC4122)
+	return u.String(), nil
+}
+
+// lockedSource is a rand.Source that is safe for concurrent use
+type lockedSource struct {
+	m   sync.Mutex
+	src rand.Source
+}
+
+func newLockedSource(seed int64) *lockedSource {
+	return &lockedSource{src: rand.NewSource(seed)}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.src.Seed(seed)
+}
+
+// WithSeed seeds the world's random source. The same seed and the same
+// sequence of actions always produce the same world.
+func WithSeed(seed int64) ServerOption {
+	return func(s *environmentServer) {
+		s.src.Seed(seed)
+	}
+}
+
+// WithIDGenerator replaces the default id generator
+func WithIDGenerator(g IDGenerator) ServerOption {
+	return func(s *environmentServer) {
+		s.ids = g
+	}
+}
@@ -0,0 +1,150 @@
+package environment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v2"
+
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+)
+
+// WorldRules holds every number that balances the ecosystem, so it can be
+// tuned from a config file instead of a recompile
+type WorldRules struct {
+	// Energy costs
+	LivingEnergyCost uint32 `json:"livingEnergyCost" yaml:"livingEnergyCost"`
+	MoveEnergyCost   uint32 `json:"moveEnergyCost" yaml:"moveEnergyCost"`
+	AttackEnergyCost uint32 `json:"attackEnergyCost" yaml:"attackEnergyCost"`
+	// Energy gained from eating food
+	EnergyGainOnEat uint32 `json:"energyGainOnEat" yaml:"energyGainOnEat"`
+	// Health removed from the target of an attack
+	AttackDamage uint32 `json:"attackDamage" yaml:"attackDamage"`
+	// Starting stats for created agents
+	StartingEnergy uint32 `json:"startingEnergy" yaml:"startingEnergy"`
+	StartingHealth uint32 `json:"startingHealth" yaml:"startingHealth"`
+	// Max agents a model can manually create
+	MaxUserCreatedEntities uint32 `json:"maxUserCreatedEntities" yaml:"maxUserCreatedEntities"`
+	// Pheromones left behind by moving agents
+	PheromoneDecay     float32 `json:"pheromoneDecay" yaml:"pheromoneDecay"`
+	PheromoneDelThresh uint32  `json:"pheromoneDelThresh" yaml:"pheromoneDelThresh"`
+	// Extra energy to move onto slow ground
+	SlowGroundEnergyCost uint32 `json:"slowGroundEnergyCost" yaml:"slowGroundEnergyCost"`
+	// Food regrowth. With a capacity, food grows back in every region towards
+	// the food an open region can hold, by the growth rate every interval
+	// (seconds, or ticks in tick mode). Without one eaten food is replaced one
+	// for one.
+	FoodCapacity       uint32  `json:"foodCapacity" yaml:"foodCapacity"`
+	FoodGrowthRate     float32 `json:"foodGrowthRate" yaml:"foodGrowthRate"`
+	FoodGrowthInterval uint32  `json:"foodGrowthInterval" yaml:"foodGrowthInterval"`
+	// Seasons swing the growth rate up and down by the amplitude, over a year
+	// of seasonLength growth steps
+	SeasonLength    uint32  `json:"seasonLength" yaml:"seasonLength"`
+	SeasonAmplitude float32 `json:"seasonAmplitude" yaml:"seasonAmplitude"`
+}
+
+// DefaultWorldRules returns the rules the public world has always used
+func DefaultWorldRules() WorldRules {
+	return WorldRules{
+		LivingEnergyCost:       1,
+		MoveEnergyCost:         2,
+		AttackEnergyCost:       5,
+		EnergyGainOnEat:        10,
+		AttackDamage:           10,
+		StartingEnergy:         100,
+		StartingHealth:         100,
+		MaxUserCreatedEntities: 5,
+		PheromoneDecay:         1.2,
+		PheromoneDelThresh:     5,
+		SlowGroundEnergyCost:   2,
+		FoodGrowthRate:         0.2,
+		FoodGrowthInterval:     10,
+	}
+}
+
+// LoadWorldRules reads rules from a json or yaml file. Any rule missing from
+// the file keeps its default value.
+func LoadWorldRules(path string) (WorldRules, error) {
+	rules := DefaultWorldRules()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("Error reading rules file: %v", err)
+	}
+
+	switch filepath.Ext(path) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&rules)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &rules)
+	default:
+		err = errors.New("rules file must be .json, .yaml or .yml")
+	}
+	if err != nil {
+		return rules, fmt.Errorf("Error parsing rules file: %v", err)
+	}
+
+	if err := rules.Validate(); err != nil {
+		return rules, err
+	}
+
+	return rules, nil
+}
+
+// Validate makes sure the rules can produce a working world
+func (r WorldRules) Validate() error {
+	if r.StartingHealth == 0 {
+		return errors.New("invalid rules: startingHealth must be greater than 0")
+	}
+	if r.MaxUserCreatedEntities == 0 {
+		return errors.New("invalid rules: maxUserCreatedEntities must be greater than 0")
+	}
+	// Pheromones need to fade, otherwise they are never cleaned up
+	if r.PheromoneDecay <= 1 {
+		return errors.New("invalid rules: pheromoneDecay must be greater than 1")
+	}
+	if r.PheromoneDelThresh >= 100 {
+		return errors.New("invalid rules: pheromoneDelThresh must be less than 100")
+	}
+	if r.FoodCapacity > regionSize*regionSize {
+		return fmt.Errorf("invalid rules: foodCapacity must be at most %v, the cells in a region", regionSize*regionSize)
+	}
+	if r.FoodCapacity > 0 && r.FoodGrowthInterval == 0 {
+		return errors.New("invalid rules: foodGrowthInterval must be greater than 0")
+	}
+	if r.FoodGrowthRate < 0 {
+		return errors.New("invalid rules: foodGrowthRate can't be negative")
+	}
+	if r.SeasonAmplitude < 0 || r.SeasonAmplitude > 1 {
+		return errors.New("invalid rules: seasonAmplitude must be between 0 and 1")
+	}
+	return nil
+}
+
+// toProto converts the rules to their api representation
+func (r WorldRules) toProto() *envApi.WorldRules {
+	return &envApi.WorldRules{
+		LivingEnergyCost:       r.LivingEnergyCost,
+		MoveEnergyCost:         r.MoveEnergyCost,
+		AttackEnergyCost:       r.AttackEnergyCost,
+		EnergyGainOnEat:        r.EnergyGainOnEat,
+		AttackDamage:           r.AttackDamage,
+		StartingEnergy:         r.StartingEnergy,
+		StartingHealth:         r.StartingHealth,
+		MaxUserCreatedEntities: r.MaxUserCreatedEntities,
+		PheromoneDecay:         r.PheromoneDecay,
+		PheromoneDelThresh:     r.PheromoneDelThresh,
+		SlowGroundEnergyCost:   r.SlowGroundEnergyCost,
+		FoodCapacity:           r.FoodCapacity,
+		FoodGrowthRate:         r.FoodGrowthRate,
+		FoodGrowthInterval:     r.FoodGrowthInterval,
+		SeasonLength:           r.SeasonLength,
+		SeasonAmplitude:        r.SeasonAmplitude,
+	}
+}
@@ -0,0 +1,161 @@
+package environment
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadWorldRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rules")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	withRules := func(f func(r *WorldRules)) WorldRules {
+		r := DefaultWorldRules()
+		f(&r)
+		return r
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		want     WorldRules
+		wantErr  error
+	}{
+		{
+			name:     "Json overrides given rules only",
+			filename: "rules.json",
+			content:  `{"moveEnergyCost": 4, "attackDamage": 25}`,
+			want: withRules(func(r *WorldRules) {
+				r.MoveEnergyCost = 4
+				r.AttackDamage = 25
+			}),
+		},
+		{
+			name:     "Yaml overrides given rules only",
+			filename: "rules.yaml",
+			content:  "livingEnergyCost: 0\nstartingEnergy: 50\npheromoneDecay: 2.5\n",
+			want: withRules(func(r *WorldRules) {
+				r.LivingEnergyCost = 0
+				r.StartingEnergy = 50
+				r.PheromoneDecay = 2.5
+			}),
+		},
+		{
+			name:     "Unknown json rule fails",
+			filename: "rules.json",
+			content:  `{"moveEnergyCosts": 4}`,
+			wantErr:  errors.New(`Error parsing rules file: json: unknown field "moveEnergyCosts"`),
+		},
+		{
+			name:     "Unknown yaml rule fails",
+			filename: "rules.yml",
+			content:  "moveEnergyCosts: 4\n",
+			wantErr:  errors.New("Error parsing rules file: yaml: unmarshal errors:\n  line 1: field moveEnergyCosts not found in type environment.WorldRules"),
+		},
+		{
+			name:     "Unsupported extension fails",
+			filename: "rules.toml",
+			content:  "",
+			wantErr:  errors.New("Error parsing rules file: rules file must be .json, .yaml or .yml"),
+		},
+		{
+			name:     "Invalid rules fail validation",
+			filename: "rules.json",
+			content:  `{"startingHealth": 0}`,
+			wantErr:  errors.New("invalid rules: startingHealth must be greater than 0"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.filename)
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("error writing rules file: %v", err)
+			}
+
+			got, err := LoadWorldRules(path)
+			if err != nil {
+				if tt.wantErr == nil {
+					t.Errorf("error: %v, wantErr: %v", err, tt.wantErr)
+					return
+				}
+				if err.Error() != tt.wantErr.Error() {
+					t.Errorf("error message: '%v', want error message: '%v'", err, tt.wantErr.Error())
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				t.Errorf("expected error: %v", tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateWorldRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(r *WorldRules)
+		wantErr error
+	}{
+		{
+			name:   "Default rules are valid",
+			modify: func(r *WorldRules) {},
+		},
+		{
+			name:    "Must be able to create entities",
+			modify:  func(r *WorldRules) { r.MaxUserCreatedEntities = 0 },
+			wantErr: errors.New("invalid rules: maxUserCreatedEntities must be greater than 0"),
+		},
+		{
+			name:    "Pheromones must decay",
+			modify:  func(r *WorldRules) { r.PheromoneDecay = 1 },
+			wantErr: errors.New("invalid rules: pheromoneDecay must be greater than 1"),
+		},
+		{
+			name:    "Pheromones must be visible when created",
+			modify:  func(r *WorldRules) { r.PheromoneDelThresh = 100 },
+			wantErr: errors.New("invalid rules: pheromoneDelThresh must be less than 100"),
+		},
+		{
+			name:    "Regions can't hold more food than cells",
+			modify:  func(r *WorldRules) { r.FoodCapacity = 101 },
+			wantErr: errors.New("invalid rules: foodCapacity must be at most 100, the cells in a region"),
+		},
+		{
+			name: "Food has to grow every so often",
+			modify: func(r *WorldRules) {
+				r.FoodCapacity = 20
+				r.FoodGrowthInterval = 0
+			},
+			wantErr: errors.New("invalid rules: foodGrowthInterval must be greater than 0"),
+		},
+		{
+			name:    "Seasons can't make growth negative",
+			modify:  func(r *WorldRules) { r.SeasonAmplitude = 1.5 },
+			wantErr: errors.New("invalid rules: seasonAmplitude must be between 0 and 1"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := DefaultWorldRules()
+			tt.modify(&r)
+			err := r.Validate()
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"go.uber.org/zap"
+)
+
+// Food SpawnFood scatters over the whole world
+const spawnFoodCount = 500
+
+// Regions with no more cells than this are ranked cell by cell, bigger ones
+// are sampled
+const spawnScanCells = 1024
+
+// spawnCandidate is a free cell entities could be spawned in, ranked by a
+// random key weighted by the distribution
+type spawnCandidate struct {
+	pos position
+	key float64
+}
+
+func (s *environmentServer) SpawnFood(ctx context.Context, req *empty.Empty) (*empty.Empty, error) {
+	if _, err := s.spawnEntities(&envApi.SpawnEntitiesRequest{
+		ClassID: envApi.Entity_FOOD,
+		Count:   spawnFoodCount,
+	}); err != nil {
+		return nil, err
+	}
+
+	// Return
+	return &empty.Empty{}, nil
+}
+
+// SpawnEntities places exactly the requested number of entities in a part of
+// the world, spread out by the requested distribution, and returns their ids
+func (s *environmentServer) SpawnEntities(ctx context.Context, req *envApi.SpawnEntitiesRequest) (*envApi.SpawnEntitiesResponse, error) {
+	return s.spawnEntities(req)
+}
+
+// spawnEntities places the entities of a spawn request
+func (s *environmentServer) spawnEntities(req *envApi.SpawnEntitiesRequest) (*envApi.SpawnEntitiesResponse, error) {
+	// Validate the request before touching anything
+	if req.ClassID == envApi.Entity_EMPTY || req.ClassID > envApi.Entity_FOOD {
+		err := errors.New("invalid class")
+		s.logger.Warn("rejected spawn", zap.Error(err))
+		return nil, err
+	}
+	ownerUID := ""
+	if req.ClassID == envApi.Entity_AGENT {
+		if len(req.ModelID) == 0 {
+			err := errors.New("missing model id")
+			s.logger.Warn("rejected spawn", zap.Error(err))
+			return nil, err
+		}
+		remoteModelMD, err := s.datacomDAL.GetRemoteModelMetadataByID(req.ModelID)
+		if err != nil {
+			s.logger.Error("getting remote model", zap.Error(err), zap.String("model", req.ModelID))
+			return nil, err
+		}
+		ownerUID = remoteModelMD.OwnerUID
+	}
+	bounds := req.Bounds
+	if bounds == nil {
+		bounds = &envApi.Rect{X1: s.worldSize.Width - 1, Y1: s.worldSize.Height - 1}
+	}
+	if bounds.X0 > bounds.X1 || bounds.Y0 > bounds.Y1 || !s.onMap(bounds.X1, bounds.Y1) {
+		err := fmt.Errorf("invalid bounds %v,%v to %v,%v", bounds.X0, bounds.Y0, bounds.X1, bounds.Y1)
+		s.logger.Warn("rejected spawn", zap.Error(err))
+		return nil, err
+	}
+	if req.Spread < 0 || req.Radius < 0 {
+		err := errors.New("spread and radius can't be negative")
+		s.logger.Warn("rejected spawn", zap.Error(err))
+		return nil, err
+	}
+
+	// Rank every free cell, the best ranked cells are spawned in
+	candidates, err := s.spawnCandidates(req, bounds)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) < int(req.Count) {
+		err := fmt.Errorf("only %v free cells for %v entities", len(candidates), req.Count)
+		s.logger.Warn("rejected spawn", zap.Error(err))
+		return nil, err
+	}
+
+	// Either every entity is spawned or none are, whatever was spawned is
+	// removed again when the rest can't be
+	ids := []string{}
+	fail := func(err error) (*envApi.SpawnEntitiesResponse, error) {
+		s.removeSpawned(ids, req.ClassID)
+		return nil, err
+	}
+	for _, c := range candidates {
+		if len(ids) == int(req.Count) {
+			break
+		}
+		entityID, err := s.ids.NewID()
+		if err != nil {
+			err := errors.New("Error generating id")
+			s.logger.Error("generating id", zap.Error(err))
+			return fail(err)
+		}
+		e := envApi.Entity{
+			Id:       entityID,
+			ClassID:  req.ClassID,
+			X:        c.pos.x,
+			Y:        c.pos.y,
+			OwnerUID: ownerUID,
+			ModelID:  req.ModelID,
+		}
+		if req.ClassID == envApi.Entity_AGENT {
+			e.Energy = s.rules.StartingEnergy
+			e.Health = s.rules.StartingHealth
+		}
+		// A cell taken since it was ranked is passed over for the next best
+		placed, err := s.spawnEntityAt(e, true)
+		if err != nil {
+			return fail(err)
+		}
+		if !placed {
+			continue
+		}
+		if req.ClassID == envApi.Entity_AGENT {
+			s.datacomDAL.AddEntityMetadataToFireabase(e)
+		}
+		ids = append(ids, entityID)
+	}
+	if len(ids) < int(req.Count) {
+		err := fmt.Errorf("only %v free cells left for %v entities, the rest filled up while spawning", len(ids), req.Count)
+		s.logger.Warn("rejected spawn", zap.Error(err))
+		return fail(err)
+	}
+
+	return &envApi.SpawnEntitiesResponse{
+		Ids: ids,
+	}, nil
+}
+
+// removeSpawned takes back the entities of a spawn that failed part way.
+// Entities already gone, like food eaten in the meantime, are skipped.
+func (s *environmentServer) removeSpawned(ids []string, class envApi.Entity_Class) {
+	for _, id := range ids {
+		if _, err := s.datacomDAL.DeleteEntity(id); err != nil {
+			s.logger.Error("removing spawned entity", zap.Error(err), zap.String("entity", id))
+			continue
+		}
+		if class == envApi.Entity_AGENT {
+			s.datacomDAL.RemoveEntityMetadataFromFirebase(id)
+		}
+	}
+}
+
+// spawnCandidates picks free cells in the bounds, best first. Big regions are
+// sampled by rejection, small ones and ones too full to sample have every
+// free cell ranked: drawn without replacement weighted by the distribution,
+// each gets its log weight plus gumbel noise, and the highest keys win.
+func (s *environmentServer) spawnCandidates(req *envApi.SpawnEntitiesRequest, bounds *envApi.Rect) ([]spawnCandidate, error) {
+	entities, err := s.datacomDAL.GetEntitiesInSpace(bounds.X0, bounds.Y0, bounds.X1, bounds.Y1)
+	if err != nil {
+		s.logger.Error("getting entities in region", zap.Error(err))
+		return nil, err
+	}
+	occupied := make(map[position]bool, len(entities))
+	for _, e := range entities {
+		occupied[position{e.X, e.Y}] = true
+	}
+
+	// Distances are from the center of the rectangle
+	cx := (float64(bounds.X0) + float64(bounds.X1)) / 2
+	cy := (float64(bounds.Y0) + float64(bounds.Y1)) / 2
+	side := math.Min(float64(bounds.X1-bounds.X0+1), float64(bounds.Y1-bounds.Y0+1))
+	spread := float64(req.Spread)
+	radius := float64(req.Radius)
+	if radius == 0 {
+		radius = side / 3
+	}
+	if spread == 0 {
+		spread = side / 6
+		if req.Distribution == envApi.SpawnEntitiesRequest_RING {
+			spread = 1
+		}
+	}
+	logWeight := func(x float64, y float64) float64 {
+		d := math.Hypot(x-cx, y-cy)
+		switch req.Distribution {
+		case envApi.SpawnEntitiesRequest_GAUSSIAN:
+			return -d * d / (2 * spread * spread)
+		case envApi.SpawnEntitiesRequest_RING:
+			return -(d - radius) * (d - radius) / (2 * spread * spread)
+		default:
+			return 0
+		}
+	}
+
+	area := uint64(bounds.X1-bounds.X0+1) * uint64(bounds.Y1-bounds.Y0+1)
+	if area > spawnScanCells {
+		if candidates, ok := s.sampleSpawnCells(req, bounds, occupied, cx, cy, spread, radius); ok {
+			return candidates, nil
+		}
+	}
+
+	candidates := []spawnCandidate{}
+	for y := bounds.Y0; y <= bounds.Y1; y++ {
+		for x := bounds.X0; x <= bounds.X1; x++ {
+			if occupied[position{x, y}] || !s.passable(x, y) {
+				continue
+			}
+			gumbel := -math.Log(-math.Log(1 - s.rand.Float64()))
+			candidates = append(candidates, spawnCandidate{
+				pos: position{x, y},
+				key: logWeight(float64(x), float64(y)) + gumbel,
+			})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].key > candidates[j].key
+	})
+	return candidates, nil
+}
+
+// sampleSpawnCells draws the requested number of free cells from the
+// distribution, trying a bounded number of cells for each. It gives up on
+// regions too full to find them in.
+func (s *environmentServer) sampleSpawnCells(req *envApi.SpawnEntitiesRequest, bounds *envApi.Rect, occupied map[position]bool, cx float64, cy float64, spread float64, radius float64) ([]spawnCandidate, bool) {
+	w := float64(bounds.X1 - bounds.X0 + 1)
+	h := float64(bounds.Y1 - bounds.Y0 + 1)
+	picked := make(map[position]bool, req.Count)
+	candidates := make([]spawnCandidate, 0, req.Count)
+	for i := 0; i < maxRandomCellAttempts*int(req.Count) && len(candidates) < int(req.Count); i++ {
+		var x, y float64
+		switch req.Distribution {
+		case envApi.SpawnEntitiesRequest_GAUSSIAN:
+			x = cx + s.rand.NormFloat64()*spread
+			y = cy + s.rand.NormFloat64()*spread
+		case envApi.SpawnEntitiesRequest_RING:
+			// There are more cells further out, keep a distance as often as
+			// its circumference is long
+			d := radius + s.rand.NormFloat64()*spread
+			if d < 0 || s.rand.Float64()*(radius+4*spread) > d {
+				continue
+			}
+			angle := s.rand.Float64() * 2 * math.Pi
+			x = cx + d*math.Cos(angle)
+			y = cy + d*math.Sin(angle)
+		default:
+			x = float64(bounds.X0) + math.Floor(s.rand.Float64()*w)
+			y = float64(bounds.Y0) + math.Floor(s.rand.Float64()*h)
+		}
+		x, y = math.Floor(x+0.5), math.Floor(y+0.5)
+		if x < float64(bounds.X0) || x > float64(bounds.X1) || y < float64(bounds.Y0) || y > float64(bounds.Y1) {
+			continue
+		}
+		pos := position{uint32(x), uint32(y)}
+		if occupied[pos] || picked[pos] || !s.passable(pos.x, pos.y) {
+			continue
+		}
+		picked[pos] = true
+		candidates = append(candidates, spawnCandidate{pos: pos})
+	}
+	return candidates, len(candidates) == int(req.Count)
+}
@@ -0,0 +1,229 @@
+package environment
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/datacom"
+	datacomMocks "github.com/terrariumai/simulation/pkg/datacom/mocks"
+	"github.com/terrariumai/simulation/pkg/environment/mocks"
+)
+
+// failingDAL fails to create entities once it has created a few
+type failingDAL struct {
+	*datacom.MemoryDatacom
+	creates int
+}
+
+func (d *failingDAL) CreateEntity(e envApi.Entity, shouldPublish bool) error {
+	if d.creates == 0 {
+		return errors.New("MOCK-ERROR")
+	}
+	d.creates--
+	return d.MemoryDatacom.CreateEntity(e, shouldPublish)
+}
+
+func TestSpawnEntities(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+	size := datacom.WorldSize{Width: 40, Height: 40}
+
+	// distance of an entity from the center of the world
+	distance := func(e *envApi.Entity) float64 {
+		return math.Hypot(float64(e.X)-19.5, float64(e.Y)-19.5)
+	}
+
+	tests := []struct {
+		name string
+		req  *envApi.SpawnEntitiesRequest
+		// Already in the world
+		existing []envApi.Entity
+		// Checked against every spawned entity
+		check func(e *envApi.Entity) bool
+		// Creates that succeed before the datacom starts failing, never
+		// fails when 0
+		failAfter int
+		wantErr   bool
+	}{
+		{
+			name: "Uniform in a rectangle",
+			req:  &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_FOOD, Count: 10, Bounds: &envApi.Rect{X0: 2, Y0: 3, X1: 6, Y1: 7}},
+			check: func(e *envApi.Entity) bool {
+				return e.ClassID == envApi.Entity_FOOD && e.X >= 2 && e.X <= 6 && e.Y >= 3 && e.Y <= 7
+			},
+		},
+		{
+			name:     "Fills every free cell, around the ones taken",
+			req:      &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_ROCK, Count: 24, Bounds: &envApi.Rect{X0: 0, Y0: 0, X1: 4, Y1: 4}},
+			existing: []envApi.Entity{{Id: "food", ClassID: envApi.Entity_FOOD, X: 2, Y: 2}},
+			check: func(e *envApi.Entity) bool {
+				return e.ClassID == envApi.Entity_ROCK && !(e.X == 2 && e.Y == 2)
+			},
+		},
+		{
+			name:  "Gaussian clusters around the center",
+			req:   &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_FOOD, Count: 30, Distribution: envApi.SpawnEntitiesRequest_GAUSSIAN, Spread: 2},
+			check: func(e *envApi.Entity) bool { return distance(e) < 10 },
+		},
+		{
+			name:  "Ring around the center",
+			req:   &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_FOOD, Count: 30, Distribution: envApi.SpawnEntitiesRequest_RING, Radius: 12},
+			check: func(e *envApi.Entity) bool { return distance(e) > 8 && distance(e) < 16 },
+		},
+		{
+			name: "Agents belong to the model's owner",
+			req:  &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_AGENT, Count: 3, ModelID: "MOCK-MODEL-ID"},
+			check: func(e *envApi.Entity) bool {
+				return e.OwnerUID == "MOCK-UID" && e.ModelID == "MOCK-MODEL-ID" && e.Energy == 100 && e.Health == 100
+			},
+		},
+		{
+			name:     "More entities than free cells",
+			req:      &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_FOOD, Count: 25, Bounds: &envApi.Rect{X0: 0, Y0: 0, X1: 4, Y1: 4}},
+			existing: []envApi.Entity{{Id: "food", ClassID: envApi.Entity_FOOD, X: 2, Y: 2}},
+			wantErr:  true,
+		},
+		{
+			name:      "Failing part way spawns nothing",
+			req:       &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_AGENT, Count: 5, ModelID: "MOCK-MODEL-ID"},
+			failAfter: 3,
+			wantErr:   true,
+		},
+		{name: "Invalid class", req: &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_EMPTY, Count: 1}, wantErr: true},
+		{name: "Agents need a model", req: &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_AGENT, Count: 1}, wantErr: true},
+		{name: "Bounds off the map", req: &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_FOOD, Count: 1, Bounds: &envApi.Rect{X1: 40, Y1: 5}}, wantErr: true},
+		{name: "Bounds inside out", req: &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_FOOD, Count: 1, Bounds: &envApi.Rect{X0: 5, X1: 4, Y1: 5}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPAL := &datacomMocks.PubsubAccessLayer{}
+			mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			dal, _ := datacom.NewMemoryDatacom(mockPAL, size, datacom.Bounded)
+			for _, e := range tt.existing {
+				dal.CreateEntity(e, false)
+			}
+			var d DataAccessLayer = dal
+			if tt.failAfter > 0 {
+				d = &failingDAL{dal, tt.failAfter}
+			}
+			s := NewEnvironmentServer("testing", d, DefaultWorldRules(), WithSeed(1), WithWorldSize(size))
+
+			resp, err := s.SpawnEntities(ctx, tt.req)
+			entities, _ := dal.GetEntitiesInSpace(0, 0, 39, 39)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				if len(entities) != len(tt.existing) {
+					t.Errorf("expected nothing to be spawned, got %v", entities)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Exactly the returned ids were spawned
+			if len(resp.Ids) != int(tt.req.Count) || len(entities) != len(tt.existing)+int(tt.req.Count) {
+				t.Fatalf("got %v ids and %v new entities, want %v", len(resp.Ids), len(entities)-len(tt.existing), tt.req.Count)
+			}
+			for _, id := range resp.Ids {
+				e, _, err := dal.GetEntity(id)
+				if err != nil {
+					t.Fatalf("spawned entity %v is missing: %v", id, err)
+				}
+				if !tt.check(e) {
+					t.Errorf("spawned entity %v is out of place", e)
+				}
+			}
+		})
+	}
+}
+
+func TestSpawnIntoHugeRegion(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+	// Far too many cells to rank one by one
+	size := datacom.WorldSize{Width: 32768, Height: 32768}
+	center := func(e *envApi.Entity) float64 {
+		return math.Hypot(float64(e.X)-16383.5, float64(e.Y)-16383.5)
+	}
+
+	tests := []struct {
+		name  string
+		req   *envApi.SpawnEntitiesRequest
+		check func(e *envApi.Entity) bool
+	}{
+		{
+			name:  "Uniform",
+			req:   &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_FOOD, Count: 5},
+			check: func(e *envApi.Entity) bool { return e.X < size.Width && e.Y < size.Height },
+		},
+		{
+			name:  "Gaussian",
+			req:   &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_FOOD, Count: 5, Distribution: envApi.SpawnEntitiesRequest_GAUSSIAN, Spread: 2},
+			check: func(e *envApi.Entity) bool { return center(e) < 10 },
+		},
+		{
+			name:  "Ring",
+			req:   &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_FOOD, Count: 5, Distribution: envApi.SpawnEntitiesRequest_RING, Radius: 100, Spread: 1},
+			check: func(e *envApi.Entity) bool { return center(e) > 95 && center(e) < 105 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDAL := &mocks.DataAccessLayer{}
+			mockCellLocks(mockDAL)
+			mockEmptyWorld(mockDAL)
+			mockDAL.On("IsCellOccupied", mock.Anything, mock.Anything).Return(false, nil, "", nil)
+			var spawned []envApi.Entity
+			mockDAL.On("CreateEntity", mock.Anything, true).Return(nil).Run(func(args mock.Arguments) {
+				spawned = append(spawned, args.Get(0).(envApi.Entity))
+			})
+			s := NewEnvironmentServer("testing", mockDAL, DefaultWorldRules(), WithSeed(1), WithWorldSize(size))
+
+			resp, err := s.SpawnEntities(ctx, tt.req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(resp.Ids) != int(tt.req.Count) || len(spawned) != int(tt.req.Count) {
+				t.Fatalf("got %v ids and %v entities, want %v", len(resp.Ids), len(spawned), tt.req.Count)
+			}
+			for i := range spawned {
+				if !tt.check(&spawned[i]) {
+					t.Errorf("spawned entity %v is out of place", spawned[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSpawnIntoNearlyFullRegion(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+	size := datacom.WorldSize{Width: 40, Height: 40}
+	mockPAL := &datacomMocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dal, _ := datacom.NewMemoryDatacom(mockPAL, size, datacom.Bounded)
+	s := NewEnvironmentServer("testing", dal, DefaultWorldRules(), WithSeed(1), WithWorldSize(size))
+
+	// Too big to rank cell by cell, but only a row left free is too full to
+	// sample, so every free cell is ranked after all
+	if _, err := s.SpawnEntities(ctx, &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_ROCK, Count: 40 * 39, Bounds: &envApi.Rect{X1: 39, Y0: 1, Y1: 39}}); err != nil {
+		t.Fatalf("unexpected error filling the world: %v", err)
+	}
+	resp, err := s.SpawnEntities(ctx, &envApi.SpawnEntitiesRequest{ClassID: envApi.Entity_FOOD, Count: 40})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, id := range resp.Ids {
+		if e, _, _ := dal.GetEntity(id); e.Y != 0 {
+			t.Errorf("spawned entity %v is out of place", e)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package environment
+
+import (
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/datacom"
+	"github.com/terrariumai/simulation/pkg/terrain"
+	"github.com/terrariumai/simulation/pkg/worldgen"
+)
+
+// WithTerrain sets the walls, water and slow ground of the world. It has to
+// be the same size as the world, and the same map the datacom was given.
+// Without it the world is all ground.
+func WithTerrain(m *terrain.Map) ServerOption {
+	return func(s *environmentServer) {
+		s.terrain = m
+	}
+}
+
+// WithGenerator generates the terrain and food of every reset from its seed,
+// unless the reset brings its own generator
+func WithGenerator(p worldgen.Params) ServerOption {
+	return func(s *environmentServer) {
+		s.generator = &p
+	}
+}
+
+// currentTerrain returns the terrain, which a generated reset can swap out
+func (s *environmentServer) currentTerrain() *terrain.Map {
+	s.terrainM.RLock()
+	defer s.terrainM.RUnlock()
+	return s.terrain
+}
+
+// setTerrain swaps the terrain for a freshly generated one
+func (s *environmentServer) setTerrain(m *terrain.Map) {
+	s.terrainM.Lock()
+	defer s.terrainM.Unlock()
+	s.terrain = m
+}
+
+// generatorParams fits a reset's generator, or else the server's, to the
+// world. Nil if the reset doesn't generate anything.
+func (s *environmentServer) generatorParams(g *envApi.WorldGenerator) *worldgen.Params {
+	var p worldgen.Params
+	switch {
+	case g != nil:
+		p = worldgen.Params{
+			Scale:           float64(g.Scale),
+			Walls:           float64(g.Walls),
+			Water:           float64(g.Water),
+			SlowGround:      float64(g.SlowGround),
+			FoodPatches:     g.FoodPatches,
+			FoodPatchRadius: g.FoodPatchRadius,
+			FoodDensity:     float64(g.FoodDensity),
+		}
+	case s.generator != nil:
+		p = *s.generator
+	default:
+		return nil
+	}
+	p.Width, p.Height = s.worldSize.Width, s.worldSize.Height
+	p.Torus = s.topology == datacom.Torus
+	return &p
+}
+
+// passable checks if an entity can move onto or be placed on a cell
+func (s *environmentServer) passable(x uint32, y uint32) bool {
+	return s.onMap(x, y) && s.currentTerrain().At(x, y).Passable()
+}
+
+// moveEnergyCost is the energy it takes to move onto a cell
+func (s *environmentServer) moveEnergyCost(x uint32, y uint32) uint32 {
+	if s.currentTerrain().At(x, y) == terrain.Slow {
+		return s.rules.MoveEnergyCost + s.rules.SlowGroundEnergyCost
+	}
+	return s.rules.MoveEnergyCost
+}
+
+// openCells counts the cells of a terrain that can be stood on
+func (s *environmentServer) openCells(m *terrain.Map) uint64 {
+	if m == nil {
+		return uint64(s.worldSize.Width) * uint64(s.worldSize.Height)
+	}
+	var count uint64
+	for y := uint32(0); y < s.worldSize.Height; y++ {
+		for x := uint32(0); x < s.worldSize.Width; x++ {
+			if m.At(x, y).Passable() {
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,399 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Tick mode collects one action per agent and resolves them all at once at the
+// end of every tick, so the order actions arrive in never decides the outcome.
+// Every tick resolves against the world as it was when the tick started:
+//
+//   1. Every acting agent pays the living cost
+//   2. Attacks land together. Damage from every attacker on a target is summed,
+//      and two agents attacking each other both take damage
+//   3. Agents eat. Agents eating the same food split the energy evenly
+//   4. Agents move. A move fails if the target cell was occupied when the tick
+//      started, or if more than one agent moves into the same cell
+//
+// An agent killed in an earlier phase doesn't act in a later one. With food
+// regrowth, food grows after every foodGrowthInterval ticks are resolved.
+
+// queuedAction is an action waiting for the next tick
+type queuedAction struct {
+	req  *envApi.ExecuteAgentActionRequest
+	done chan actionResult
+}
+
+// actionResult is the outcome of an action once its tick is resolved
+type actionResult struct {
+	resp *envApi.ExecuteAgentActionResponse
+	err  error
+}
+
+// tickEntity is an entity taking part in a tick, along with the content it
+// had when the tick started
+type tickEntity struct {
+	entity  *envApi.Entity
+	content string
+	// Whether the entity needs to be written back
+	changed bool
+	dead    bool
+}
+
+// WithTickInterval runs the world in tick mode, resolving every queued action
+// once per interval
+func WithTickInterval(interval time.Duration) ServerOption {
+	return func(s *environmentServer) {
+		s.tickInterval = interval
+	}
+}
+
+// runTicks resolves a tick every interval, forever
+func (s *environmentServer) runTicks() {
+	ticker := time.NewTicker(s.tickInterval)
+	for range ticker.C {
+		s.resolveTick()
+	}
+}
+
+// queueAction adds an action to the current tick and waits for it to be resolved
+func (s *environmentServer) queueAction(ctx context.Context, req *envApi.ExecuteAgentActionRequest) (*envApi.ExecuteAgentActionResponse, error) {
+	s.tickM.Lock()
+	if _, ok := s.queued[req.Id]; ok {
+		s.tickM.Unlock()
+		err := fmt.Errorf("agent %v already has an action this tick", req.Id)
+		s.logger.Warn("rejected action", zap.Error(err), zap.String("entity", req.Id))
+		return nil, err
+	}
+	q := &queuedAction{
+		req:  req,
+		done: make(chan actionResult, 1),
+	}
+	s.queued[req.Id] = q
+	s.tickM.Unlock()
+
+	// Note: the action still resolves if the caller goes away
+	select {
+	case result := <-q.done:
+		return result.resp, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dropQueuedActions fails every action waiting for the next tick, they were
+// sent for a world that has been reset
+func (s *environmentServer) dropQueuedActions() {
+	if s.tickInterval == 0 {
+		return
+	}
+	s.tickM.Lock()
+	queued := s.queued
+	s.queued = make(map[string]*queuedAction)
+	s.tickM.Unlock()
+	for _, q := range queued {
+		q.done <- actionResult{err: status.Error(codes.Aborted, "the world was reset before the action's tick")}
+	}
+}
+
+// resolveTick resolves every action queued for the current tick and starts
+// the next one
+func (s *environmentServer) resolveTick() {
+	s.resolveM.Lock()
+	defer s.resolveM.Unlock()
+	s.tickM.Lock()
+	queued := s.queued
+	s.queued = make(map[string]*queuedAction)
+	s.tick++
+	tick := s.tick
+	s.tickM.Unlock()
+
+	if len(queued) > 0 {
+		reqs := make(map[string]*envApi.ExecuteAgentActionRequest, len(queued))
+		for id, q := range queued {
+			reqs[id] = q.req
+		}
+		results := s.resolveActions(reqs)
+		for id, q := range queued {
+			result := results[id]
+			if result.resp != nil {
+				result.resp.Tick = tick
+			}
+			q.done <- result
+		}
+	}
+
+	// Food grows between ticks, every growth interval
+	if s.growthDue(tick) {
+		s.growIfOwner(s.tickInterval * time.Duration(s.rules.FoodGrowthInterval))
+	}
+}
+
+// resolveActions resolves a tick worth of actions, keyed by agent id
+func (s *environmentServer) resolveActions(reqs map[string]*envApi.ExecuteAgentActionRequest) map[string]actionResult {
+	results := make(map[string]actionResult, len(reqs))
+	invalid := func(id string) {
+		results[id] = actionResult{resp: &envApi.ExecuteAgentActionResponse{
+			Value: envApi.ExecuteAgentActionResponse_ERR_INVALID_TARGET,
+		}}
+	}
+
+	// Go through agents in id order so nothing depends on arrival order
+	ids := make([]string, 0, len(reqs))
+	for id := range reqs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Every entity touched this tick, in the order they were first seen
+	entities := make(map[string]*tickEntity)
+	touched := []string{}
+	track := func(e *envApi.Entity, content string) *tickEntity {
+		if t, ok := entities[e.Id]; ok {
+			return t
+		}
+		t := &tickEntity{entity: e, content: content}
+		entities[e.Id] = t
+		touched = append(touched, e.Id)
+		return t
+	}
+
+	// Lock every agent's cell and the cell it is facing for the whole tick
+	cells := []position{}
+	lockedAt := make(map[string]position)
+	for _, id := range ids {
+		entity, _, err := s.datacomDAL.GetEntity(id)
+		if err != nil {
+			continue
+		}
+		lockedAt[id] = position{entity.X, entity.Y}
+		cells = append(cells, lockedAt[id])
+		if reqs[id].Action != envApi.ExecuteAgentActionRequest_WAIT {
+			x, y := s.targetCell(entity, reqs[id].Direction)
+			cells = append(cells, position{x, y})
+		}
+	}
+	unlock, err := s.lockCells(cells...)
+	if err != nil {
+		for _, id := range ids {
+			results[id] = actionResult{err: err}
+		}
+		return results
+	}
+	defer unlock()
+
+	// Get the acting agents
+	actors := []string{}
+	for _, id := range ids {
+		entity, content, err := s.datacomDAL.GetEntity(id)
+		if err != nil {
+			// An error here essentially means the agent was removed manually
+			results[id] = actionResult{err: err}
+			continue
+		}
+		// Only another environment moving the agent could get it out of its cell
+		if lockedAt[id] != (position{entity.X, entity.Y}) {
+			err := errors.New("entity moved, try again")
+			s.logger.Warn("resolving action", zap.Error(err), zap.String("entity", id))
+			results[id] = actionResult{err: err}
+			continue
+		}
+		t := track(entity, content)
+		// Agents are always written back, they at least paid to live
+		t.changed = true
+		actors = append(actors, id)
+	}
+
+	// Look at every targeted cell before anything changes
+	targetPositions := make(map[string]position)
+	targets := make(map[string]*tickEntity)
+	badTargets := make(map[string]bool)
+	for _, id := range actors {
+		req := reqs[id]
+		if req.Action == envApi.ExecuteAgentActionRequest_WAIT {
+			continue
+		}
+		x, y := s.targetCell(entities[id].entity, req.Direction)
+		targetPositions[id] = position{x, y}
+		if !s.onMap(x, y) {
+			badTargets[id] = true
+			continue
+		}
+		isCellOccupied, other, otherContent, err := s.datacomDAL.IsCellOccupied(x, y)
+		if err != nil {
+			badTargets[id] = true
+			continue
+		}
+		if isCellOccupied {
+			targets[id] = track(other, otherContent)
+		}
+	}
+
+	// 1. Living energy cost
+	for _, id := range actors {
+		t := entities[id]
+		if !spendEnergy(t.entity, s.rules.LivingEnergyCost) {
+			t.dead = true
+			continue
+		}
+		if reqs[id].Action > envApi.ExecuteAgentActionRequest_ATTACK {
+			invalid(id)
+		}
+	}
+
+	// 2. Attacks, every attacker hits the target as it was at the start
+	damage := make(map[string]uint32)
+	attacked := []string{}
+	for _, id := range actors {
+		t := entities[id]
+		if t.dead || reqs[id].Action != envApi.ExecuteAgentActionRequest_ATTACK {
+			continue
+		}
+		other, ok := targets[id]
+		if badTargets[id] || !ok || other.entity.ClassID != envApi.Entity_AGENT {
+			invalid(id)
+			continue
+		}
+		if !spendEnergy(t.entity, s.rules.AttackEnergyCost) {
+			t.dead = true
+			continue
+		}
+		if _, ok := damage[other.entity.Id]; !ok {
+			attacked = append(attacked, other.entity.Id)
+		}
+		damage[other.entity.Id] += s.rules.AttackDamage
+	}
+	for _, id := range attacked {
+		t := entities[id]
+		if t.dead {
+			continue
+		}
+		t.changed = true
+		if t.entity.Health > damage[id] {
+			t.entity.Health -= damage[id]
+		} else {
+			t.dead = true
+		}
+	}
+
+	// 3. Eating, agents on the same food share it
+	eaters := make(map[string][]string)
+	eaten := []string{}
+	for _, id := range actors {
+		if entities[id].dead || reqs[id].Action != envApi.ExecuteAgentActionRequest_EAT {
+			continue
+		}
+		food, ok := targets[id]
+		if badTargets[id] || !ok || food.entity.ClassID != envApi.Entity_FOOD {
+			invalid(id)
+			continue
+		}
+		if len(eaters[food.entity.Id]) == 0 {
+			eaten = append(eaten, food.entity.Id)
+		}
+		eaters[food.entity.Id] = append(eaters[food.entity.Id], id)
+	}
+	for _, foodID := range eaten {
+		share := s.rules.EnergyGainOnEat / uint32(len(eaters[foodID]))
+		for _, id := range eaters[foodID] {
+			entities[id].entity.Energy += share
+		}
+		entities[foodID].dead = true
+	}
+
+	// 4. Moves, only into cells that were empty and nobody else wants
+	moving := make(map[position]int)
+	for _, id := range actors {
+		if entities[id].dead || reqs[id].Action != envApi.ExecuteAgentActionRequest_MOVE {
+			continue
+		}
+		pos := targetPositions[id]
+		if _, occupied := targets[id]; badTargets[id] || occupied || !s.passable(pos.x, pos.y) {
+			invalid(id)
+			continue
+		}
+		moving[pos]++
+	}
+	for _, id := range actors {
+		t := entities[id]
+		if t.dead || reqs[id].Action != envApi.ExecuteAgentActionRequest_MOVE {
+			continue
+		}
+		if _, ok := results[id]; ok {
+			continue
+		}
+		pos := targetPositions[id]
+		if moving[pos] > 1 {
+			invalid(id)
+			continue
+		}
+		if !spendEnergy(t.entity, s.moveEnergyCost(pos.x, pos.y)) {
+			t.dead = true
+			continue
+		}
+		s.leavePheromone(t.entity)
+		t.entity.X = pos.x
+		t.entity.Y = pos.y
+	}
+
+	// Write everything back
+	for _, id := range touched {
+		t := entities[id]
+		if t.dead {
+			s.datacomDAL.DeleteEntity(id)
+			if t.entity.ClassID == envApi.Entity_AGENT {
+				s.datacomDAL.RemoveEntityMetadataFromFirebase(id)
+			}
+			continue
+		}
+		if !t.changed {
+			continue
+		}
+		if err := s.datacomDAL.UpdateEntity(t.content, *t.entity); err != nil {
+			s.logger.Error("updating entity", zap.Error(err), zap.String("entity", t.entity.Id))
+			if _, ok := reqs[id]; ok {
+				results[id] = actionResult{err: err}
+			}
+		}
+	}
+
+	// Without regrowth the food that was eaten is replaced, this artificially
+	// keeps the ecosystem in check
+	for range eaten {
+		if s.rules.FoodCapacity > 0 {
+			break
+		}
+		if err := s.spawnRandomFood(true); err != nil {
+			s.logger.Error("spawning food", zap.Error(err))
+		}
+	}
+
+	// Dying trumps everything else, anyone left succeeded
+	for _, id := range actors {
+		if result, ok := results[id]; ok && result.err != nil {
+			continue
+		}
+		if entities[id].dead {
+			results[id] = actionResult{resp: &envApi.ExecuteAgentActionResponse{
+				Value: envApi.ExecuteAgentActionResponse_ERR_DIED,
+			}}
+			continue
+		}
+		if _, ok := results[id]; !ok {
+			results[id] = actionResult{resp: &envApi.ExecuteAgentActionResponse{
+				Value: envApi.ExecuteAgentActionResponse_OK,
+			}}
+		}
+	}
+
+	return results
+}
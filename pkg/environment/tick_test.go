@@ -0,0 +1,287 @@
+package environment
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	datacom "github.com/terrariumai/simulation/pkg/datacom"
+	pubsubMocks "github.com/terrariumai/simulation/pkg/datacom/mocks"
+	"github.com/terrariumai/simulation/pkg/terrain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tickWorld builds a redis backed world holding the given entities
+func tickWorld(t *testing.T, redisAddr string, entities []envApi.Entity, opts ...ServerOption) (*environmentServer, *datacom.Datacom) {
+	mockPAL := &pubsubMocks.PubsubAccessLayer{}
+	mockPAL.On("QueuePublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dc, err := datacom.NewDatacom("training", datacom.DefaultWorldID, redisAddr, mockPAL)
+	if err != nil {
+		t.Fatalf("error creating datacom: %v", err)
+	}
+	for _, e := range entities {
+		if err := dc.CreateEntity(e, false); err != nil {
+			t.Fatalf("error creating entity: %v", err)
+		}
+	}
+	opts = append([]ServerOption{WithSeed(1)}, opts...)
+	s := NewEnvironmentServer("testing", dc, DefaultWorldRules(), opts...).(*environmentServer)
+	return s, dc
+}
+
+func agent(id string, x uint32, y uint32, health uint32) envApi.Entity {
+	return envApi.Entity{Id: id, ClassID: envApi.Entity_AGENT, X: x, Y: y, Energy: 100, Health: health, ModelID: "MOCK-MODEL"}
+}
+
+func TestResolveActions(t *testing.T) {
+	food := envApi.Entity{Id: "F", ClassID: envApi.Entity_FOOD, X: 5, Y: 5}
+	act := func(id string, action envApi.ExecuteAgentActionRequest_Action, direction envApi.ExecuteAgentActionRequest_Direction) *envApi.ExecuteAgentActionRequest {
+		return &envApi.ExecuteAgentActionRequest{Id: id, Action: action, Direction: direction}
+	}
+	const (
+		move   = envApi.ExecuteAgentActionRequest_MOVE
+		eat    = envApi.ExecuteAgentActionRequest_EAT
+		attack = envApi.ExecuteAgentActionRequest_ATTACK
+		up     = envApi.ExecuteAgentActionRequest_UP
+		left   = envApi.ExecuteAgentActionRequest_LEFT
+		right  = envApi.ExecuteAgentActionRequest_RIGHT
+		ok     = envApi.ExecuteAgentActionResponse_OK
+		bad    = envApi.ExecuteAgentActionResponse_ERR_INVALID_TARGET
+		died   = envApi.ExecuteAgentActionResponse_ERR_DIED
+	)
+
+	terrainMap := terrain.New(100, 100)
+	terrainMap.Set(6, 5, terrain.Wall)
+	terrainMap.Set(4, 8, terrain.Slow)
+
+	tests := []struct {
+		name     string
+		entities []envApi.Entity
+		actions  []*envApi.ExecuteAgentActionRequest
+		want     map[string]envApi.ExecuteAgentActionResponse_ResponseValue
+		// Entities after the tick, nil if they should be gone
+		wantEntities map[string]*envApi.Entity
+		wantFood     int
+		opts         []ServerOption
+	}{
+		{
+			name:     "Agents moving into the same cell both fail",
+			entities: []envApi.Entity{agent("A", 5, 5, 100), agent("B", 7, 5, 100)},
+			actions:  []*envApi.ExecuteAgentActionRequest{act("A", move, right), act("B", move, left)},
+			want:     map[string]envApi.ExecuteAgentActionResponse_ResponseValue{"A": bad, "B": bad},
+			wantEntities: map[string]*envApi.Entity{
+				"A": {Id: "A", ClassID: envApi.Entity_AGENT, X: 5, Y: 5, Energy: 99, Health: 100, ModelID: "MOCK-MODEL"},
+				"B": {Id: "B", ClassID: envApi.Entity_AGENT, X: 7, Y: 5, Energy: 99, Health: 100, ModelID: "MOCK-MODEL"},
+			},
+		},
+		{
+			name:     "Moving into a cell emptied this tick fails",
+			entities: []envApi.Entity{agent("A", 4, 5, 100), agent("B", 5, 5, 100)},
+			actions:  []*envApi.ExecuteAgentActionRequest{act("A", move, right), act("B", move, right)},
+			want:     map[string]envApi.ExecuteAgentActionResponse_ResponseValue{"A": bad, "B": ok},
+			wantEntities: map[string]*envApi.Entity{
+				"A": {Id: "A", ClassID: envApi.Entity_AGENT, X: 4, Y: 5, Energy: 99, Health: 100, ModelID: "MOCK-MODEL"},
+				"B": {Id: "B", ClassID: envApi.Entity_AGENT, X: 6, Y: 5, Energy: 97, Health: 100, ModelID: "MOCK-MODEL"},
+			},
+		},
+		{
+			name:     "Agents attacking each other both take damage",
+			entities: []envApi.Entity{agent("A", 5, 5, 100), agent("B", 6, 5, 100)},
+			actions:  []*envApi.ExecuteAgentActionRequest{act("A", attack, right), act("B", attack, left)},
+			want:     map[string]envApi.ExecuteAgentActionResponse_ResponseValue{"A": ok, "B": ok},
+			wantEntities: map[string]*envApi.Entity{
+				"A": {Id: "A", ClassID: envApi.Entity_AGENT, X: 5, Y: 5, Energy: 94, Health: 90, ModelID: "MOCK-MODEL"},
+				"B": {Id: "B", ClassID: envApi.Entity_AGENT, X: 6, Y: 5, Energy: 94, Health: 90, ModelID: "MOCK-MODEL"},
+			},
+		},
+		{
+			name:         "Agents killing each other both die",
+			entities:     []envApi.Entity{agent("A", 5, 5, 10), agent("B", 6, 5, 10)},
+			actions:      []*envApi.ExecuteAgentActionRequest{act("A", attack, right), act("B", attack, left)},
+			want:         map[string]envApi.ExecuteAgentActionResponse_ResponseValue{"A": died, "B": died},
+			wantEntities: map[string]*envApi.Entity{"A": nil, "B": nil},
+		},
+		{
+			name:     "Attacks on the same target add up",
+			entities: []envApi.Entity{agent("A", 4, 5, 100), agent("B", 5, 5, 15), agent("C", 6, 5, 100)},
+			actions:  []*envApi.ExecuteAgentActionRequest{act("A", attack, right), act("C", attack, left)},
+			want:     map[string]envApi.ExecuteAgentActionResponse_ResponseValue{"A": ok, "C": ok},
+			wantEntities: map[string]*envApi.Entity{
+				"A": {Id: "A", ClassID: envApi.Entity_AGENT, X: 4, Y: 5, Energy: 94, Health: 100, ModelID: "MOCK-MODEL"},
+				"B": nil,
+			},
+		},
+		{
+			name:     "Agents eating the same food share it",
+			entities: []envApi.Entity{agent("A", 4, 5, 100), food, agent("C", 6, 5, 100)},
+			actions:  []*envApi.ExecuteAgentActionRequest{act("A", eat, right), act("C", eat, left)},
+			want:     map[string]envApi.ExecuteAgentActionResponse_ResponseValue{"A": ok, "C": ok},
+			wantEntities: map[string]*envApi.Entity{
+				"A": {Id: "A", ClassID: envApi.Entity_AGENT, X: 4, Y: 5, Energy: 104, Health: 100, ModelID: "MOCK-MODEL"},
+				"C": {Id: "C", ClassID: envApi.Entity_AGENT, X: 6, Y: 5, Energy: 104, Health: 100, ModelID: "MOCK-MODEL"},
+				"F": nil,
+			},
+			// The eaten food is replaced
+			wantFood: 1,
+		},
+		{
+			name:     "Agent killed by an attack doesn't eat",
+			entities: []envApi.Entity{agent("A", 5, 3, 100), agent("B", 5, 4, 10), food},
+			actions:  []*envApi.ExecuteAgentActionRequest{act("A", attack, up), act("B", eat, up)},
+			want:     map[string]envApi.ExecuteAgentActionResponse_ResponseValue{"A": ok, "B": died},
+			wantEntities: map[string]*envApi.Entity{
+				"B": nil,
+				"F": {Id: "F", ClassID: envApi.Entity_FOOD, X: 5, Y: 5},
+			},
+			wantFood: 1,
+		},
+		{
+			name:     "Walls block moves and slow ground costs extra",
+			entities: []envApi.Entity{agent("A", 5, 5, 100), agent("B", 5, 8, 100)},
+			actions:  []*envApi.ExecuteAgentActionRequest{act("A", move, right), act("B", move, left)},
+			want:     map[string]envApi.ExecuteAgentActionResponse_ResponseValue{"A": bad, "B": ok},
+			wantEntities: map[string]*envApi.Entity{
+				"A": {Id: "A", ClassID: envApi.Entity_AGENT, X: 5, Y: 5, Energy: 99, Health: 100, ModelID: "MOCK-MODEL"},
+				"B": {Id: "B", ClassID: envApi.Entity_AGENT, X: 4, Y: 8, Energy: 95, Health: 100, ModelID: "MOCK-MODEL"},
+			},
+			opts: []ServerOption{WithTerrain(terrainMap)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, redisServer := setup()
+			defer teardown(redisServer)
+			s, dc := tickWorld(t, redisServer.Addr(), tt.entities, tt.opts...)
+
+			reqs := make(map[string]*envApi.ExecuteAgentActionRequest)
+			for _, req := range tt.actions {
+				reqs[req.Id] = req
+			}
+			results := s.resolveActions(reqs)
+
+			for id, want := range tt.want {
+				result := results[id]
+				if result.err != nil {
+					t.Errorf("%v: unexpected error: %v", id, result.err)
+					continue
+				}
+				if result.resp.Value != want {
+					t.Errorf("%v: got %v, want %v", id, result.resp.Value, want)
+				}
+			}
+			for id, want := range tt.wantEntities {
+				got, _, err := dc.GetEntity(id)
+				if want == nil {
+					if err == nil {
+						t.Errorf("%v: expected entity to be gone, got %v", id, got)
+					}
+					continue
+				}
+				if err != nil {
+					t.Errorf("%v: unexpected error: %v", id, err)
+					continue
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%v: got %v, want %v", id, got, want)
+				}
+			}
+			all, err := dc.GetEntitiesInSpace(0, 0, datacom.DefaultWorldSize.Width-1, datacom.DefaultWorldSize.Height-1)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			foodCount := 0
+			for _, e := range all {
+				if e.ClassID == envApi.Entity_FOOD {
+					foodCount++
+				}
+			}
+			if foodCount != tt.wantFood {
+				t.Errorf("got %v food, want %v", foodCount, tt.wantFood)
+			}
+		})
+	}
+}
+
+func TestTickQueue(t *testing.T) {
+	_, redisServer := setup()
+	defer teardown(redisServer)
+	// Long enough that the ticker never fires, ticks are resolved by hand
+	s, _ := tickWorld(t, redisServer.Addr(), []envApi.Entity{agent("A", 5, 5, 100)}, WithTickInterval(time.Hour))
+
+	type response struct {
+		resp *envApi.ExecuteAgentActionResponse
+		err  error
+	}
+	done := make(chan response)
+	go func() {
+		resp, err := s.ExecuteAgentAction(context.Background(), &envApi.ExecuteAgentActionRequest{Id: "A"})
+		done <- response{resp, err}
+	}()
+
+	// Wait for the action to be queued
+	for {
+		s.tickM.Lock()
+		queued := len(s.queued)
+		s.tickM.Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Only one action per agent per tick
+	_, err := s.ExecuteAgentAction(context.Background(), &envApi.ExecuteAgentActionRequest{Id: "A"})
+	if err == nil || err.Error() != "agent A already has an action this tick" {
+		t.Errorf("got error %v, want a duplicate action error", err)
+	}
+
+	s.resolveTick()
+	got := <-done
+	if got.err != nil {
+		t.Fatalf("unexpected error: %v", got.err)
+	}
+	want := &envApi.ExecuteAgentActionResponse{Value: envApi.ExecuteAgentActionResponse_OK, Tick: 1}
+	if !reflect.DeepEqual(got.resp, want) {
+		t.Errorf("got %v, want %v", got.resp, want)
+	}
+}
+
+func TestResetDropsQueuedActions(t *testing.T) {
+	ctx, redisServer := setup()
+	defer teardown(redisServer)
+	s, _ := tickWorld(t, redisServer.Addr(), []envApi.Entity{agent("A", 5, 5, 100)}, WithTickInterval(time.Hour))
+
+	done := make(chan error)
+	go func() {
+		_, err := s.ExecuteAgentAction(context.Background(), &envApi.ExecuteAgentActionRequest{Id: "A"})
+		done <- err
+	}()
+	for {
+		s.tickM.Lock()
+		queued := len(s.queued)
+		s.tickM.Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The action was for the old world, it never resolves against the new one
+	if _, err := s.ResetWorld(ctx, &envApi.ResetWorldRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := <-done; status.Code(err) != codes.Aborted {
+		t.Errorf("got %v, want Aborted", err)
+	}
+	s.tickM.Lock()
+	defer s.tickM.Unlock()
+	if len(s.queued) != 0 {
+		t.Errorf("expected the queue to be empty, got %v", s.queued)
+	}
+}
@@ -0,0 +1,108 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	envApi "github.com/terrariumai/simulation/pkg/api/environment"
+	"github.com/terrariumai/simulation/pkg/datacom"
+	"go.uber.org/zap"
+)
+
+// Worlds share redis and the pubsub keys, each one is served by its own
+// environment server. Any server can create, list and delete the others.
+
+// WithWorld sets the world the server is serving. The datacom has to be for
+// the same world. Defaults to datacom.DefaultWorldID.
+func WithWorld(worldID string) ServerOption {
+	return func(s *environmentServer) {
+		s.worldID = worldID
+	}
+}
+
+// WithWorldSize sets the size of the world the server is serving, it has to
+// match the datacom. Defaults to datacom.DefaultWorldSize.
+func WithWorldSize(size datacom.WorldSize) ServerOption {
+	return func(s *environmentServer) {
+		s.worldSize = size
+	}
+}
+
+// WithWorldTopology sets what happens at the edges of the world the server is
+// serving, it has to match the datacom. Defaults to datacom.Bounded.
+func WithWorldTopology(topology datacom.Topology) ServerOption {
+	return func(s *environmentServer) {
+		s.topology = topology
+	}
+}
+
+// topologies matches the api's topologies up with the datacom's
+var topologies = map[envApi.World_Topology]datacom.Topology{
+	envApi.World_BOUNDED: datacom.Bounded,
+	envApi.World_TORUS:   datacom.Torus,
+}
+
+// apiWorld converts a datacom world to the api's
+func apiWorld(world datacom.World) *envApi.World {
+	apiWorld := &envApi.World{Id: world.ID, Width: world.Size.Width, Height: world.Size.Height}
+	for apiTopology, topology := range topologies {
+		if topology == world.Topology {
+			apiWorld.Topology = apiTopology
+		}
+	}
+	return apiWorld
+}
+
+// CreateWorld registers a new, empty world. Worlds without a size get the
+// default one, and are bounded unless asked to be a torus.
+func (s *environmentServer) CreateWorld(ctx context.Context, req *envApi.CreateWorldRequest) (*envApi.World, error) {
+	size := datacom.WorldSize{Width: req.Width, Height: req.Height}
+	if req.Width == 0 && req.Height == 0 {
+		size = datacom.DefaultWorldSize
+	}
+	topology, ok := topologies[req.Topology]
+	if !ok {
+		err := fmt.Errorf("invalid topology %v", req.Topology)
+		s.logger.Warn("rejected world", zap.Error(err), zap.String("target", req.Id))
+		return nil, err
+	}
+	if err := s.datacomDAL.CreateWorld(req.Id, size, topology); err != nil {
+		s.logger.Error("creating world", zap.Error(err), zap.String("target", req.Id))
+		return nil, err
+	}
+
+	return apiWorld(datacom.World{ID: req.Id, Size: size, Topology: topology}), nil
+}
+
+// ListWorlds returns every world, starting with the default one
+func (s *environmentServer) ListWorlds(ctx context.Context, req *empty.Empty) (*envApi.ListWorldsResponse, error) {
+	list, err := s.datacomDAL.ListWorlds()
+	if err != nil {
+		s.logger.Error("listing worlds", zap.Error(err))
+		return nil, err
+	}
+
+	worlds := []*envApi.World{}
+	for _, world := range list {
+		worlds = append(worlds, apiWorld(world))
+	}
+	return &envApi.ListWorldsResponse{Worlds: worlds}, nil
+}
+
+// DeleteWorld removes a world and everything in it. A server can't delete the
+// world it is serving.
+func (s *environmentServer) DeleteWorld(ctx context.Context, req *envApi.DeleteWorldRequest) (*empty.Empty, error) {
+	if req.Id == s.worldID {
+		err := errors.New("can't delete the world this server is running")
+		s.logger.Warn("rejected world deletion", zap.Error(err), zap.String("target", req.Id))
+		return nil, err
+	}
+	if err := s.datacomDAL.DeleteWorld(req.Id); err != nil {
+		s.logger.Error("deleting world", zap.Error(err), zap.String("target", req.Id))
+		return nil, err
+	}
+
+	return &empty.Empty{}, nil
+}
@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// New builds a json logger for the services from the -log-level and
+// -log-time-format flags. Level goes from Debug(-1) to Fatal(5), and an
+// empty time format logs epoch seconds. Logs go to stderr unless output
+// paths are given.
+func New(level int, timeFormat string, outputPaths ...string) (*zap.Logger, error) {
+	if level < int(zapcore.DebugLevel) || level > int(zapcore.FatalLevel) {
+		return nil, fmt.Errorf("invalid log level %v, must be between %v and %v", level, int(zapcore.DebugLevel), int(zapcore.FatalLevel))
+	}
+
+	cfg := zap.NewProductionConfig()
+	cfg.Level = zap.NewAtomicLevelAt(zapcore.Level(level))
+	if len(timeFormat) > 0 {
+		cfg.EncoderConfig.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendString(t.Format(timeFormat))
+		}
+	}
+	if len(outputPaths) > 0 {
+		cfg.OutputPaths = outputPaths
+		cfg.ErrorOutputPaths = outputPaths
+	}
+	return cfg.Build()
+}
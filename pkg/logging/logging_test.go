@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      int
+		timeFormat string
+		// Messages logged at debug, info and warn that should be written
+		want    []string
+		wantErr bool
+	}{
+		{name: "Debug logs everything", level: -1, want: []string{"debug", "info", "warn"}},
+		{name: "Info is the default", level: 0, want: []string{"info", "warn"}},
+		{name: "Warn drops info", level: 1, want: []string{"warn"}},
+		{name: "Fatal only logs fatal", level: 5, want: []string{}},
+		{name: "Custom time format", level: 0, timeFormat: time.RFC3339, want: []string{"info", "warn"}},
+		{name: "Level too low", level: -2, wantErr: true},
+		{name: "Level too high", level: 6, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "logging")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "log.json")
+
+			logger, err := New(tt.level, tt.timeFormat, path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			logger.Sync()
+
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			for decoder.More() {
+				line := map[string]interface{}{}
+				if err := decoder.Decode(&line); err != nil {
+					t.Fatalf("invalid json line: %v", err)
+				}
+				got = append(got, line["msg"].(string))
+				if len(tt.timeFormat) > 0 {
+					ts, ok := line["ts"].(string)
+					if _, err := time.Parse(tt.timeFormat, ts); !ok || err != nil {
+						t.Errorf("time %v isn't in format %v", line["ts"], tt.timeFormat)
+					}
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("logged %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("logged %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
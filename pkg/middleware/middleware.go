@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
+	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Every call to our services runs through the same chain of interceptors:
+//
+//   1. Tags and request ids, a request id is taken from the caller or made
+//      up, and logged with everything the call logs
+//   2. Logging, one line per call with its status and latency
+//   3. Recovery, a panicking handler fails its call with Internal instead of
+//      taking the whole service down
+//   4. Authentication, when the service has it
+//
+// Logging wraps recovery so crashed calls are logged like any other failure.
+
+// UnaryServerInterceptor chains the interceptors every unary call runs
+// through, auth can be nil
+func UnaryServerInterceptor(logger *zap.Logger, auth grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	interceptors := []grpc.UnaryServerInterceptor{
+		grpc_ctxtags.UnaryServerInterceptor(),
+		unaryServerRequestID(),
+		grpc_zap.UnaryServerInterceptor(logger),
+		grpc_recovery.UnaryServerInterceptor(grpc_recovery.WithRecoveryHandler(recoveryHandler(logger))),
+	}
+	if auth != nil {
+		interceptors = append(interceptors, auth)
+	}
+	return grpc_middleware.ChainUnaryServer(interceptors...)
+}
+
+// StreamServerInterceptor chains the interceptors every stream runs through,
+// auth can be nil
+func StreamServerInterceptor(logger *zap.Logger, auth grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	interceptors := []grpc.StreamServerInterceptor{
+		grpc_ctxtags.StreamServerInterceptor(),
+		streamServerRequestID(),
+		grpc_zap.StreamServerInterceptor(logger),
+		grpc_recovery.StreamServerInterceptor(grpc_recovery.WithRecoveryHandler(recoveryHandler(logger))),
+	}
+	if auth != nil {
+		interceptors = append(interceptors, auth)
+	}
+	return grpc_middleware.ChainStreamServer(interceptors...)
+}
+
+// ServerOptions installs the interceptor chains on a server
+func ServerOptions(logger *zap.Logger, unaryAuth grpc.UnaryServerInterceptor, streamAuth grpc.StreamServerInterceptor) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(UnaryServerInterceptor(logger, unaryAuth)),
+		grpc.StreamInterceptor(StreamServerInterceptor(logger, streamAuth)),
+	}
+}
+
+// DialOptions passes request ids on from every call made through a client
+func DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(StreamClientInterceptor()),
+	}
+}
+
+// recoveryHandler logs a panic with its stack and fails the call. What
+// panicked isn't sent back, it can hold anything.
+func recoveryHandler(logger *zap.Logger) grpc_recovery.RecoveryHandlerFunc {
+	return func(p interface{}) error {
+		logger.Error("recovered from panic", zap.Any("panic", p), zap.Stack("stack"))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
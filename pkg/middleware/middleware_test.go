@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// mockServerStream is a stream with nothing on it
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *mockServerStream) Context() context.Context    { return s.ctx }
+func (s *mockServerStream) SetHeader(metadata.MD) error { return nil }
+
+func TestServerInterceptors(t *testing.T) {
+	requestCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDHeader, "MOCK-REQUEST-ID"))
+	denyAll := func(ctx context.Context) error {
+		return status.Error(codes.Unauthenticated, "who are you")
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		// Auth check, nil without one
+		auth    func(ctx context.Context) error
+		handler func(ctx context.Context) error
+		// Request id the handler should see, any when empty
+		wantRequestID string
+		wantCode      codes.Code
+	}{
+		{name: "Successful call", ctx: context.Background(), handler: func(ctx context.Context) error { return nil }, wantCode: codes.OK},
+		{name: "Failed call", ctx: context.Background(), handler: func(ctx context.Context) error { return status.Error(codes.NotFound, "no such entity") }, wantCode: codes.NotFound},
+		{name: "Panicking call", ctx: context.Background(), handler: func(ctx context.Context) error { panic("MOCK-PANIC") }, wantCode: codes.Internal},
+		{name: "Keeps the caller's request id", ctx: requestCtx, handler: func(ctx context.Context) error { return nil }, wantRequestID: "MOCK-REQUEST-ID", wantCode: codes.OK},
+		{name: "Auth stops the call", ctx: requestCtx, auth: denyAll, handler: func(ctx context.Context) error { panic("called without auth") }, wantCode: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		// Every case runs as a unary call and as a stream
+		for _, kind := range []string{"unary", "stream"} {
+			t.Run(tt.name+" "+kind, func(t *testing.T) {
+				core, logs := observer.New(zapcore.DebugLevel)
+				logger := zap.New(core)
+				requestID := ""
+				handler := func(ctx context.Context) error {
+					requestID = RequestIDFromContext(ctx)
+					return tt.handler(ctx)
+				}
+
+				var err error
+				if kind == "unary" {
+					var auth grpc.UnaryServerInterceptor
+					if tt.auth != nil {
+						auth = func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+							if err := tt.auth(ctx); err != nil {
+								return nil, err
+							}
+							return handler(ctx, req)
+						}
+					}
+					info := &grpc.UnaryServerInfo{FullMethod: "/mock.Service/Call"}
+					_, err = UnaryServerInterceptor(logger, auth)(tt.ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+						return nil, handler(ctx)
+					})
+				} else {
+					var auth grpc.StreamServerInterceptor
+					if tt.auth != nil {
+						auth = func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+							if err := tt.auth(stream.Context()); err != nil {
+								return err
+							}
+							return handler(srv, stream)
+						}
+					}
+					info := &grpc.StreamServerInfo{FullMethod: "/mock.Service/Stream"}
+					err = StreamServerInterceptor(logger, auth)(nil, &mockServerStream{ctx: tt.ctx}, info, func(srv interface{}, stream grpc.ServerStream) error {
+						return handler(stream.Context())
+					})
+				}
+
+				if code := status.Code(err); code != tt.wantCode {
+					t.Errorf("got %v (%v), want %v", code, err, tt.wantCode)
+				}
+				if tt.wantCode == codes.OK && len(requestID) == 0 {
+					t.Errorf("the handler didn't get a request id")
+				}
+				if len(tt.wantRequestID) > 0 && requestID != tt.wantRequestID {
+					t.Errorf("got request id %v, want %v", requestID, tt.wantRequestID)
+				}
+
+				// The call is logged once it finishes, with its status,
+				// latency and request id
+				finished := logs.FilterMessageSnippet("finished").All()
+				if len(finished) != 1 {
+					t.Fatalf("got %v finished lines, want 1: %v", len(finished), logs.All())
+				}
+				fields := finished[0].ContextMap()
+				if fields["grpc.code"] != tt.wantCode.String() {
+					t.Errorf("logged code %v, want %v", fields["grpc.code"], tt.wantCode)
+				}
+				if _, ok := fields["grpc.time_ms"]; !ok {
+					t.Errorf("latency wasn't logged: %v", fields)
+				}
+				if id, ok := fields[RequestIDTag].(string); !ok || len(id) == 0 || (len(requestID) > 0 && id != requestID) {
+					t.Errorf("logged request id %v, want %v", fields[RequestIDTag], requestID)
+				}
+				if tt.wantCode == codes.Internal && logs.FilterMessage("recovered from panic").Len() != 1 {
+					t.Errorf("the panic wasn't logged")
+				}
+			})
+		}
+	}
+}
+
+func TestClientInterceptor(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want []string
+	}{
+		{name: "Passes the request id on", ctx: NewRequestIDContext(context.Background(), "MOCK-REQUEST-ID"), want: []string{"MOCK-REQUEST-ID"}},
+		{name: "Keeps other metadata", ctx: metadata.AppendToOutgoingContext(NewRequestIDContext(context.Background(), "MOCK-REQUEST-ID"), "x-service-token", "MOCK-TOKEN"), want: []string{"MOCK-REQUEST-ID"}},
+		{name: "Outside of a request", ctx: context.Background()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var md metadata.MD
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				md, _ = metadata.FromOutgoingContext(ctx)
+				return nil
+			}
+			if err := UnaryClientInterceptor()(tt.ctx, "/mock.Service/Call", nil, nil, nil, invoker); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := md[RequestIDHeader]; len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+				t.Errorf("sent request ids %v, want %v", got, tt.want)
+			}
+			if original, _ := metadata.FromOutgoingContext(tt.ctx); len(original["x-service-token"]) > 0 && len(md["x-service-token"]) == 0 {
+				t.Errorf("lost the other metadata: %v", md)
+			}
+		})
+	}
+}
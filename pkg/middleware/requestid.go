@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	uuid "github.com/satori/go.uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader carries a request's id between services, so one request can
+// be followed through the collective and every environment call it makes
+const RequestIDHeader = "x-request-id"
+
+// RequestIDTag is the field request ids are logged under
+const RequestIDTag = "request.id"
+
+// Longest request id taken from a caller, longer ones are replaced
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key the request id is kept under
+type requestIDKey struct{}
+
+// NewRequestIDContext returns a context carrying a request id, calls made
+// with it pass the id on
+func NewRequestIDContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext is the id of the request being handled, empty outside
+// of one
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestID takes the caller's request id, or makes a new one when they
+// didn't send one
+func requestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md[RequestIDHeader]; len(ids) > 0 && len(ids[0]) > 0 && len(ids[0]) <= maxRequestIDLength {
+		return ids[0]
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return ""
+	}
+	return id.String()
+}
+
+// withRequestID puts the request id in the context and the logging tags
+func withRequestID(ctx context.Context) (context.Context, string) {
+	id := requestID(ctx)
+	grpc_ctxtags.Extract(ctx).Set(RequestIDTag, id)
+	return NewRequestIDContext(ctx, id), id
+}
+
+// unaryServerRequestID gives every call a request id, and sends it back to
+// the caller
+func unaryServerRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, id := withRequestID(ctx)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+		return handler(ctx, req)
+	}
+}
+
+// streamServerRequestID is unaryServerRequestID for streams
+func streamServerRequestID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, id := withRequestID(stream.Context())
+		stream.SetHeader(metadata.Pairs(RequestIDHeader, id))
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// UnaryClientInterceptor passes the request id on to the services called
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor is UnaryClientInterceptor for streams
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+	}
+}
+
+// outgoingRequestID adds the request id to a call's metadata
+func outgoingRequestID(ctx context.Context) context.Context {
+	id := RequestIDFromContext(ctx)
+	if len(id) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, RequestIDHeader, id)
+}
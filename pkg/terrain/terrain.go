@@ -0,0 +1,184 @@
+package terrain
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Type is what the ground of a cell is made of
+type Type uint8
+
+const (
+	// Ground is open, anything can stand on it
+	Ground Type = iota
+	// Wall can't be entered or stood on
+	Wall
+	// Water can't be entered or stood on
+	Water
+	// Slow ground costs extra energy to move onto
+	Slow
+)
+
+// Passable checks if entities can move onto or be placed on the terrain
+func (t Type) Passable() bool {
+	return t != Wall && t != Water
+}
+
+// Characters of each terrain type in ascii maps
+var asciiTypes = map[rune]Type{
+	'.': Ground,
+	'#': Wall,
+	'~': Water,
+	',': Slow,
+}
+
+// Colors of each terrain type in png maps
+var pngTypes = map[color.RGBA]Type{
+	{255, 255, 255, 255}: Ground,
+	{0, 0, 0, 255}:       Wall,
+	{0, 0, 255, 255}:     Water,
+	{128, 128, 128, 255}: Slow,
+}
+
+// Map is the terrain of every cell in a world
+type Map struct {
+	Width  uint32
+	Height uint32
+	// Row by row, starting at y = 0
+	cells []Type
+}
+
+// New creates a map that is all ground
+func New(width uint32, height uint32) *Map {
+	return &Map{
+		Width:  width,
+		Height: height,
+		cells:  make([]Type, int(width)*int(height)),
+	}
+}
+
+// At returns the terrain of a cell. A nil map, and anything off the map, is
+// ground.
+func (m *Map) At(x uint32, y uint32) Type {
+	if m == nil || x >= m.Width || y >= m.Height {
+		return Ground
+	}
+	return m.cells[int(y)*int(m.Width)+int(x)]
+}
+
+// Set changes the terrain of a cell
+func (m *Map) Set(x uint32, y uint32, t Type) {
+	if x >= m.Width || y >= m.Height {
+		return
+	}
+	m.cells[int(y)*int(m.Width)+int(x)] = t
+}
+
+// validate makes sure something can be placed on the map
+func (m *Map) validate() error {
+	if m.Width == 0 || m.Height == 0 {
+		return errors.New("invalid map: it is empty")
+	}
+	for _, t := range m.cells {
+		if t.Passable() {
+			return nil
+		}
+	}
+	return errors.New("invalid map: there is nowhere to stand")
+}
+
+// Load reads a map from an ascii (.txt or .map) or png file
+func Load(path string) (*Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading map file: %v", err)
+	}
+	defer f.Close()
+
+	switch filepath.Ext(path) {
+	case ".txt", ".map":
+		return ParseASCII(f)
+	case ".png":
+		return ParsePNG(f)
+	default:
+		return nil, errors.New("map file must be .txt, .map or .png")
+	}
+}
+
+// ParseASCII reads a map drawn with one character per cell: '.' ground, '#'
+// wall, '~' water and ',' slow ground. The first line is the top row of the
+// world, the one with the highest y.
+func ParseASCII(r io.Reader) (*Map, error) {
+	rows := [][]Type{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) == 0 {
+			continue
+		}
+		row := []Type{}
+		for _, c := range line {
+			t, ok := asciiTypes[c]
+			if !ok {
+				return nil, fmt.Errorf("invalid map: unknown terrain %q on line %v", c, len(rows)+1)
+			}
+			row = append(row, t)
+		}
+		if len(rows) > 0 && len(row) != len(rows[0]) {
+			return nil, fmt.Errorf("invalid map: line %v is %v wide, want %v", len(rows)+1, len(row), len(rows[0]))
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error reading map: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("invalid map: it is empty")
+	}
+
+	m := New(uint32(len(rows[0])), uint32(len(rows)))
+	for i, row := range rows {
+		for x, t := range row {
+			m.Set(uint32(x), m.Height-1-uint32(i), t)
+		}
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ParsePNG reads a map with one pixel per cell: white ground, black wall, blue
+// (#0000ff) water and grey (#808080) slow ground. The top row of pixels is
+// the top row of the world, the one with the highest y.
+func ParsePNG(r io.Reader) (*Map, error) {
+	img, err := png.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading map: %v", err)
+	}
+
+	bounds := img.Bounds()
+	m := New(uint32(bounds.Dx()), uint32(bounds.Dy()))
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			c := color.RGBAModel.Convert(img.At(px, py)).(color.RGBA)
+			t, ok := pngTypes[c]
+			if !ok {
+				return nil, fmt.Errorf("invalid map: unknown terrain color #%02x%02x%02x at %v", c.R, c.G, c.B, image.Pt(px, py))
+			}
+			m.Set(uint32(px-bounds.Min.X), m.Height-1-uint32(py-bounds.Min.Y), t)
+		}
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
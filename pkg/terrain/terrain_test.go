@@ -0,0 +1,137 @@
+package terrain
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseASCII(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[[2]uint32]Type
+		wantErr bool
+	}{
+		{
+			name: "Reads every terrain with the first line on top",
+			data: "#~\n,.\n",
+			want: map[[2]uint32]Type{{0, 1}: Wall, {1, 1}: Water, {0, 0}: Slow, {1, 0}: Ground},
+		},
+		{
+			name: "Ignores windows line endings and blank lines",
+			data: "..\r\n#.\r\n\r\n",
+			want: map[[2]uint32]Type{{0, 0}: Wall, {1, 0}: Ground, {0, 1}: Ground},
+		},
+		{name: "Unknown terrain", data: "..\n.x\n", wantErr: true},
+		{name: "Ragged rows", data: "...\n..\n", wantErr: true},
+		{name: "Empty", data: "\n", wantErr: true},
+		{name: "Nowhere to stand", data: "#~\n~#\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseASCII(strings.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseASCII() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for pos, want := range tt.want {
+				if got := m.At(pos[0], pos[1]); got != want {
+					t.Errorf("At(%v, %v) = %v, want %v", pos[0], pos[1], got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePNG(t *testing.T) {
+	encode := func(pixels [][]color.RGBA) []byte {
+		img := image.NewRGBA(image.Rect(0, 0, len(pixels[0]), len(pixels)))
+		for y, row := range pixels {
+			for x, c := range row {
+				img.Set(x, y, c)
+			}
+		}
+		var buf bytes.Buffer
+		png.Encode(&buf, img)
+		return buf.Bytes()
+	}
+	white := color.RGBA{255, 255, 255, 255}
+	black := color.RGBA{0, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	grey := color.RGBA{128, 128, 128, 255}
+
+	m, err := ParsePNG(bytes.NewReader(encode([][]color.RGBA{
+		{black, blue, white},
+		{grey, white, white},
+	})))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Width != 3 || m.Height != 2 {
+		t.Errorf("got a %vx%v map, want 3x2", m.Width, m.Height)
+	}
+	want := map[[2]uint32]Type{{0, 1}: Wall, {1, 1}: Water, {2, 1}: Ground, {0, 0}: Slow, {1, 0}: Ground}
+	for pos, want := range want {
+		if got := m.At(pos[0], pos[1]); got != want {
+			t.Errorf("At(%v, %v) = %v, want %v", pos[0], pos[1], got, want)
+		}
+	}
+
+	if _, err := ParsePNG(bytes.NewReader(encode([][]color.RGBA{{white, {255, 0, 0, 255}}}))); err == nil {
+		t.Errorf("expected an error for an unknown color")
+	}
+	if _, err := ParsePNG(strings.NewReader("not a png")); err == nil {
+		t.Errorf("expected an error for a file that isn't a png")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "terrain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		file    string
+		data    string
+		wantErr bool
+	}{
+		{"maze.txt", "#.\n..\n", false},
+		{"island.map", "~.\n~~\n", false},
+		{"maze.json", "#.\n..\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			ioutil.WriteFile(path, []byte(tt.data), 0644)
+			m, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (m.Width != 2 || m.Height != 2) {
+				t.Errorf("got a %vx%v map, want 2x2", m.Width, m.Height)
+			}
+		})
+	}
+	if _, err := Load(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestNilMapIsGround(t *testing.T) {
+	var m *Map
+	if m.At(3, 4) != Ground {
+		t.Errorf("expected a nil map to be ground")
+	}
+	if New(2, 2).At(5, 0) != Ground {
+		t.Errorf("expected off the map to be ground")
+	}
+}
@@ -0,0 +1,312 @@
+package worldgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"sort"
+
+	"github.com/terrariumai/simulation/pkg/terrain"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// Cells across the biggest terrain features when no scale is given
+	defaultScale = 10
+	// Layers of finer noise added on top of the biggest features
+	octaves = 3
+)
+
+// Params shape a generated world. Fractions are of the whole world, so
+// Walls 0.2 turns roughly a fifth of the cells into walls.
+type Params struct {
+	// Size of the world, taken from the world being generated rather than
+	// params files
+	Width  uint32 `json:"-" yaml:"-"`
+	Height uint32 `json:"-" yaml:"-"`
+	// Whether the world wraps around, so terrain and patches are seamless
+	// across the edges
+	Torus bool `json:"-" yaml:"-"`
+	// Cells across the biggest terrain features, defaultScale if 0
+	Scale float64 `json:"scale" yaml:"scale"`
+	// Terrain coverage. Water fills the lowest ground, walls the highest and
+	// slow ground the hills just below the walls.
+	Walls      float64 `json:"walls" yaml:"walls"`
+	Water      float64 `json:"water" yaml:"water"`
+	SlowGround float64 `json:"slowGround" yaml:"slowGround"`
+	// Food patches, each a circle of the given radius around a random open
+	// cell, with FoodDensity of its open cells holding food
+	FoodPatches     uint32  `json:"foodPatches" yaml:"foodPatches"`
+	FoodPatchRadius uint32  `json:"foodPatchRadius" yaml:"foodPatchRadius"`
+	FoodDensity     float64 `json:"foodDensity" yaml:"foodDensity"`
+}
+
+// Position is a cell in the generated world
+type Position struct {
+	X uint32
+	Y uint32
+}
+
+// World is a generated layout, its terrain and where food starts
+type World struct {
+	Terrain *terrain.Map
+	Food    []Position
+}
+
+// LoadParams reads params from a json or yaml file, the size and topology
+// come from the world
+func LoadParams(path string) (Params, error) {
+	p := Params{}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return p, fmt.Errorf("Error reading generator file: %v", err)
+	}
+
+	switch filepath.Ext(path) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&p)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &p)
+	default:
+		err = errors.New("generator file must be .json, .yaml or .yml")
+	}
+	if err != nil {
+		return p, fmt.Errorf("Error parsing generator file: %v", err)
+	}
+	return p, nil
+}
+
+// validate checks that the params describe a world that can be generated
+func (p Params) validate() error {
+	if p.Width == 0 || p.Height == 0 {
+		return errors.New("invalid params: the world is empty")
+	}
+	if p.Scale < 0 {
+		return errors.New("invalid params: scale can't be negative")
+	}
+	for _, f := range []float64{p.Walls, p.Water, p.SlowGround, p.FoodDensity} {
+		if f < 0 || f > 1 {
+			return fmt.Errorf("invalid params: fraction %v isn't between 0 and 1", f)
+		}
+	}
+	if p.Walls+p.Water >= 1 {
+		return errors.New("invalid params: walls and water leave nowhere to stand")
+	}
+	if p.Walls+p.Water+p.SlowGround > 1 {
+		return errors.New("invalid params: terrain covers more than the world")
+	}
+	// Placing food takes a pass over every patch's square, keep both in bounds
+	if p.FoodPatchRadius > p.Width || p.FoodPatchRadius > p.Height {
+		return errors.New("invalid params: food patches are wider than the world")
+	}
+	if uint64(p.FoodPatches) > uint64(p.Width)*uint64(p.Height) {
+		return errors.New("invalid params: more food patches than cells")
+	}
+	return nil
+}
+
+// Generate builds a world from a seed. The same seed and params always build
+// the same world. Every open cell can be walked to from every other, pockets
+// cut off by walls or water are walled in.
+func Generate(seed int64, p Params) (*World, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if p.Scale == 0 {
+		p.Scale = defaultScale
+	}
+	r := rand.New(rand.NewSource(seed))
+
+	m := generateTerrain(r, p)
+	open := connect(m, p.Torus)
+	return &World{
+		Terrain: m,
+		Food:    placeFood(r, p, m, open),
+	}, nil
+}
+
+// generateTerrain lays terrain out by the height of a noise field
+func generateTerrain(r *rand.Rand, p Params) *terrain.Map {
+	heights := noise(r, p)
+
+	// Cut the heights where the fractions of the world fall
+	sorted := append([]float64{}, heights...)
+	sort.Float64s(sorted)
+	cut := func(fraction float64) float64 {
+		i := int(fraction * float64(len(sorted)))
+		if i <= 0 {
+			return math.Inf(-1)
+		}
+		if i >= len(sorted) {
+			return math.Inf(1)
+		}
+		return sorted[i]
+	}
+	waterLine := cut(p.Water)
+	slowLine := cut(1 - p.Walls - p.SlowGround)
+	wallLine := cut(1 - p.Walls)
+
+	m := terrain.New(p.Width, p.Height)
+	for i, h := range heights {
+		x, y := uint32(i)%p.Width, uint32(i)/p.Width
+		switch {
+		case h < waterLine:
+			m.Set(x, y, terrain.Water)
+		case h >= wallLine:
+			m.Set(x, y, terrain.Wall)
+		case h >= slowLine:
+			m.Set(x, y, terrain.Slow)
+		}
+	}
+	return m
+}
+
+// noise is smoothed value noise, a height for every cell row by row. Each
+// octave is a lattice of random heights twice as fine and half as tall as
+// the last, interpolated between its points.
+func noise(r *rand.Rand, p Params) []float64 {
+	heights := make([]float64, int(p.Width)*int(p.Height))
+	amplitude := 1.0
+	for o := uint(0); o < octaves; o++ {
+		// Lattice cells across the world on each axis
+		nx := int(math.Max(1, math.Round(float64(p.Width)/p.Scale))) << o
+		ny := int(math.Max(1, math.Round(float64(p.Height)/p.Scale))) << o
+		lattice := make([]float64, (nx+1)*(ny+1))
+		for i := range lattice {
+			lattice[i] = r.Float64()
+		}
+		at := func(i int, j int) float64 {
+			// The far edge of a torus is its near edge
+			if p.Torus {
+				i, j = i%nx, j%ny
+			}
+			return lattice[j*(nx+1)+i]
+		}
+		for y := 0; y < int(p.Height); y++ {
+			fy := float64(y) * float64(ny) / float64(p.Height)
+			j, ty := int(fy), smooth(fy-math.Floor(fy))
+			for x := 0; x < int(p.Width); x++ {
+				fx := float64(x) * float64(nx) / float64(p.Width)
+				i, tx := int(fx), smooth(fx-math.Floor(fx))
+				bottom := lerp(at(i, j), at(i+1, j), tx)
+				top := lerp(at(i, j+1), at(i+1, j+1), tx)
+				heights[y*int(p.Width)+x] += amplitude * lerp(bottom, top, ty)
+			}
+		}
+		amplitude /= 2
+	}
+	return heights
+}
+
+// smooth eases t between 0 and 1 so the lattice doesn't show
+func smooth(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
+
+func lerp(a float64, b float64, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// connect keeps the biggest area of open cells and walls in the rest, so
+// anywhere can be walked to. It returns the open cells left.
+func connect(m *terrain.Map, torus bool) []Position {
+	seen := make([]bool, int(m.Width)*int(m.Height))
+	var biggest []Position
+	for y := uint32(0); y < m.Height; y++ {
+		for x := uint32(0); x < m.Width; x++ {
+			if seen[int(y)*int(m.Width)+int(x)] || !m.At(x, y).Passable() {
+				continue
+			}
+			area := fill(m, torus, seen, Position{x, y})
+			if len(area) > len(biggest) {
+				for _, pos := range biggest {
+					m.Set(pos.X, pos.Y, terrain.Wall)
+				}
+				biggest = area
+			} else {
+				for _, pos := range area {
+					m.Set(pos.X, pos.Y, terrain.Wall)
+				}
+			}
+		}
+	}
+	// Keep the open cells in the order they are laid out
+	sort.Slice(biggest, func(i, j int) bool {
+		if biggest[i].Y != biggest[j].Y {
+			return biggest[i].Y < biggest[j].Y
+		}
+		return biggest[i].X < biggest[j].X
+	})
+	return biggest
+}
+
+// fill collects the open cells that can be walked to from start
+func fill(m *terrain.Map, torus bool, seen []bool, start Position) []Position {
+	area := []Position{}
+	stack := []Position{start}
+	seen[int(start.Y)*int(m.Width)+int(start.X)] = true
+	for len(stack) > 0 {
+		pos := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		area = append(area, pos)
+		for _, d := range [][2]int64{{0, 1}, {1, 0}, {0, -1}, {-1, 0}} {
+			next, ok := step(m, torus, pos, d[0], d[1])
+			if !ok || seen[int(next.Y)*int(m.Width)+int(next.X)] || !m.At(next.X, next.Y).Passable() {
+				continue
+			}
+			seen[int(next.Y)*int(m.Width)+int(next.X)] = true
+			stack = append(stack, next)
+		}
+	}
+	return area
+}
+
+// step moves from a cell, wrapping around the edges of a torus
+func step(m *terrain.Map, torus bool, pos Position, dx int64, dy int64) (Position, bool) {
+	x, y := int64(pos.X)+dx, int64(pos.Y)+dy
+	w, h := int64(m.Width), int64(m.Height)
+	if torus {
+		return Position{uint32((x%w + w) % w), uint32((y%h + h) % h)}, true
+	}
+	if x < 0 || y < 0 || x >= w || y >= h {
+		return Position{}, false
+	}
+	return Position{uint32(x), uint32(y)}, true
+}
+
+// placeFood scatters food in patches around random open cells
+func placeFood(r *rand.Rand, p Params, m *terrain.Map, open []Position) []Position {
+	food := []Position{}
+	if len(open) == 0 {
+		return food
+	}
+	taken := make(map[Position]bool)
+	radius := int64(p.FoodPatchRadius)
+	for i := uint32(0); i < p.FoodPatches; i++ {
+		center := open[r.Intn(len(open))]
+		for dy := -radius; dy <= radius; dy++ {
+			for dx := -radius; dx <= radius; dx++ {
+				if dx*dx+dy*dy > radius*radius {
+					continue
+				}
+				// Roll for every cell so the layout doesn't depend on the terrain
+				fed := r.Float64() < p.FoodDensity
+				pos, ok := step(m, p.Torus, center, dx, dy)
+				if !ok || !fed || taken[pos] || !m.At(pos.X, pos.Y).Passable() {
+					continue
+				}
+				taken[pos] = true
+				food = append(food, pos)
+			}
+		}
+	}
+	return food
+}
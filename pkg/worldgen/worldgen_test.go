@@ -0,0 +1,186 @@
+package worldgen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/terrariumai/simulation/pkg/terrain"
+)
+
+var islands = Params{
+	Width:           60,
+	Height:          40,
+	Walls:           0.2,
+	Water:           0.25,
+	SlowGround:      0.1,
+	FoodPatches:     5,
+	FoodPatchRadius: 3,
+	FoodDensity:     0.5,
+}
+
+func TestGenerate(t *testing.T) {
+	torus := islands
+	torus.Torus = true
+	tests := []struct {
+		name   string
+		params Params
+	}{
+		{"Bounded", islands},
+		{"Torus", torus},
+		{"All ground", Params{Width: 10, Height: 10, Torus: true, FoodPatches: 1, FoodPatchRadius: 10, FoodDensity: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := Generate(7, tt.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if w.Terrain.Width != tt.params.Width || w.Terrain.Height != tt.params.Height {
+				t.Fatalf("terrain is %vx%v, want %vx%v", w.Terrain.Width, w.Terrain.Height, tt.params.Width, tt.params.Height)
+			}
+
+			// Every open cell can be walked to from the first one
+			seen := make([]bool, int(w.Terrain.Width)*int(w.Terrain.Height))
+			open := []Position{}
+			for y := uint32(0); y < w.Terrain.Height; y++ {
+				for x := uint32(0); x < w.Terrain.Width; x++ {
+					if w.Terrain.At(x, y).Passable() {
+						open = append(open, Position{x, y})
+					}
+				}
+			}
+			if len(open) == 0 {
+				t.Fatalf("expected somewhere to stand")
+			}
+			if reached := fill(w.Terrain, tt.params.Torus, seen, open[0]); len(reached) != len(open) {
+				t.Errorf("reached %v of %v open cells", len(reached), len(open))
+			}
+
+			if len(w.Food) == 0 {
+				t.Errorf("expected food")
+			}
+			taken := make(map[Position]bool)
+			for _, pos := range w.Food {
+				if !w.Terrain.At(pos.X, pos.Y).Passable() || taken[pos] {
+					t.Errorf("food at %v is on %v or doubled up", pos, w.Terrain.At(pos.X, pos.Y))
+				}
+				taken[pos] = true
+			}
+		})
+	}
+
+	// Fully packed food covers the whole of a small open world
+	w, _ := Generate(7, tests[2].params)
+	if len(w.Food) != 100 {
+		t.Errorf("got %v food, want 100", len(w.Food))
+	}
+}
+
+func TestGenerateCoverage(t *testing.T) {
+	w, _ := Generate(3, islands)
+	counts := make(map[terrain.Type]int)
+	for y := uint32(0); y < w.Terrain.Height; y++ {
+		for x := uint32(0); x < w.Terrain.Width; x++ {
+			counts[w.Terrain.At(x, y)]++
+		}
+	}
+	cells := float64(islands.Width * islands.Height)
+	// Walled in pockets add to the walls, and take from everything else
+	if got := float64(counts[terrain.Water]) / cells; got > islands.Water+0.01 || got < islands.Water-0.1 {
+		t.Errorf("water covers %v, want about %v", got, islands.Water)
+	}
+	if got := float64(counts[terrain.Wall]) / cells; got < islands.Walls-0.01 || got > islands.Walls+0.2 {
+		t.Errorf("walls cover %v, want about %v", got, islands.Walls)
+	}
+	if counts[terrain.Slow] == 0 {
+		t.Errorf("expected slow ground")
+	}
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	first, _ := Generate(42, islands)
+	second, _ := Generate(42, islands)
+	other, _ := Generate(43, islands)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("the same seed generated different worlds")
+	}
+	if reflect.DeepEqual(first, other) {
+		t.Errorf("different seeds generated the same world")
+	}
+}
+
+func TestParamsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Params
+	}{
+		{"Empty world", Params{Width: 0, Height: 10}},
+		{"Negative scale", Params{Width: 10, Height: 10, Scale: -1}},
+		{"Fraction over 1", Params{Width: 10, Height: 10, FoodDensity: 1.5}},
+		{"Nowhere to stand", Params{Width: 10, Height: 10, Walls: 0.5, Water: 0.5}},
+		{"Too much terrain", Params{Width: 10, Height: 10, Walls: 0.4, Water: 0.4, SlowGround: 0.4}},
+		{"Food patches bigger than the world", Params{Width: 10, Height: 20, FoodPatches: 1, FoodPatchRadius: 21}},
+		{"Food patches wider than the world", Params{Width: 10, Height: 20, FoodPatches: 1, FoodPatchRadius: 15}},
+		{"More food patches than cells", Params{Width: 10, Height: 10, FoodPatches: 101, FoodPatchRadius: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Generate(1, tt.params); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadParams(t *testing.T) {
+	dir, err := ioutil.TempDir("", "worldgen")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		want     Params
+		wantErr  bool
+	}{
+		{
+			name:     "Json",
+			filename: "params.json",
+			content:  `{"walls": 0.2, "foodPatches": 3, "foodDensity": 0.5}`,
+			want:     Params{Walls: 0.2, FoodPatches: 3, FoodDensity: 0.5},
+		},
+		{
+			name:     "Yaml",
+			filename: "params.yaml",
+			content:  "water: 0.3\nslowGround: 0.1\nscale: 20\n",
+			want:     Params{Water: 0.3, SlowGround: 0.1, Scale: 20},
+		},
+		{name: "The size comes from the world", filename: "size.json", content: `{"Width": 10}`, wantErr: true},
+		{name: "Unknown param", filename: "unknown.yaml", content: "mountains: 0.1\n", wantErr: true},
+		{name: "Unknown extension", filename: "params.txt", content: "walls: 0.1\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.filename)
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("error writing params file: %v", err)
+			}
+			got, err := LoadParams(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}